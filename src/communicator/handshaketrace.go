@@ -0,0 +1,84 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// this package implement base communicator for network connections.
+package communicator
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// HandshakeTrace logs each step of setting up a data channel at trace level, stamped with the
+// time since the trace began, to show where a connection that hangs has stalled. A nil
+// *HandshakeTrace logs nothing.
+type HandshakeTrace struct {
+	log   log.T
+	start time.Time
+}
+
+// NewHandshakeTrace starts a trace logging to log.
+func NewHandshakeTrace(log log.T) *HandshakeTrace {
+	return &HandshakeTrace{log: log, start: time.Now()}
+}
+
+// Step logs one handshake step.
+func (t *HandshakeTrace) Step(format string, params ...interface{}) {
+	if t == nil {
+		return
+	}
+	t.log.Tracef("[ws-trace] +%v %s", time.Since(t.start).Round(time.Millisecond), fmt.Sprintf(format, params...))
+}
+
+// withClientTrace returns ctx with hooks that trace the websocket dial: DNS, TCP connect, TLS
+// and the upgrade response.
+func (t *HandshakeTrace) withClientTrace(ctx context.Context) context.Context {
+	if t == nil {
+		return ctx
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(hostPort string) { t.Step("dialing %s", hostPort) },
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			t.Step("resolving %s", info.Host)
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			if info.Err != nil {
+				t.Step("resolve failed: %v", info.Err)
+			} else {
+				t.Step("resolved to %v", info.Addrs)
+			}
+		},
+		ConnectStart: func(network, addr string) { t.Step("connecting to %s", addr) },
+		ConnectDone: func(network, addr string, err error) {
+			if err != nil {
+				t.Step("connect to %s failed: %v", addr, err)
+			} else {
+				t.Step("connected to %s", addr)
+			}
+		},
+		TLSHandshakeStart: func() { t.Step("TLS handshake started") },
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err != nil {
+				t.Step("TLS handshake failed: %v", err)
+			} else {
+				t.Step("TLS handshake done (%s)", tls.VersionName(state.Version))
+			}
+		},
+		GotFirstResponseByte: func() { t.Step("upgrade response started") },
+	})
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// this package implement base communicator for network connections.
+package communicator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// tracedSteps returns the steps a HandshakeTrace logged to mockLog.
+func tracedSteps(mockLog *log.Mock) []string {
+	var steps []string
+	for _, call := range mockLog.Calls {
+		if call.Method == "Tracef" && call.Arguments.String(0) == "[ws-trace] +%v %s" {
+			steps = append(steps, call.Arguments.Get(1).([]interface{})[1].(string))
+		}
+	}
+	return steps
+}
+
+// WHEN a traced websocket channel opens, THEN each dial step SHALL be logged in order.
+func TestOpenWebSocketChannelHandshakeTrace(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(handlerToBeTested))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	u.Scheme = "ws"
+	traceLog := log.NewMockLog()
+
+	websocketchannel := WebSocketChannel{Url: u.String()}
+	websocketchannel.SetHandshakeTrace(NewHandshakeTrace(traceLog))
+	assert.Nil(t, websocketchannel.Open(log.NewMockLog()))
+	defer websocketchannel.Close(log.NewMockLog())
+
+	steps := strings.Join(tracedSteps(traceLog), "\n")
+	assert.Regexp(t, `(?s)dialing .*connecting to .*connected to .*upgrade response started.*websocket upgraded`, steps)
+}
+
+// WHEN the websocket cannot be reached, THEN the trace SHALL end with the failed step.
+func TestOpenWebSocketChannelHandshakeTraceFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(handlerToBeTested))
+	u, _ := url.Parse(srv.URL)
+	u.Scheme = "ws"
+	srv.Close()
+	traceLog := log.NewMockLog()
+
+	websocketchannel := WebSocketChannel{Url: u.String(), HandshakeTrace: NewHandshakeTrace(traceLog)}
+	assert.NotNil(t, websocketchannel.Open(log.NewMockLog()))
+
+	steps := tracedSteps(traceLog)
+	assert.Contains(t, strings.Join(steps, "\n"), "connect to "+u.Host+" failed")
+	assert.Contains(t, steps[len(steps)-1], "websocket dial failed")
+}
+
+// WHEN no trace is set, THEN tracing SHALL do nothing.
+func TestHandshakeTraceNil(t *testing.T) {
+	var trace *HandshakeTrace
+	trace.Step("not logged")
+	ctx := context.Background()
+	assert.Equal(t, ctx, trace.withClientTrace(ctx))
+}
@@ -5,6 +5,7 @@ package mocks
 
 import (
 	mock "github.com/stretchr/testify/mock"
+	communicator "github.com/zph/session-manager-plugin/src/communicator"
 	log "github.com/zph/session-manager-plugin/src/log"
 
 	time "time"
@@ -95,6 +96,16 @@ func (_m *IWebSocketChannel) SetChannelToken(_a0 string) {
 	_m.Called(_a0)
 }
 
+// SetDialTimeout provides a mock function with given fields: timeout
+func (_m *IWebSocketChannel) SetDialTimeout(timeout time.Duration) {
+	_m.Called(timeout)
+}
+
+// SetHandshakeTrace provides a mock function with given fields: trace
+func (_m *IWebSocketChannel) SetHandshakeTrace(trace *communicator.HandshakeTrace) {
+	_m.Called(trace)
+}
+
 // SetOnError provides a mock function with given fields: onErrorHandler
 func (_m *IWebSocketChannel) SetOnError(onErrorHandler func(error)) {
 	_m.Called(onErrorHandler)
@@ -15,7 +15,10 @@
 package communicator
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -38,6 +41,8 @@ type IWebSocketChannel interface {
 	SetChannelToken(string)
 	SetOnError(onErrorHandler func(error))
 	SetOnMessage(onMessageHandler func([]byte))
+	SetDialTimeout(timeout time.Duration)
+	SetHandshakeTrace(trace *HandshakeTrace)
 }
 
 // WebSocketChannel parent class for DataChannel.
@@ -50,6 +55,11 @@ type WebSocketChannel struct {
 	writeLock    *sync.Mutex
 	Connection   *websocket.Conn
 	ChannelToken string
+	// DialTimeout bounds connecting to Url and completing the websocket handshake; zero keeps
+	// the dialer's default handshake timeout
+	DialTimeout time.Duration
+	// HandshakeTrace, if set, logs each step of opening the connection
+	HandshakeTrace *HandshakeTrace
 }
 
 // IsOpen returns true if the websocket connection is open.
@@ -93,6 +103,16 @@ func (webSocketChannel *WebSocketChannel) SetOnMessage(onMessageHandler func([]b
 	webSocketChannel.OnMessage = onMessageHandler
 }
 
+// SetDialTimeout sets DialTimeout field of websocket channel
+func (webSocketChannel *WebSocketChannel) SetDialTimeout(timeout time.Duration) {
+	webSocketChannel.DialTimeout = timeout
+}
+
+// SetHandshakeTrace sets HandshakeTrace field of websocket channel
+func (webSocketChannel *WebSocketChannel) SetHandshakeTrace(trace *HandshakeTrace) {
+	webSocketChannel.HandshakeTrace = trace
+}
+
 // Initialize initializes websocket channel fields
 func (webSocketChannel *WebSocketChannel) Initialize(log log.T, channelUrl string, channelToken string) {
 	webSocketChannel.ChannelToken = channelToken
@@ -157,10 +177,24 @@ func (webSocketChannel *WebSocketChannel) Open(log log.T) error {
 	// initialize the write mutex
 	webSocketChannel.writeLock = &sync.Mutex{}
 
-	ws, err := websocketutil.NewWebsocketUtil(log, nil).OpenConnection(webSocketChannel.Url)
+	var dialer *websocket.Dialer
+	if webSocketChannel.DialTimeout > 0 {
+		// The handshake timeout bounds the whole dial, including DNS, TCP and TLS
+		custom := *websocket.DefaultDialer
+		custom.HandshakeTimeout = webSocketChannel.DialTimeout
+		dialer = &custom
+	}
+	ctx := webSocketChannel.HandshakeTrace.withClientTrace(context.Background())
+	ws, err := websocketutil.NewWebsocketUtil(log, dialer).OpenConnectionContext(ctx, webSocketChannel.Url)
 	if err != nil {
+		webSocketChannel.HandshakeTrace.Step("websocket dial failed: %v", err)
+		var netErr net.Error
+		if webSocketChannel.DialTimeout > 0 && errors.As(err, &netErr) && netErr.Timeout() {
+			return fmt.Errorf("failed to connect to stream URL within %v: %w", webSocketChannel.DialTimeout, err)
+		}
 		return err
 	}
+	webSocketChannel.HandshakeTrace.Step("websocket upgraded")
 	webSocketChannel.Connection = ws
 	webSocketChannel.setOpen(true)
 	webSocketChannel.StartPings(log, config.PingTimeInterval)
@@ -17,11 +17,13 @@ package communicator
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
@@ -169,6 +171,33 @@ func TestOpenCloseWebSocketChannel(t *testing.T) {
 	t.Log("Ending test: TestOpenCloseWebSocketChannel")
 }
 
+// WHEN the stream URL accepts TCP connections but never completes the websocket handshake, THEN
+// Open SHALL fail once DialTimeout has passed with an error naming the timeout.
+func TestOpenWebSocketChannelDialTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	websocketchannel := WebSocketChannel{
+		Url:         "ws://" + listener.Addr().String(),
+		DialTimeout: 100 * time.Millisecond,
+	}
+	started := time.Now()
+	err = websocketchannel.Open(log.NewMockLog())
+	assert.ErrorContains(t, err, "failed to connect to stream URL within 100ms")
+	assert.Less(t, time.Since(started), 5*time.Second)
+	assert.False(t, websocketchannel.IsOpen())
+}
+
 func TestReadWriteTextToWebSocketChannel(t *testing.T) {
 	t.Log("Starting test: TestReadWriteWebSocketChannel ")
 	srv := httptest.NewServer(http.HandlerFunc(handlerToBeTested))
@@ -37,6 +37,8 @@ const (
 	DataChannelRetryMaxIntervalMillis  = 5000
 	RetryAttempt                       = 5
 	PingTimeInterval                   = 5 * time.Minute
+	MinCopyBufferSize                  = StreamDataPayloadSize
+	MaxCopyBufferSize                  = 4 * 1024 * 1024
 
 	// Plugin names
 	ShellPluginName                  = "Standard_Stream"
@@ -48,4 +50,6 @@ const (
 	TerminateSessionFlagSupportedAfterThisAgentVersion            = "2.3.722.0"
 	TCPMultiplexingSupportedAfterThisAgentVersion                 = "3.0.196.0"
 	TCPMultiplexingWithSmuxKeepAliveDisabledAfterThisAgentVersion = "3.1.1511.0"
+	// AWS-StartPortForwardingSessionToRemoteHost is documented to need agent 3.1.1374.0 or later
+	RemoteHostPortForwardingSupportedAfterThisAgentVersion = "3.1.1373.0"
 )
@@ -13,6 +13,8 @@ import (
 	message "github.com/zph/session-manager-plugin/src/message"
 
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // IDataChannel is an autogenerated mock type for the IDataChannel type
@@ -289,6 +291,24 @@ func (_m *IDataChannel) Open(_a0 log.T) error {
 	return r0
 }
 
+// OutgoingBufferDepth provides a mock function with no fields
+func (_m *IDataChannel) OutgoingBufferDepth() int {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for OutgoingBufferDepth")
+	}
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
 // OutputMessageHandler provides a mock function with given fields: _a0, stopHandler, sessionID, rawMessage
 func (_m *IDataChannel) OutputMessageHandler(_a0 log.T, stopHandler datachannel.Stop, sessionID string, rawMessage []byte) error {
 	ret := _m.Called(_a0, stopHandler, sessionID, rawMessage)
@@ -468,6 +488,16 @@ func (_m *IDataChannel) SetWsChannel(wsChannel communicator.IWebSocketChannel) {
 	_m.Called(wsChannel)
 }
 
+// SetHandshakeTrace provides a mock function with given fields: trace
+func (_m *IDataChannel) SetHandshakeTrace(trace *communicator.HandshakeTrace) {
+	_m.Called(trace)
+}
+
+// StartKeepalive provides a mock function with given fields: _a0, interval
+func (_m *IDataChannel) StartKeepalive(_a0 log.T, interval time.Duration) {
+	_m.Called(_a0, interval)
+}
+
 // NewIDataChannel creates a new instance of IDataChannel. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewIDataChannel(t interface {
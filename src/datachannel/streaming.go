@@ -22,8 +22,11 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/rand/v2"
 	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -49,11 +52,13 @@ type IDataChannel interface {
 	FinalizeDataChannelHandshake(log log.T, tokenValue string) error
 	SendInputDataMessage(log log.T, payloadType message.PayloadType, inputData []byte) error
 	ResendStreamDataMessageScheduler(log log.T) error
+	StartKeepalive(log log.T, interval time.Duration)
 	ProcessAcknowledgedMessage(log log.T, acknowledgeMessageContent message.AcknowledgeContent) error
 	OutputMessageHandler(log log.T, stopHandler Stop, sessionID string, rawMessage []byte) error
 	SendAcknowledgeMessage(log log.T, clientMessage message.ClientMessage) error
 	AddDataToOutgoingMessageBuffer(streamMessage StreamingMessage)
 	RemoveDataFromOutgoingMessageBuffer(streamMessageElement *list.Element)
+	OutgoingBufferDepth() int
 	AddDataToIncomingMessageBuffer(streamMessage StreamingMessage)
 	RemoveDataFromIncomingMessageBuffer(sequenceNumber int64)
 	CalculateRetransmissionTimeout(log log.T, streamingMessage StreamingMessage)
@@ -69,6 +74,7 @@ type IDataChannel interface {
 	GetSessionProperties() interface{}
 	GetWsChannel() communicator.IWebSocketChannel
 	SetWsChannel(wsChannel communicator.IWebSocketChannel)
+	SetHandshakeTrace(trace *communicator.HandshakeTrace)
 	GetStartPublicationReceived() <-chan struct{}
 	GetStreamDataSequenceNumber() int64
 	GetAgentVersion() string
@@ -90,6 +96,10 @@ type DataChannel struct {
 	//buffer to store outgoing stream messages until acknowledged
 	//using linked list for this buffer as access to oldest message is required and it support faster deletion from any position of list
 	OutgoingMessageBuffer ListMessageBuffer
+	//warn when more than this many outgoing messages await acknowledgement (0 = never)
+	OutgoingBufferHighWater int
+	//latest acknowledgement sent, repeated as a keepalive
+	lastAcknowledged atomic.Pointer[message.AcknowledgeContent]
 	//buffer to store incoming stream messages if received out of sequence
 	//using map for this buffer as incoming messages can be out of order and retrieval would be faster by sequenceId
 	IncomingMessageBuffer MapMessageBuffer
@@ -119,11 +129,18 @@ type DataChannel struct {
 
 	// AgentVersion received during handshake
 	agentVersion string
+	// handshakeActions are the agent's requested actions and how each was processed, e.g. "SessionType:Success"
+	handshakeActions []string
 
 	// READY-007: Closed when StartPublicationMessage is received from agent
 	startPublicationReceived chan struct{}
 	startPublicationOnce     sync.Once
 
+	// handshakeTrace, if set, logs each step of opening the data channel
+	handshakeTrace *communicator.HandshakeTrace
+	// agentMessageTraced is set once the first message from the agent has been traced
+	agentMessageTraced atomic.Bool
+
 	mutex sync.Mutex
 }
 
@@ -228,7 +245,12 @@ func (dataChannel *DataChannel) FinalizeDataChannelHandshake(log log.T, tokenVal
 		log.Errorf("Error serializing openDataChannelInput: %s", err)
 		return
 	}
-	return dataChannel.SendMessage(log, openDataChannelInputBytes, websocket.TextMessage)
+	if err = dataChannel.SendMessage(log, openDataChannelInputBytes, websocket.TextMessage); err != nil {
+		dataChannel.handshakeTrace.Step("sending open data channel message failed: %v", err)
+		return
+	}
+	dataChannel.handshakeTrace.Step("open data channel message sent, waiting for the agent")
+	return
 }
 
 // SendMessage sends a message to the service through datachannel
@@ -346,8 +368,10 @@ func (dataChannel *DataChannel) SendInputDataMessage(
 // and resends first message if time elapsed since lastSentTime of the message is more than acknowledge wait time
 func (dataChannel *DataChannel) ResendStreamDataMessageScheduler(log log.T) (err error) {
 	go func() {
+		aboveHighWater := false
 		for {
 			time.Sleep(config.ResendSleepInterval)
+			aboveHighWater = dataChannel.watchOutgoingBufferDepth(log, aboveHighWater)
 			dataChannel.OutgoingMessageBuffer.Mutex.Lock()
 			streamMessageElement := dataChannel.OutgoingMessageBuffer.Messages.Front()
 			dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
@@ -379,6 +403,60 @@ func (dataChannel *DataChannel) ResendStreamDataMessageScheduler(log log.T) (err
 	return
 }
 
+// StartKeepalive spawns a separate go thread which repeats the latest acknowledgement about every
+// interval until the session ends, so proxies that close connections without application traffic,
+// despite websocket pings, see data flow. The agent ignores acknowledgements of messages it no
+// longer holds, and stream data messages can't carry an empty payload. Each wait varies by up to
+// a fifth of interval so many clients don't send in step.
+func (dataChannel *DataChannel) StartKeepalive(log log.T, interval time.Duration) {
+	go func() {
+		for {
+			time.Sleep(keepaliveDelay(interval))
+			if dataChannel.IsSessionEnded() {
+				return
+			}
+			acknowledged := dataChannel.lastAcknowledged.Load()
+			if acknowledged == nil {
+				continue
+			}
+			msg, err := message.SerializeClientMessageWithAcknowledgeContent(log, *acknowledged)
+			if err == nil {
+				err = SendMessageCall(log, dataChannel, msg, websocket.BinaryMessage)
+			}
+			if err != nil {
+				log.Debugf("Unable to send keepalive message: %v", err)
+			}
+		}
+	}()
+}
+
+// keepaliveDelay returns interval varied uniformly by up to a fifth either way.
+func keepaliveDelay(interval time.Duration) time.Duration {
+	jitter := interval / 5
+	return interval - jitter + rand.N(2*jitter+1)
+}
+
+// watchOutgoingBufferDepth warns when the outgoing buffer grows past OutgoingBufferHighWater, which
+// means data is sent faster than MGS acknowledges it. above reports whether the previous check had
+// already warned; the warning is repeated only after the buffer drains to half the mark.
+func (dataChannel *DataChannel) watchOutgoingBufferDepth(log log.T, above bool) bool {
+	highWater := dataChannel.OutgoingBufferHighWater
+	if highWater <= 0 {
+		return false
+	}
+	depth := dataChannel.OutgoingBufferDepth()
+	switch {
+	case !above && depth > highWater:
+		log.Warnf("Outgoing message buffer holds %d unacknowledged messages (high-water mark %d, capacity %d); data is sent faster than it is acknowledged",
+			depth, highWater, dataChannel.OutgoingMessageBuffer.Capacity)
+		return true
+	case above && depth <= highWater/2:
+		log.Infof("Outgoing message buffer drained to %d unacknowledged messages", depth)
+		return false
+	}
+	return above
+}
+
 // ProcessAcknowledgedMessage processes acknowledge messages by deleting them from OutgoingMessageBuffer
 func (dataChannel *DataChannel) ProcessAcknowledgedMessage(log log.T, acknowledgeMessageContent message.AcknowledgeContent) error {
 	dataChannel.mutex.Lock()
@@ -418,6 +496,7 @@ func (dataChannel *DataChannel) SendAcknowledgeMessage(log log.T, streamDataMess
 		log.Errorf("Error sending acknowledge message %v", err)
 		return
 	}
+	dataChannel.lastAcknowledged.Store(&dataStreamAcknowledgeContent)
 	return
 }
 
@@ -435,6 +514,9 @@ func (dataChannel *DataChannel) OutputMessageHandler(log log.T, stopHandler Stop
 	}
 
 	log.Tracef("Processing stream data message of type: %s", outputMessage.MessageType)
+	if dataChannel.agentMessageTraced.CompareAndSwap(false, true) {
+		dataChannel.handshakeTrace.Step("first message from the agent: %s", outputMessage.MessageType)
+	}
 	switch outputMessage.MessageType {
 	case message.OutputStreamMessage:
 		return dataChannel.HandleOutputMessage(log, *outputMessage, rawMessage)
@@ -445,6 +527,7 @@ func (dataChannel *DataChannel) OutputMessageHandler(log log.T, stopHandler Stop
 	case message.StartPublicationMessage:
 		// READY-007: Signal that agent is ready for data transfer
 		dataChannel.startPublicationOnce.Do(func() {
+			dataChannel.handshakeTrace.Step("agent ready: start_publication received")
 			close(dataChannel.startPublicationReceived)
 		})
 		return nil
@@ -467,6 +550,7 @@ func (dataChannel *DataChannel) handleHandshakeRequest(log log.T, clientMessage
 	}
 
 	dataChannel.agentVersion = handshakeRequest.AgentVersion
+	dataChannel.handshakeTrace.Step("handshake request received from agent %s", handshakeRequest.AgentVersion)
 
 	var errorList []error
 	var handshakeResponse message.HandshakeResponsePayload
@@ -509,6 +593,7 @@ func (dataChannel *DataChannel) handleHandshakeRequest(log log.T, clientMessage
 			errorList = append(errorList, errors.New(processedAction.Error))
 		}
 		handshakeResponse.ProcessedClientActions = append(handshakeResponse.ProcessedClientActions, processedAction)
+		dataChannel.handshakeActions = append(dataChannel.handshakeActions, handshakeActionSummary(processedAction))
 	}
 	for _, x := range errorList {
 		handshakeResponse.Errors = append(handshakeResponse.Errors, x.Error())
@@ -534,12 +619,43 @@ func (dataChannel *DataChannel) handleHandshakeComplete(log log.T, clientMessage
 		dataChannel.isSessionTypeSet <- false
 	}
 
+	dataChannel.handshakeTrace.Step("handshake complete")
 	log.Debugf("Handshake Complete. Handshake time to complete is: %s seconds",
 		handshakeComplete.HandshakeTimeToComplete.Seconds())
+	dataChannel.logNegotiation(log, clientMessage.SchemaVersion)
 
 	return err
 }
 
+// logNegotiation logs what the handshake settled on in one debug line, for comparing targets that
+// behave differently. The agent's details are only known here, not when the data channel is initialized.
+func (dataChannel *DataChannel) logNegotiation(log log.T, schemaVersion uint32) {
+	actions := "none"
+	if len(dataChannel.handshakeActions) > 0 {
+		actions = strings.Join(dataChannel.handshakeActions, ",")
+	}
+	agentVersion := dataChannel.agentVersion
+	log.Debugf("Handshake negotiated: agent_version=%s client_version=%s schema_version=%d session_type=%s "+
+		"encryption=%t actions=%s tcp_multiplexing=%t smux_keepalive_disabled=%t remote_host_forwarding=%t terminate_session_flag=%t",
+		agentVersion, version.Version, schemaVersion, dataChannel.sessionType, dataChannel.encryptionEnabled, actions,
+		version.DoesAgentSupportTCPMultiplexing(log, agentVersion),
+		version.DoesAgentSupportDisableSmuxKeepAlive(log, agentVersion),
+		version.DoesAgentSupportRemoteHostPortForwarding(log, agentVersion),
+		version.DoesAgentSupportTerminateSessionFlag(log, agentVersion))
+}
+
+// handshakeActionSummary describes a processed handshake action as "ActionType:status".
+func handshakeActionSummary(action message.ProcessedClientAction) string {
+	status := "Unsupported"
+	switch action.ActionStatus {
+	case message.Success:
+		status = "Success"
+	case message.Failed:
+		status = "Failed"
+	}
+	return fmt.Sprintf("%s:%s", action.ActionType, status)
+}
+
 // handleEncryptionChallengeRequest receives EncryptionChallenge and responds.
 func (dataChannel *DataChannel) handleEncryptionChallengeRequest(log log.T, clientMessage message.ClientMessage) error {
 	var err error
@@ -592,6 +708,7 @@ func (dataChannel *DataChannel) sendHandshakeResponse(log log.T, response messag
 	if err := dataChannel.SendInputDataMessage(log, message.HandshakeResponsePayloadType, resultBytes); err != nil {
 		return err
 	}
+	dataChannel.handshakeTrace.Step("handshake response sent")
 	return nil
 }
 
@@ -842,6 +959,13 @@ func (dataChannel *DataChannel) AddDataToOutgoingMessageBuffer(streamMessage Str
 	dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
 }
 
+// OutgoingBufferDepth returns how many sent stream messages are awaiting acknowledgement.
+func (dataChannel *DataChannel) OutgoingBufferDepth() int {
+	dataChannel.OutgoingMessageBuffer.Mutex.Lock()
+	defer dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
+	return dataChannel.OutgoingMessageBuffer.Messages.Len()
+}
+
 // RemoveDataFromOutgoingMessageBuffer removes given element from OutgoingMessageBuffer
 func (dataChannel *DataChannel) RemoveDataFromOutgoingMessageBuffer(streamMessageElement *list.Element) {
 	dataChannel.OutgoingMessageBuffer.Mutex.Lock()
@@ -979,6 +1103,12 @@ func (dataChannel *DataChannel) SetWsChannel(wsChannel communicator.IWebSocketCh
 	dataChannel.wsChannel = wsChannel
 }
 
+// SetHandshakeTrace traces opening the data channel, including its websocket, to trace
+func (dataChannel *DataChannel) SetHandshakeTrace(trace *communicator.HandshakeTrace) {
+	dataChannel.handshakeTrace = trace
+	dataChannel.wsChannel.SetHandshakeTrace(trace)
+}
+
 // GetStartPublicationReceived returns a channel that is closed when StartPublicationMessage is received.
 // READY-007
 func (dataChannel *DataChannel) GetStartPublicationReceived() <-chan struct{} {
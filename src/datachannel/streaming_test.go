@@ -15,12 +15,14 @@
 package datachannel
 
 import (
+	"container/list"
 	"encoding/json"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+	"testing/synctest"
 	"time"
 
 	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
@@ -28,6 +30,7 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/zph/session-manager-plugin/src/communicator"
 	communicatorMocks "github.com/zph/session-manager-plugin/src/communicator/mocks"
 	"github.com/zph/session-manager-plugin/src/config"
 	"github.com/zph/session-manager-plugin/src/encryption"
@@ -139,6 +142,38 @@ func TestFinalizeDataChannelHandshake(t *testing.T) {
 	mockWsChannel.AssertExpectations(t)
 }
 
+// WHEN a handshake trace is set, THEN it SHALL reach the websocket and trace the open data
+// channel message and the agent's first message.
+func TestDataChannelHandshakeTrace(t *testing.T) {
+	wsChannel := &communicatorMocks.IWebSocketChannel{}
+	dataChannel := getDataChannel()
+	dataChannel.wsChannel = wsChannel
+	traceLog := log.NewMockLog()
+	trace := communicator.NewHandshakeTrace(traceLog)
+	wsChannel.On("SetHandshakeTrace", trace).Return()
+	wsChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	wsChannel.On("GetStreamUrl").Return(streamUrl)
+
+	dataChannel.SetHandshakeTrace(trace)
+	assert.Nil(t, dataChannel.FinalizeDataChannelHandshake(mockLogger, channelToken))
+	startPublication := getClientMessage(0, message.StartPublicationMessage, uint32(message.Output), []byte("ready"))
+	serialized, _ := startPublication.SerializeClientMessage(mockLogger)
+	assert.Nil(t, dataChannel.OutputMessageHandler(mockLogger, func() {}, sessionId, serialized))
+
+	var steps []string
+	for _, call := range traceLog.Calls {
+		if call.Method == "Tracef" {
+			steps = append(steps, call.Arguments.Get(1).([]interface{})[1].(string))
+		}
+	}
+	assert.Equal(t, []string{
+		"open data channel message sent, waiting for the agent",
+		"first message from the agent: " + message.StartPublicationMessage,
+		"agent ready: start_publication received",
+	}, steps)
+	wsChannel.AssertExpectations(t)
+}
+
 func TestSendMessage(t *testing.T) {
 	datachannel := getDataChannel()
 	mockWsChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
@@ -161,6 +196,53 @@ func TestSendInputDataMessage(t *testing.T) {
 	mockWsChannel.AssertExpectations(t)
 }
 
+// WHEN keepalives are started, THEN the latest acknowledgement SHALL be repeated each interval
+// once there is one, and the sender SHALL stop once the session has ended.
+func TestStartKeepalive(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var sent [][]byte
+		original := SendMessageCall
+		SendMessageCall = func(log log.T, dataChannel *DataChannel, input []byte, inputType int) error {
+			sent = append(sent, input)
+			return nil
+		}
+		defer func() { SendMessageCall = original }()
+		dataChannel := &DataChannel{}
+		dataChannel.Initialize(mockLogger, clientId, sessionId, instanceId, false)
+
+		dataChannel.StartKeepalive(mockLogger, time.Minute)
+		time.Sleep(90 * time.Second)
+		assert.Empty(t, sent, "nothing to repeat before the first acknowledgement")
+
+		outputMessage := getClientMessage(3, message.OutputStreamMessage, uint32(message.Output), payload)
+		assert.NoError(t, dataChannel.SendAcknowledgeMessage(mockLogger, outputMessage))
+		time.Sleep(2 * time.Minute)
+		synctest.Wait()
+		assert.Len(t, sent, 3)
+		for _, input := range sent {
+			var clientMessage message.ClientMessage
+			assert.NoError(t, clientMessage.DeserializeClientMessage(mockLogger, input))
+			assert.Equal(t, message.AcknowledgeMessage, clientMessage.MessageType)
+			acknowledge, err := clientMessage.DeserializeDataStreamAcknowledgeContent(mockLogger)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(3), acknowledge.SequenceNumber)
+		}
+
+		dataChannel.EndSession()
+		time.Sleep(2 * time.Minute)
+		assert.Len(t, sent, 3)
+	})
+}
+
+// WHEN a keepalive delay is drawn, THEN it SHALL stay within a fifth of the interval.
+func TestKeepaliveDelay(t *testing.T) {
+	for range 1000 {
+		delay := keepaliveDelay(10 * time.Second)
+		assert.GreaterOrEqual(t, delay, 8*time.Second)
+		assert.LessOrEqual(t, delay, 12*time.Second)
+	}
+}
+
 func TestProcessAcknowledgedMessage(t *testing.T) {
 	dataChannel := getDataChannel()
 	dataChannel.AddDataToOutgoingMessageBuffer(streamingMessages[0])
@@ -210,6 +292,55 @@ func TestAddDataToOutgoingMessageBuffer(t *testing.T) {
 	assert.Equal(t, int64(2), bufferedStreamMessage.SequenceNumber)
 }
 
+// WHEN the outgoing buffer grows past OutgoingBufferHighWater, THEN one warning SHALL be logged
+// until it drains to half the mark, after which crossing the mark again SHALL warn again.
+func TestWatchOutgoingBufferDepth(t *testing.T) {
+	dataChannel := getDataChannel()
+	dataChannel.OutgoingBufferHighWater = 4
+	logger := log.NewMockLog()
+
+	var elements []*list.Element
+	push := func(n int) {
+		for range n {
+			dataChannel.AddDataToOutgoingMessageBuffer(streamingMessages[0])
+			elements = append(elements, dataChannel.OutgoingMessageBuffer.Messages.Back())
+		}
+	}
+	drain := func(n int) {
+		for range n {
+			dataChannel.RemoveDataFromOutgoingMessageBuffer(elements[0])
+			elements = elements[1:]
+		}
+	}
+
+	push(4)
+	above := dataChannel.watchOutgoingBufferDepth(logger, false)
+	assert.False(t, above)
+	assert.Equal(t, 4, dataChannel.OutgoingBufferDepth())
+
+	push(2)
+	above = dataChannel.watchOutgoingBufferDepth(logger, above)
+	assert.True(t, above)
+	above = dataChannel.watchOutgoingBufferDepth(logger, above)
+	assert.True(t, above)
+	logger.AssertNumberOfCalls(t, "Warnf", 1)
+
+	drain(3)
+	above = dataChannel.watchOutgoingBufferDepth(logger, above)
+	assert.True(t, above, "depth 3 is above half the mark")
+	drain(1)
+	above = dataChannel.watchOutgoingBufferDepth(logger, above)
+	assert.False(t, above)
+
+	push(3)
+	dataChannel.watchOutgoingBufferDepth(logger, above)
+	logger.AssertNumberOfCalls(t, "Warnf", 2)
+
+	dataChannel.OutgoingBufferHighWater = 0
+	assert.False(t, dataChannel.watchOutgoingBufferDepth(logger, false))
+	logger.AssertNumberOfCalls(t, "Warnf", 2)
+}
+
 func TestAddDataToIncomingMessageBuffer(t *testing.T) {
 	dataChannel := getDataChannel()
 	dataChannel.IncomingMessageBuffer.Capacity = 2
@@ -606,6 +737,35 @@ func TestProcessSessionTypeHandshakeActionForNonInteractiveCommands(t *testing.T
 	assert.Equal(t, config.ShellPluginName, dataChannel.sessionType)
 }
 
+// WHEN the handshake completes, THEN one debug line SHALL summarize the agent version, schema
+// version, session type, processed actions and the features the agent supports.
+func TestLogNegotiation(t *testing.T) {
+	dataChannel := getDataChannel()
+	dataChannel.agentVersion = "3.1.1400.0"
+	dataChannel.sessionType = config.PortPluginName
+	dataChannel.handshakeActions = []string{
+		handshakeActionSummary(message.ProcessedClientAction{ActionType: message.SessionType, ActionStatus: message.Success}),
+		handshakeActionSummary(message.ProcessedClientAction{ActionType: "Compression"}),
+	}
+	logger := log.NewMockLog()
+
+	dataChannel.logNegotiation(logger, 1)
+
+	var line string
+	for _, call := range logger.Calls {
+		if format, ok := call.Arguments.Get(0).(string); ok && call.Method == "Debugf" && strings.HasPrefix(format, "Handshake negotiated") {
+			line = fmt.Sprintf(format, call.Arguments.Get(1).([]interface{})...)
+		}
+	}
+	for _, field := range []string{
+		"agent_version=3.1.1400.0", "client_version=" + version.Version, "schema_version=1", "session_type=" + config.PortPluginName,
+		"encryption=false", "actions=SessionType:Success,Compression:Unsupported", "tcp_multiplexing=true",
+		"smux_keepalive_disabled=false", "remote_host_forwarding=true", "terminate_session_flag=true",
+	} {
+		assert.Contains(t, line, field)
+	}
+}
+
 func buildHandshakeRequest() message.HandshakeRequestPayload {
 	handshakeRquest := message.HandshakeRequestPayload{}
 	handshakeRquest.AgentVersion = "10.0.0.1"
@@ -0,0 +1,218 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package forwardspec parses and validates port forward specifications, as given to
+// ssm-port-forward -L, so other tools can check them by the same rules before starting a forward.
+package forwardspec
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Protocols a specification may be prefixed with.
+const (
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+)
+
+// Parts of a specification a *SpecError can be about.
+const (
+	FieldProtocol   = "protocol"
+	FieldBindHost   = "bind_host"
+	FieldLocalPort  = "local_port"
+	FieldRemoteHost = "remote_host"
+	FieldRemotePort = "remote_port"
+)
+
+// Kinds of *SpecError, for errors.Is.
+var (
+	ErrSyntax   = errors.New("invalid port forward specification")
+	ErrProtocol = errors.New("invalid protocol")
+	ErrHost     = errors.New("invalid host")
+	ErrPort     = errors.New("invalid port")
+)
+
+// SpecError describes why a specification was rejected.
+type SpecError struct {
+	// Spec is the specification as given
+	Spec string
+	// Field is the part at fault, one of the Field constants, or empty when the specification
+	// cannot be split into parts
+	Field string
+	// Kind is ErrSyntax, ErrProtocol, ErrHost or ErrPort
+	Kind error
+	// Message is the full description returned by Error
+	Message string
+}
+
+func (e *SpecError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the error's Kind.
+func (e *SpecError) Unwrap() error {
+	return e.Kind
+}
+
+// Spec is a parsed port forward specification:
+//
+//	[protocol/][bindHost:]localPort:[remoteHost:]remotePort
+//
+// Hosts may be bracketed, which IPv6 addresses must be: [::1]:8080:[fd00::5]:80.
+type Spec struct {
+	Protocol   string // tcp or udp; empty when the spec has no prefix
+	BindHost   string // empty when the spec has no bind host
+	LocalPort  string // 0 lets the OS choose; empty for a destination from ParseDestination
+	RemoteHost string // localhost when the spec has no remote host
+	RemotePort string
+}
+
+// ParseSpec parses and validates spec. With three fields the middle one is the remote host, as
+// with ssh -L; a bind host therefore always comes with an explicit remote host. The local port
+// must be 0-65535 and the remote port 1-65535. Errors are *SpecError.
+func ParseSpec(spec string) (Spec, error) {
+	parsed, err := split(spec)
+	if err != nil {
+		return parsed, err
+	}
+	if parsed.Protocol != "" && parsed.Protocol != ProtocolTCP && parsed.Protocol != ProtocolUDP {
+		return parsed, &SpecError{Spec: spec, Field: FieldProtocol, Kind: ErrProtocol,
+			Message: fmt.Sprintf("invalid protocol: %s (expected tcp or udp)", parsed.Protocol)}
+	}
+	if err := checkPort(spec, FieldLocalPort, parsed.LocalPort, 0); err != nil {
+		return parsed, err
+	}
+	if err := checkPort(spec, FieldRemotePort, parsed.RemotePort, 1); err != nil {
+		return parsed, err
+	}
+	return parsed, nil
+}
+
+// ParseDestination parses and validates a host:port destination, such as ssm-port-forward
+// --stdio takes; an IPv6 host must be bracketed. The returned Spec has no local port. Errors
+// are *SpecError.
+func ParseDestination(destination string) (Spec, error) {
+	host, port, err := net.SplitHostPort(destination)
+	if err != nil {
+		return Spec{}, &SpecError{Spec: destination, Kind: ErrSyntax,
+			Message: fmt.Sprintf("invalid stdio destination %s: expected host:port (bracket IPv6 addresses)", destination)}
+	}
+	if host == "" {
+		return Spec{}, &SpecError{Spec: destination, Field: FieldRemoteHost, Kind: ErrHost,
+			Message: fmt.Sprintf("invalid stdio destination %s: empty host", destination)}
+	}
+	parsed := Spec{RemoteHost: host, RemotePort: port}
+	if err := checkPort(destination, FieldRemotePort, port, 1); err != nil {
+		return parsed, err
+	}
+	return parsed, nil
+}
+
+// checkPort checks that port, the spec's field, is a number from lowest to 65535.
+func checkPort(spec string, field string, port string, lowest int) error {
+	name := strings.ReplaceAll(field, "_", " ")
+	number, err := strconv.Atoi(port)
+	if err != nil {
+		return &SpecError{Spec: spec, Field: field, Kind: ErrPort, Message: fmt.Sprintf("invalid %s: %s", name, port)}
+	}
+	if number < lowest || number > 65535 {
+		return &SpecError{Spec: spec, Field: field, Kind: ErrPort,
+			Message: fmt.Sprintf("%s out of range (%d-65535): %s", name, lowest, port)}
+	}
+	return nil
+}
+
+// split splits spec into its parts, checking only its syntax: ports are returned as written.
+func split(spec string) (Spec, error) {
+	var parsed Spec
+	rest := spec
+	if protocol, after, found := strings.Cut(rest, "/"); found {
+		parsed.Protocol = protocol
+		rest = after
+	}
+
+	fields, bracketed, err := splitFields(rest)
+	if err != nil {
+		return parsed, &SpecError{Spec: spec, Kind: ErrSyntax,
+			Message: fmt.Sprintf("invalid port forward specification %s: %v", spec, err)}
+	}
+
+	// hostFields names the fields that hold hosts; only those may be bracketed
+	var hostFields []string
+	switch len(fields) {
+	case 2:
+		parsed.LocalPort, parsed.RemoteHost, parsed.RemotePort = fields[0], "localhost", fields[1]
+		hostFields = []string{"", ""}
+	case 3:
+		parsed.LocalPort, parsed.RemoteHost, parsed.RemotePort = fields[0], fields[1], fields[2]
+		hostFields = []string{"", FieldRemoteHost, ""}
+	case 4:
+		parsed.BindHost, parsed.LocalPort, parsed.RemoteHost, parsed.RemotePort = fields[0], fields[1], fields[2], fields[3]
+		hostFields = []string{FieldBindHost, "", FieldRemoteHost, ""}
+	default:
+		return parsed, &SpecError{Spec: spec, Kind: ErrSyntax,
+			Message: fmt.Sprintf("invalid port forward specification: %s (expected [bindHost:]localPort:[remoteHost:]remotePort; "+
+				"bracket IPv6 addresses)", spec)}
+	}
+
+	for i, field := range fields {
+		if bracketed[i] && hostFields[i] == "" {
+			return parsed, &SpecError{Spec: spec, Kind: ErrSyntax,
+				Message: fmt.Sprintf("invalid port forward specification %s: only hosts may be bracketed, got [%s]", spec, field)}
+		}
+		if field == "" && hostFields[i] != "" {
+			return parsed, &SpecError{Spec: spec, Field: hostFields[i], Kind: ErrHost,
+				Message: fmt.Sprintf("invalid port forward specification %s: empty host", spec)}
+		}
+	}
+	return parsed, nil
+}
+
+// splitFields splits s on colons outside brackets, stripping the brackets and reporting which
+// fields had them.
+func splitFields(s string) (fields []string, bracketed []bool, err error) {
+	for {
+		if strings.HasPrefix(s, "[") {
+			end := strings.Index(s, "]")
+			if end < 0 {
+				return nil, nil, fmt.Errorf("missing ] in %s", s)
+			}
+			fields = append(fields, s[1:end])
+			bracketed = append(bracketed, true)
+			s = s[end+1:]
+			if s == "" {
+				return fields, bracketed, nil
+			}
+			if s[0] != ':' {
+				return nil, nil, fmt.Errorf("expected : after ]%s", s)
+			}
+			s = s[1:]
+			continue
+		}
+
+		field, rest, found := strings.Cut(s, ":")
+		if strings.ContainsAny(field, "[]") {
+			return nil, nil, fmt.Errorf("unexpected bracket in %s", field)
+		}
+		fields = append(fields, field)
+		bracketed = append(bracketed, false)
+		if !found {
+			return fields, bracketed, nil
+		}
+		s = rest
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package forwardspec parses and validates port forward specifications, as given to
+// ssm-port-forward -L, so other tools can check them by the same rules before starting a forward.
+package forwardspec
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// WHEN a specification is split, THEN split SHALL tokenize optional protocol, bind host
+// (bracketed for IPv6), local port, optional remote host and remote port.
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Spec
+	}{
+		{"8080:80", Spec{LocalPort: "8080", RemoteHost: "localhost", RemotePort: "80"}},
+		{"8080:db.internal:5432", Spec{LocalPort: "8080", RemoteHost: "db.internal", RemotePort: "5432"}},
+		{"0.0.0.0:8080:host:80", Spec{BindHost: "0.0.0.0", LocalPort: "8080", RemoteHost: "host", RemotePort: "80"}},
+		{"[::1]:8080:host:80", Spec{BindHost: "::1", LocalPort: "8080", RemoteHost: "host", RemotePort: "80"}},
+		{"8080:[fd00::5]:80", Spec{LocalPort: "8080", RemoteHost: "fd00::5", RemotePort: "80"}},
+		{"[::]:8080:[fd00::5]:80", Spec{BindHost: "::", LocalPort: "8080", RemoteHost: "fd00::5", RemotePort: "80"}},
+		{"udp/5353:10.0.0.2:53", Spec{Protocol: "udp", LocalPort: "5353", RemoteHost: "10.0.0.2", RemotePort: "53"}},
+		{"tcp/127.0.0.1:0:host:80", Spec{Protocol: "tcp", BindHost: "127.0.0.1", LocalPort: "0", RemoteHost: "host", RemotePort: "80"}},
+		// three fields are always localPort:remoteHost:remotePort, never bindHost:localPort:remotePort
+		{"127.0.0.1:8080:80", Spec{LocalPort: "127.0.0.1", RemoteHost: "8080", RemotePort: "80"}},
+	}
+	for _, tt := range tests {
+		got, err := split(tt.spec)
+		assert.Nil(t, err, tt.spec)
+		assert.Equal(t, tt.want, got, tt.spec)
+	}
+}
+
+// WHEN a specification is malformed, THEN split SHALL reject it with a *SpecError.
+func TestSplitErrors(t *testing.T) {
+	invalid := []string{
+		"8080",              // no remote port
+		"::1:8080:host:80",  // unbracketed IPv6 bind host
+		"8080:fd00::5:80",   // unbracketed IPv6 remote host
+		"[::1:8080:host:80", // unterminated bracket
+		"[::1]8080:host:80", // no colon after bracket
+		"[8080]:80",         // bracketed port
+		"8080:[]:80",        // empty host
+		"a:b:c:d:e",         // too many fields
+		"8080:host]:80",     // stray bracket
+	}
+	for _, spec := range invalid {
+		_, err := split(spec)
+		var specErr *SpecError
+		assert.True(t, errors.As(err, &specErr), "expected %q to be rejected with a *SpecError, got %v", spec, err)
+	}
+}
+
+// WHEN a specification is parsed, THEN ParseSpec SHALL accept valid protocols and ports and
+// reject the rest with the kind and field at fault.
+func TestParseSpec(t *testing.T) {
+	got, err := ParseSpec("udp/[::1]:0:[fd00::5]:65535")
+	assert.Nil(t, err)
+	assert.Equal(t, Spec{Protocol: "udp", BindHost: "::1", LocalPort: "0", RemoteHost: "fd00::5", RemotePort: "65535"}, got)
+
+	tests := []struct {
+		spec    string
+		kind    error
+		field   string
+		message string
+	}{
+		{"sctp/8080:80", ErrProtocol, FieldProtocol, "invalid protocol: sctp (expected tcp or udp)"},
+		{"http:8080:80", ErrPort, FieldLocalPort, "invalid local port: http"},
+		{"127.0.0.1:8080:80", ErrPort, FieldLocalPort, "invalid local port: 127.0.0.1"},
+		{"70000:80", ErrPort, FieldLocalPort, "local port out of range (0-65535): 70000"},
+		{"8080:db", ErrPort, FieldRemotePort, "invalid remote port: db"},
+		{"8080:0", ErrPort, FieldRemotePort, "remote port out of range (1-65535): 0"},
+		{"8080:[]:80", ErrHost, FieldRemoteHost, "invalid port forward specification 8080:[]:80: empty host"},
+		{"[]:8080:db:80", ErrHost, FieldBindHost, "invalid port forward specification []:8080:db:80: empty host"},
+		{"8080", ErrSyntax, "", "invalid port forward specification: 8080 (expected [bindHost:]localPort:[remoteHost:]remotePort; bracket IPv6 addresses)"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSpec(tt.spec)
+		var specErr *SpecError
+		if assert.True(t, errors.As(err, &specErr), tt.spec) {
+			assert.True(t, errors.Is(err, tt.kind), tt.spec)
+			assert.Equal(t, tt.field, specErr.Field, tt.spec)
+			assert.Equal(t, tt.spec, specErr.Spec)
+			assert.Equal(t, tt.message, err.Error())
+		}
+	}
+}
+
+// WHEN a destination is parsed, THEN ParseDestination SHALL accept host:port with bracketed IPv6
+// hosts and a valid port, and reject anything else.
+func TestParseDestination(t *testing.T) {
+	got, err := ParseDestination("db.internal:22")
+	assert.Nil(t, err)
+	assert.Equal(t, Spec{RemoteHost: "db.internal", RemotePort: "22"}, got)
+	got, err = ParseDestination("[fd00::5]:22")
+	assert.Nil(t, err)
+	assert.Equal(t, Spec{RemoteHost: "fd00::5", RemotePort: "22"}, got)
+
+	for _, destination := range []string{"22", "fd00::5:22", ":22", "8080:host:22", "host:ssh", "host:0"} {
+		_, err := ParseDestination(destination)
+		var specErr *SpecError
+		assert.True(t, errors.As(err, &specErr), "expected %q to be rejected with a *SpecError, got %v", destination, err)
+	}
+}
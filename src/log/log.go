@@ -16,9 +16,11 @@ package log
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
 )
@@ -88,14 +90,27 @@ var (
 // LogConfig is the struct holding relevant info for a logger instance.
 type LogConfig struct {
 	ClientName string
+	// IncludeCaller adds the file:line of the logging call site to each entry
+	IncludeCaller bool
+	// JSON logs context as a "context" array field instead of a message prefix,
+	// so every entry is plain newline-delimited JSON for log aggregation
+	JSON bool
+	// JSONWriter, when set, receives a JSON copy of every entry while stderr switches to
+	// human-readable lines, unless JSON keeps stderr in JSON as well
+	JSONWriter io.Writer
 }
 
 // ContextFormatFilter adds context strings to log messages.
 type ContextFormatFilter struct {
 	Context []string
+	// AsField leaves messages unprefixed; the zerolog wrapper logs Context as a "context" array field instead
+	AsField bool
 }
 
 func (f ContextFormatFilter) Filter(params ...interface{}) (newParams []interface{}) {
+	if f.AsField {
+		return params
+	}
 	newParams = make([]interface{}, len(f.Context)+len(params))
 	for i, param := range f.Context {
 		newParams[i] = param + " "
@@ -108,9 +123,13 @@ func (f ContextFormatFilter) Filter(params ...interface{}) (newParams []interfac
 }
 
 func (f ContextFormatFilter) Filterf(format string, params ...interface{}) (newFormat string, newParams []interface{}) {
+	if f.AsField {
+		return format, params
+	}
 	newFormat = ""
 	for _, param := range f.Context {
-		newFormat += param + " "
+		// context is literal text, so escape it before it becomes part of the format
+		newFormat += strings.ReplaceAll(param, "%", "%%") + " "
 	}
 	newFormat += format
 	newParams = params
@@ -136,7 +155,7 @@ func (w *zerologWrapper) Tracef(format string, params ...interface{}) {
 
 	newFmt, newParams := w.format.Filterf(format, params...)
 	msg := fmt.Sprintf(newFmt, newParams...)
-	w.logger.Trace().Msg(msg)
+	w.event(w.logger.Trace()).Msg(msg)
 }
 
 func (w *zerologWrapper) Debugf(format string, params ...interface{}) {
@@ -145,7 +164,7 @@ func (w *zerologWrapper) Debugf(format string, params ...interface{}) {
 
 	newFmt, newParams := w.format.Filterf(format, params...)
 	msg := fmt.Sprintf(newFmt, newParams...)
-	w.logger.Debug().Msg(msg)
+	w.event(w.logger.Debug()).Msg(msg)
 }
 
 func (w *zerologWrapper) Infof(format string, params ...interface{}) {
@@ -154,7 +173,7 @@ func (w *zerologWrapper) Infof(format string, params ...interface{}) {
 
 	newFmt, newParams := w.format.Filterf(format, params...)
 	msg := fmt.Sprintf(newFmt, newParams...)
-	w.logger.Info().Msg(msg)
+	w.event(w.logger.Info()).Msg(msg)
 }
 
 func (w *zerologWrapper) Warnf(format string, params ...interface{}) error {
@@ -163,7 +182,7 @@ func (w *zerologWrapper) Warnf(format string, params ...interface{}) error {
 
 	newFmt, newParams := w.format.Filterf(format, params...)
 	msg := fmt.Sprintf(newFmt, newParams...)
-	w.logger.Warn().Msg(msg)
+	w.event(w.logger.Warn()).Msg(msg)
 	return nil
 }
 
@@ -173,7 +192,7 @@ func (w *zerologWrapper) Errorf(format string, params ...interface{}) error {
 
 	newFmt, newParams := w.format.Filterf(format, params...)
 	msg := fmt.Sprintf(newFmt, newParams...)
-	w.logger.Error().Msg(msg)
+	w.event(w.logger.Error()).Msg(msg)
 	return nil
 }
 
@@ -184,7 +203,7 @@ func (w *zerologWrapper) Criticalf(format string, params ...interface{}) error {
 	newFmt, newParams := w.format.Filterf(format, params...)
 	msg := fmt.Sprintf(newFmt, newParams...)
 	// No direct "critical" in zerolog: we can log as error or panic
-	w.logger.Error().Msg("[CRITICAL] " + msg)
+	w.event(w.logger.Error()).Msg("[CRITICAL] " + msg)
 	return nil
 }
 
@@ -193,7 +212,7 @@ func (w *zerologWrapper) Trace(v ...interface{}) {
 	defer w.unlockIfNeeded()
 
 	msg := fmt.Sprint(w.format.Filter(v...)...)
-	w.logger.Trace().Msg(msg)
+	w.event(w.logger.Trace()).Msg(msg)
 }
 
 func (w *zerologWrapper) Debug(v ...interface{}) {
@@ -201,7 +220,7 @@ func (w *zerologWrapper) Debug(v ...interface{}) {
 	defer w.unlockIfNeeded()
 
 	msg := fmt.Sprint(w.format.Filter(v...)...)
-	w.logger.Debug().Msg(msg)
+	w.event(w.logger.Debug()).Msg(msg)
 }
 
 func (w *zerologWrapper) Info(v ...interface{}) {
@@ -209,7 +228,7 @@ func (w *zerologWrapper) Info(v ...interface{}) {
 	defer w.unlockIfNeeded()
 
 	msg := fmt.Sprint(w.format.Filter(v...)...)
-	w.logger.Info().Msg(msg)
+	w.event(w.logger.Info()).Msg(msg)
 }
 
 func (w *zerologWrapper) Warn(v ...interface{}) error {
@@ -217,7 +236,7 @@ func (w *zerologWrapper) Warn(v ...interface{}) error {
 	defer w.unlockIfNeeded()
 
 	msg := fmt.Sprint(w.format.Filter(v...)...)
-	w.logger.Warn().Msg(msg)
+	w.event(w.logger.Warn()).Msg(msg)
 	return nil
 }
 
@@ -226,7 +245,7 @@ func (w *zerologWrapper) Error(v ...interface{}) error {
 	defer w.unlockIfNeeded()
 
 	msg := fmt.Sprint(w.format.Filter(v...)...)
-	w.logger.Error().Msg(msg)
+	w.event(w.logger.Error()).Msg(msg)
 	return nil
 }
 
@@ -235,7 +254,7 @@ func (w *zerologWrapper) Critical(v ...interface{}) error {
 	defer w.unlockIfNeeded()
 
 	msg := fmt.Sprint(w.format.Filter(v...)...)
-	w.logger.Error().Msg("[CRITICAL] " + msg)
+	w.event(w.logger.Error()).Msg("[CRITICAL] " + msg)
 	return nil
 }
 
@@ -257,11 +276,19 @@ func (w *zerologWrapper) WithContext(context ...string) (contextLogger T) {
 
 	return &zerologWrapper{
 		logger: w.logger,
-		format: ContextFormatFilter{Context: newCtx},
+		format: ContextFormatFilter{Context: newCtx, AsField: w.format.AsField},
 		m:      w.m,
 	}
 }
 
+// event adds the context field to e when context is logged as a field
+func (w *zerologWrapper) event(e *zerolog.Event) *zerolog.Event {
+	if w.format.AsField && len(w.format.Context) > 0 {
+		e = e.Strs("context", w.format.Context)
+	}
+	return e
+}
+
 // Helper to avoid repeated lock/unlock calls
 func (w *zerologWrapper) lockIfNeeded() {
 	if w.m != nil {
@@ -289,10 +316,24 @@ func (w *zerologWrapper) ReplaceDelegate(newLogger zerolog.Logger) {
 // Logger initializes the logging system if not already loaded and returns the logger interface.
 //
 // Here, we assume the external package has ALREADY configured and returned a zerolog.Logger.
+// Setting the LOG_CALLER environment variable to any value adds caller file:line to entries,
+// and setting LOG_JSON logs context as a JSON field.
 func Logger(useWatcher bool, clientName string) T {
-	logConfig := LogConfig{
-		ClientName: clientName,
+	return LoggerWithConfig(useWatcher, DefaultLogConfig(clientName))
+}
+
+// DefaultLogConfig returns the config Logger uses for clientName, with options taken from
+// the LOG_CALLER and LOG_JSON environment variables.
+func DefaultLogConfig(clientName string) LogConfig {
+	return LogConfig{
+		ClientName:    clientName,
+		IncludeCaller: os.Getenv("LOG_CALLER") != "",
+		JSON:          os.Getenv("LOG_JSON") != "",
 	}
+}
+
+// LoggerWithConfig is Logger with explicit options. The config only takes effect if no logger has been loaded yet.
+func LoggerWithConfig(useWatcher bool, logConfig LogConfig) T {
 	if !isLoaded() {
 		logger := logConfig.InitLogger(useWatcher)
 		cache(logger)
@@ -305,10 +346,14 @@ func (config *LogConfig) InitLogger(useWatcher bool) (logger T) {
 	// Suppose you have some external function: externalpkg.GetLogger() -> zerolog.Logger
 	// We'll call that here.
 	// For demonstration, let's pretend there's a global or function returning a pre-configured logger.
-	zlog := getPreConfiguredZerolog()
+	zlog := config.zerolog()
 
 	// Wrap it in our T interface
-	logger = withContext(zlog)
+	logger = &zerologWrapper{
+		logger: zlog,
+		format: ContextFormatFilter{AsField: config.JSON},
+		m:      pkgMutex,
+	}
 
 	// The "watcher" stuff can remain a stub or be removed, depending on your needs
 	if useWatcher {
@@ -325,7 +370,7 @@ func (config *LogConfig) startWatcher(logger T) {
 // replaceLogger is a no-op or example of re-getting the external logger.
 func (config *LogConfig) replaceLogger() {
 	logger := getCached()
-	zlog := getPreConfiguredZerolog()
+	zlog := config.zerolog()
 
 	w, ok := logger.(*zerologWrapper)
 	if !ok {
@@ -335,6 +380,34 @@ func (config *LogConfig) replaceLogger() {
 	w.ReplaceDelegate(zlog)
 }
 
+// zerolog returns the pre-configured logger with this config's options applied.
+func (config *LogConfig) zerolog() zerolog.Logger {
+	zlog := getPreConfiguredZerolog()
+	if config.JSONWriter != nil {
+		zlog = zlog.Output(zerolog.MultiLevelWriter(config.consoleWriter(), config.JSONWriter))
+	}
+	if config.IncludeCaller {
+		zlog = withCaller(zlog)
+	}
+	return zlog
+}
+
+// consoleWriter returns the stderr writer used alongside JSONWriter: plain JSON when the config
+// asks for JSON, otherwise one human-readable line per entry.
+func (config *LogConfig) consoleWriter() io.Writer {
+	if config.JSON {
+		return os.Stderr
+	}
+	return zerolog.ConsoleWriter{Out: os.Stderr, NoColor: true, TimeFormat: time.RFC3339}
+}
+
+// withCaller adds the caller's file:line to entries from zlog. zerologWrapper methods call
+// zerolog directly, so one extra frame is skipped to report the wrapper's caller rather than
+// the wrapper itself.
+func withCaller(zlog zerolog.Logger) zerolog.Logger {
+	return zlog.With().CallerWithSkipFrameCount(zerolog.CallerSkipFrameCount + 1).Logger()
+}
+
 // withContext creates a new T with optional context.
 func withContext(zlog zerolog.Logger, context ...string) T {
 	w := &zerologWrapper{
@@ -366,6 +439,18 @@ func getCached() T {
 	return *loadedLogger
 }
 
+// Quiet suppresses all logging below error level, overriding LOG_LEVEL.
+// Call it after Logger, which applies LOG_LEVEL when the logger is first loaded.
+func Quiet() {
+	zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+}
+
+// Verbose enables logging at every level, including trace, overriding LOG_LEVEL.
+// Call it after Logger, which applies LOG_LEVEL when the logger is first loaded.
+func Verbose() {
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+}
+
 // -------------------------------------------------------------------
 // 6) Stub for "Pre-Configured" Zerolog Logger
 // -------------------------------------------------------------------
@@ -0,0 +1,132 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package log is used to initialize the logger.
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// WHEN caller reporting is enabled, THEN entries SHALL name the line that called the
+// wrapper, not the wrapper's own Infof/Errorf frame.
+func TestWithCallerReportsCallSite(t *testing.T) {
+	level := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+	defer zerolog.SetGlobalLevel(level)
+
+	var buf bytes.Buffer
+	logger := withContext(withCaller(zerolog.New(&buf))).WithContext("[ctx]")
+
+	// each function logs and returns the line it logged from
+	logFuncs := []func() int{
+		func() int {
+			logger.Infof("formatted %d", 1)
+			_, _, line, _ := runtime.Caller(0)
+			return line - 1
+		},
+		func() int {
+			logger.Error("plain")
+			_, _, line, _ := runtime.Caller(0)
+			return line - 1
+		},
+	}
+	for _, logFunc := range logFuncs {
+		buf.Reset()
+		line := logFunc()
+
+		var entry struct {
+			Caller string `json:"caller"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("Invalid log entry %q: %v", buf.String(), err)
+		}
+		if want := "log_test.go:" + strconv.Itoa(line); !strings.HasSuffix(entry.Caller, want) {
+			t.Errorf("Expected caller %s, got %s", want, entry.Caller)
+		}
+	}
+}
+
+// WHEN context is logged as a field, THEN messages SHALL stay unprefixed and the context
+// SHALL appear as a JSON array.
+func TestContextAsField(t *testing.T) {
+	level := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+	defer zerolog.SetGlobalLevel(level)
+
+	var buf bytes.Buffer
+	logger := &zerologWrapper{logger: zerolog.New(&buf), format: ContextFormatFilter{AsField: true}}
+
+	logger.WithContext("[8080->db:5432]", "[100%]").Infof("opened %d", 1)
+
+	var entry struct {
+		Message string   `json:"message"`
+		Context []string `json:"context"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Invalid log entry %q: %v", buf.String(), err)
+	}
+	if entry.Message != "opened 1" {
+		t.Errorf("Expected unprefixed message, got %q", entry.Message)
+	}
+	if len(entry.Context) != 2 || entry.Context[0] != "[8080->db:5432]" || entry.Context[1] != "[100%]" {
+		t.Errorf("Expected context field, got %v", entry.Context)
+	}
+
+	buf.Reset()
+	logger.Info("no context")
+	if bytes.Contains(buf.Bytes(), []byte(`"context"`)) {
+		t.Errorf("Expected no context field without context, got %s", buf.String())
+	}
+}
+
+// WHEN a JSON writer is configured, THEN every entry SHALL also be written to it as JSON,
+// context included, while stderr gets the human-readable form.
+func TestJSONWriterCopiesEntries(t *testing.T) {
+	level := zerolog.GlobalLevel()
+	defer zerolog.SetGlobalLevel(level)
+
+	var buf bytes.Buffer
+	config := LogConfig{JSONWriter: &buf}
+	zlog := config.zerolog()
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+	logger := (&zerologWrapper{logger: zlog}).WithContext("[8080->db:5432]")
+
+	logger.Warnf("slow %d", 1)
+
+	var entry struct {
+		Level   string `json:"level"`
+		Message string `json:"message"`
+		Time    string `json:"time"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Invalid JSON log entry %q: %v", buf.String(), err)
+	}
+	if entry.Level != "warn" || entry.Message != "[8080->db:5432] slow 1" || entry.Time == "" {
+		t.Errorf("Unexpected JSON log entry: %+v", entry)
+	}
+	if _, ok := config.consoleWriter().(zerolog.ConsoleWriter); !ok {
+		t.Errorf("Expected human-readable stderr alongside the JSON writer")
+	}
+	config.JSON = true
+	if _, ok := config.consoleWriter().(zerolog.ConsoleWriter); ok {
+		t.Errorf("Expected JSON stderr when JSON is set")
+	}
+}
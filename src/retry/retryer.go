@@ -15,6 +15,7 @@
 package retry
 
 import (
+	"errors"
 	"math"
 	"time"
 )
@@ -32,6 +33,20 @@ type RepeatableExponentialRetryer struct {
 	MaxAttempts         int
 }
 
+// stopError marks an error that retrying cannot fix.
+type stopError struct {
+	err error
+}
+
+func (e *stopError) Error() string { return e.err.Error() }
+
+func (e *stopError) Unwrap() error { return e.err }
+
+// Stop wraps err so that Call returns it at once instead of retrying.
+func Stop(err error) error {
+	return &stopError{err}
+}
+
 // NextSleepTime calculates the next delay of retry.
 func (retryer *RepeatableExponentialRetryer) NextSleepTime(attempt int) time.Duration {
 	return time.Duration(float64(retryer.InitialDelayInMilli)*math.Pow(retryer.GeometricRatio, float64(attempt))) * time.Millisecond
@@ -43,6 +58,10 @@ func (retryer *RepeatableExponentialRetryer) Call() (err error) {
 	failedAttemptsSoFar := 0
 	for {
 		err := retryer.CallableFunc()
+		var stop *stopError
+		if errors.As(err, &stop) {
+			return stop.err
+		}
 		if err == nil || failedAttemptsSoFar == retryer.MaxAttempts {
 			return err
 		}
@@ -13,3 +13,31 @@
 
 // retry implements back off retry strategy for reconnect web socket connection.
 package retry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zph/session-manager-plugin/src/config"
+)
+
+// WHEN the callable returns an error wrapped with Stop, THEN Call SHALL return the unwrapped
+// error without retrying.
+func TestRepeatableExponentialRetryerStops(t *testing.T) {
+	permanent := errors.New("AccessDeniedException")
+	calls := 0
+	retryer := RepeatableExponentialRetryer{
+		CallableFunc: func() error {
+			calls++
+			return Stop(permanent)
+		},
+		GeometricRatio:      config.RetryBase,
+		InitialDelayInMilli: config.DataChannelRetryInitialDelayMillis,
+		MaxDelayInMilli:     config.DataChannelRetryMaxIntervalMillis,
+		MaxAttempts:         config.DataChannelNumMaxRetries,
+	}
+	err := retryer.Call()
+	assert.Equal(t, permanent, err)
+	assert.Equal(t, 1, calls)
+}
@@ -15,45 +15,162 @@
 package sdkutil
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/zph/session-manager-plugin/src/sdkutil/retryer"
 )
 
 var defaultRegion string
 var defaultProfile string
+var baseConfig *aws.Config
+var signingRegion string
+var signingName string
+
+// imdsRegionTimeout bounds asking the instance metadata service for the region, so hosts
+// outside EC2 give up quickly.
+var imdsRegionTimeout = 2 * time.Second
+
+// imdsRegion holds the region looked up from instance metadata; it is looked up at most once.
+var imdsRegion struct {
+	sync.Mutex
+	looked bool
+	region string
+}
 
 // GetNewSessionWithEndpoint creates aws sdk session with given profile, region and endpoint
 func GetNewSessionWithEndpoint(endpoint string) (sess *session.Session, err error) {
 	if sess, err = session.NewSessionWithOptions(session.Options{
-		Config: aws.Config{
-			Retryer:    newRetryer(),
-			SleepDelay: sleepDelay,
-			Region:     aws.String(defaultRegion),
-			Endpoint:   aws.String(endpoint),
-		},
+		Config:            newConfig(endpoint),
 		SharedConfigState: session.SharedConfigEnable,
 		Profile:           defaultProfile,
 	}); err != nil {
 		return nil, fmt.Errorf("Error creating new aws sdk session %s", err)
 	}
+	if aws.StringValue(sess.Config.Region) == "" {
+		// Nothing from flags, environment or profile; on EC2, default to the instance's region
+		if region := regionFromIMDS(); region != "" {
+			sess.Config.Region = aws.String(region)
+		}
+	}
 	return sess, nil
 }
 
+// regionFromIMDS returns the region of the EC2 instance this runs on, or "" off EC2 or when
+// instance metadata is disabled (AWS_EC2_METADATA_DISABLED=true).
+func regionFromIMDS() string {
+	imdsRegion.Lock()
+	defer imdsRegion.Unlock()
+	if !imdsRegion.looked {
+		imdsRegion.looked = true
+		imdsRegion.region, _ = lookupIMDSRegion()
+	}
+	return imdsRegion.region
+}
+
+// lookupIMDSRegion asks instance metadata for the region. Its client gets a session of its own
+// so a custom SSM endpoint or signing resolver is not applied to the metadata service.
+var lookupIMDSRegion = func() (string, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+		Profile:           defaultProfile,
+	})
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), imdsRegionTimeout)
+	defer cancel()
+	return ec2metadata.New(sess).RegionWithContext(ctx)
+}
+
 // GetDefaultSession creates aws sdk session with given profile and region
 func GetDefaultSession() (sess *session.Session, err error) {
 	return GetNewSessionWithEndpoint("")
 }
 
-// Sets the region and profile for default aws sessions
+// Sets the region and profile for default aws sessions, usually as picked by ResolveRegion and
+// ResolveProfile. With an empty region, sessions take it from the profile and, failing that, from
+// instance metadata when running on EC2.
 func SetRegionAndProfile(region string, profile string) {
 	defaultRegion = region
 	defaultProfile = profile
 }
 
+// SetSigningOverrides makes new sessions sign SSM API requests with region and name instead of the
+// ones resolved for the endpoint, for private deployments whose endpoints do not follow the public
+// naming. Empty values keep the resolved ones. The data channel authenticates with the session
+// token rather than SigV4, so it is unaffected.
+func SetSigningOverrides(region string, name string) {
+	signingRegion = region
+	signingName = name
+}
+
+// SetBaseConfig makes new sessions start from cfg, for embedders that already manage their own
+// configuration. Credentials set on cfg are used as is instead of being resolved from the
+// environment or shared profile. A region or endpoint given to this package still overrides cfg.
+// Pass nil to restore the default behavior.
+func SetBaseConfig(cfg *aws.Config) {
+	baseConfig = cfg
+}
+
+// newConfig builds the session config from the base config, the default region and endpoint
+func newConfig(endpoint string) aws.Config {
+	cfg := aws.Config{}
+	if baseConfig != nil {
+		cfg = *baseConfig.Copy()
+	}
+	if cfg.Retryer == nil {
+		cfg.Retryer = newRetryer()
+	}
+	if cfg.SleepDelay == nil {
+		cfg.SleepDelay = sleepDelay
+	}
+	if cfg.Region == nil || defaultRegion != "" {
+		cfg.Region = aws.String(defaultRegion)
+	}
+	if cfg.Endpoint == nil || endpoint != "" {
+		cfg.Endpoint = aws.String(endpoint)
+	}
+	if signingRegion != "" || signingName != "" {
+		// A set Endpoint bypasses the resolver, so the resolver takes over the endpoint too
+		cfg.EndpointResolver = newSigningResolver(cfg.EndpointResolver, aws.StringValue(cfg.Endpoint), aws.BoolValue(cfg.DisableSSL))
+		cfg.Endpoint = nil
+	}
+	return cfg
+}
+
+// newSigningResolver resolves endpoints with base, or as endpoint when one is given, and applies
+// the signing overrides to the result.
+func newSigningResolver(base endpoints.Resolver, endpoint string, disableSSL bool) endpoints.Resolver {
+	if base == nil {
+		base = endpoints.DefaultResolver()
+	}
+	region, name := signingRegion, signingName
+	return endpoints.ResolverFunc(func(service, resolveRegion string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+		resolved := endpoints.ResolvedEndpoint{URL: endpoints.AddScheme(endpoint, disableSSL), SigningRegion: resolveRegion}
+		if endpoint == "" {
+			var err error
+			if resolved, err = base.EndpointFor(service, resolveRegion, opts...); err != nil {
+				return resolved, err
+			}
+		}
+		if region != "" {
+			resolved.SigningRegion = region
+		}
+		if name != "" {
+			resolved.SigningName = name
+			resolved.SigningNameDerived = false
+		}
+		return resolved, nil
+	})
+}
+
 var newRetryer = func() aws.RequestRetryer {
 	r := retryer.SsmCliRetryer{}
 	r.NumMaxRetries = 3
@@ -0,0 +1,140 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package sdkutil provides utilities used to call awssdk.
+package sdkutil
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+)
+
+// WHEN no base config is set, THEN sessions SHALL use the default region and endpoint.
+func TestNewConfigDefaults(t *testing.T) {
+	SetRegionAndProfile("us-west-2", "")
+	defer SetRegionAndProfile("", "")
+
+	cfg := newConfig("")
+	assert.Equal(t, "us-west-2", aws.StringValue(cfg.Region))
+	assert.Equal(t, "", aws.StringValue(cfg.Endpoint))
+	assert.Nil(t, cfg.Credentials)
+	assert.NotNil(t, cfg.Retryer)
+}
+
+// WHEN a base config with credentials is set, THEN new sessions SHALL use those credentials
+// and keep the base region unless one is given explicitly.
+func TestGetNewSessionWithBaseConfig(t *testing.T) {
+	creds := credentials.NewStaticCredentials("AKID", "secret", "")
+	SetBaseConfig(&aws.Config{Credentials: creds, Region: aws.String("eu-west-1")})
+	defer SetBaseConfig(nil)
+
+	sess, err := GetNewSessionWithEndpoint("")
+	assert.NoError(t, err)
+	assert.Same(t, creds, sess.Config.Credentials)
+	assert.Equal(t, "eu-west-1", aws.StringValue(sess.Config.Region))
+
+	SetRegionAndProfile("ap-south-1", "")
+	defer SetRegionAndProfile("", "")
+	assert.Equal(t, "ap-south-1", aws.StringValue(newConfig("").Region))
+}
+
+// WHEN signing overrides are set, THEN SSM clients SHALL sign with them, both for the resolved
+// endpoint and for an explicit one.
+func TestSigningOverrides(t *testing.T) {
+	SetBaseConfig(&aws.Config{Credentials: credentials.NewStaticCredentials("AKID", "secret", ""), Region: aws.String("us-east-1")})
+	defer SetBaseConfig(nil)
+	SetSigningOverrides("us-gov-west-1", "ssm-private")
+	defer SetSigningOverrides("", "")
+
+	sess, err := GetNewSessionWithEndpoint("")
+	assert.NoError(t, err)
+	info := ssm.New(sess).ClientInfo
+	assert.Equal(t, "us-gov-west-1", info.SigningRegion)
+	assert.Equal(t, "ssm-private", info.SigningName)
+	assert.Equal(t, "https://ssm.us-east-1.amazonaws.com", info.Endpoint)
+
+	sess, err = GetNewSessionWithEndpoint("vpce-0123.ssm.us-east-1.vpce.amazonaws.com")
+	assert.NoError(t, err)
+	info = ssm.New(sess).ClientInfo
+	assert.Equal(t, "us-gov-west-1", info.SigningRegion)
+	assert.Equal(t, "ssm-private", info.SigningName)
+	assert.Equal(t, "https://vpce-0123.ssm.us-east-1.vpce.amazonaws.com", info.Endpoint)
+}
+
+// WHEN no signing overrides are set, THEN SSM clients SHALL sign as ssm in the session region.
+func TestNoSigningOverrides(t *testing.T) {
+	SetBaseConfig(&aws.Config{Credentials: credentials.NewStaticCredentials("AKID", "secret", ""), Region: aws.String("us-east-1")})
+	defer SetBaseConfig(nil)
+
+	sess, err := GetNewSessionWithEndpoint("")
+	assert.NoError(t, err)
+	info := ssm.New(sess).ClientInfo
+	assert.Equal(t, "us-east-1", info.SigningRegion)
+	assert.Equal(t, "ssm", info.SigningName)
+}
+
+// stubIMDSRegion makes instance metadata report region, counting lookups.
+func stubIMDSRegion(t *testing.T, region string) *int {
+	lookups := 0
+	original := lookupIMDSRegion
+	lookupIMDSRegion = func() (string, error) {
+		lookups++
+		return region, nil
+	}
+	imdsRegion.looked = false
+	t.Cleanup(func() {
+		lookupIMDSRegion = original
+		imdsRegion.looked = false
+		imdsRegion.region = ""
+	})
+	// No region from the environment or a shared config file
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("AWS_CONFIG_FILE", t.TempDir()+"/config")
+	return &lookups
+}
+
+// WHEN no region is given by flag, environment or profile, THEN sessions SHALL take it from
+// instance metadata, looked up only once.
+func TestRegionFromIMDS(t *testing.T) {
+	lookups := stubIMDSRegion(t, "eu-central-1")
+
+	for i := 0; i < 2; i++ {
+		sess, err := GetNewSessionWithEndpoint("")
+		assert.NoError(t, err)
+		assert.Equal(t, "eu-central-1", aws.StringValue(sess.Config.Region))
+	}
+	assert.Equal(t, 1, *lookups)
+}
+
+// WHEN a region is set explicitly or in the environment, THEN it SHALL take precedence and
+// instance metadata SHALL not be asked.
+func TestExplicitRegionSkipsIMDS(t *testing.T) {
+	lookups := stubIMDSRegion(t, "eu-central-1")
+
+	SetRegionAndProfile("us-west-2", "")
+	sess, err := GetNewSessionWithEndpoint("")
+	SetRegionAndProfile("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "us-west-2", aws.StringValue(sess.Config.Region))
+
+	t.Setenv("AWS_REGION", "ap-southeast-2")
+	sess, err = GetNewSessionWithEndpoint("")
+	assert.NoError(t, err)
+	assert.Equal(t, "ap-southeast-2", aws.StringValue(sess.Config.Region))
+	assert.Equal(t, 0, *lookups)
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package sdkutil provides utilities used to call awssdk.
+package sdkutil
+
+import (
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// Where a region or profile was taken from, other than an environment variable, which is
+// reported by name.
+const (
+	SourceFlag         = "flag"
+	SourceSharedConfig = "shared config"
+	SourceIMDS         = "instance metadata"
+	SourceDefault      = "default"
+)
+
+// Setting is a region or profile and where it came from: a Source constant or the name of the
+// environment variable that set it.
+type Setting struct {
+	Value  string
+	Source string
+}
+
+// Environment variables read for the region and profile, in order of precedence, as the SDK
+// reads them with shared config enabled.
+var (
+	regionEnvVars  = []string{"AWS_REGION", "AWS_DEFAULT_REGION"}
+	profileEnvVars = []string{"AWS_PROFILE", "AWS_DEFAULT_PROFILE"}
+)
+
+// ResolveRegion picks the region by explicit precedence: flag, then AWS_REGION, then
+// AWS_DEFAULT_REGION. With none set the Value is empty and the region is left to the profile in
+// the shared config and, failing that, instance metadata; RegionSource tells which it was.
+func ResolveRegion(flag string) Setting {
+	return resolveSetting(flag, regionEnvVars, SourceSharedConfig)
+}
+
+// ResolveProfile picks the profile by explicit precedence: flag, then AWS_PROFILE, then
+// AWS_DEFAULT_PROFILE. With none set the Value is empty and the default profile is used.
+func ResolveProfile(flag string) Setting {
+	return resolveSetting(flag, profileEnvVars, SourceDefault)
+}
+
+func resolveSetting(flag string, envVars []string, fallback string) Setting {
+	if flag != "" {
+		return Setting{Value: flag, Source: SourceFlag}
+	}
+	for _, name := range envVars {
+		if value := os.Getenv(name); value != "" {
+			return Setting{Value: value, Source: name}
+		}
+	}
+	return Setting{Source: fallback}
+}
+
+// RegionSource names where sess got its region, for a session created with resolved as the region
+// given to SetRegionAndProfile: resolved's source when it has a value, otherwise instance metadata
+// or the shared config.
+func RegionSource(sess *session.Session, resolved Setting) string {
+	if resolved.Value != "" {
+		return resolved.Source
+	}
+	region := aws.StringValue(sess.Config.Region)
+	imdsRegion.Lock()
+	defer imdsRegion.Unlock()
+	if region != "" && imdsRegion.looked && region == imdsRegion.region {
+		return SourceIMDS
+	}
+	return SourceSharedConfig
+}
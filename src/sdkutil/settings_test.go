@@ -0,0 +1,73 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package sdkutil provides utilities used to call awssdk.
+package sdkutil
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/assert"
+)
+
+// WHEN the region is given by flag and environment, THEN the flag SHALL win, then AWS_REGION,
+// then AWS_DEFAULT_REGION, and with none the shared config SHALL be named as the source.
+func TestResolveRegion(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	assert.Equal(t, Setting{Source: SourceSharedConfig}, ResolveRegion(""))
+
+	t.Setenv("AWS_DEFAULT_REGION", "eu-west-1")
+	assert.Equal(t, Setting{Value: "eu-west-1", Source: "AWS_DEFAULT_REGION"}, ResolveRegion(""))
+
+	t.Setenv("AWS_REGION", "us-east-2")
+	assert.Equal(t, Setting{Value: "us-east-2", Source: "AWS_REGION"}, ResolveRegion(""))
+	assert.Equal(t, Setting{Value: "ap-south-1", Source: SourceFlag}, ResolveRegion("ap-south-1"))
+}
+
+// WHEN the profile is given by flag and environment, THEN the flag SHALL win, then AWS_PROFILE,
+// then AWS_DEFAULT_PROFILE, and with none the default profile SHALL be used.
+func TestResolveProfile(t *testing.T) {
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_DEFAULT_PROFILE", "")
+	assert.Equal(t, Setting{Source: SourceDefault}, ResolveProfile(""))
+
+	t.Setenv("AWS_DEFAULT_PROFILE", "legacy")
+	assert.Equal(t, Setting{Value: "legacy", Source: "AWS_DEFAULT_PROFILE"}, ResolveProfile(""))
+
+	t.Setenv("AWS_PROFILE", "dev")
+	assert.Equal(t, Setting{Value: "dev", Source: "AWS_PROFILE"}, ResolveProfile(""))
+	assert.Equal(t, Setting{Value: "prod", Source: SourceFlag}, ResolveProfile("prod"))
+}
+
+// WHEN no region was resolved, THEN the source SHALL be instance metadata only for the region
+// looked up there, and the shared config otherwise.
+func TestRegionSource(t *testing.T) {
+	sess := &session.Session{Config: aws.NewConfig().WithRegion("us-west-2")}
+	assert.Equal(t, "AWS_REGION", RegionSource(sess, Setting{Value: "us-west-2", Source: "AWS_REGION"}))
+	assert.Equal(t, SourceSharedConfig, RegionSource(sess, Setting{Source: SourceSharedConfig}))
+
+	imdsRegion.Lock()
+	saved := imdsRegion.looked
+	savedRegion := imdsRegion.region
+	imdsRegion.looked, imdsRegion.region = true, "us-west-2"
+	imdsRegion.Unlock()
+	defer func() {
+		imdsRegion.Lock()
+		imdsRegion.looked, imdsRegion.region = saved, savedRegion
+		imdsRegion.Unlock()
+	}()
+	assert.Equal(t, SourceIMDS, RegionSource(sess, Setting{Source: SourceSharedConfig}))
+}
@@ -0,0 +1,36 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package sdkutil provides utilities used to call awssdk.
+package sdkutil
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// throttlingErrorCodes are AWS error codes that mean the request was rate limited.
+var throttlingErrorCodes = map[string]bool{
+	"Throttling":               true,
+	"ThrottlingException":      true,
+	"ThrottledException":       true,
+	"RequestLimitExceeded":     true,
+	"TooManyRequestsException": true,
+}
+
+// IsThrottling reports whether err, or an error it wraps, is an AWS error for a rate limited request.
+func IsThrottling(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && throttlingErrorCodes[awsErr.Code()]
+}
@@ -0,0 +1,37 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package sdkutil provides utilities used to call awssdk.
+package sdkutil
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+)
+
+// WHEN an error is checked for throttling, THEN only AWS rate limiting codes SHALL count, also
+// when wrapped.
+func TestIsThrottling(t *testing.T) {
+	throttled := awserr.NewRequestFailure(awserr.New("ThrottlingException", "Rate exceeded", nil), 400, "req")
+	assert.True(t, IsThrottling(throttled))
+	assert.True(t, IsThrottling(awserr.New("RequestLimitExceeded", "limit", nil)))
+	assert.True(t, IsThrottling(fmt.Errorf("start session: %w", throttled)))
+
+	assert.False(t, IsThrottling(awserr.New("AccessDeniedException", "denied", nil)))
+	assert.False(t, IsThrottling(errors.New("ThrottlingException")))
+	assert.False(t, IsThrottling(nil))
+}
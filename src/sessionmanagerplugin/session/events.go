@@ -0,0 +1,82 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package session starts the session.
+package session
+
+import "time"
+
+// Types of ForwardEvent.
+const (
+	EventEstablished  = "established"
+	EventConnAccepted = "conn_accepted"
+	EventConnClosed   = "conn_closed"
+	EventReconnecting = "reconnecting"
+	EventReconnected  = "reconnected"
+	EventTerminated   = "terminated"
+)
+
+// DefaultEventBuffer is how many events the channel from Session.Events holds when
+// Session.EventBuffer is not set.
+const DefaultEventBuffer = 64
+
+// ForwardEvent is one change in a session's lifecycle, received from Session.Events.
+type ForwardEvent struct {
+	Type string
+	Time time.Time
+	// Source is the local client's address, for EventConnAccepted and EventConnClosed
+	Source string
+	// Conn describes the finished connection, for EventConnClosed
+	Conn *ConnRecord
+	// Err is why Execute returned, for EventTerminated; nil if the session ended normally
+	Err error
+}
+
+// Events returns a channel receiving the session's lifecycle events, for host applications to
+// observe a forward from code rather than from its logs. It must be called before Execute, as
+// port sessions work on a copy of the Session, and returns the same channel on every call.
+//
+// The channel holds EventBuffer events. When it is full, further events are dropped unless
+// BlockOnEvents is set, in which case the forward waits for the consumer. EventEstablished needs
+// PortReady, which Events creates if it is not set. The channel is never closed: a port session
+// can still report its last connections closing after EventTerminated.
+func (s *Session) Events() <-chan ForwardEvent {
+	if s.events == nil {
+		size := s.EventBuffer
+		if size <= 0 {
+			size = DefaultEventBuffer
+		}
+		s.events = make(chan ForwardEvent, size)
+		if s.PortReady == nil {
+			s.PortReady = make(chan struct{})
+		}
+	}
+	return s.events
+}
+
+// EmitEvent sends event to the channel from Events, stamped with the current time. It does
+// nothing if Events was not called.
+func (s *Session) EmitEvent(event ForwardEvent) {
+	if s.events == nil {
+		return
+	}
+	event.Time = time.Now()
+	if s.BlockOnEvents {
+		s.events <- event
+		return
+	}
+	select {
+	case s.events <- event:
+	default:
+	}
+}
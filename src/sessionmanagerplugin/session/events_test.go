@@ -0,0 +1,75 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package session starts the session.
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// WHEN Events is called, THEN it SHALL return one buffered channel for the session and its
+// copies, and create PortReady for EventEstablished.
+func TestEvents(t *testing.T) {
+	s := &Session{}
+	events := s.Events()
+	assert.Equal(t, DefaultEventBuffer, cap(events))
+	assert.Equal(t, events, s.Events())
+	assert.NotNil(t, s.PortReady)
+
+	copied := *s
+	copied.EmitEvent(ForwardEvent{Type: EventReconnecting})
+	event := <-events
+	assert.Equal(t, EventReconnecting, event.Type)
+	assert.False(t, event.Time.IsZero())
+}
+
+// WHEN Events was not called, THEN EmitEvent SHALL do nothing.
+func TestEmitEventWithoutEvents(t *testing.T) {
+	s := &Session{}
+	s.EmitEvent(ForwardEvent{Type: EventTerminated})
+	assert.Nil(t, s.events)
+}
+
+// WHEN the channel is full, THEN events SHALL be dropped, or with BlockOnEvents wait for the
+// consumer.
+func TestEmitEventFull(t *testing.T) {
+	s := &Session{EventBuffer: 1}
+	events := s.Events()
+	s.EmitEvent(ForwardEvent{Type: EventReconnecting})
+	s.EmitEvent(ForwardEvent{Type: EventReconnected})
+	assert.Equal(t, EventReconnecting, (<-events).Type)
+	assert.Len(t, events, 0)
+
+	s.BlockOnEvents = true
+	s.EmitEvent(ForwardEvent{Type: EventReconnecting})
+	sent := make(chan struct{})
+	go func() {
+		s.EmitEvent(ForwardEvent{Type: EventTerminated, Err: errors.New("failed")})
+		close(sent)
+	}()
+	select {
+	case <-sent:
+		t.Fatal("Expected EmitEvent to wait for the consumer")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, EventReconnecting, (<-events).Type)
+	<-sent
+	terminated := <-events
+	assert.Equal(t, EventTerminated, terminated.Type)
+	assert.EqualError(t, terminated.Err, "failed")
+}
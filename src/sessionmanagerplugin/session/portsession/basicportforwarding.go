@@ -26,12 +26,12 @@ import (
 	"github.com/zph/session-manager-plugin/src/log"
 	"github.com/zph/session-manager-plugin/src/message"
 	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
-	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session/sessionutil"
 	"github.com/zph/session-manager-plugin/src/version"
 )
 
 // BasicPortForwarding is type of port session
-// accepts one client connection at a time
+// accepts one client connection at a time, so Session.MaxConnections and
+// Session.AcceptConcurrency never apply
 type BasicPortForwarding struct {
 	port           IPortSession
 	stream         net.Conn
@@ -39,6 +39,13 @@ type BasicPortForwarding struct {
 	sessionId      string
 	portParameters PortParameters
 	session        session.Session
+	// uploadLimiter and downloadLimiter throttle forwarded bytes; nil when unlimited
+	uploadLimiter   *rateLimiter
+	downloadLimiter *rateLimiter
+	// tracker records the current connection for Session.OnConnClosed
+	tracker connTracker
+	// sampler picks the connections logged under Session.ConnLogSample
+	sampler connSampler
 }
 
 // IsStreamNotSet checks if stream is not set
@@ -48,6 +55,7 @@ func (p *BasicPortForwarding) IsStreamNotSet() (status bool) {
 
 // Stop closes the stream
 func (p *BasicPortForwarding) Stop() {
+	p.tracker.close(p.session, CloseReasonSessionEnded)
 	if p.listener != nil {
 		p.listener.Close()
 	}
@@ -60,6 +68,9 @@ func (p *BasicPortForwarding) Stop() {
 // InitializeStreams establishes connection and initializes the stream
 func (p *BasicPortForwarding) InitializeStreams(log log.T, agentVersion string) (err error) {
 	p.handleControlSignals(log)
+	if p.session.TransferLogInterval > 0 {
+		go logTransferProgress(log, p.session.TransferLogInterval, p.session.DataChannel.IsSessionEnded, p.tracker.progress)
+	}
 	if err = p.startLocalConn(log); err != nil {
 		return
 	}
@@ -68,10 +79,15 @@ func (p *BasicPortForwarding) InitializeStreams(log log.T, agentVersion string)
 
 // ReadStream reads data from the stream
 func (p *BasicPortForwarding) ReadStream(log log.T) (err error) {
-	msg := make([]byte, config.StreamDataPayloadSize)
+	bufferSize := config.StreamDataPayloadSize
+	if p.session.BufferSize > 0 {
+		bufferSize = p.session.BufferSize
+	}
+	msg := make([]byte, bufferSize)
 	for {
 		numBytes, err := p.stream.Read(msg)
 		if err != nil {
+			p.tracker.close(p.session, closeReason(err, CloseReasonClient))
 			log.Debugf("Reading from port %s failed with error: %v. Close this connection, listen and accept new one.",
 				p.portParameters.PortNumber, err)
 
@@ -90,10 +106,26 @@ func (p *BasicPortForwarding) ReadStream(log log.T) (err error) {
 		}
 
 		log.Tracef("Received message of size %d from stdin.", numBytes)
-		if err = p.session.DataChannel.SendInputDataMessage(log, message.Output, msg[:numBytes]); err != nil {
+		if p.session.ReadOnly {
+			// Keep reading so the client isn't blocked, but send nothing to the remote
+			continue
+		}
+		p.tracker.bytesIn.Add(int64(numBytes))
+		p.uploadLimiter.wait(numBytes)
+		data, err := interceptOutbound(p.session, msg[:numBytes])
+		if err != nil {
 			log.Errorf("Failed to send packet: %v", err)
 			return err
 		}
+		// Reads may exceed the data channel payload size, so send them in payload-sized chunks
+		for start := 0; start < len(data); start += config.StreamDataPayloadSize {
+			end := min(start+config.StreamDataPayloadSize, len(data))
+			if err = p.session.DataChannel.SendInputDataMessage(log, message.Output, data[start:end]); err != nil {
+				log.Errorf("Failed to send packet: %v", err)
+				return err
+			}
+			p.session.Transfer.AddSent(end - start)
+		}
 		// Sleep to process more data
 		time.Sleep(time.Millisecond)
 	}
@@ -101,7 +133,9 @@ func (p *BasicPortForwarding) ReadStream(log log.T) (err error) {
 
 // WriteStream writes data to stream
 func (p *BasicPortForwarding) WriteStream(outputMessage message.ClientMessage) error {
-	_, err := p.stream.Write(outputMessage.Payload)
+	p.downloadLimiter.wait(len(outputMessage.Payload))
+	n, err := p.stream.Write(outputMessage.Payload)
+	p.tracker.bytesOut.Add(int64(n))
 	return err
 }
 
@@ -125,7 +159,10 @@ func (p *BasicPortForwarding) startLocalConn(log log.T) (err error) {
 		}
 	}
 	if p.session.DataChannel.IsSessionEnded() == false {
-		log.Infof("Connection accepted for session %s.", p.sessionId)
+		p.sampler.logger(log, p.session.ConnLogSample).Infof("Connection accepted for session %s.", p.sessionId)
+	}
+	if p.stream != nil {
+		p.tracker.open(p.session, p.stream)
 	}
 
 	return
@@ -141,18 +178,19 @@ func (p *BasicPortForwarding) startLocalListener(log log.T, portNumber string) (
 	var displayMessage string
 	switch p.portParameters.LocalConnectionType {
 	case "unix":
-		if p.listener, err = net.Listen(p.portParameters.LocalConnectionType, p.portParameters.LocalUnixSocket); err != nil {
+		if p.listener, err = listenLocal(p.session, p.portParameters.LocalConnectionType, p.portParameters.LocalUnixSocket); err != nil {
 			return
 		}
 		displayMessage = fmt.Sprintf("Unix socket %s opened for sessionId %s.", p.portParameters.LocalUnixSocket, p.sessionId)
 	default:
-		if p.listener, err = net.Listen("tcp", "localhost:"+portNumber); err != nil {
+		if p.listener, err = listenLocal(p.session, "tcp", localListenAddress(p.session, portNumber)); err != nil {
 			return
 		}
 		// get port number the TCP listener opened
 		p.portParameters.LocalPortNumber = strconv.Itoa(p.listener.Addr().(*net.TCPAddr).Port)
 		displayMessage = fmt.Sprintf("Port %s opened for sessionId %s.", p.portParameters.LocalPortNumber, p.sessionId)
 	}
+	p.listener = wrapLocalListener(p.session, p.listener)
 
 	log.Info(displayMessage)
 	return
@@ -161,9 +199,11 @@ func (p *BasicPortForwarding) startLocalListener(log log.T, portNumber string) (
 // handleControlSignals handles terminate signals
 func (p *BasicPortForwarding) handleControlSignals(log log.T) {
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, sessionutil.ControlSignals...)
+	signal.Notify(c, controlSignals(p.session)...)
 	go func() {
 		<-c
+		// Basic forwarding serves a single connection, so there is nothing to drain
+		closeDrained(p.session)
 		p.session.DataChannel.EndSession()
 		if version.DoesAgentSupportTerminateSessionFlag(log, p.session.DataChannel.GetAgentVersion()) {
 			if err := p.session.DataChannel.SendFlag(log, message.TerminateSession); err != nil {
@@ -188,6 +228,9 @@ func (p *BasicPortForwarding) reconnect(log log.T) (err error) {
 			return err
 		}
 	}
+	if p.stream != nil {
+		p.tracker.open(p.session, p.stream)
+	}
 
 	return
 }
@@ -0,0 +1,115 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+// Close reasons reported in session.ConnRecord
+const (
+	CloseReasonClient       = "client_closed"
+	CloseReasonRemote       = "remote_closed"
+	CloseReasonSessionEnded = "session_ended"
+	CloseReasonIdle         = "idle_timeout"
+)
+
+// closeReason describes why a copy direction stopped. A nil or EOF error is an orderly
+// close by the side being read; anything else is reported as is.
+func closeReason(err error, orderly string) string {
+	if err == nil || errors.Is(err, io.EOF) {
+		return orderly
+	}
+	if errors.Is(err, net.ErrClosed) {
+		return CloseReasonSessionEnded
+	}
+	return err.Error()
+}
+
+// reportConnOpened passes a newly forwarded connection to the session's OnConnOpened hook, if
+// any, and its events.
+func reportConnOpened(s session.Session, source string) {
+	if s.OnConnOpened != nil {
+		s.OnConnOpened(source)
+	}
+	s.EmitEvent(session.ForwardEvent{Type: session.EventConnAccepted, Source: source})
+}
+
+// reportConn passes a finished connection to the session's OnConnClosed hook, if any, and its
+// events.
+func reportConn(s session.Session, source string, opened time.Time, bytesIn int64, bytesOut int64, reason string) {
+	record := session.ConnRecord{
+		Source:      source,
+		Opened:      opened,
+		Duration:    time.Since(opened).String(),
+		BytesIn:     bytesIn,
+		BytesOut:    bytesOut,
+		CloseReason: reason,
+	}
+	if s.OnConnClosed != nil {
+		s.OnConnClosed(record)
+	}
+	s.EmitEvent(session.ForwardEvent{Type: session.EventConnClosed, Source: source, Conn: &record})
+}
+
+// connTracker accumulates byte counts for the single connection served by basic forwarding.
+// Reads and writes happen on different goroutines, so counters are atomic.
+type connTracker struct {
+	mutex    sync.Mutex
+	source   string
+	opened   time.Time
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+}
+
+// open starts tracking a newly accepted connection and reports it as opened.
+func (t *connTracker) open(s session.Session, conn net.Conn) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.source = conn.RemoteAddr().String()
+	t.opened = time.Now()
+	t.bytesIn.Store(0)
+	t.bytesOut.Store(0)
+	reportConnOpened(s, t.source)
+}
+
+// close reports the tracked connection, at most once per open.
+func (t *connTracker) close(s session.Session, reason string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.opened.IsZero() {
+		return
+	}
+	reportConn(s, t.source, t.opened, t.bytesIn.Load(), t.bytesOut.Load(), reason)
+	t.opened = time.Time{}
+}
+
+// progress returns the open connection's source and bytes in and out so far; the source is
+// empty when no connection is open.
+func (t *connTracker) progress() (source string, bytesIn int64, bytesOut int64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.opened.IsZero() {
+		return "", 0, 0
+	}
+	return t.source, t.bytesIn.Load(), t.bytesOut.Load()
+}
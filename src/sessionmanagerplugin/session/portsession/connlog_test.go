@@ -0,0 +1,107 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+// WHEN the local client closes first, THEN handleDataTransfer SHALL report the bytes copied
+// in each direction and the client as the close reason.
+func TestHandleDataTransferStats(t *testing.T) {
+	client, src := net.Pipe()
+	dst, remote := net.Pipe()
+	defer remote.Close()
+
+	go func() {
+		client.Write([]byte("hello"))
+		client.Close()
+	}()
+	go io.Copy(io.Discard, remote)
+
+	stats := handleDataTransfer(dst, src, 0, false)
+	assert.Equal(t, int64(5), stats.toDst)
+	assert.Equal(t, int64(0), stats.toSrc)
+	assert.Equal(t, CloseReasonClient, stats.reason)
+}
+
+// WHEN a copy stops, THEN closeReason SHALL distinguish orderly closes, session shutdown and errors.
+func TestCloseReason(t *testing.T) {
+	assert.Equal(t, CloseReasonRemote, closeReason(nil, CloseReasonRemote))
+	assert.Equal(t, CloseReasonClient, closeReason(io.EOF, CloseReasonClient))
+	assert.Equal(t, CloseReasonSessionEnded, closeReason(net.ErrClosed, CloseReasonClient))
+	assert.Equal(t, "connection reset", closeReason(errors.New("connection reset"), CloseReasonClient))
+}
+
+// WHEN a tracked connection opens and closes, THEN connTracker SHALL report the open, and the
+// close once with its byte counts.
+func TestConnTrackerReportsOnce(t *testing.T) {
+	var records []session.ConnRecord
+	var opened []string
+	s := session.Session{
+		OnConnOpened: func(source string) { opened = append(opened, source) },
+		OnConnClosed: func(record session.ConnRecord) {
+			records = append(records, record)
+		},
+	}
+
+	conn, peer := net.Pipe()
+	defer conn.Close()
+	defer peer.Close()
+
+	var tracker connTracker
+	tracker.close(s, CloseReasonSessionEnded) // nothing open yet
+	tracker.open(s, conn)
+	tracker.bytesIn.Add(3)
+	tracker.bytesOut.Add(7)
+	tracker.close(s, CloseReasonClient)
+	tracker.close(s, CloseReasonSessionEnded)
+
+	assert.Equal(t, []string{conn.RemoteAddr().String()}, opened)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, conn.RemoteAddr().String(), records[0].Source)
+		assert.Equal(t, int64(3), records[0].BytesIn)
+		assert.Equal(t, int64(7), records[0].BytesOut)
+		assert.Equal(t, CloseReasonClient, records[0].CloseReason)
+		assert.False(t, records[0].Opened.IsZero())
+	}
+}
+
+// WHEN Events was called, THEN connections opening and closing SHALL be sent as events, without
+// hooks being set.
+func TestReportConnEvents(t *testing.T) {
+	s := session.Session{}
+	events := s.Events()
+
+	reportConnOpened(s, "127.0.0.1:5000")
+	reportConn(s, "127.0.0.1:5000", time.Now(), 3, 7, CloseReasonClient)
+
+	accepted := <-events
+	assert.Equal(t, session.EventConnAccepted, accepted.Type)
+	assert.Equal(t, "127.0.0.1:5000", accepted.Source)
+	closed := <-events
+	assert.Equal(t, session.EventConnClosed, closed.Type)
+	if assert.NotNil(t, closed.Conn) {
+		assert.Equal(t, int64(3), closed.Conn.BytesIn)
+		assert.Equal(t, CloseReasonClient, closed.Conn.CloseReason)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"sync/atomic"
+
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// connSampler picks the local connections whose opening and progress are logged, one in every
+// Session.ConnLogSample starting with the first. The zero value is ready to use.
+type connSampler struct {
+	count atomic.Uint64
+}
+
+// logger returns log for a sampled connection and, for the others, a logger that drops
+// everything below warning level.
+func (c *connSampler) logger(log log.T, every int) log.T {
+	if every <= 1 || c.count.Add(1)%uint64(every) == 1 {
+		return log
+	}
+	return quietLogger{log}
+}
+
+// quietLogger passes warnings and errors on to T and drops trace, debug and info messages.
+type quietLogger struct {
+	log.T
+}
+
+func (quietLogger) Tracef(format string, params ...interface{}) {}
+func (quietLogger) Debugf(format string, params ...interface{}) {}
+func (quietLogger) Infof(format string, params ...interface{})  {}
+func (quietLogger) Trace(v ...interface{})                      {}
+func (quietLogger) Debug(v ...interface{})                      {}
+func (quietLogger) Info(v ...interface{})                       {}
+
+// WithContext keeps the context logger quiet too.
+func (q quietLogger) WithContext(context ...string) log.T {
+	return quietLogger{q.T.WithContext(context...)}
+}
@@ -0,0 +1,42 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// WHEN ConnLogSample is N, THEN the first of every N connections SHALL get the session's logger
+// and the rest a logger that drops messages below warning level.
+func TestConnSamplerLogger(t *testing.T) {
+	var sampler connSampler
+	var logged []int
+	for i := 1; i <= 7; i++ {
+		if _, quiet := sampler.logger(mockLog, 3).(quietLogger); !quiet {
+			logged = append(logged, i)
+		}
+	}
+	assert.Equal(t, []int{1, 4, 7}, logged)
+}
+
+// WHEN ConnLogSample is 0 or 1, THEN every connection SHALL be logged.
+func TestConnSamplerLogsAll(t *testing.T) {
+	var sampler connSampler
+	for _, every := range []int{0, 1} {
+		assert.Equal(t, mockLog, sampler.logger(mockLog, every))
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	dataChannelMock "github.com/zph/session-manager-plugin/src/datachannel/mocks"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+// WHEN IgnoreInterrupt is set, THEN the port session SHALL stop on its other control signals only.
+func TestControlSignalsIgnoreInterrupt(t *testing.T) {
+	assert.Contains(t, controlSignals(session.Session{}), os.Interrupt)
+	signals := controlSignals(session.Session{IgnoreInterrupt: true})
+	assert.NotContains(t, signals, os.Interrupt)
+	assert.Contains(t, signals, syscall.SIGQUIT)
+}
+
+// signalChildEnv marks the test process re-run by TestHandleControlSignalsIgnoresInterrupt.
+const signalChildEnv = "PORTSESSION_SIGNAL_CHILD"
+
+// WHEN IgnoreInterrupt is set and SIGINT arrives after the control signal handler is installed,
+// THEN the session SHALL NOT be terminated.
+func TestHandleControlSignalsIgnoresInterrupt(t *testing.T) {
+	if os.Getenv(signalChildEnv) == "" {
+		// SIGINT reaches every handler in the process, including those of other tests' sessions,
+		// so it is raised in a child running only this test
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHandleControlSignalsIgnoresInterrupt$")
+		cmd.Env = append(os.Environ(), signalChildEnv+"=1")
+		output, err := cmd.CombinedOutput()
+		assert.Nil(t, err, "%s", output)
+		return
+	}
+
+	dataChannel := &dataChannelMock.IDataChannel{}
+	drained := make(chan struct{})
+	p := &MuxPortForwarding{session: session.Session{
+		DataChannel:     dataChannel,
+		Drained:         drained,
+		IgnoreInterrupt: true,
+	}}
+	p.handleControlSignals(mockLog)
+
+	// Observe the signal here so the test process is not killed by it
+	delivered := make(chan os.Signal, 1)
+	signal.Notify(delivered, syscall.SIGINT)
+	defer signal.Stop(delivered)
+	assert.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGINT))
+	select {
+	case <-delivered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGINT was not delivered")
+	}
+
+	select {
+	case <-drained:
+		t.Fatal("Session was stopped on SIGINT")
+	case <-time.After(200 * time.Millisecond):
+	}
+	dataChannel.AssertNotCalled(t, "SendFlag")
+}
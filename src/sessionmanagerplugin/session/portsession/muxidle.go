@@ -0,0 +1,123 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// muxChannel is the local end of one multiplexed client connection, tracked so it can be
+// closed once it has moved no data for Session.MuxIdleTimeout.
+type muxChannel struct {
+	source     string
+	conn       io.Closer
+	lastActive atomic.Int64 // unix nanoseconds
+	reaped     atomic.Bool
+}
+
+func (c *muxChannel) touch() {
+	c.lastActive.Store(time.Now().UnixNano())
+}
+
+// activityConn stamps its channel as active whenever data is read or written.
+type activityConn struct {
+	io.ReadWriteCloser
+	channel *muxChannel
+}
+
+func (c activityConn) Read(b []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(b)
+	if n > 0 {
+		c.channel.touch()
+	}
+	return n, err
+}
+
+func (c activityConn) Write(b []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Write(b)
+	if n > 0 {
+		c.channel.touch()
+	}
+	return n, err
+}
+
+// trackIdle registers the local end of a client connection for idle reaping and wraps it to
+// stamp its activity. Without a MuxIdleTimeout conn is returned unchanged with a nil channel.
+func (p *MuxPortForwarding) trackIdle(source string, conn io.ReadWriteCloser) (io.ReadWriteCloser, *muxChannel) {
+	if p.session.MuxIdleTimeout <= 0 {
+		return conn, nil
+	}
+	channel := &muxChannel{source: source, conn: conn}
+	channel.touch()
+
+	p.channelMutex.Lock()
+	defer p.channelMutex.Unlock()
+	if p.channels == nil {
+		p.channels = make(map[*muxChannel]struct{})
+	}
+	p.channels[channel] = struct{}{}
+	return activityConn{conn, channel}, channel
+}
+
+// untrackIdle forgets channel once its connection has finished and reports whether it was
+// closed for being idle.
+func (p *MuxPortForwarding) untrackIdle(channel *muxChannel) bool {
+	if channel == nil {
+		return false
+	}
+	p.channelMutex.Lock()
+	defer p.channelMutex.Unlock()
+	delete(p.channels, channel)
+	return channel.reaped.Load()
+}
+
+// reapIdleChannels periodically closes channels that have moved no data for Session.MuxIdleTimeout
+func (p *MuxPortForwarding) reapIdleChannels(log log.T, ctx context.Context) error {
+	ticker := time.NewTicker(p.session.MuxIdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			p.expireIdleChannels(log, time.Now().Add(-p.session.MuxIdleTimeout))
+		}
+	}
+}
+
+// expireIdleChannels closes channels last active before cutoff. Closing the local end ends the
+// connection's data transfer, which closes its mux stream in turn.
+func (p *MuxPortForwarding) expireIdleChannels(log log.T, cutoff time.Time) {
+	p.channelMutex.Lock()
+	var idle []*muxChannel
+	for channel := range p.channels {
+		if channel.lastActive.Load() < cutoff.UnixNano() {
+			idle = append(idle, channel)
+			delete(p.channels, channel)
+		}
+	}
+	p.channelMutex.Unlock()
+
+	for _, channel := range idle {
+		log.Infof("Closing idle connection from %s for session [%s]: no data for %v", channel.source, p.sessionId, p.session.MuxIdleTimeout)
+		channel.reaped.Store(true)
+		channel.conn.Close()
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// WHEN data moves through a tracked connection, THEN its channel SHALL be stamped active.
+func TestTrackIdleStampsActivity(t *testing.T) {
+	conn, peer := net.Pipe()
+	defer peer.Close()
+
+	s := getSessionMock()
+	s.MuxIdleTimeout = time.Minute
+	p := &MuxPortForwarding{session: s}
+	local, channel := p.trackIdle("src", conn)
+	defer local.Close()
+	assert.Contains(t, p.channels, channel)
+
+	channel.lastActive.Store(0)
+	go peer.Write([]byte("abc"))
+	local.Read(make([]byte, 3))
+	assert.NotZero(t, channel.lastActive.Load())
+
+	assert.False(t, p.untrackIdle(channel))
+	assert.NotContains(t, p.channels, channel)
+}
+
+// WHEN no mux idle timeout is set, THEN trackIdle SHALL leave the connection unwrapped and untracked.
+func TestTrackIdleDisabled(t *testing.T) {
+	conn, peer := net.Pipe()
+	defer conn.Close()
+	defer peer.Close()
+
+	p := &MuxPortForwarding{session: getSessionMock()}
+	local, channel := p.trackIdle("src", conn)
+	assert.Equal(t, conn, local)
+	assert.Nil(t, channel)
+	assert.False(t, p.untrackIdle(channel))
+}
+
+// WHEN a channel has moved no data since the cutoff, THEN expireIdleChannels SHALL close it and
+// mark it reaped, leaving active channels open.
+func TestExpireIdleChannels(t *testing.T) {
+	s := getSessionMock()
+	s.MuxIdleTimeout = time.Minute
+	p := &MuxPortForwarding{session: s}
+
+	idleConn, idlePeer := net.Pipe()
+	defer idlePeer.Close()
+	activeConn, activePeer := net.Pipe()
+	defer activeConn.Close()
+	defer activePeer.Close()
+
+	_, idle := p.trackIdle("idle", idleConn)
+	idle.lastActive.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	_, active := p.trackIdle("active", activeConn)
+
+	p.expireIdleChannels(log.NewMockLog(), time.Now().Add(-time.Minute))
+
+	assert.NotContains(t, p.channels, idle)
+	assert.Contains(t, p.channels, active)
+	assert.True(t, p.untrackIdle(idle))
+	assert.False(t, active.reaped.Load())
+	_, err := idlePeer.Read(make([]byte, 1))
+	assert.Error(t, err, "idle connection should be closed")
+}
+
+// WHEN a reaped connection's transfer ends, THEN it SHALL be reported with the idle close reason.
+func TestReapedTransferEnds(t *testing.T) {
+	s := getSessionMock()
+	s.MuxIdleTimeout = time.Minute
+	p := &MuxPortForwarding{session: s}
+
+	client, src := net.Pipe()
+	defer client.Close()
+	dst, remote := net.Pipe()
+	defer remote.Close()
+
+	local, channel := p.trackIdle("client", src)
+	channel.lastActive.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	done := make(chan transferStats, 1)
+	go func() { done <- handleDataTransfer(dst, local, 0, false) }()
+
+	p.expireIdleChannels(log.NewMockLog(), time.Now().Add(-time.Minute))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("transfer did not end after the channel was reaped")
+	}
+	assert.True(t, p.untrackIdle(channel))
+}
@@ -17,7 +17,9 @@ package portsession
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"hash/fnv"
 	"io"
@@ -27,6 +29,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/xtaci/smux"
@@ -39,6 +42,10 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+// remoteTLSHandshakeTimeout bounds the TLS handshake with the remote for each tunnelled connection
+// when the session sets no ConnectTimeout.
+var remoteTLSHandshakeTimeout = 10 * time.Second
+
 // MuxClient contains smux client session and corresponding network connection
 type MuxClient struct {
 	conn          net.Conn
@@ -62,6 +69,20 @@ type MuxPortForwarding struct {
 	session        session.Session
 	muxClient      *MuxClient
 	mgsConn        *MgsConn
+	activeConns    int32
+	draining       int32
+	// uploadLimiter and downloadLimiter are shared by all client connections; nil when unlimited
+	uploadLimiter   *rateLimiter
+	downloadLimiter *rateLimiter
+	// stdio is the local end of a stdio forward; os.Stdin and os.Stdout when nil
+	stdio io.ReadWriteCloser
+	// channels are the open client connections tracked for idle reaping
+	channels     map[*muxChannel]struct{}
+	channelMutex sync.Mutex
+	// setupSlots bounds connections setting up their tunnel stream at once; nil when unlimited
+	setupSlots chan struct{}
+	// sampler picks the connections logged under Session.ConnLogSample
+	sampler connSampler
 }
 
 func (c *MgsConn) close() {
@@ -128,17 +149,27 @@ func (p *MuxPortForwarding) ReadStream(log log.T) (err error) {
 		return p.handleClientConnections(log, ctx)
 	})
 
+	if p.session.MuxIdleTimeout > 0 {
+		g.Go(func() error {
+			return p.reapIdleChannels(log, ctx)
+		})
+	}
+
 	g.Go(func() error {
 		for {
 			time.Sleep(50 * time.Millisecond)
-			if p.session.DataChannel.IsSessionEnded() == true {
+			if p.session.DataChannel.IsSessionEnded() == true || ctx.Err() != nil {
 				p.Stop()
 				return nil
 			}
 		}
 	})
 
-	return g.Wait()
+	if err = g.Wait(); errors.Is(err, errStdioClosed) {
+		p.session.DataChannel.EndSession()
+		return nil
+	}
+	return err
 }
 
 // WriteStream writes data to stream
@@ -205,9 +236,13 @@ func (p *MuxPortForwarding) initialize(log log.T, agentVersion string) (err erro
 // handleControlSignals handles terminate signals
 func (p *MuxPortForwarding) handleControlSignals(log log.T) {
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, sessionutil.ControlSignals...)
+	signal.Notify(c, controlSignals(p.session)...)
 	go func() {
 		<-c
+		if p.session.DrainTimeout > 0 {
+			p.drain(log, p.session.DrainTimeout)
+		}
+		closeDrained(p.session)
 		if err := p.session.DataChannel.SendFlag(log, message.TerminateSession); err != nil {
 			log.Errorf("Failed to send TerminateSession flag: %v", err)
 		}
@@ -230,10 +265,11 @@ func (p *MuxPortForwarding) transferDataToServer(log log.T, ctx context.Context)
 			}
 
 			log.Tracef("Received message of size %d from mux client.", numBytes)
-			if err = p.session.DataChannel.SendInputDataMessage(log, message.Output, msg[:numBytes]); err != nil {
+			if err = sendOutput(log, p.session, msg[:numBytes]); err != nil {
 				log.Errorf("Failed to send packet on data channel: %v", err)
 				return
 			}
+			p.session.Transfer.AddSent(numBytes)
 			// sleep to process more data
 			time.Sleep(time.Millisecond)
 		}
@@ -246,8 +282,11 @@ func (p *MuxPortForwarding) handleClientConnections(log log.T, ctx context.Conte
 		displayMsg string
 	)
 
+	if p.session.PortForwardingStdio {
+		return p.handleStdio(log, ctx)
+	}
 	if p.portParameters.LocalConnectionType == "unix" {
-		if p.muxClient.localListener, err = net.Listen(p.portParameters.LocalConnectionType, p.portParameters.LocalUnixSocket); err != nil {
+		if p.muxClient.localListener, err = listenLocal(p.session, p.portParameters.LocalConnectionType, p.portParameters.LocalUnixSocket); err != nil {
 			return err
 		}
 		displayMsg = fmt.Sprintf("Unix socket %s opened for sessionId %s.", p.portParameters.LocalUnixSocket, p.sessionId)
@@ -256,12 +295,13 @@ func (p *MuxPortForwarding) handleClientConnections(log log.T, ctx context.Conte
 		if p.portParameters.LocalPortNumber == "" {
 			localPortNumber = "0"
 		}
-		if p.muxClient.localListener, err = net.Listen("tcp", "localhost:"+localPortNumber); err != nil {
+		if p.muxClient.localListener, err = listenLocal(p.session, "tcp", localListenAddress(p.session, localPortNumber)); err != nil {
 			return err
 		}
 		p.portParameters.LocalPortNumber = strconv.Itoa(p.muxClient.localListener.Addr().(*net.TCPAddr).Port)
 		displayMsg = fmt.Sprintf("Port %s opened for sessionId %s.", p.portParameters.LocalPortNumber, p.sessionId)
 	}
+	p.muxClient.localListener = wrapLocalListener(p.session, p.muxClient.localListener)
 
 	defer p.muxClient.localListener.Close()
 
@@ -269,45 +309,252 @@ func (p *MuxPortForwarding) handleClientConnections(log log.T, ctx context.Conte
 
 	log.Infof("Waiting for connections...\n")
 
+	if p.session.AcceptConcurrency > 0 {
+		p.setupSlots = make(chan struct{}, p.session.AcceptConcurrency)
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 			if conn, err := p.muxClient.localListener.Accept(); err != nil {
+				if atomic.LoadInt32(&p.draining) == 1 {
+					return nil
+				}
 				log.Errorf("Error while accepting connection: %v", err)
 			} else {
-				log.Infof("Connection accepted from %s\n for session [%s]", conn.RemoteAddr(), p.sessionId)
-
-				stream, err := p.muxClient.session.OpenStream()
-				if err != nil {
+				connLog := p.sampler.logger(log, p.session.ConnLogSample)
+				if p.session.Paused != nil && p.session.Paused.Load() {
+					connLog.Infof("Closing connection from %s for session [%s]: forward is paused", conn.RemoteAddr(), p.sessionId)
+					conn.Close()
+					continue
+				}
+				if !p.acquireConn() {
+					log.Warnf("Rejecting connection from %s for session [%s]: connection limit of %d reached",
+						conn.RemoteAddr(), p.sessionId, p.session.MaxConnections)
+					conn.Close()
 					continue
 				}
-				log.Debugf("Client stream opened %d\n", stream.ID())
-				go handleDataTransfer(stream, conn)
+				connLog.Infof("Connection accepted from %s\n for session [%s]", conn.RemoteAddr(), p.sessionId)
+
+				// Set up each connection on its own goroutine so a slow tunnel stream or
+				// remote TLS handshake doesn't hold up accepting the next one
+				go func() {
+					defer p.releaseConn()
+					opened := time.Now()
+					stream, ok := p.setupConn(connLog, ctx, conn, opened)
+					if !ok {
+						return
+					}
+					remoteWrites := p.watchWriteTimeout(connLog, conn, stream, "remote")
+					localWrites := p.watchWriteTimeout(connLog, conn, conn, "local client")
+					remote := p.watchRemoteFailure(connLog, conn, remoteWrites, opened)
+					local, stopProgress := p.trackTransferProgress(connLog, conn.RemoteAddr().String(), limitConn(localWrites, p.uploadLimiter, p.downloadLimiter))
+					local, channel := p.trackIdle(conn.RemoteAddr().String(), local)
+					stats := handleDataTransfer(remote, local, p.session.BufferSize, p.session.ReadOnly)
+					stopProgress()
+					if stats.reason == CloseReasonRemote && remote.reason != "" {
+						stats.reason = remote.reason
+					}
+					if remoteWrites.timedOut.Load() || localWrites.timedOut.Load() {
+						stats.reason = CloseReasonWriteTimeout
+					}
+					if p.untrackIdle(channel) {
+						stats.reason = CloseReasonIdle
+					}
+					reportConn(p.session, conn.RemoteAddr().String(), opened, stats.toDst, stats.toSrc, stats.reason)
+				}()
 			}
 		}
 	}
 }
 
-// handleDataTransfer launches routines to transfer data between source and destination
-func handleDataTransfer(dst io.ReadWriteCloser, src io.ReadWriteCloser) {
+// setupConn opens the tunnel stream for an accepted connection, sends any PROXY header and
+// originates remote TLS, returning the remote end to copy to. At most Session.AcceptConcurrency
+// connections are set up at once; the rest wait their turn. On failure it closes conn and
+// returns false, reporting the connection as opened at opened if its stream was set up.
+func (p *MuxPortForwarding) setupConn(log log.T, ctx context.Context, conn net.Conn, opened time.Time) (io.ReadWriteCloser, bool) {
+	if p.setupSlots != nil {
+		select {
+		case p.setupSlots <- struct{}{}:
+			defer func() { <-p.setupSlots }()
+		case <-ctx.Done():
+			conn.Close()
+			return nil, false
+		}
+	}
+
+	stream, err := p.openStream()
+	if err != nil {
+		log.Warnf("Closing connection from %s: failed to open tunnel stream: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return nil, false
+	}
+	log.Debugf("Client stream opened %d\n", stream.ID())
+	reportConnOpened(p.session, conn.RemoteAddr().String())
+	if err := p.writeProxyHeader(stream, conn); err != nil {
+		log.Warnf("Failed to send PROXY header for connection from %s: %v", conn.RemoteAddr(), err)
+		stream.Close()
+		conn.Close()
+		reportConn(p.session, conn.RemoteAddr().String(), opened, 0, 0, CloseReasonRemote)
+		return nil, false
+	}
+	remote, err := originateRemoteTLS(ctx, stream, p.session.RemoteTLSConfig, p.connectTimeout(remoteTLSHandshakeTimeout))
+	if err != nil {
+		log.Warnf("TLS handshake with remote failed for connection from %s: %v", conn.RemoteAddr(), err)
+		stream.Close()
+		conn.Close()
+		reportConn(p.session, conn.RemoteAddr().String(), opened, 0, 0, CloseReasonRemote)
+		return nil, false
+	}
+	return remote, true
+}
+
+// errConnectTimeout is returned when a mux stream is not opened within the session's ConnectTimeout.
+var errConnectTimeout = errors.New("connect timeout expired")
+
+// connectTimeout returns the session's ConnectTimeout, or fallback when none is set.
+func (p *MuxPortForwarding) connectTimeout(fallback time.Duration) time.Duration {
+	if p.session.ConnectTimeout > 0 {
+		return p.session.ConnectTimeout
+	}
+	return fallback
+}
+
+// openStream opens the mux stream for a newly accepted connection. A stalled data channel
+// blocks OpenStream indefinitely, so it is abandoned after the session's ConnectTimeout.
+// OpenStream returns once the stream is requested; it does not wait for the remote end.
+func (p *MuxPortForwarding) openStream() (*smux.Stream, error) {
+	if p.session.ConnectTimeout <= 0 {
+		return p.muxClient.session.OpenStream()
+	}
+
+	type result struct {
+		stream *smux.Stream
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		stream, err := p.muxClient.session.OpenStream()
+		done <- result{stream, err}
+	}()
+
+	timer := time.NewTimer(p.session.ConnectTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.stream, r.err
+	case <-timer.C:
+		go func() {
+			// Close the stream if it opens after the connection was given up on
+			if r := <-done; r.stream != nil {
+				r.stream.Close()
+			}
+		}()
+		return nil, fmt.Errorf("%w after %v", errConnectTimeout, p.session.ConnectTimeout)
+	}
+}
+
+// acquireConn reserves a slot for a new client connection.
+// It returns false when the session's MaxConnections limit has been reached.
+func (p *MuxPortForwarding) acquireConn() bool {
+	active := atomic.AddInt32(&p.activeConns, 1)
+	if p.session.MaxConnections > 0 && int(active) > p.session.MaxConnections {
+		atomic.AddInt32(&p.activeConns, -1)
+		return false
+	}
+	return true
+}
+
+// releaseConn frees a slot reserved by acquireConn.
+func (p *MuxPortForwarding) releaseConn() {
+	atomic.AddInt32(&p.activeConns, -1)
+}
+
+// drain stops accepting new client connections and waits up to timeout for open ones to close.
+func (p *MuxPortForwarding) drain(log log.T, timeout time.Duration) {
+	atomic.StoreInt32(&p.draining, 1)
+	if p.muxClient != nil && p.muxClient.localListener != nil {
+		p.muxClient.localListener.Close()
+	}
+
+	log.Infof("Draining %d open connections for session [%s] (timeout: %v)", atomic.LoadInt32(&p.activeConns), p.sessionId, timeout)
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt32(&p.activeConns) > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if remaining := atomic.LoadInt32(&p.activeConns); remaining > 0 {
+		log.Warnf("Drain timeout exceeded with %d connections still open, terminating", remaining)
+	} else {
+		log.Infof("All connections drained for session [%s]", p.sessionId)
+	}
+}
+
+// transferStats summarises a finished handleDataTransfer.
+type transferStats struct {
+	toDst  int64  // bytes copied from src to dst
+	toSrc  int64  // bytes copied from dst to src
+	reason string // why the first direction stopped
+}
+
+// handleDataTransfer launches routines to transfer data between source and destination.
+// A positive bufferSize sets the copy buffer in each direction; zero keeps io.Copy's defaults.
+// When readOnly is set, src is still read until it closes but its bytes are discarded, not sent to dst.
+func handleDataTransfer(dst io.ReadWriteCloser, src io.ReadWriteCloser, bufferSize int, readOnly bool) (stats transferStats) {
 	var wait sync.WaitGroup
+	var once sync.Once
 	wait.Add(2)
 
 	go func() {
-		io.Copy(dst, src)
+		var n int64
+		var err error
+		if readOnly {
+			_, err = copyWithBuffer(io.Discard, src, bufferSize)
+		} else {
+			n, err = copyWithBuffer(dst, src, bufferSize)
+		}
+		once.Do(func() { stats.reason = closeReason(err, CloseReasonClient) })
+		stats.toDst = n
 		dst.Close()
 		wait.Done()
 	}()
 
 	go func() {
-		io.Copy(src, dst)
+		n, err := copyWithBuffer(src, dst, bufferSize)
+		once.Do(func() { stats.reason = closeReason(err, CloseReasonRemote) })
+		stats.toSrc = n
 		src.Close()
 		wait.Done()
 	}()
 
 	wait.Wait()
+	return stats
+}
+
+// originateRemoteTLS performs a TLS handshake with the remote over stream when cfg is set, so the
+// local client can speak plaintext to a TLS-only backend. Without cfg the stream is returned as is.
+func originateRemoteTLS(ctx context.Context, stream net.Conn, cfg *tls.Config, timeout time.Duration) (io.ReadWriteCloser, error) {
+	if cfg == nil {
+		return stream, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn := tls.Client(stream, cfg)
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// copyWithBuffer copies src to dst through a bufferSize buffer. io.CopyBuffer ignores the
+// buffer when either side implements WriterTo/ReaderFrom (TCP connections and smux streams do),
+// so those are hidden to make an explicit size take effect.
+func copyWithBuffer(dst io.Writer, src io.Reader, bufferSize int) (int64, error) {
+	if bufferSize <= 0 {
+		return io.Copy(dst, src)
+	}
+	return io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, make([]byte, bufferSize))
 }
 
 // getUnixSocketPath generates the unix socket file name based on sessionId and returns the path.
@@ -15,11 +15,20 @@
 package portsession
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
 	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/xtaci/smux"
 )
 
 // MockNetListener is a mock net.Listener for testing
@@ -91,7 +100,7 @@ func TestHandleDataTransferSrcToDst(t *testing.T) {
 		done <- true
 	}()
 
-	handleDataTransfer(in1, out)
+	handleDataTransfer(in1, out, 0, false)
 	<-done // Wait for read goroutine to complete
 	assert.EqualValues(t, outputMessage.Payload, msg)
 }
@@ -144,7 +153,353 @@ func TestHandleDataTransferDstToSrc(t *testing.T) {
 		done <- true
 	}()
 
-	handleDataTransfer(in, out1)
+	handleDataTransfer(in, out1, 0, false)
 	<-done // Wait for read goroutine to complete
 	assert.EqualValues(t, outputMessage.Payload, msg)
 }
+
+// WHEN the session is read-only, THEN handleDataTransfer SHALL deliver the remote's bytes to the
+// client while discarding everything the client sends.
+func TestHandleDataTransferReadOnly(t *testing.T) {
+	client, local := net.Pipe()
+	remote, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	done := make(chan transferStats, 1)
+	go func() { done <- handleDataTransfer(remote, local, 0, true) }()
+
+	received := make(chan []byte, 1)
+	go func() {
+		data, _ := io.ReadAll(server)
+		received <- data
+	}()
+
+	_, err := client.Write([]byte("DELETE FROM accounts"))
+	assert.Nil(t, err)
+	go server.Write(outputMessage.Payload)
+	msg := make([]byte, len(outputMessage.Payload))
+	_, err = io.ReadFull(client, msg)
+	assert.Nil(t, err)
+	assert.Equal(t, outputMessage.Payload, msg)
+
+	client.Close()
+	stats := <-done
+	assert.Empty(t, <-received)
+	assert.Equal(t, int64(0), stats.toDst)
+	assert.Equal(t, int64(len(outputMessage.Payload)), stats.toSrc)
+	assert.Equal(t, CloseReasonClient, stats.reason)
+}
+
+// WHEN MaxConnections is set, THEN acquireConn SHALL reject connections beyond the cap
+// and accept again once a slot is released.
+func TestAcquireConnRespectsMaxConnections(t *testing.T) {
+	p := &MuxPortForwarding{session: getSessionMock()}
+	p.session.MaxConnections = 2
+
+	assert.True(t, p.acquireConn())
+	assert.True(t, p.acquireConn())
+	assert.False(t, p.acquireConn())
+
+	p.releaseConn()
+	assert.True(t, p.acquireConn())
+}
+
+// WHEN MaxConnections is zero, THEN acquireConn SHALL never reject.
+func TestAcquireConnUnlimited(t *testing.T) {
+	p := &MuxPortForwarding{session: getSessionMock()}
+
+	for i := 0; i < 100; i++ {
+		assert.True(t, p.acquireConn())
+	}
+}
+
+// WHEN drain is called with open connections, THEN the local listener SHALL be closed
+// immediately and drain SHALL return once the connections finish.
+func TestDrainWaitsForOpenConnections(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.Nil(t, err)
+
+	p := &MuxPortForwarding{
+		session:   getSessionMock(),
+		muxClient: &MuxClient{localListener: listener},
+	}
+	assert.True(t, p.acquireConn())
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		p.releaseConn()
+	}()
+
+	start := time.Now()
+	p.drain(mockLog, 5*time.Second)
+	elapsed := time.Since(start)
+
+	_, err = net.Dial("tcp", listener.Addr().String())
+	assert.NotNil(t, err, "listener should be closed while draining")
+	assert.Less(t, elapsed, 2*time.Second)
+	assert.GreaterOrEqual(t, elapsed, 100*time.Millisecond)
+}
+
+// WHEN connections stay open past the drain timeout, THEN drain SHALL give up at the deadline.
+func TestDrainTimeout(t *testing.T) {
+	p := &MuxPortForwarding{session: getSessionMock()}
+	assert.True(t, p.acquireConn())
+
+	start := time.Now()
+	p.drain(mockLog, 200*time.Millisecond)
+
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// WHEN remote TLS is not configured, THEN originateRemoteTLS SHALL return the stream unchanged;
+// WHEN the remote does not speak TLS, THEN the handshake SHALL fail.
+func TestOriginateRemoteTLS(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	conn, err := originateRemoteTLS(context.Background(), client, nil, time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, client, conn)
+
+	go func() {
+		// Answer the ClientHello with plaintext, as a non-TLS backend would
+		buf := make([]byte, 1024)
+		server.Read(buf)
+		server.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+		server.Close()
+	}()
+	_, err = originateRemoteTLS(context.Background(), client, &tls.Config{ServerName: "db.internal"}, time.Second)
+	assert.NotNil(t, err)
+}
+
+// WHEN the data channel stalls while a tunnel stream is opened, THEN openStream SHALL give up
+// after the session's ConnectTimeout instead of leaving the client hanging.
+func TestOpenStreamConnectTimeout(t *testing.T) {
+	// Nothing reads the far end of the pipe, so the SYN frame write never completes
+	client, server := net.Pipe()
+	defer server.Close()
+	muxSession, err := smux.Client(client, smux.DefaultConfig())
+	assert.Nil(t, err)
+	defer muxSession.Close()
+
+	sess := getSessionMock()
+	sess.ConnectTimeout = 100 * time.Millisecond
+	p := &MuxPortForwarding{session: sess, muxClient: &MuxClient{session: muxSession}}
+
+	start := time.Now()
+	stream, err := p.openStream()
+	assert.Nil(t, stream)
+	assert.ErrorIs(t, err, errConnectTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// WHEN a burst of connections is accepted, THEN each SHALL be set up and serviced in parallel,
+// with no more than the session's AcceptConcurrency setting up their tunnel stream at once.
+func TestHandleClientConnectionsConcurrent(t *testing.T) {
+	for _, limit := range []int{0, 4} {
+		t.Run(fmt.Sprintf("limit=%d", limit), func(t *testing.T) {
+			const connections = 16
+			client, server := net.Pipe()
+			muxClient, err := smux.Client(client, smux.DefaultConfig())
+			assert.Nil(t, err)
+			defer muxClient.Close()
+			muxServer, err := smux.Server(server, smux.DefaultConfig())
+			assert.Nil(t, err)
+			defer muxServer.Close()
+			go func() {
+				for {
+					stream, err := muxServer.AcceptStream()
+					if err != nil {
+						return
+					}
+					go io.Copy(stream, stream)
+				}
+			}()
+
+			// Hold each setup briefly so overlapping ones are seen
+			var setups, peak atomic.Int32
+			sess := getSessionMock()
+			sess.AcceptConcurrency = limit
+			sess.OnConnOpened = func(string) {
+				current := setups.Add(1)
+				for {
+					seen := peak.Load()
+					if current <= seen || peak.CompareAndSwap(seen, current) {
+						break
+					}
+				}
+				time.Sleep(20 * time.Millisecond)
+				setups.Add(-1)
+			}
+
+			listener, err := net.Listen("tcp", "localhost:0")
+			assert.Nil(t, err)
+			port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+			listener.Close()
+			p := &MuxPortForwarding{
+				session:        sess,
+				muxClient:      &MuxClient{session: muxClient},
+				portParameters: PortParameters{LocalPortNumber: port},
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go p.handleClientConnections(mockLog, ctx)
+
+			var wg sync.WaitGroup
+			var serviced atomic.Int32
+			for i := 0; i < connections; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					var conn net.Conn
+					var err error
+					for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+						if conn, err = net.Dial("tcp", net.JoinHostPort("localhost", port)); err == nil {
+							break
+						}
+					}
+					if conn == nil {
+						return
+					}
+					defer conn.Close()
+					conn.SetDeadline(time.Now().Add(5 * time.Second))
+					request := fmt.Sprintf("request %d", i)
+					if _, err := conn.Write([]byte(request)); err != nil {
+						return
+					}
+					reply := make([]byte, len(request))
+					if _, err := io.ReadFull(conn, reply); err == nil && string(reply) == request {
+						serviced.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+
+			assert.Equal(t, int32(connections), serviced.Load())
+			assert.Greater(t, peak.Load(), int32(1))
+			if limit > 0 {
+				assert.LessOrEqual(t, peak.Load(), int32(limit))
+			}
+		})
+	}
+}
+
+// WHEN the session is paused, THEN accepted connections SHALL be closed without opening a stream;
+// WHEN it is resumed, THEN new connections SHALL be forwarded again.
+func TestHandleClientConnectionsPaused(t *testing.T) {
+	client, server := net.Pipe()
+	muxClient, err := smux.Client(client, smux.DefaultConfig())
+	assert.Nil(t, err)
+	defer muxClient.Close()
+	muxServer, err := smux.Server(server, smux.DefaultConfig())
+	assert.Nil(t, err)
+	defer muxServer.Close()
+	go func() {
+		for {
+			stream, err := muxServer.AcceptStream()
+			if err != nil {
+				return
+			}
+			go io.Copy(stream, stream)
+		}
+	}()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.Nil(t, err)
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	listener.Close()
+	sess := getSessionMock()
+	sess.Paused = new(atomic.Bool)
+	sess.Paused.Store(true)
+	p := &MuxPortForwarding{
+		session:        sess,
+		muxClient:      &MuxClient{session: muxClient},
+		portParameters: PortParameters{LocalPortNumber: port},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.handleClientConnections(mockLog, ctx)
+
+	echo := func() (string, error) {
+		var conn net.Conn
+		for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+			if conn, err = net.Dial("tcp", net.JoinHostPort("localhost", port)); err == nil {
+				break
+			}
+		}
+		if conn == nil {
+			return "", err
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		conn.Write([]byte("ping"))
+		reply, err := io.ReadAll(io.LimitReader(conn, 4))
+		return string(reply), err
+	}
+
+	// Closing with the request unread may reset rather than end the connection
+	reply, _ := echo()
+	assert.Empty(t, reply)
+	assert.Equal(t, 0, muxClient.NumStreams())
+
+	sess.Paused.Store(false)
+	reply, err = echo()
+	assert.Nil(t, err)
+	assert.Equal(t, "ping", reply)
+}
+
+// tcpPair returns both ends of a loopback TCP connection.
+func tcpPair(b *testing.B) (net.Conn, net.Conn) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		b.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		b.Fatalf("Failed to dial: %v", err)
+	}
+	server, err := listener.Accept()
+	if err != nil {
+		b.Fatalf("Failed to accept: %v", err)
+	}
+	return client, server
+}
+
+// BenchmarkHandleDataTransfer compares copy buffer sizes for a bulk one-way transfer
+// between two loopback TCP connections.
+func BenchmarkHandleDataTransfer(b *testing.B) {
+	const transferSize = 16 * 1024 * 1024
+	payload := make([]byte, transferSize)
+
+	// 0 is the default io.Copy path, which splices between TCP connections where possible
+	for _, bufferSize := range []int{0, 4 * 1024, 64 * 1024} {
+		name := "default"
+		if bufferSize > 0 {
+			name = fmt.Sprintf("%dKiB", bufferSize/1024)
+		}
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(transferSize)
+			for i := 0; i < b.N; i++ {
+				srcWriter, src := tcpPair(b)
+				dst, dstReader := tcpPair(b)
+
+				done := make(chan struct{})
+				go func() {
+					io.Copy(io.Discard, dstReader)
+					dstReader.Close()
+					close(done)
+				}()
+				go func() {
+					srcWriter.Write(payload)
+					srcWriter.Close()
+				}()
+
+				handleDataTransfer(dst, src, bufferSize, false)
+				<-done
+			}
+		})
+	}
+}
@@ -15,25 +15,79 @@
 package portsession
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/binary"
 	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
 
 	"github.com/zph/session-manager-plugin/src/config"
 	"github.com/zph/session-manager-plugin/src/jsonutil"
 	"github.com/zph/session-manager-plugin/src/log"
 	"github.com/zph/session-manager-plugin/src/message"
 	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session/sessionutil"
 	"github.com/zph/session-manager-plugin/src/version"
 )
 
 const (
 	LocalPortForwardingType = "LocalPortForwarding"
+	ProtocolUDP             = "udp"
 )
 
+// Address families for Session.PortForwardingNetwork
+const (
+	NetworkTCP4 = "tcp4"
+	NetworkTCP6 = "tcp6"
+	NetworkTCP  = "tcp"
+)
+
+// Forwarding implementations Initialize picks between, reported to Session.OnForwardingType.
+// Mux and UDP forwarding carry many local connections at once; basic forwarding serves one at a
+// time and standard-stream forwarding relays a single stream.
+const (
+	ForwardingMux            = "mux"
+	ForwardingUDP            = "udp"
+	ForwardingBasic          = "basic"
+	ForwardingStandardStream = "standard-stream"
+)
+
+// controlSignals returns the signals that stop a port session: sessionutil.ControlSignals, less
+// SIGINT when s.IgnoreInterrupt is set. Notifying for SIGINT would undo a caller's signal.Ignore.
+func controlSignals(s session.Session) []os.Signal {
+	if !s.IgnoreInterrupt {
+		return sessionutil.ControlSignals
+	}
+	var signals []os.Signal
+	for _, sig := range sessionutil.ControlSignals {
+		if sig != os.Interrupt {
+			signals = append(signals, sig)
+		}
+	}
+	return signals
+}
+
+// ListenNetwork returns the network to open a protocol ("tcp" or "udp") listener on, restricted
+// to family's address family. NetworkTCP or an empty family leaves it unrestricted.
+func ListenNetwork(protocol string, family string) string {
+	switch family {
+	case NetworkTCP4:
+		return protocol + "4"
+	case NetworkTCP6:
+		return protocol + "6"
+	}
+	return protocol
+}
+
 type PortSession struct {
 	session.Session
 	portParameters  PortParameters
 	portSessionType IPortSession
+	// preflightErr is a requested feature the agent does not support, reported before any listener opens
+	preflightErr error
 }
 
 type IPortSession interface {
@@ -66,27 +120,61 @@ func (s *PortSession) Initialize(log log.T, sessionVar *session.Session) {
 	if err := jsonutil.Remarshal(s.SessionProperties, &s.portParameters); err != nil {
 		log.Errorf("Invalid format: %v", err)
 	}
+	s.preflightErr = checkAgentFeatures(log, s.Session, s.portParameters, s.DataChannel.GetAgentVersion())
 
-	if s.portParameters.Type == LocalPortForwardingType {
+	var forwardingType string
+	if s.portParameters.Type == LocalPortForwardingType && s.PortForwardingProtocol == ProtocolUDP {
+		forwardingType = ForwardingUDP
+		s.portSessionType = &UDPPortForwarding{
+			MuxPortForwarding: MuxPortForwarding{
+				sessionId:      s.SessionId,
+				portParameters: s.portParameters,
+				session:        s.Session,
+			},
+		}
+	} else if s.portParameters.Type == LocalPortForwardingType {
 		if version.DoesAgentSupportTCPMultiplexing(log, s.DataChannel.GetAgentVersion()) {
+			forwardingType = ForwardingMux
 			s.portSessionType = &MuxPortForwarding{
-				sessionId:      s.SessionId,
+				sessionId:       s.SessionId,
+				portParameters:  s.portParameters,
+				session:         s.Session,
+				uploadLimiter:   newRateLimiter(s.RateLimit),
+				downloadLimiter: newRateLimiter(s.RateLimit),
+			}
+		} else if s.PortForwardingStdio {
+			// Without multiplexing the agent relays one raw connection, which stdin and stdout carry directly
+			forwardingType = ForwardingStandardStream
+			s.portSessionType = &StandardStreamForwarding{
 				portParameters: s.portParameters,
 				session:        s.Session,
 			}
 		} else {
+			forwardingType = ForwardingBasic
 			s.portSessionType = &BasicPortForwarding{
-				sessionId:      s.SessionId,
-				portParameters: s.portParameters,
-				session:        s.Session,
+				sessionId:       s.SessionId,
+				portParameters:  s.portParameters,
+				session:         s.Session,
+				uploadLimiter:   newRateLimiter(s.RateLimit),
+				downloadLimiter: newRateLimiter(s.RateLimit),
 			}
 		}
 	} else {
+		forwardingType = ForwardingStandardStream
 		s.portSessionType = &StandardStreamForwarding{
 			portParameters: s.portParameters,
 			session:        s.Session,
 		}
 	}
+	if forwardingType == ForwardingBasic {
+		log.Infof("Using %s port forwarding for agent version %s: one local connection at a time",
+			forwardingType, s.DataChannel.GetAgentVersion())
+	} else {
+		log.Infof("Using %s port forwarding for agent version %s", forwardingType, s.DataChannel.GetAgentVersion())
+	}
+	if s.OnForwardingType != nil {
+		s.OnForwardingType(forwardingType)
+	}
 
 	s.DataChannel.RegisterOutputStreamHandler(s.ProcessStreamMessagePayload, true)
 
@@ -99,6 +187,7 @@ func (s *PortSession) Initialize(log log.T, sessionVar *session.Session) {
 				// already closed
 			default:
 				close(s.Session.PortReady)
+				s.Session.EmitEvent(session.ForwardEvent{Type: session.EventEstablished})
 			}
 		}()
 	}
@@ -128,6 +217,9 @@ func (s *PortSession) Stop() {
 
 // StartSession redirects inputStream/outputStream data to datachannel.
 func (s *PortSession) SetSessionHandlers(log log.T) (err error) {
+	if s.preflightErr != nil {
+		return s.preflightErr
+	}
 	if err = s.portSessionType.InitializeStreams(log, s.DataChannel.GetAgentVersion()); err != nil {
 		return err
 	}
@@ -153,10 +245,139 @@ func (s *PortSession) ProcessStreamMessagePayload(log log.T, outputMessage messa
 	}
 
 	log.Tracef("Received payload of size %d from datachannel.", outputMessage.PayloadLength)
+	s.Transfer.AddReceived(len(outputMessage.Payload))
+	if s.StreamInterceptor != nil {
+		if outputMessage.Payload, err = s.StreamInterceptor.Inbound(outputMessage.Payload); err != nil {
+			return true, err
+		}
+		if len(outputMessage.Payload) == 0 {
+			return true, nil
+		}
+	}
 	err = s.portSessionType.WriteStream(outputMessage)
 	return true, err
 }
 
+// interceptOutbound passes bytes read from the local side through the session's
+// StreamInterceptor, if one is set, returning the bytes to send to the agent.
+func interceptOutbound(s session.Session, data []byte) ([]byte, error) {
+	if s.StreamInterceptor == nil {
+		return data, nil
+	}
+	return s.StreamInterceptor.Outbound(data)
+}
+
+// sendOutput sends bytes read from the local side to the agent, through the session's
+// StreamInterceptor when one is set. Bytes the interceptor drops are not sent.
+func sendOutput(log log.T, s session.Session, data []byte) error {
+	data, err := interceptOutbound(s, data)
+	if err != nil || len(data) == 0 {
+		return err
+	}
+	return s.DataChannel.SendInputDataMessage(log, message.Output, data)
+}
+
+// checkAgentFeatures returns a descriptive error when the session requests a feature the agent
+// version does not support. UDP needs multiplexing, which like Initialize is assumed absent when
+// the version is unknown; the remote host check is skipped then and left to the agent.
+func checkAgentFeatures(log log.T, s session.Session, portParameters PortParameters, agentVersion string) error {
+	if portParameters.Type != LocalPortForwardingType {
+		return nil
+	}
+	if s.PortForwardingProtocol == ProtocolUDP && !version.DoesAgentSupportTCPMultiplexing(log, agentVersion) {
+		return fmt.Errorf("UDP port forwarding requires agent version above %s, got %q",
+			config.TCPMultiplexingSupportedAfterThisAgentVersion, agentVersion)
+	}
+	if s.RemoteTLSConfig != nil && !version.DoesAgentSupportTCPMultiplexing(log, agentVersion) {
+		return fmt.Errorf("remote TLS requires agent version above %s, got %q",
+			config.TCPMultiplexingSupportedAfterThisAgentVersion, agentVersion)
+	}
+	if s.ProxyProtocol != "" && !version.DoesAgentSupportTCPMultiplexing(log, agentVersion) {
+		return fmt.Errorf("PROXY protocol requires agent version above %s, got %q",
+			config.TCPMultiplexingSupportedAfterThisAgentVersion, agentVersion)
+	}
+	if s.PortForwardingToRemoteHost && agentVersion != "" && !version.DoesAgentSupportRemoteHostPortForwarding(log, agentVersion) {
+		return fmt.Errorf("remote host forwarding requires agent version above %s, got %s",
+			config.RemoteHostPortForwardingSupportedAfterThisAgentVersion, agentVersion)
+	}
+	return nil
+}
+
+// localListenAddress is the address local listeners bind for port: the session's bind host, or localhost.
+func localListenAddress(s session.Session, port string) string {
+	host := s.PortForwardingBindHost
+	if host == "" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// setListenBacklog applies Session.ListenBacklog to a new listener's socket; a variable for tests.
+var setListenBacklog = sessionutil.SetListenBacklog
+
+// listenLocal opens a local listener, or takes over Session.LocalListener for TCP, applying the
+// session's listen backlog when one is set.
+func listenLocal(s session.Session, network string, address string) (net.Listener, error) {
+	listener := s.LocalListener
+	if listener == nil || network != "tcp" {
+		config := listenConfig(s)
+		if network == "tcp" {
+			network = ListenNetwork(network, s.PortForwardingNetwork)
+		}
+		var err error
+		if listener, err = config.Listen(context.Background(), network, address); err != nil {
+			return nil, err
+		}
+	}
+	if s.ListenBacklog > 0 {
+		if raw, ok := listener.(syscall.Conn); ok {
+			conn, err := raw.SyscallConn()
+			if err == nil {
+				err = setListenBacklog(conn, s.ListenBacklog)
+			}
+			if err != nil {
+				listener.Close()
+				return nil, fmt.Errorf("failed to set listen backlog to %d: %w", s.ListenBacklog, err)
+			}
+		}
+	}
+	reportListening(s, listener.Addr())
+	return listener, nil
+}
+
+// listenConfig returns the configuration for opening local sockets with the session's options.
+func listenConfig(s session.Session) net.ListenConfig {
+	return net.ListenConfig{Control: sessionutil.ReuseControl(s.ListenReuseAddr, s.ListenReusePort)}
+}
+
+// reportListening passes the address of a newly opened local listener to Session.OnListening.
+func reportListening(s session.Session, addr net.Addr) {
+	if s.OnListening != nil {
+		s.OnListening(addr)
+	}
+}
+
+// wrapLocalListener terminates TLS on listener when the session has a local TLS config.
+func wrapLocalListener(s session.Session, listener net.Listener) net.Listener {
+	if s.LocalTLSConfig == nil {
+		return listener
+	}
+	return tls.NewListener(listener, s.LocalTLSConfig)
+}
+
+// closeDrained signals that the session has finished draining connections.
+func closeDrained(s session.Session) {
+	if s.Drained == nil {
+		return
+	}
+	select {
+	case <-s.Drained:
+		// already closed
+	default:
+		close(s.Drained)
+	}
+}
+
 // handleFlagMessage processes flag control messages from the agent.
 // READY-005, READY-006
 func (s *PortSession) handleFlagMessage(log log.T, outputMessage message.ClientMessage) (isHandlerReady bool, err error) {
@@ -15,9 +15,14 @@
 package portsession
 
 import (
+	"crypto/tls"
 	"encoding/binary"
 	"io/ioutil"
+	"net"
 	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
 	"testing"
 	"time"
 
@@ -27,6 +32,8 @@ import (
 	"github.com/zph/session-manager-plugin/src/datachannel"
 	"github.com/zph/session-manager-plugin/src/jsonutil"
 	"github.com/zph/session-manager-plugin/src/message"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session/sessionutil"
 )
 
 // Test Initialize
@@ -67,11 +74,14 @@ func TestInitializePortSessionForPortForwardingWithOldAgent(t *testing.T) {
 	portSession := PortSession{
 		Session: getSessionMockWithParams(portParameters, "2.2.0.0"),
 	}
+	var forwardingType string
+	portSession.Session.OnForwardingType = func(kind string) { forwardingType = kind }
 	portSession.Initialize(mockLog, &portSession.Session)
 
 	mockWebSocketChannel.AssertExpectations(t)
 	assert.Equal(t, portParameters, portSession.portParameters, "Initialize port parameters")
 	assert.IsType(t, &BasicPortForwarding{}, portSession.portSessionType)
+	assert.Equal(t, ForwardingBasic, forwardingType)
 }
 
 func TestInitializePortSessionForPortForwarding(t *testing.T) {
@@ -89,11 +99,73 @@ func TestInitializePortSessionForPortForwarding(t *testing.T) {
 	portSession := PortSession{
 		Session: getSessionMockWithParams(portParameters, "3.1.0.0"),
 	}
+	var forwardingType string
+	portSession.Session.OnForwardingType = func(kind string) { forwardingType = kind }
 	portSession.Initialize(mockLog, &portSession.Session)
 
 	mockWebSocketChannel.AssertExpectations(t)
 	assert.Equal(t, portParameters, portSession.portParameters, "Initialize port parameters")
 	assert.IsType(t, &MuxPortForwarding{}, portSession.portSessionType)
+	assert.Equal(t, ForwardingMux, forwardingType)
+}
+
+// WHEN the session forwards to a remote host and the agent is too old for it, THEN SetSessionHandlers
+// SHALL fail with a descriptive error before any local listener is opened.
+func TestSetSessionHandlersRejectsUnsupportedFeature(t *testing.T) {
+	mockWebSocketChannel = mocks.IWebSocketChannel{}
+	t.Cleanup(func() {
+		mockWebSocketChannel = mocks.IWebSocketChannel{}
+	})
+
+	var portParameters PortParameters
+	jsonutil.Remarshal(map[string]interface{}{"portNumber": "8080", "type": "LocalPortForwarding"}, &portParameters)
+
+	mockWebSocketChannel.On("SetOnMessage", mock.Anything)
+
+	portSession := PortSession{
+		Session: getSessionMockWithParams(portParameters, "3.1.0.0"),
+	}
+	portSession.Session.PortForwardingToRemoteHost = true
+	portSession.Initialize(mockLog, &portSession.Session)
+
+	err := portSession.SetSessionHandlers(mockLog)
+	assert.ErrorContains(t, err, "remote host forwarding requires agent version above")
+	assert.Nil(t, portSession.portSessionType.(*MuxPortForwarding).muxClient, "no listener should be set up")
+}
+
+// WHEN requested features are checked against the agent version, THEN only unsupported ones SHALL fail.
+func TestCheckAgentFeatures(t *testing.T) {
+	local := PortParameters{Type: LocalPortForwardingType}
+	tests := []struct {
+		name         string
+		session      session.Session
+		params       PortParameters
+		agentVersion string
+		wantErr      string
+	}{
+		{"plain forward on old agent", session.Session{}, local, "2.2.0.0", ""},
+		{"remote host on new agent", session.Session{PortForwardingToRemoteHost: true}, local, "3.2.0.0", ""},
+		{"remote host on old agent", session.Session{PortForwardingToRemoteHost: true}, local, "3.0.196.1", "remote host forwarding"},
+		{"remote host on unknown agent", session.Session{PortForwardingToRemoteHost: true}, local, "", ""},
+		{"udp without multiplexing", session.Session{PortForwardingProtocol: ProtocolUDP}, local, "2.2.0.0", "UDP port forwarding"},
+		{"udp on unknown agent", session.Session{PortForwardingProtocol: ProtocolUDP}, local, "", "UDP port forwarding"},
+		{"udp with multiplexing", session.Session{PortForwardingProtocol: ProtocolUDP}, local, "3.1.0.0", ""},
+		{"remote tls without multiplexing", session.Session{RemoteTLSConfig: &tls.Config{}}, local, "2.2.0.0", "remote TLS"},
+		{"remote tls with multiplexing", session.Session{RemoteTLSConfig: &tls.Config{}}, local, "3.1.0.0", ""},
+		{"proxy protocol without multiplexing", session.Session{ProxyProtocol: ProxyProtocolV1}, local, "2.2.0.0", "PROXY protocol"},
+		{"proxy protocol with multiplexing", session.Session{ProxyProtocol: ProxyProtocolV2}, local, "3.1.0.0", ""},
+		{"not local port forwarding", session.Session{PortForwardingToRemoteHost: true}, PortParameters{}, "2.2.0.0", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkAgentFeatures(mockLog, tt.session, tt.params, tt.agentVersion)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
 }
 
 // Test ProcessStreamMessagePayload
@@ -246,3 +318,127 @@ func TestInitializeSignalsReadyOnStartPublication(t *testing.T) {
 		t.Fatal("PortReady should be closed after StartPublicationMessage is received")
 	}
 }
+
+// WHEN a bind host is set, THEN local listeners SHALL bind it instead of localhost.
+func TestLocalListenAddress(t *testing.T) {
+	assert.Equal(t, "localhost:8080", localListenAddress(session.Session{}, "8080"))
+	assert.Equal(t, "0.0.0.0:0", localListenAddress(session.Session{PortForwardingBindHost: "0.0.0.0"}, "0"))
+	assert.Equal(t, "[::1]:8080", localListenAddress(session.Session{PortForwardingBindHost: "::1"}, "8080"))
+}
+
+// WHEN the session has a local TLS config, THEN wrapLocalListener SHALL terminate TLS on the listener
+// and keep its address; otherwise it SHALL return the listener unchanged.
+func TestWrapLocalListener(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	assert.Equal(t, listener, wrapLocalListener(session.Session{}, listener))
+
+	wrapped := wrapLocalListener(session.Session{LocalTLSConfig: &tls.Config{}}, listener)
+	assert.NotEqual(t, listener, wrapped)
+	assert.Equal(t, listener.Addr(), wrapped.Addr())
+}
+
+// WHEN the session sets a listen backlog, THEN listenLocal SHALL pass it to the listener's socket;
+// without one the OS default SHALL be kept.
+func TestListenLocalAppliesBacklog(t *testing.T) {
+	var applied []int
+	setListenBacklog = func(conn syscall.RawConn, backlog int) error {
+		applied = append(applied, backlog)
+		return sessionutil.SetListenBacklog(conn, backlog)
+	}
+	t.Cleanup(func() { setListenBacklog = sessionutil.SetListenBacklog })
+
+	listener, err := listenLocal(session.Session{}, "tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	listener.Close()
+	assert.Empty(t, applied)
+
+	listener, err = listenLocal(session.Session{ListenBacklog: 1024}, "tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	assert.Equal(t, []int{1024}, applied)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.Nil(t, err)
+	conn.Close()
+}
+
+// WHEN a local listener opens, THEN listenLocal SHALL report its address to Session.OnListening.
+func TestListenLocalReportsAddress(t *testing.T) {
+	var reported net.Addr
+	s := session.Session{OnListening: func(addr net.Addr) { reported = addr }}
+
+	listener, err := listenLocal(s, "tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	assert.Equal(t, listener.Addr().String(), reported.String())
+}
+
+// WHEN ListenReusePort is set, THEN a second local listener SHALL bind the port the first still holds.
+func TestListenLocalReusePort(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SO_REUSEPORT is not supported on Windows")
+	}
+	s := session.Session{ListenReuseAddr: true, ListenReusePort: true}
+	first, err := listenLocal(s, "tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer first.Close()
+
+	second, err := listenLocal(s, "tcp", first.Addr().String())
+	assert.Nil(t, err)
+	second.Close()
+
+	_, err = listenLocal(session.Session{}, "tcp", first.Addr().String())
+	assert.NotNil(t, err)
+}
+
+// WHEN the session has a pre-bound listener, THEN listenLocal SHALL serve it for TCP instead of
+// binding the address, and SHALL still bind unix sockets itself.
+func TestListenLocalUsesLocalListener(t *testing.T) {
+	preBound, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer preBound.Close()
+	var reported net.Addr
+	s := session.Session{LocalListener: preBound, OnListening: func(addr net.Addr) { reported = addr }}
+
+	listener, err := listenLocal(s, "tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	assert.Same(t, preBound, listener)
+	assert.Equal(t, preBound.Addr(), reported)
+
+	socket := filepath.Join(t.TempDir(), "forward.sock")
+	listener, err = listenLocal(s, "unix", socket)
+	assert.Nil(t, err)
+	defer listener.Close()
+	assert.Equal(t, socket, listener.Addr().String())
+}
+
+// WHEN a family is selected, THEN ListenNetwork SHALL restrict tcp and udp to it, and leave them
+// unrestricted for tcp or no family.
+func TestListenNetwork(t *testing.T) {
+	assert.Equal(t, "tcp4", ListenNetwork("tcp", NetworkTCP4))
+	assert.Equal(t, "tcp6", ListenNetwork("tcp", NetworkTCP6))
+	assert.Equal(t, "tcp", ListenNetwork("tcp", NetworkTCP))
+	assert.Equal(t, "tcp", ListenNetwork("tcp", ""))
+	assert.Equal(t, "udp4", ListenNetwork(ProtocolUDP, NetworkTCP4))
+	assert.Equal(t, "udp6", ListenNetwork(ProtocolUDP, NetworkTCP6))
+	assert.Equal(t, "udp", ListenNetwork(ProtocolUDP, NetworkTCP))
+}
+
+// WHEN PortForwardingNetwork is tcp4 or tcp6, THEN listenLocal SHALL bind a wildcard address in
+// that family only.
+func TestListenLocalNetwork(t *testing.T) {
+	listener, err := listenLocal(session.Session{PortForwardingNetwork: NetworkTCP4}, "tcp", ":0")
+	assert.Nil(t, err)
+	assert.NotNil(t, listener.Addr().(*net.TCPAddr).IP.To4())
+	listener.Close()
+
+	listener, err = listenLocal(session.Session{PortForwardingNetwork: NetworkTCP6}, "tcp", ":0")
+	if err != nil {
+		t.Skipf("IPv6 is not available: %v", err)
+	}
+	assert.Nil(t, listener.Addr().(*net.TCPAddr).IP.To4())
+	listener.Close()
+}
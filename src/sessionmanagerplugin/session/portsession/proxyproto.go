@@ -0,0 +1,106 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// PROXY protocol versions for Session.ProxyProtocol.
+const (
+	ProxyProtocolV1 = "v1"
+	ProxyProtocolV2 = "v2"
+)
+
+// proxyV2Signature starts every PROXY protocol v2 header.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyHeader returns the PROXY protocol header announcing a connection from src to dst. Addresses
+// that are not TCP are announced as unknown, which receivers treat as the tunnel's own address.
+func proxyHeader(version string, src, dst net.Addr) ([]byte, error) {
+	srcTCP, srcOK := src.(*net.TCPAddr)
+	dstTCP, dstOK := dst.(*net.TCPAddr)
+	known := srcOK && dstOK
+	var srcIP, dstIP net.IP
+	ipv4 := false
+	if known {
+		// Both addresses must share a family, so a mixed pair is announced as IPv6
+		srcIP, dstIP = srcTCP.IP.To4(), dstTCP.IP.To4()
+		ipv4 = srcIP != nil && dstIP != nil
+		if !ipv4 {
+			srcIP, dstIP = srcTCP.IP.To16(), dstTCP.IP.To16()
+			known = srcIP != nil && dstIP != nil
+		}
+	}
+
+	switch version {
+	case ProxyProtocolV1:
+		if !known {
+			return []byte("PROXY UNKNOWN\r\n"), nil
+		}
+		if ipv4 {
+			return fmt.Appendf(nil, "PROXY TCP4 %s %s %d %d\r\n", srcIP, dstIP, srcTCP.Port, dstTCP.Port), nil
+		}
+		return fmt.Appendf(nil, "PROXY TCP6 %s %s %d %d\r\n", ipv6String(srcIP), ipv6String(dstIP), srcTCP.Port, dstTCP.Port), nil
+	case ProxyProtocolV2:
+		var header bytes.Buffer
+		header.Write(proxyV2Signature)
+		header.WriteByte(0x21) // version 2, PROXY command
+		switch {
+		case !known:
+			header.WriteByte(0x00) // AF_UNSPEC
+			binary.Write(&header, binary.BigEndian, uint16(0))
+			return header.Bytes(), nil
+		case ipv4:
+			header.WriteByte(0x11) // AF_INET, STREAM
+		default:
+			header.WriteByte(0x21) // AF_INET6, STREAM
+		}
+		binary.Write(&header, binary.BigEndian, uint16(2*len(srcIP)+4))
+		header.Write(srcIP)
+		header.Write(dstIP)
+		binary.Write(&header, binary.BigEndian, uint16(srcTCP.Port))
+		binary.Write(&header, binary.BigEndian, uint16(dstTCP.Port))
+		return header.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported PROXY protocol version %q", version)
+	}
+}
+
+// ipv6String formats ip in IPv6 notation; net.IP prints IPv4-mapped addresses as dotted IPv4,
+// which a TCP6 line may not carry.
+func ipv6String(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return "::ffff:" + v4.String()
+	}
+	return ip.String()
+}
+
+// writeProxyHeader sends the PROXY protocol header for conn at the start of stream when the
+// session asks for one, before any client data or remote TLS handshake.
+func (p *MuxPortForwarding) writeProxyHeader(stream net.Conn, conn net.Conn) error {
+	if p.session.ProxyProtocol == "" {
+		return nil
+	}
+	header, err := proxyHeader(p.session.ProxyProtocol, conn.RemoteAddr(), conn.LocalAddr())
+	if err != nil {
+		return err
+	}
+	_, err = stream.Write(header)
+	return err
+}
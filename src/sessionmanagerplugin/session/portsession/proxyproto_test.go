@@ -0,0 +1,98 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+// WHEN a v1 header is built, THEN it SHALL carry both addresses in text, using TCP6 when either
+// address is IPv6 and UNKNOWN for non-TCP addresses.
+func TestProxyHeaderV1(t *testing.T) {
+	local := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8080}
+	client := &net.TCPAddr{IP: net.ParseIP("192.168.1.20"), Port: 51234}
+
+	header, err := proxyHeader(ProxyProtocolV1, client, local)
+	require.NoError(t, err)
+	assert.Equal(t, "PROXY TCP4 192.168.1.20 127.0.0.1 51234 8080\r\n", string(header))
+
+	header, err = proxyHeader(ProxyProtocolV1, &net.TCPAddr{IP: net.ParseIP("::1"), Port: 51234}, local)
+	require.NoError(t, err)
+	assert.Equal(t, "PROXY TCP6 ::1 ::ffff:127.0.0.1 51234 8080\r\n", string(header))
+
+	header, err = proxyHeader(ProxyProtocolV1, &net.UnixAddr{Name: "/tmp/alice.sock"}, local)
+	require.NoError(t, err)
+	assert.Equal(t, "PROXY UNKNOWN\r\n", string(header))
+}
+
+// WHEN a v2 header is built, THEN it SHALL be the binary signature, command, family and
+// addresses, with AF_UNSPEC and no addresses for non-TCP addresses.
+func TestProxyHeaderV2(t *testing.T) {
+	local := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8080}
+	client := &net.TCPAddr{IP: net.ParseIP("192.168.1.20"), Port: 51234}
+
+	header, err := proxyHeader(ProxyProtocolV2, client, local)
+	require.NoError(t, err)
+	want := append([]byte("\r\n\r\n\x00\r\nQUIT\n"), 0x21, 0x11, 0x00, 0x0c,
+		192, 168, 1, 20, 127, 0, 0, 1, 0xc8, 0x22, 0x1f, 0x90)
+	assert.Equal(t, want, header)
+
+	header, err = proxyHeader(ProxyProtocolV2, &net.TCPAddr{IP: net.ParseIP("::1"), Port: 51234}, local)
+	require.NoError(t, err)
+	assert.Equal(t, byte(0x21), header[13])
+	assert.Equal(t, []byte{0x00, 0x24}, header[14:16])
+	assert.Len(t, header, 16+36)
+
+	header, err = proxyHeader(ProxyProtocolV2, &net.UnixAddr{Name: "/tmp/alice.sock"}, local)
+	require.NoError(t, err)
+	assert.Equal(t, append([]byte("\r\n\r\n\x00\r\nQUIT\n"), 0x21, 0x00, 0x00, 0x00), header)
+}
+
+// WHEN the version is not v1 or v2, THEN proxyHeader SHALL return an error.
+func TestProxyHeaderUnsupportedVersion(t *testing.T) {
+	_, err := proxyHeader("v3", &net.TCPAddr{}, &net.TCPAddr{})
+	assert.ErrorContains(t, err, "unsupported PROXY protocol version")
+}
+
+// WHEN ProxyProtocol is set, THEN writeProxyHeader SHALL write the header to the stream before
+// anything else, and write nothing when it is unset.
+func TestWriteProxyHeader(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	stream, remote := net.Pipe()
+	defer stream.Close()
+	defer remote.Close()
+
+	p := &MuxPortForwarding{session: session.Session{ProxyProtocol: ProxyProtocolV1}}
+	done := make(chan error, 1)
+	go func() { done <- p.writeProxyHeader(stream, server) }()
+
+	// net.Pipe addresses are not TCP, so the header announces an unknown source
+	buf := make([]byte, len("PROXY UNKNOWN\r\n"))
+	_, err := io.ReadFull(remote, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "PROXY UNKNOWN\r\n", string(buf))
+	require.NoError(t, <-done)
+
+	p.session.ProxyProtocol = ""
+	assert.NoError(t, p.writeProxyHeader(stream, server))
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket that throttles forwarded bytes.
+// A nil *rateLimiter is valid and never blocks, so disabled limits cost a nil check.
+type rateLimiter struct {
+	mutex  sync.Mutex
+	rate   float64 // bytes per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter for bytesPerSecond, or nil when bytesPerSecond is not positive.
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:  float64(bytesPerSecond),
+		burst: float64(bytesPerSecond),
+		last:  time.Now(),
+	}
+}
+
+// wait blocks until n bytes may pass. Tokens are reserved before sleeping so that
+// concurrent callers queue behind each other instead of all waking at once.
+func (l *rateLimiter) wait(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+
+	l.mutex.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mutex.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// rateLimitedConn throttles reads (client to remote) and writes (remote to client)
+// on a local client connection.
+type rateLimitedConn struct {
+	io.ReadWriteCloser
+	readLimiter  *rateLimiter
+	writeLimiter *rateLimiter
+}
+
+func (c *rateLimitedConn) Read(b []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(b)
+	c.readLimiter.wait(n)
+	return n, err
+}
+
+func (c *rateLimitedConn) Write(b []byte) (int, error) {
+	c.writeLimiter.wait(len(b))
+	return c.ReadWriteCloser.Write(b)
+}
+
+// limitConn wraps conn with the given limiters, returning conn unchanged when both are disabled.
+func limitConn(conn io.ReadWriteCloser, readLimiter *rateLimiter, writeLimiter *rateLimiter) io.ReadWriteCloser {
+	if readLimiter == nil && writeLimiter == nil {
+		return conn
+	}
+	return &rateLimitedConn{conn, readLimiter, writeLimiter}
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// WHEN the rate limit is disabled, THEN newRateLimiter SHALL return nil and limitConn SHALL not wrap.
+func TestRateLimiterDisabled(t *testing.T) {
+	assert.Nil(t, newRateLimiter(0))
+	assert.Nil(t, newRateLimiter(-1))
+
+	var l *rateLimiter
+	assert.NotPanics(t, func() { l.wait(1024) })
+
+	conn, other := net.Pipe()
+	defer conn.Close()
+	defer other.Close()
+	assert.Equal(t, conn, limitConn(conn, nil, nil))
+}
+
+// WHEN data is copied through a rate-limited connection for a few seconds,
+// THEN the achieved rate SHALL stay within tolerance of the configured limit.
+func TestRateLimitedConnThroughput(t *testing.T) {
+	const (
+		rate  = 64 * 1024
+		total = 3 * rate
+	)
+
+	src := &rateLimitedConn{
+		ReadWriteCloser: nopCloser{bytes.NewReader(make([]byte, total))},
+		readLimiter:     newRateLimiter(rate),
+	}
+
+	start := time.Now()
+	n, err := io.CopyBuffer(io.Discard, src, make([]byte, 1024))
+	elapsed := time.Since(start)
+
+	assert.Nil(t, err)
+	assert.EqualValues(t, total, n)
+
+	achieved := float64(n) / elapsed.Seconds()
+	assert.InDelta(t, rate, achieved, rate*0.1, "achieved %.0f B/s over %v", achieved, elapsed)
+}
+
+// nopCloser adapts a reader into an io.ReadWriteCloser for limiter tests.
+type nopCloser struct {
+	io.Reader
+}
+
+func (nopCloser) Write(b []byte) (int, error) { return len(b), nil }
+func (nopCloser) Close() error                { return nil }
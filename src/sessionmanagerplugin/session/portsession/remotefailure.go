@@ -0,0 +1,110 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// Close reasons for a tunnel stream the remote closed without sending anything, which is how the
+// agent reports that it could not connect to the remote host. The agent sends no error detail, so
+// the two are told apart by how long the connect took.
+const (
+	CloseReasonRemoteRefused     = "remote_refused"
+	CloseReasonRemoteUnreachable = "remote_unreachable"
+)
+
+// remoteUnreachableAfter separates the two failure classes: a refused connect fails within a round
+// trip of the agent, while a filtered or unroutable host only fails once the agent's connect
+// attempt times out.
+const remoteUnreachableAfter = 10 * time.Second
+
+// remoteFailureClass returns the close reason for a remote that closed without data after elapsed.
+func remoteFailureClass(elapsed time.Duration) string {
+	if elapsed >= remoteUnreachableAfter {
+		return CloseReasonRemoteUnreachable
+	}
+	return CloseReasonRemoteRefused
+}
+
+// remoteFailureConn wraps a connection's tunnel stream and classifies the remote closing it before
+// sending any data. Reads happen on one copy goroutine and reason is read after the copy ends;
+// closed is set from the other copy goroutine.
+type remoteFailureConn struct {
+	io.ReadWriteCloser
+	opened    time.Time
+	received  bool
+	closed    atomic.Bool
+	reason    string
+	onFailure func(reason string, elapsed time.Duration)
+}
+
+func (c *remoteFailureConn) Read(b []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(b)
+	if n > 0 {
+		c.received = true
+	}
+	if errors.Is(err, io.EOF) && !c.received && !c.closed.Load() && c.reason == "" {
+		elapsed := time.Since(c.opened)
+		c.reason = remoteFailureClass(elapsed)
+		// Called before the copy returns, so the local connection is not closed yet
+		c.onFailure(c.reason, elapsed)
+	}
+	return n, err
+}
+
+// Close marks the stream as closed from this side, so the EOF that follows isn't blamed on the remote.
+func (c *remoteFailureConn) Close() error {
+	c.closed.Store(true)
+	return c.ReadWriteCloser.Close()
+}
+
+// watchRemoteFailure logs a remote that closes conn's stream without sending data as refused or
+// unreachable and, with Session.ResetOnRemoteFailure, arranges for conn to be closed with a TCP
+// RST rather than a FIN so the client sees a failed connect rather than an empty response.
+func (p *MuxPortForwarding) watchRemoteFailure(log log.T, conn net.Conn, remote io.ReadWriteCloser, opened time.Time) *remoteFailureConn {
+	return &remoteFailureConn{
+		ReadWriteCloser: remote,
+		opened:          opened,
+		onFailure: func(reason string, elapsed time.Duration) {
+			hint := "the remote likely refused the connection; is the service running?"
+			if reason == CloseReasonRemoteUnreachable {
+				hint = "the remote was likely unreachable; check firewalls and security groups"
+			}
+			log.Warnf("Connection from %s closed by the remote without data after %v (%s): %s",
+				conn.RemoteAddr(), elapsed.Round(time.Millisecond), reason, hint)
+			if p.session.ResetOnRemoteFailure {
+				resetOnClose(conn)
+			}
+		},
+	}
+}
+
+// resetOnClose makes closing conn send a TCP RST. Connections that aren't TCP are left as they are.
+func resetOnClose(conn net.Conn) {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+}
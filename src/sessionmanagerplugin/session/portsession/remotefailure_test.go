@@ -0,0 +1,114 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+// WHEN the remote closes without data, THEN a prompt close SHALL count as refused and one after
+// the agent's connect timeout as unreachable.
+func TestRemoteFailureClass(t *testing.T) {
+	assert.Equal(t, CloseReasonRemoteRefused, remoteFailureClass(50*time.Millisecond))
+	assert.Equal(t, CloseReasonRemoteUnreachable, remoteFailureClass(remoteUnreachableAfter))
+}
+
+// WHEN the remote closes the stream before sending anything, THEN the watcher SHALL name the
+// failure class; WHEN it sent data first, THEN it SHALL report nothing.
+func TestHandleDataTransferRemoteFailure(t *testing.T) {
+	for _, sendFirst := range []bool{false, true} {
+		client, src := net.Pipe()
+		dst, agent := net.Pipe()
+		defer client.Close()
+
+		var failures []string
+		remote := &remoteFailureConn{
+			ReadWriteCloser: dst,
+			opened:          time.Now(),
+			onFailure:       func(reason string, _ time.Duration) { failures = append(failures, reason) },
+		}
+		go func() {
+			if sendFirst {
+				agent.Write([]byte("hello"))
+			}
+			agent.Close()
+		}()
+		go io.Copy(io.Discard, client)
+
+		stats := handleDataTransfer(remote, src, 0, false)
+		assert.Equal(t, CloseReasonRemote, stats.reason)
+		if sendFirst {
+			assert.Empty(t, remote.reason)
+			assert.Empty(t, failures)
+		} else {
+			assert.Equal(t, CloseReasonRemoteRefused, remote.reason)
+			assert.Equal(t, []string{CloseReasonRemoteRefused}, failures)
+		}
+	}
+}
+
+// WHEN the local client closes first, THEN the EOF on the stream SHALL NOT be blamed on the remote.
+func TestRemoteFailureIgnoresLocalClose(t *testing.T) {
+	client, src := net.Pipe()
+	dst, agent := net.Pipe()
+	defer agent.Close()
+
+	remote := &remoteFailureConn{
+		ReadWriteCloser: dst,
+		opened:          time.Now(),
+		onFailure:       func(string, time.Duration) { t.Error("Unexpected remote failure") },
+	}
+	go io.Copy(io.Discard, agent)
+	client.Close()
+
+	stats := handleDataTransfer(remote, src, 0, false)
+	assert.Equal(t, CloseReasonClient, stats.reason)
+	assert.Empty(t, remote.reason)
+}
+
+// WHEN ResetOnRemoteFailure is set and the remote fails, THEN the local client SHALL see a reset
+// rather than an orderly close.
+func TestWatchRemoteFailureResets(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	assert.Nil(t, err)
+	defer client.Close()
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+
+	p := &MuxPortForwarding{session: session.Session{ResetOnRemoteFailure: true}}
+	dst, agent := net.Pipe()
+	remote := p.watchRemoteFailure(mockLog, conn, dst, time.Now())
+	agent.Close()
+
+	stats := handleDataTransfer(remote, conn, 0, false)
+	assert.Equal(t, CloseReasonRemoteRefused, remote.reason)
+	assert.Equal(t, CloseReasonRemote, stats.reason)
+
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = client.Read(make([]byte, 1))
+	assert.True(t, errors.Is(err, syscall.ECONNRESET), "expected a reset, got %v", err)
+}
@@ -24,7 +24,6 @@ import (
 	"github.com/zph/session-manager-plugin/src/log"
 	"github.com/zph/session-manager-plugin/src/message"
 	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
-	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session/sessionutil"
 )
 
 type StandardStreamForwarding struct {
@@ -58,9 +57,10 @@ func (p *StandardStreamForwarding) InitializeStreams(log log.T, agentVersion str
 // handleControlSignals handles terminate signals
 func (p *StandardStreamForwarding) handleControlSignals(log log.T) {
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, sessionutil.ControlSignals...)
+	signal.Notify(c, controlSignals(p.session)...)
 	go func() {
 		<-c
+		closeDrained(p.session)
 		p.session.DataChannel.EndSession()
 		p.Stop()
 	}()
@@ -76,10 +76,11 @@ func (p *StandardStreamForwarding) ReadStream(log log.T) (err error) {
 		}
 
 		log.Tracef("Received message of size %d from stdin.", numBytes)
-		if err = p.session.DataChannel.SendInputDataMessage(log, message.Output, msg[:numBytes]); err != nil {
+		if err = sendOutput(log, p.session, msg[:numBytes]); err != nil {
 			log.Errorf("Failed to send packet: %v", err)
 			return err
 		}
+		p.session.Transfer.AddSent(numBytes)
 		// Sleep to process more data
 		time.Sleep(time.Millisecond)
 	}
@@ -0,0 +1,114 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/log"
+	"github.com/zph/session-manager-plugin/src/message"
+)
+
+// stdioSource identifies the stdio connection in connection records.
+const stdioSource = "stdio"
+
+// errStdioClosed stops the mux session's routines once the stdio connection has finished.
+var errStdioClosed = errors.New("stdio connection closed")
+
+// stdioConn is the local end of a stdio forward: reads come from stdin, writes go to stdout.
+// Close is a no-op since the process owns its standard streams.
+type stdioConn struct {
+	io.Reader
+	io.Writer
+}
+
+func (stdioConn) Close() error { return nil }
+
+// handleStdio forwards stdin and stdout over a single tunnel stream in place of a local listener,
+// as an SSH ProxyCommand would, and terminates the session once the connection closes.
+func (p *MuxPortForwarding) handleStdio(log log.T, ctx context.Context) error {
+	local := p.stdio
+	if local == nil {
+		local = stdioConn{os.Stdin, os.Stdout}
+	}
+
+	stream, err := p.openStream()
+	if err != nil {
+		return fmt.Errorf("failed to open tunnel stream: %w", err)
+	}
+	log.Debugf("Stdio stream opened %d\n", stream.ID())
+	reportConnOpened(p.session, stdioSource)
+	opened := time.Now()
+
+	remote, err := originateRemoteTLS(ctx, stream, p.session.RemoteTLSConfig, p.connectTimeout(remoteTLSHandshakeTimeout))
+	if err != nil {
+		stream.Close()
+		reportConn(p.session, stdioSource, opened, 0, 0, CloseReasonRemote)
+		return fmt.Errorf("TLS handshake with remote failed: %w", err)
+	}
+	local, stopProgress := p.trackTransferProgress(log, stdioSource, limitConn(local, p.uploadLimiter, p.downloadLimiter))
+	stats := forwardStdio(remote, local, p.session.BufferSize)
+	stopProgress()
+	reportConn(p.session, stdioSource, opened, stats.toDst, stats.toSrc, stats.reason)
+	log.Infof("Stdio connection for session [%s] closed: %s", p.sessionId, stats.reason)
+
+	if err := p.session.DataChannel.SendFlag(log, message.TerminateSession); err != nil {
+		log.Errorf("Failed to send TerminateSession flag: %v", err)
+	}
+	return errStdioClosed
+}
+
+// forwardStdio copies between the tunnel stream and the local stdio connection until the remote
+// stops sending. Unlike handleDataTransfer it does not wait for the local side: a read from stdin
+// cannot be interrupted, so EOF on stdin closes the stream and the remote's close ends the forward.
+func forwardStdio(remote io.ReadWriteCloser, local io.ReadWriteCloser, bufferSize int) (stats transferStats) {
+	var sent atomic.Int64
+	localDone := make(chan struct{})
+	go func() {
+		copyWithBuffer(countingWriter{remote, &sent}, local, bufferSize)
+		close(localDone)
+		remote.Close()
+	}()
+
+	n, err := copyWithBuffer(local, remote, bufferSize)
+	remote.Close()
+	stats.toDst = sent.Load()
+	stats.toSrc = n
+	select {
+	case <-localDone:
+		stats.reason = CloseReasonClient
+	default:
+		stats.reason = closeReason(err, CloseReasonRemote)
+	}
+	return stats
+}
+
+// countingWriter counts bytes as they are written, so progress is known before the copy ends.
+type countingWriter struct {
+	io.Writer
+	n *atomic.Int64
+}
+
+func (w countingWriter) Write(b []byte) (int, error) {
+	n, err := w.Writer.Write(b)
+	w.n.Add(int64(n))
+	return n, err
+}
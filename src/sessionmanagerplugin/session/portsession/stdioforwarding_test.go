@@ -0,0 +1,92 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zph/session-manager-plugin/src/communicator/mocks"
+	"github.com/zph/session-manager-plugin/src/jsonutil"
+)
+
+// WHEN the remote closes a stdio forward, THEN forwardStdio SHALL return without waiting for
+// stdin, which cannot be interrupted, and report the bytes received.
+func TestForwardStdioRemoteCloses(t *testing.T) {
+	remote, agent := net.Pipe()
+	stdinReader, stdinWriter := io.Pipe()
+	defer stdinWriter.Close()
+	var stdout bytes.Buffer
+
+	go func() {
+		stdinWriter.Write([]byte("SSH-2.0-client\r\n"))
+	}()
+	go func() {
+		buf := make([]byte, 64)
+		agent.Read(buf)
+		agent.Write([]byte("SSH-2.0-server\r\n"))
+		agent.Close()
+	}()
+
+	stats := forwardStdio(remote, stdioConn{stdinReader, &stdout}, 0)
+	assert.Equal(t, "SSH-2.0-server\r\n", stdout.String())
+	assert.Equal(t, int64(16), stats.toSrc)
+	assert.Equal(t, CloseReasonRemote, stats.reason)
+}
+
+// WHEN stdin reaches EOF, THEN forwardStdio SHALL close the stream and report the client as the close reason.
+func TestForwardStdioStdinEOF(t *testing.T) {
+	remote, agent := net.Pipe()
+	defer agent.Close()
+	var stdout bytes.Buffer
+
+	received := make(chan []byte, 1)
+	go func() {
+		data, _ := io.ReadAll(agent)
+		received <- data
+	}()
+
+	stats := forwardStdio(remote, stdioConn{strings.NewReader("ping"), &stdout}, 0)
+	assert.Equal(t, []byte("ping"), <-received)
+	assert.Equal(t, int64(4), stats.toDst)
+	assert.Equal(t, CloseReasonClient, stats.reason)
+}
+
+// WHEN a stdio forward runs against an agent without multiplexing, THEN Initialize SHALL relay
+// the agent's single raw connection over stdin and stdout.
+func TestInitializePortSessionForStdioWithOldAgent(t *testing.T) {
+	mockWebSocketChannel = mocks.IWebSocketChannel{}
+	t.Cleanup(func() {
+		mockWebSocketChannel = mocks.IWebSocketChannel{}
+	})
+
+	var portParameters PortParameters
+	jsonutil.Remarshal(map[string]interface{}{"portNumber": "22", "type": "LocalPortForwarding"}, &portParameters)
+
+	mockWebSocketChannel.On("SetOnMessage", mock.Anything)
+
+	portSession := PortSession{
+		Session: getSessionMockWithParams(portParameters, "2.2.0.0"),
+	}
+	portSession.PortForwardingStdio = true
+	portSession.Initialize(mockLog, &portSession.Session)
+
+	assert.IsType(t, &StandardStreamForwarding{}, portSession.portSessionType)
+}
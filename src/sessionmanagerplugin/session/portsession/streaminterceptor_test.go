@@ -0,0 +1,120 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	dataChannelMock "github.com/zph/session-manager-plugin/src/datachannel/mocks"
+	"github.com/zph/session-manager-plugin/src/message"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+// funcInterceptor is a StreamInterceptor built from two functions.
+type funcInterceptor struct {
+	outbound func([]byte) ([]byte, error)
+	inbound  func([]byte) ([]byte, error)
+}
+
+func (f funcInterceptor) Outbound(data []byte) ([]byte, error) { return f.outbound(data) }
+func (f funcInterceptor) Inbound(data []byte) ([]byte, error)  { return f.inbound(data) }
+
+// WHEN a StreamInterceptor is set, THEN bytes read locally SHALL reach the agent as it returns
+// them, and SHALL NOT be sent when it drops them or fails.
+func TestSendOutputIntercepted(t *testing.T) {
+	dataChannel := &dataChannelMock.IDataChannel{}
+	dataChannel.On("SendInputDataMessage", mock.Anything, message.Output, []byte("HELLO")).Return(nil).Once()
+	s := session.Session{DataChannel: dataChannel, StreamInterceptor: funcInterceptor{
+		outbound: func(data []byte) ([]byte, error) { return bytes.ToUpper(data), nil },
+	}}
+	assert.Nil(t, sendOutput(mockLog, s, []byte("hello")))
+
+	s.StreamInterceptor = funcInterceptor{outbound: func([]byte) ([]byte, error) { return nil, nil }}
+	assert.Nil(t, sendOutput(mockLog, s, []byte("dropped")))
+
+	injected := errors.New("injected fault")
+	s.StreamInterceptor = funcInterceptor{outbound: func([]byte) ([]byte, error) { return nil, injected }}
+	assert.ErrorIs(t, sendOutput(mockLog, s, []byte("failed")), injected)
+
+	dataChannel.AssertExpectations(t)
+}
+
+// WHEN no StreamInterceptor is set, THEN bytes SHALL be sent unchanged.
+func TestSendOutputWithoutInterceptor(t *testing.T) {
+	dataChannel := &dataChannelMock.IDataChannel{}
+	dataChannel.On("SendInputDataMessage", mock.Anything, message.Output, []byte("hello")).Return(nil).Once()
+	assert.Nil(t, sendOutput(mockLog, session.Session{DataChannel: dataChannel}, []byte("hello")))
+	dataChannel.AssertExpectations(t)
+}
+
+// WHEN a StreamInterceptor is set, THEN bytes from the agent SHALL be written locally as it
+// returns them, and nothing SHALL be written for bytes it drops.
+func TestProcessStreamMessagePayloadIntercepted(t *testing.T) {
+	in, out, _ := os.Pipe()
+	defer in.Close()
+
+	var seen [][]byte
+	sess := getSessionMock()
+	sess.StreamInterceptor = funcInterceptor{inbound: func(data []byte) ([]byte, error) {
+		seen = append(seen, data)
+		if bytes.Equal(data, []byte("drop")) {
+			return nil, nil
+		}
+		return bytes.ToUpper(data), nil
+	}}
+	portSession := PortSession{
+		Session:         sess,
+		portParameters:  PortParameters{PortNumber: "22"},
+		portSessionType: &StandardStreamForwarding{inputStream: in, outputStream: out},
+	}
+
+	for _, payload := range []string{"drop", "testing123"} {
+		msg := message.ClientMessage{PayloadType: uint32(message.Output), Payload: []byte(payload), PayloadLength: uint32(len(payload))}
+		isReady, err := portSession.ProcessStreamMessagePayload(mockLog, msg)
+		assert.True(t, isReady)
+		assert.Nil(t, err)
+	}
+	out.Close()
+
+	written, _ := io.ReadAll(in)
+	assert.Equal(t, "TESTING123", string(written))
+	assert.Equal(t, [][]byte{[]byte("drop"), []byte("testing123")}, seen)
+}
+
+// WHEN a StreamInterceptor observes the stream, THEN it SHALL see the local side's bytes without
+// any data channel send being stubbed out.
+func TestStandardStreamReadStreamIntercepted(t *testing.T) {
+	in, out, _ := os.Pipe()
+	out.Write([]byte("hello"))
+	out.Close()
+
+	var captured []byte
+	sess := getSessionMock()
+	sess.Transfer = &session.TransferStats{}
+	sess.StreamInterceptor = funcInterceptor{outbound: func(data []byte) ([]byte, error) {
+		captured = append(captured, data...)
+		return nil, nil
+	}}
+	forwarding := &StandardStreamForwarding{session: sess, inputStream: in, portParameters: PortParameters{PortNumber: "22"}}
+
+	assert.Nil(t, forwarding.ReadStream(mockLog))
+	assert.Equal(t, "hello", string(captured))
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// countingConn counts the bytes read from (sent upstream) and written to (received from the
+// remote) the local end of a forwarded connection, so they can be logged while it is open.
+type countingConn struct {
+	io.ReadWriteCloser
+	sent     *atomic.Int64
+	received *atomic.Int64
+}
+
+func (c countingConn) Read(b []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(b)
+	c.sent.Add(int64(n))
+	return n, err
+}
+
+func (c countingConn) Write(b []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Write(b)
+	c.received.Add(int64(n))
+	return n, err
+}
+
+// logTransferProgress logs "sent=X recv=Y" for a connection at debug level every interval until
+// done reports true. progress returns the connection's source and counts; an empty source means
+// no connection is open and nothing is logged.
+func logTransferProgress(log log.T, interval time.Duration, done func() bool, progress func() (source string, sent int64, received int64)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if done() {
+			return
+		}
+		if source, sent, received := progress(); source != "" {
+			log.Debugf("Connection from %s: sent=%d recv=%d", source, sent, received)
+		}
+	}
+}
+
+// trackTransferProgress wraps the local end of a mux connection to count bytes in each direction
+// and logs them every Session.TransferLogInterval until the returned stop function is called.
+// Without an interval conn is returned unchanged.
+func (p *MuxPortForwarding) trackTransferProgress(log log.T, source string, conn io.ReadWriteCloser) (io.ReadWriteCloser, func()) {
+	if p.session.TransferLogInterval <= 0 {
+		return conn, func() {}
+	}
+	var sent, received atomic.Int64
+	var stopped atomic.Bool
+	go logTransferProgress(log, p.session.TransferLogInterval, stopped.Load,
+		func() (string, int64, int64) { return source, sent.Load(), received.Load() })
+	return countingConn{conn, &sent, &received}, func() { stopped.Store(true) }
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// WHEN data flows through a countingConn, THEN reads SHALL count as sent and writes as received.
+func TestCountingConn(t *testing.T) {
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	var sent, received atomic.Int64
+	counted := countingConn{conn, &sent, &received}
+	defer counted.Close()
+
+	go func() {
+		peer.Write([]byte("abc"))
+		buf := make([]byte, 5)
+		peer.Read(buf)
+	}()
+	buf := make([]byte, 3)
+	counted.Read(buf)
+	counted.Write([]byte("hello"))
+
+	assert.Equal(t, int64(3), sent.Load())
+	assert.Equal(t, int64(5), received.Load())
+}
+
+// WHEN a transfer log interval is set, THEN logTransferProgress SHALL log the counts at debug
+// level while a connection is open, skip ticks without one, and stop once done.
+func TestLogTransferProgress(t *testing.T) {
+	logger := log.NewMockLog()
+	var source atomic.Value
+	source.Store("")
+	var stopped atomic.Bool
+
+	finished := make(chan struct{})
+	go func() {
+		logTransferProgress(logger, 10*time.Millisecond, stopped.Load, func() (string, int64, int64) {
+			return source.Load().(string), 3, 5
+		})
+		close(finished)
+	}()
+
+	time.Sleep(35 * time.Millisecond)
+	source.Store("127.0.0.1:5000")
+	time.Sleep(35 * time.Millisecond)
+	stopped.Store(true)
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("logTransferProgress did not stop")
+	}
+
+	logger.AssertNotCalled(t, "Debugf", "Connection from %s: sent=%d recv=%d", []interface{}{"", int64(3), int64(5)})
+	logger.AssertCalled(t, "Debugf", "Connection from %s: sent=%d recv=%d", []interface{}{"127.0.0.1:5000", int64(3), int64(5)})
+}
+
+// WHEN no transfer log interval is set, THEN trackTransferProgress SHALL leave the connection unwrapped.
+func TestTrackTransferProgressDisabled(t *testing.T) {
+	conn, peer := net.Pipe()
+	defer conn.Close()
+	defer peer.Close()
+
+	p := &MuxPortForwarding{session: getSessionMock()}
+	local, stop := p.trackTransferProgress(mockLog, "src", conn)
+	stop()
+	assert.Equal(t, conn, local)
+}
@@ -0,0 +1,268 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/log"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// maxDatagramSize is the largest UDP payload that can be carried in one frame.
+	maxDatagramSize = 65535
+)
+
+// udpClientIdleTimeout is how long a client address may stay silent before its stream is closed.
+var udpClientIdleTimeout = 60 * time.Second
+
+// UDPPortForwarding is type of port session
+// relays local UDP datagrams through the multiplexed session.
+//
+// The SSM agent only forwards TCP, so each local client address gets its own mux stream and
+// datagrams travel over it with a 2-byte big-endian length prefix. The remote service must
+// accept that framing on TCP, as DNS does (RFC 1035 section 4.2.2).
+type UDPPortForwarding struct {
+	MuxPortForwarding
+	packetConn net.PacketConn
+	clients    map[string]*udpClient
+	mutex      sync.Mutex
+}
+
+// udpClient is the stream serving one local client address.
+type udpClient struct {
+	addr       net.Addr
+	stream     io.ReadWriteCloser
+	lastActive atomic.Int64 // unix nanoseconds
+}
+
+func (c *udpClient) touch() {
+	c.lastActive.Store(time.Now().UnixNano())
+}
+
+// Stop closes the local UDP socket, all client streams and the mux session
+func (p *UDPPortForwarding) Stop() {
+	if p.packetConn != nil {
+		p.packetConn.Close()
+	}
+	p.mutex.Lock()
+	for key, client := range p.clients {
+		client.stream.Close()
+		delete(p.clients, key)
+	}
+	p.mutex.Unlock()
+	p.MuxPortForwarding.Stop()
+}
+
+// ReadStream relays datagrams between local clients and the data channel
+func (p *UDPPortForwarding) ReadStream(log log.T) (err error) {
+	g, ctx := errgroup.WithContext(context.Background())
+
+	// reads data from smux client and transfers to server over datachannel
+	g.Go(func() error {
+		return p.transferDataToServer(log, ctx)
+	})
+
+	g.Go(func() error {
+		return p.handleDatagrams(log, ctx)
+	})
+
+	g.Go(func() error {
+		return p.reapIdleClients(log, ctx)
+	})
+
+	g.Go(func() error {
+		for {
+			time.Sleep(50 * time.Millisecond)
+			if p.session.DataChannel.IsSessionEnded() == true {
+				p.Stop()
+				return nil
+			}
+		}
+	})
+
+	return g.Wait()
+}
+
+// handleDatagrams opens the local UDP socket and forwards each datagram on its client's stream
+func (p *UDPPortForwarding) handleDatagrams(log log.T, ctx context.Context) (err error) {
+	localPortNumber := p.portParameters.LocalPortNumber
+	if localPortNumber == "" {
+		localPortNumber = "0"
+	}
+	config := listenConfig(p.session)
+	if p.packetConn, err = config.ListenPacket(context.Background(), ListenNetwork(ProtocolUDP, p.session.PortForwardingNetwork), localListenAddress(p.session, localPortNumber)); err != nil {
+		return err
+	}
+	defer p.packetConn.Close()
+	reportListening(p.session, p.packetConn.LocalAddr())
+
+	p.portParameters.LocalPortNumber = strconv.Itoa(p.packetConn.LocalAddr().(*net.UDPAddr).Port)
+	log.Infof("UDP port %s opened for sessionId %s.", p.portParameters.LocalPortNumber, p.sessionId)
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		n, addr, err := p.packetConn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			log.Errorf("Error while reading datagram: %v", err)
+			continue
+		}
+
+		client, err := p.clientFor(log, addr)
+		if err != nil {
+			log.Warnf("Dropping datagram from %s: %v", addr, err)
+			continue
+		}
+		client.touch()
+		if err = writeDatagram(client.stream, buf[:n]); err != nil {
+			log.Debugf("Failed to forward datagram from %s: %v", addr, err)
+			p.removeClient(addr.String(), client)
+		}
+	}
+}
+
+// clientFor returns the stream for addr, opening a new one for a first-time client
+func (p *UDPPortForwarding) clientFor(log log.T, addr net.Addr) (*udpClient, error) {
+	key := addr.String()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if client, ok := p.clients[key]; ok {
+		return client, nil
+	}
+
+	if !p.acquireConn() {
+		return nil, errors.New("connection limit reached")
+	}
+	stream, err := p.muxClient.session.OpenStream()
+	if err != nil {
+		p.releaseConn()
+		return nil, err
+	}
+
+	client := &udpClient{addr: addr, stream: stream}
+	client.touch()
+	if p.clients == nil {
+		p.clients = make(map[string]*udpClient)
+	}
+	p.clients[key] = client
+	log.Infof("UDP client %s connected for session [%s]", addr, p.sessionId)
+
+	go p.relayResponses(log, client)
+	return client, nil
+}
+
+// relayResponses writes framed responses from the client's stream back to its address
+func (p *UDPPortForwarding) relayResponses(log log.T, client *udpClient) {
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, err := readDatagram(client.stream, buf)
+		if err != nil {
+			log.Debugf("UDP client %s stream closed: %v", client.addr, err)
+			p.removeClient(client.addr.String(), client)
+			return
+		}
+		client.touch()
+		if _, err = p.packetConn.WriteTo(buf[:n], client.addr); err != nil {
+			log.Debugf("Failed to deliver datagram to %s: %v", client.addr, err)
+		}
+	}
+}
+
+// removeClient closes client's stream and forgets it, if it is still the registered client for key
+func (p *UDPPortForwarding) removeClient(key string, client *udpClient) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.clients[key] != client {
+		return
+	}
+	delete(p.clients, key)
+	client.stream.Close()
+	p.releaseConn()
+}
+
+// reapIdleClients periodically expires clients that have been silent for udpClientIdleTimeout
+func (p *UDPPortForwarding) reapIdleClients(log log.T, ctx context.Context) error {
+	ticker := time.NewTicker(udpClientIdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			p.expireIdleClients(log, time.Now().Add(-udpClientIdleTimeout))
+		}
+	}
+}
+
+// expireIdleClients removes clients last active before cutoff
+func (p *UDPPortForwarding) expireIdleClients(log log.T, cutoff time.Time) {
+	p.mutex.Lock()
+	var idle []*udpClient
+	for _, client := range p.clients {
+		if client.lastActive.Load() < cutoff.UnixNano() {
+			idle = append(idle, client)
+		}
+	}
+	p.mutex.Unlock()
+
+	for _, client := range idle {
+		log.Debugf("Expiring idle UDP client %s", client.addr)
+		p.removeClient(client.addr.String(), client)
+	}
+}
+
+// writeDatagram writes payload to w with a 2-byte big-endian length prefix
+func writeDatagram(w io.Writer, payload []byte) error {
+	if len(payload) > maxDatagramSize {
+		return errors.New("datagram too large")
+	}
+	frame := make([]byte, 2+len(payload))
+	binary.BigEndian.PutUint16(frame, uint16(len(payload)))
+	copy(frame[2:], payload)
+	_, err := w.Write(frame)
+	return err
+}
+
+// readDatagram reads one length-prefixed frame from r into buf and returns its length
+func readDatagram(r io.Reader, buf []byte) (int, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, err
+	}
+	n := int(binary.BigEndian.Uint16(header[:]))
+	if n > len(buf) {
+		return 0, errors.New("datagram larger than buffer")
+	}
+	return io.ReadFull(r, buf[:n])
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// WHEN a datagram is framed with writeDatagram, THEN readDatagram SHALL return the same payload.
+func TestDatagramFramingRoundTrip(t *testing.T) {
+	var frame bytes.Buffer
+	assert.NoError(t, writeDatagram(&frame, []byte("query")))
+	assert.NoError(t, writeDatagram(&frame, []byte{}))
+	assert.Equal(t, []byte{0, 5, 'q', 'u', 'e', 'r', 'y', 0, 0}, frame.Bytes())
+
+	buf := make([]byte, maxDatagramSize)
+	n, err := readDatagram(&frame, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "query", string(buf[:n]))
+
+	n, err = readDatagram(&frame, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	assert.Error(t, writeDatagram(&frame, make([]byte, maxDatagramSize+1)))
+	_, err = readDatagram(bytes.NewReader([]byte{0, 9, 'x'}), make([]byte, 4))
+	assert.Error(t, err)
+}
+
+// WHEN a UDP client has been silent past the cutoff, THEN expireIdleClients SHALL close its stream
+// and release its connection slot while active clients are kept.
+func TestExpireIdleClients(t *testing.T) {
+	p := &UDPPortForwarding{clients: map[string]*udpClient{}}
+
+	idleStream, idlePeer := net.Pipe()
+	defer idlePeer.Close()
+	activeStream, activePeer := net.Pipe()
+	defer activeStream.Close()
+	defer activePeer.Close()
+
+	idle := &udpClient{addr: &net.UDPAddr{Port: 1}, stream: idleStream}
+	idle.lastActive.Store(time.Now().Add(-time.Minute).UnixNano())
+	active := &udpClient{addr: &net.UDPAddr{Port: 2}, stream: activeStream}
+	active.touch()
+	p.clients[idle.addr.String()] = idle
+	p.clients[active.addr.String()] = active
+	p.activeConns = 2
+
+	p.expireIdleClients(log.NewMockLog(), time.Now().Add(-time.Second))
+
+	assert.NotContains(t, p.clients, idle.addr.String())
+	assert.Contains(t, p.clients, active.addr.String())
+	assert.Equal(t, int32(1), p.activeConns)
+	_, err := idlePeer.Read(make([]byte, 1))
+	assert.Error(t, err, "idle stream should be closed")
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// CloseReasonWriteTimeout is the close reason for a connection torn down because a write to one
+// side made no progress for Session.IOTimeout.
+const CloseReasonWriteTimeout = "write_timeout"
+
+// writeDeadliner is implemented by net.Conn, tls.Conn and smux streams.
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// writeTimeoutConn bounds each write to one side of a connection, so a peer that stops reading
+// fails the copy into it instead of blocking it forever. Reads are left unbounded: an idle
+// connection is blocked reading, which Session.MuxIdleTimeout handles.
+type writeTimeoutConn struct {
+	io.ReadWriteCloser
+	timeout   time.Duration
+	timedOut  atomic.Bool
+	onTimeout func()
+}
+
+func (c *writeTimeoutConn) Write(b []byte) (int, error) {
+	if c.timeout <= 0 {
+		return c.ReadWriteCloser.Write(b)
+	}
+	if deadliner, ok := c.ReadWriteCloser.(writeDeadliner); ok {
+		deadliner.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+	n, err := c.ReadWriteCloser.Write(b)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() && c.timedOut.CompareAndSwap(false, true) {
+		c.onTimeout()
+	}
+	return n, err
+}
+
+// watchWriteTimeout wraps side, the local connection conn or its tunnel stream, so that with
+// Session.IOTimeout a write to it that makes no progress for that long fails and is logged,
+// tearing the connection down. peer names side in the log.
+func (p *MuxPortForwarding) watchWriteTimeout(log log.T, conn net.Conn, side io.ReadWriteCloser, peer string) *writeTimeoutConn {
+	return &writeTimeoutConn{
+		ReadWriteCloser: side,
+		timeout:         p.session.IOTimeout,
+		onTimeout: func() {
+			log.Warnf("Closing connection from %s for session [%s]: a write to the %s made no progress for %v",
+				conn.RemoteAddr(), p.sessionId, peer, p.session.IOTimeout)
+		},
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package portsession starts port session.
+package portsession
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+// WHEN the local client stops reading and IOTimeout is set, THEN the stalled write SHALL time out
+// and tear the connection down instead of blocking forever.
+func TestHandleDataTransferWriteTimeout(t *testing.T) {
+	client, conn := net.Pipe()
+	stream, agent := net.Pipe()
+	defer client.Close()
+	defer agent.Close()
+
+	p := &MuxPortForwarding{session: session.Session{IOTimeout: 50 * time.Millisecond}}
+	remoteWrites := p.watchWriteTimeout(mockLog, conn, stream, "remote")
+	localWrites := p.watchWriteTimeout(mockLog, conn, conn, "local client")
+	go agent.Write([]byte("never read"))
+
+	done := make(chan transferStats)
+	go func() { done <- handleDataTransfer(remoteWrites, localWrites, 0, false) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the stalled write to time out")
+	}
+	assert.True(t, localWrites.timedOut.Load())
+	assert.False(t, remoteWrites.timedOut.Load())
+}
+
+// WHEN IOTimeout is not set, THEN writes SHALL have no deadline.
+func TestWriteTimeoutDisabled(t *testing.T) {
+	client, conn := net.Pipe()
+	defer client.Close()
+
+	p := &MuxPortForwarding{}
+	writes := p.watchWriteTimeout(mockLog, conn, conn, "local client")
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		client.Read(make([]byte, 5))
+	}()
+	n, err := writes.Write([]byte("hello"))
+	assert.Nil(t, err)
+	assert.Equal(t, 5, n)
+	assert.False(t, writes.timedOut.Load())
+}
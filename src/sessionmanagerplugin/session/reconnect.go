@@ -0,0 +1,78 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package session starts the session.
+package session
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/zph/session-manager-plugin/src/log"
+	"github.com/zph/session-manager-plugin/src/sdkutil"
+)
+
+// Error classes a data channel reconnect can be enabled for with Session.ReconnectOn.
+const (
+	// ReconnectNet covers websocket and network failures, including ResumeSession requests that never got a response
+	ReconnectNet = "net"
+	// ReconnectThrottle covers ResumeSession being rate limited
+	ReconnectThrottle = "throttle"
+	// ReconnectServer covers ResumeSession failing with an AWS 5xx error
+	ReconnectServer = "server"
+)
+
+// ReconnectClasses lists every error class Session.ReconnectOn accepts.
+var ReconnectClasses = []string{ReconnectNet, ReconnectThrottle, ReconnectServer}
+
+// reconnectClass returns the error class of a failure to keep the data channel connected, or ""
+// when the failure is permanent, e.g. AccessDeniedException or any other AWS client error.
+func reconnectClass(err error) string {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return ReconnectNet
+	}
+	if sdkutil.IsThrottling(err) {
+		return ReconnectThrottle
+	}
+	var reqErr awserr.RequestFailure
+	if !errors.As(err, &reqErr) {
+		// The SDK reports transport failures without a status code
+		return ReconnectNet
+	}
+	if reqErr.StatusCode() >= 500 {
+		return ReconnectServer
+	}
+	return ""
+}
+
+// shouldReconnect reports whether the data channel should be resumed after err. It never is once
+// the session has ended, e.g. after a TerminateSession, nor after a permanent error; otherwise the
+// error's class must be in ReconnectOn, which allows every class when nil.
+func (s *Session) shouldReconnect(log log.T, err error) bool {
+	if s.DataChannel.IsSessionEnded() {
+		log.Infof("Not reconnecting session %s: the session has ended", s.SessionId)
+		return false
+	}
+	class := reconnectClass(err)
+	if class == "" {
+		log.Errorf("Not reconnecting session %s after a permanent error: %v", s.SessionId, err)
+		return false
+	}
+	if s.ReconnectOn != nil && !slices.Contains(s.ReconnectOn, class) {
+		log.Errorf("Not reconnecting session %s after a %s error: %v", s.SessionId, class, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package session starts the session.
+package session
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	wsChannelMock "github.com/zph/session-manager-plugin/src/communicator/mocks"
+	dataChannelMock "github.com/zph/session-manager-plugin/src/datachannel/mocks"
+)
+
+// WHEN a data channel failure is classified, THEN websocket and transport errors SHALL be net,
+// rate limiting throttle, AWS 5xx server, and other AWS errors permanent.
+func TestReconnectClass(t *testing.T) {
+	cases := map[string]struct {
+		err  error
+		want string
+	}{
+		"websocket": {errors.New("websocket: close 1006 (abnormal closure)"), ReconnectNet},
+		"transport": {awserr.New("RequestError", "send request failed", errors.New("connection reset")), ReconnectNet},
+		"throttle":  {awserr.NewRequestFailure(awserr.New("ThrottlingException", "Rate exceeded", nil), 400, "req"), ReconnectThrottle},
+		"server":    {awserr.NewRequestFailure(awserr.New("InternalServerError", "oops", nil), 503, "req"), ReconnectServer},
+		"denied":    {awserr.NewRequestFailure(awserr.New("AccessDeniedException", "not allowed", nil), 400, "req"), ""},
+		"wrapped":   {fmt.Errorf("resume: %w", awserr.NewRequestFailure(awserr.New("AccessDeniedException", "", nil), 400, "req")), ""},
+	}
+	for name, c := range cases {
+		assert.Equal(t, c.want, reconnectClass(c.err), name)
+	}
+}
+
+// WHEN ReconnectOn is set, THEN shouldReconnect SHALL allow only the listed classes, and never
+// permanent errors or a session that has already ended.
+func TestShouldReconnect(t *testing.T) {
+	mockDataChannel = &dataChannelMock.IDataChannel{}
+	mockDataChannel.On("IsSessionEnded").Return(false)
+	throttled := awserr.NewRequestFailure(awserr.New("ThrottlingException", "Rate exceeded", nil), 400, "req")
+	denied := awserr.NewRequestFailure(awserr.New("AccessDeniedException", "not allowed", nil), 400, "req")
+	dropped := errors.New("websocket: close 1006 (abnormal closure)")
+
+	s := &Session{DataChannel: mockDataChannel}
+	assert.True(t, s.shouldReconnect(logger, dropped))
+	assert.True(t, s.shouldReconnect(logger, throttled))
+	assert.False(t, s.shouldReconnect(logger, denied))
+
+	s.ReconnectOn = []string{ReconnectNet}
+	assert.True(t, s.shouldReconnect(logger, dropped))
+	assert.False(t, s.shouldReconnect(logger, throttled))
+
+	s.ReconnectOn = []string{}
+	assert.False(t, s.shouldReconnect(logger, dropped))
+
+	ended := &dataChannelMock.IDataChannel{}
+	ended.On("IsSessionEnded").Return(true)
+	s = &Session{DataChannel: ended}
+	assert.False(t, s.shouldReconnect(logger, dropped))
+}
+
+// WHEN the websocket fails after the session was terminated, THEN the data channel SHALL NOT be resumed.
+func TestOpenDataChannelNoReconnectAfterSessionEnded(t *testing.T) {
+	mockDataChannel = &dataChannelMock.IDataChannel{}
+	mockWsChannel = &wsChannelMock.IWebSocketChannel{}
+
+	var onError func(error)
+	mockWsChannel.On("SetOnError", mock.Anything).Run(func(args mock.Arguments) {
+		onError = args.Get(0).(func(error))
+	})
+	reconnects := 0
+	sessionMock := &Session{DataChannel: mockDataChannel, OnReconnect: func(bool) { reconnects++ }}
+	SetupMockActions()
+	mockDataChannel.On("Open", mock.Anything).Return(nil)
+	mockDataChannel.On("IsSessionEnded").Return(true)
+
+	assert.Nil(t, sessionMock.OpenDataChannel(logger))
+	onError(errors.New("websocket: close 1000 (normal)"))
+	assert.Equal(t, 0, reconnects)
+	mockDataChannel.AssertNotCalled(t, "Reconnect", mock.Anything)
+}
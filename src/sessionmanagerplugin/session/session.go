@@ -15,17 +15,21 @@
 package session
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/zph/session-manager-plugin/src/config"
 
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/google/uuid"
+	"github.com/zph/session-manager-plugin/src/communicator"
 	"github.com/zph/session-manager-plugin/src/datachannel"
 	"github.com/zph/session-manager-plugin/src/log"
 	"github.com/zph/session-manager-plugin/src/message"
@@ -86,10 +90,192 @@ type Session struct {
 	DisplayMode                  sessionutil.DisplayMode
 	PortForwardingUseUnixSocket  bool
 	PortForwardingUnixSocketPath string
+	// PortForwardingToRemoteHost is set when the port session forwards to a host other than the target itself
+	PortForwardingToRemoteHost bool
+	// PortForwardingBindHost is the address local port listeners bind to (default: localhost)
+	PortForwardingBindHost string
+	// PortForwardingProtocol selects the local listener protocol: "tcp" (default) or "udp"
+	PortForwardingProtocol string
+	// PortForwardingNetwork restricts local listeners to an address family: "tcp4", "tcp6", or
+	// "tcp" (default) for whichever the bind host resolves to
+	PortForwardingNetwork string
+	// PortForwardingStdio forwards the process's stdin and stdout over a single tunnel
+	// connection instead of opening a local listener, for use as an SSH ProxyCommand
+	PortForwardingStdio bool
 	// READY-007, READY-008: Closed when agent signals readiness (StartPublicationMessage)
 	PortReady chan struct{}
 	// READY-003, READY-006: Receives error when agent reports connection failure (ConnectToPortError)
 	PortError chan error
+	// MaxConnections caps concurrently accepted local connections. Zero means unlimited.
+	MaxConnections int
+	// RateLimit caps forwarded bytes per second in each direction. Zero means unlimited.
+	RateLimit int64
+	// BufferSize sizes the local connection copy buffers. Zero keeps the defaults.
+	BufferSize int
+	// DrainTimeout, when positive, makes SIGINT stop accepting new local connections and wait
+	// up to this long for existing ones to finish before terminating the session.
+	DrainTimeout time.Duration
+	// Drained is closed once draining has finished (or is not supported by the session type)
+	Drained chan struct{}
+	// IgnoreInterrupt leaves SIGINT out of the signals a port session stops on, so a caller that
+	// ignores it keeps the tunnel up on Ctrl-C; other control signals still stop the session
+	IgnoreInterrupt bool
+	// Paused, if set, is checked for each multiplexed local connection as it is accepted; while it
+	// holds true the connection is closed at once, leaving the session and open connections running
+	Paused *atomic.Bool
+	// OnReconnect, if set, is called with true when the data channel starts resuming
+	// after an error and with false once the attempt finishes.
+	OnReconnect func(reconnecting bool)
+	// ReconnectOn lists the error classes (ReconnectNet, ReconnectThrottle, ReconnectServer) the
+	// data channel is resumed after. Nil allows them all; permanent errors never reconnect.
+	ReconnectOn []string
+	// OnListening, if set, is called with the local listener's address once it is open
+	OnListening func(addr net.Addr)
+	// OnForwardingType, if set, is called once a port session has picked its forwarding
+	// implementation for the agent's version, e.g. "mux" or "basic" (see portsession.ForwardingMux)
+	OnForwardingType func(forwardingType string)
+	// OnConnOpened, if set, is called when a local client connection is accepted and forwarded.
+	// Every call is later matched by one OnConnClosed call for the same connection.
+	OnConnOpened func(source string)
+	// OnConnClosed, if set, is called once for each local client connection after it closes.
+	// It may be called concurrently from several connections.
+	OnConnClosed func(record ConnRecord)
+	// ConnectTimeout, when positive, bounds how long an accepted local connection waits for its
+	// mux stream to open (and the remote TLS handshake) before it is closed. Opening a stream does
+	// not wait for the remote to reach the target. Basic port forwarding ignores it.
+	ConnectTimeout time.Duration
+	// AcceptConcurrency, when positive, bounds how many accepted local connections set up their
+	// tunnel stream at once; the rest wait their turn rather than being closed. Zero means unlimited.
+	AcceptConcurrency int
+	// DialTimeout, when positive, bounds connecting to the stream URL and completing the
+	// websocket handshake, so an unreachable endpoint fails promptly
+	DialTimeout time.Duration
+	// KeepaliveInterval, when positive, repeats the latest acknowledgement about this often, for proxies
+	// that close connections without application traffic despite websocket pings
+	KeepaliveInterval time.Duration
+	// ListenBacklog, when positive, sets the accept backlog of local TCP and unix listeners
+	// instead of the OS maximum; the OS may clamp it
+	ListenBacklog int
+	// LocalListener, when set, is an already bound TCP listener served instead of binding the local
+	// port, so a port the caller allocated can't be taken by another process in between
+	LocalListener net.Listener
+	// ListenReuseAddr and ListenReusePort set SO_REUSEADDR and SO_REUSEPORT on local TCP and UDP
+	// sockets, so a restarted forward can bind a fixed port without waiting out TIME_WAIT
+	ListenReuseAddr bool
+	ListenReusePort bool
+	// TransferLogInterval, when positive, logs each local connection's bytes sent and received
+	// at debug level this often, to tell a remote that stops sending from a client that stops reading
+	TransferLogInterval time.Duration
+	// ConnLogSample, when above 1, logs the opening and progress of only one in every
+	// ConnLogSample local connections. Warnings and errors are always logged, and OnConnOpened
+	// and OnConnClosed still see every connection.
+	ConnLogSample int
+	// MuxIdleTimeout, when positive, closes multiplexed client connections that have moved no
+	// data in either direction for this long, so clients that vanish without closing don't linger
+	MuxIdleTimeout time.Duration
+	// IOTimeout, when positive, tears down a multiplexed client connection once a write to the
+	// client or to its tunnel stream has made no progress for this long, so a side that stops
+	// reading can't hang the connection forever
+	IOTimeout time.Duration
+	// HandshakeTrace, if set, logs each step of opening the data channel at trace level
+	HandshakeTrace *communicator.HandshakeTrace
+	// LocalTLSConfig, if set, terminates TLS on local listeners before forwarding plaintext
+	LocalTLSConfig *tls.Config
+	// RemoteTLSConfig, if set, originates TLS to the remote over each tunnelled connection
+	RemoteTLSConfig *tls.Config
+	// ProxyProtocol, if set to "v1" or "v2", sends a PROXY protocol header with the local
+	// client's address at the start of each multiplexed connection
+	ProxyProtocol string
+	// ResetOnRemoteFailure closes a multiplexed local TCP connection with an RST instead of a FIN when
+	// the remote closes it without sending any data, as the agent does when it cannot connect
+	ResetOnRemoteFailure bool
+	// ReadOnly drops bytes sent by local clients instead of forwarding them, so the remote can
+	// send to clients but never receive from them
+	ReadOnly bool
+	// StreamInterceptor, if set, sees a port session's bytes in each direction on their way to and
+	// from the data channel, to observe, shape or transform them
+	StreamInterceptor StreamInterceptor
+	// ShellOutputMode selects how shell output is displayed: "unbuffered" (default) shows it as
+	// it arrives, "line" holds partial lines until their newline
+	ShellOutputMode string
+	// ShellStripBanner drops shell output before the first prompt, such as a login banner or
+	// MOTD, so non-interactive captures start at the prompt
+	ShellStripBanner bool
+	// ShellPromptPattern is the regular expression that marks the first prompt when
+	// ShellStripBanner is set (default: shellsession.DefaultPromptPattern)
+	ShellPromptPattern string
+	// ShellEnv holds KEY=VALUE entries exported in the remote shell when the session starts; the
+	// target's shell must understand POSIX export
+	ShellEnv []string
+	// ShellCommandFile, when set, names a file of commands (see shellsession.ReadCommandFile) run
+	// one at a time in place of keyboard input, after which the shell exits
+	ShellCommandFile string
+	// ShellTerm, when set, is exported as TERM in the remote shell when the session starts
+	ShellTerm string
+	// ShellInitialSize, when set, is the terminal size sent at the start of a shell session in
+	// place of the local terminal's, until the local terminal is resized
+	ShellInitialSize message.SizeData
+	// ShellDurationWarning, when positive, shows a reminder banner in shell sessions each time this
+	// much more time has passed since the session started; the session is never ended
+	ShellDurationWarning time.Duration
+	// Transcript, if set, receives a plain-text copy of shell session output and is closed when
+	// the session stops
+	Transcript io.WriteCloser
+	// Transfer, if set, accumulates the bytes port sessions move over the data channel
+	Transfer *TransferStats
+	// EventBuffer is how many events the channel from Events holds (default: DefaultEventBuffer)
+	EventBuffer int
+	// BlockOnEvents makes the session wait for a full Events channel instead of dropping events
+	BlockOnEvents bool
+	// events is created by Events and shared by copies of the Session
+	events chan ForwardEvent
+}
+
+// TransferStats counts payload bytes a port session moved over its data channel.
+// It is safe for concurrent use, and a nil *TransferStats counts nothing.
+type TransferStats struct {
+	sent     atomic.Int64
+	received atomic.Int64
+}
+
+// AddSent records n bytes sent to the agent.
+func (t *TransferStats) AddSent(n int) {
+	if t != nil {
+		t.sent.Add(int64(n))
+	}
+}
+
+// AddReceived records n bytes received from the agent.
+func (t *TransferStats) AddReceived(n int) {
+	if t != nil {
+		t.received.Add(int64(n))
+	}
+}
+
+// Sent returns the bytes sent to the agent so far.
+func (t *TransferStats) Sent() int64 {
+	if t == nil {
+		return 0
+	}
+	return t.sent.Load()
+}
+
+// Received returns the bytes received from the agent so far.
+func (t *TransferStats) Received() int64 {
+	if t == nil {
+		return 0
+	}
+	return t.received.Load()
+}
+
+// ConnRecord describes a finished local client connection of a port session.
+type ConnRecord struct {
+	Source      string    `json:"source"`
+	Opened      time.Time `json:"opened"`
+	Duration    string    `json:"duration"`
+	BytesIn     int64     `json:"bytes_in"`  // received from the local client
+	BytesOut    int64     `json:"bytes_out"` // sent to the local client
+	CloseReason string    `json:"close_reason"`
 }
 
 type PortParameters struct {
@@ -241,6 +427,8 @@ func ValidateInputAndStartSession(args []string, out io.Writer) {
 
 // Execute create data channel and start the session
 func (s *Session) Execute(log log.T) (err error) {
+	defer func() { s.EmitEvent(ForwardEvent{Type: EventTerminated, Err: err}) }()
+
 	// sets the display mode
 	s.DisplayMode = sessionutil.NewDisplayMode(log)
 
@@ -270,6 +458,10 @@ func (s *Session) Execute(log log.T) (err error) {
 			s.SessionProperties = interface{}(portParameters)
 		}
 
+		if s.KeepaliveInterval > 0 {
+			s.DataChannel.StartKeepalive(log, s.KeepaliveInterval)
+		}
+
 		if err = setSessionHandlersWithSessionType(s, log); err != nil {
 			if s.DataChannel.IsSessionEnded() == false {
 				log.Errorf("Session ending with error: %v", err)
@@ -37,6 +37,13 @@ func (s *Session) OpenDataChannel(log log.T) (err error) {
 
 	s.DataChannel.Initialize(log, s.ClientId, s.SessionId, s.TargetId, s.IsAwsCliUpgradeNeeded)
 	s.DataChannel.SetWebsocket(log, s.StreamUrl, s.TokenValue)
+	if s.DialTimeout > 0 {
+		s.DataChannel.GetWsChannel().SetDialTimeout(s.DialTimeout)
+	}
+	if s.HandshakeTrace != nil {
+		s.HandshakeTrace.Step("opening data channel for session %s", s.SessionId)
+		s.DataChannel.SetHandshakeTrace(s.HandshakeTrace)
+	}
 	s.DataChannel.GetWsChannel().SetOnMessage(
 		func(input []byte) {
 			s.DataChannel.OutputMessageHandler(log, s.Stop, s.SessionId, input)
@@ -48,15 +55,31 @@ func (s *Session) OpenDataChannel(log log.T) (err error) {
 		s.retryParams.CallableFunc = func() (err error) { return s.DataChannel.Reconnect(log) }
 		if err = s.retryParams.Call(); err != nil {
 			log.Error(err)
+			return err
 		}
 	}
 
 	s.DataChannel.GetWsChannel().SetOnError(
 		func(err error) {
+			if !s.shouldReconnect(log, err) {
+				return
+			}
 			log.Errorf("Trying to reconnect the session: %v with seq num: %d", s.StreamUrl, s.DataChannel.GetStreamDataSequenceNumber())
-			s.retryParams.CallableFunc = func() (err error) { return s.ResumeSessionHandler(log) }
+			if s.OnReconnect != nil {
+				s.OnReconnect(true)
+				defer s.OnReconnect(false)
+			}
+			s.EmitEvent(ForwardEvent{Type: EventReconnecting})
+			s.retryParams.CallableFunc = func() (err error) {
+				if err = s.ResumeSessionHandler(log); err != nil && !s.shouldReconnect(log, err) {
+					return retry.Stop(err)
+				}
+				return err
+			}
 			if err = s.retryParams.Call(); err != nil {
 				log.Error(err)
+			} else {
+				s.EmitEvent(ForwardEvent{Type: EventReconnected})
 			}
 		})
 
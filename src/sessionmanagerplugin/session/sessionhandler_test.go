@@ -17,6 +17,8 @@ package session
 import (
 	"fmt"
 	"testing"
+	"testing/synctest"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	wsChannelMock "github.com/zph/session-manager-plugin/src/communicator/mocks"
@@ -63,6 +65,41 @@ func TestOpenDataChannelWithError(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+// WHEN the data channel cannot be opened after every retry, THEN OpenDataChannel SHALL return
+// the error so the caller fails instead of waiting on a session that never starts.
+func TestOpenDataChannelRetriesExhausted(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mockDataChannel = &dataChannelMock.IDataChannel{}
+		mockWsChannel = &wsChannelMock.IWebSocketChannel{}
+
+		sessionMock := &Session{}
+		sessionMock.DataChannel = mockDataChannel
+		SetupMockActions()
+
+		mockDataChannel.On("Open", mock.Anything).Return(fmt.Errorf("error"))
+		mockDataChannel.On("Reconnect", mock.Anything).Return(fmt.Errorf("failed to connect to stream URL within 5s"))
+		err := sessionMock.OpenDataChannel(logger)
+		assert.ErrorContains(t, err, "within 5s")
+		mockDataChannel.AssertNumberOfCalls(t, "Reconnect", config.DataChannelNumMaxRetries+1)
+	})
+}
+
+// WHEN DialTimeout is set, THEN OpenDataChannel SHALL pass it to the websocket channel before opening it.
+func TestOpenDataChannelDialTimeout(t *testing.T) {
+	mockDataChannel = &dataChannelMock.IDataChannel{}
+	mockWsChannel = &wsChannelMock.IWebSocketChannel{}
+
+	sessionMock := &Session{DialTimeout: 5 * time.Second}
+	sessionMock.DataChannel = mockDataChannel
+	SetupMockActions()
+	mockWsChannel.On("SetDialTimeout", 5*time.Second)
+	mockDataChannel.On("Open", mock.Anything).Return(nil)
+
+	err := sessionMock.OpenDataChannel(logger)
+	assert.Nil(t, err)
+	mockWsChannel.AssertCalled(t, "SetDialTimeout", 5*time.Second)
+}
+
 func TestProcessFirstMessageOutputMessageFirst(t *testing.T) {
 	outputMessage := message.ClientMessage{
 		PayloadType: uint32(message.Output),
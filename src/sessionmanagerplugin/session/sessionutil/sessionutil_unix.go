@@ -22,9 +22,12 @@ import (
 	"io"
 	"net"
 	"os"
+	"strings"
+	"syscall"
 
 	"github.com/zph/session-manager-plugin/src/log"
 	"github.com/zph/session-manager-plugin/src/message"
+	"golang.org/x/sys/unix"
 )
 
 type DisplayMode struct {
@@ -43,3 +46,42 @@ func (d *DisplayMode) DisplayMessage(log log.T, message message.ClientMessage) {
 func NewListener(log log.T, address string) (net.Listener, error) {
 	return net.Listen("unix", address)
 }
+
+// SetListenBacklog sets the accept backlog of a listening socket by calling listen again, which
+// updates the queue length of a socket that is already listening. The kernel clamps the value
+// to net.core.somaxconn on Linux and kern.ipc.somaxconn on BSD and macOS.
+func SetListenBacklog(conn syscall.RawConn, backlog int) error {
+	var err error
+	if controlErr := conn.Control(func(fd uintptr) {
+		err = syscall.Listen(int(fd), backlog)
+	}); controlErr != nil {
+		return controlErr
+	}
+	return err
+}
+
+// ReuseControl returns a net.ListenConfig Control function that sets SO_REUSEADDR and/or
+// SO_REUSEPORT on TCP and UDP sockets before they bind, or nil when neither is requested.
+// SO_REUSEPORT lets a restarted listener bind while the previous one still holds the port.
+func ReuseControl(reuseAddr bool, reusePort bool) func(network, address string, conn syscall.RawConn) error {
+	if !reuseAddr && !reusePort {
+		return nil
+	}
+	return func(network, address string, conn syscall.RawConn) error {
+		if strings.HasPrefix(network, "unix") {
+			return nil
+		}
+		var err error
+		if controlErr := conn.Control(func(fd uintptr) {
+			if reuseAddr {
+				err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1)
+			}
+			if err == nil && reusePort {
+				err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+			}
+		}); controlErr != nil {
+			return controlErr
+		}
+		return err
+	}
+}
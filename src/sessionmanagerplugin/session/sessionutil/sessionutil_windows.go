@@ -18,8 +18,10 @@
 package sessionutil
 
 import (
+	"errors"
 	"net"
 	"os"
+	"strings"
 	"syscall"
 
 	"github.com/zph/session-manager-plugin/src/log"
@@ -84,3 +86,39 @@ func NewListener(log log.T, address string) (net.Listener, error) {
 		return listener, err
 	}
 }
+
+// SetListenBacklog sets the accept backlog of a listening socket by calling listen again. Windows
+// only applies a changed backlog before the first connection is accepted and caps it at SOMAXCONN.
+func SetListenBacklog(conn syscall.RawConn, backlog int) error {
+	var err error
+	if controlErr := conn.Control(func(fd uintptr) {
+		err = syscall.Listen(syscall.Handle(fd), backlog)
+	}); controlErr != nil {
+		return controlErr
+	}
+	return err
+}
+
+// ReuseControl returns a net.ListenConfig Control function that sets SO_REUSEADDR on TCP and UDP
+// sockets before they bind, or nil when it is not requested. Windows has no SO_REUSEPORT, and its
+// SO_REUSEADDR also lets another socket bind a port that is actively listening.
+func ReuseControl(reuseAddr bool, reusePort bool) func(network, address string, conn syscall.RawConn) error {
+	if !reuseAddr && !reusePort {
+		return nil
+	}
+	return func(network, address string, conn syscall.RawConn) error {
+		if reusePort {
+			return errors.New("SO_REUSEPORT is not supported on Windows")
+		}
+		if strings.HasPrefix(network, "unix") {
+			return nil
+		}
+		var err error
+		if controlErr := conn.Control(func(fd uintptr) {
+			err = windows.SetsockoptInt(windows.Handle(fd), windows.SOL_SOCKET, windows.SO_REUSEADDR, 1)
+		}); controlErr != nil {
+			return controlErr
+		}
+		return err
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shellsession starts shell session.
+package shellsession
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// DefaultPromptPattern matches a typical sh/bash/zsh/PowerShell prompt: a line ending in $, #,
+// % or > and an optional space, with nothing after it yet.
+const DefaultPromptPattern = `(?m)^[^\r\n]*[$#%>] ?$`
+
+// maxBanner caps how much output is held while looking for the first prompt. Past it the
+// prompt is assumed not to match and everything held is displayed.
+const maxBanner = 64 * 1024
+
+// ParsePromptPattern compiles pattern; an empty pattern means DefaultPromptPattern.
+func ParsePromptPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		pattern = DefaultPromptPattern
+	}
+	prompt, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt pattern: %v", err)
+	}
+	return prompt, nil
+}
+
+// bannerFilter drops the login banner/MOTD that precedes the first shell prompt, so captured
+// output starts at the prompt.
+type bannerFilter struct {
+	mu      sync.Mutex
+	prompt  *regexp.Regexp
+	pending []byte
+	done    bool
+}
+
+// filter holds output until the first prompt, then returns it from the prompt on. Once the
+// prompt has been seen, or too much output has arrived without one, p is returned unchanged.
+func (b *bannerFilter) filter(p []byte) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.done {
+		return p
+	}
+	b.pending = append(b.pending, p...)
+
+	if loc := b.prompt.FindIndex(b.pending); loc != nil {
+		b.done = true
+		ready := b.pending[loc[0]:]
+		b.pending = nil
+		return ready
+	}
+	if len(b.pending) > maxBanner {
+		return b.flushLocked()
+	}
+	return nil
+}
+
+// flush returns any output still held because no prompt was seen, so nothing is lost when the
+// pattern never matches.
+func (b *bannerFilter) flush() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flushLocked()
+}
+
+func (b *bannerFilter) flushLocked() []byte {
+	b.done = true
+	pending := b.pending
+	b.pending = nil
+	return pending
+}
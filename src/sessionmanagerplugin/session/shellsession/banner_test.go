@@ -0,0 +1,104 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shellsession starts shell session.
+package shellsession
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zph/session-manager-plugin/src/communicator/mocks"
+	dataChannelMock "github.com/zph/session-manager-plugin/src/datachannel/mocks"
+	"github.com/zph/session-manager-plugin/src/message"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+// newBannerSession initializes a shell session that strips the banner up to pattern.
+func newBannerSession(pattern string, mode string) *ShellSession {
+	dataChannel := &dataChannelMock.IDataChannel{}
+	wsChannel := &mocks.IWebSocketChannel{}
+	dataChannel.On("RegisterOutputStreamHandler", mock.Anything, true)
+	dataChannel.On("GetWsChannel").Return(wsChannel)
+	wsChannel.On("SetOnMessage", mock.Anything)
+
+	shellSession := &ShellSession{}
+	shellSession.Initialize(logger, &session.Session{
+		DataChannel:        dataChannel,
+		ShellOutputMode:    mode,
+		ShellStripBanner:   true,
+		ShellPromptPattern: pattern,
+	})
+	return shellSession
+}
+
+// WHEN a banner arrives before the first prompt, THEN it SHALL be dropped and output SHALL be
+// displayed from the prompt on, even when the prompt spans several messages.
+func TestStripBannerDropsOutputBeforePrompt(t *testing.T) {
+	displayed := captureDisplay(t)
+	shellSession := newBannerSession("", "")
+
+	for _, chunk := range []string{"Last login: Mon\r\n", "Welcome to Amazon Linux\r\n\r\nsh-4.2", "$ "} {
+		shellSession.ProcessStreamMessagePayload(logger, message.ClientMessage{Payload: []byte(chunk)})
+	}
+	shellSession.ProcessStreamMessagePayload(logger, message.ClientMessage{Payload: []byte("uptime\r\n")})
+
+	assert.Equal(t, []string{"sh-4.2$ ", "uptime\r\n"}, displayed.lines())
+}
+
+// WHEN a prompt pattern is configured, THEN it SHALL mark where the banner ends.
+func TestStripBannerCustomPattern(t *testing.T) {
+	displayed := captureDisplay(t)
+	shellSession := newBannerSession(`(?m)^\[ec2-user@\S+ ~\]\$ $`, "")
+
+	shellSession.ProcessStreamMessagePayload(logger, message.ClientMessage{Payload: []byte("motd $\r\n[ec2-user@ip-10-0-0-1 ~]$ ")})
+
+	assert.Equal(t, []string{"[ec2-user@ip-10-0-0-1 ~]$ "}, displayed.lines())
+}
+
+// WHEN no prompt is ever recognized, THEN the held output SHALL be displayed when the session
+// ends rather than lost, and SHALL go through line buffering.
+func TestStripBannerFlushesWithoutPrompt(t *testing.T) {
+	displayed := captureDisplay(t)
+	shellSession := newBannerSession(`never-matches`, "line")
+
+	shellSession.ProcessStreamMessagePayload(logger, message.ClientMessage{Payload: []byte("banner\r\npartial")})
+	assert.Empty(t, displayed.lines())
+
+	assert.Equal(t, "banner\r\npartial", string(shellSession.heldOutput()))
+	assert.Empty(t, shellSession.heldOutput())
+}
+
+// WHEN more than maxBanner bytes arrive without a prompt, THEN filtering SHALL stop and the
+// output SHALL be displayed.
+func TestBannerFilterGivesUp(t *testing.T) {
+	prompt, err := ParsePromptPattern("never-matches")
+	assert.NoError(t, err)
+	filter := &bannerFilter{prompt: prompt}
+
+	assert.Empty(t, filter.filter([]byte(strings.Repeat("x", maxBanner))))
+	assert.Len(t, filter.filter([]byte("y")), maxBanner+1)
+	assert.Equal(t, "z", string(filter.filter([]byte("z"))))
+}
+
+// WHEN a prompt pattern doesn't compile, THEN ParsePromptPattern SHALL fail.
+func TestParsePromptPattern(t *testing.T) {
+	prompt, err := ParsePromptPattern("")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultPromptPattern, prompt.String())
+
+	_, err = ParsePromptPattern("[")
+	assert.ErrorContains(t, err, "invalid prompt pattern")
+}
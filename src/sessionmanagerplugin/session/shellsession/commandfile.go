@@ -0,0 +1,225 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shellsession starts shell session.
+package shellsession
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/log"
+	"github.com/zph/session-manager-plugin/src/message"
+)
+
+// heredocStart matches a here-document operator such as <<EOF, <<-EOF or <<'EOF', but not a <<<
+// here-string, and captures whether it strips leading tabs and its delimiter word.
+var heredocStart = regexp.MustCompile(`(?:^|[^<])<<(-?)\s*['"]?([A-Za-z_][A-Za-z0-9_]*)['"]?`)
+
+// ReadCommandFile reads a file of commands for a batch shell session. Each line is a command,
+// except that blank lines and lines starting with # are skipped, and a line with a here-document
+// (cmd <<EOF) runs with the following lines up to its delimiter as a single command, which is how
+// a command that reads stdin is given its input.
+func ReadCommandFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var commands []string
+	var block []string
+	var delimiter string
+	stripTabs := false
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if delimiter != "" {
+			block = append(block, line)
+			end := line
+			if stripTabs {
+				end = strings.TrimLeft(line, "\t")
+			}
+			if end == delimiter {
+				commands = append(commands, strings.Join(block, "\n"))
+				block, delimiter = nil, ""
+			}
+			continue
+		}
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if match := heredocStart.FindStringSubmatch(line); match != nil {
+			block, delimiter, stripTabs = []string{line}, match[2], match[1] == "-"
+			continue
+		}
+		commands = append(commands, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if delimiter != "" {
+		return nil, fmt.Errorf("%s: here-document starting %q is not closed by a %s line", path, block[0], delimiter)
+	}
+	if len(commands) == 0 {
+		return nil, fmt.Errorf("%s: no commands", path)
+	}
+	return commands, nil
+}
+
+// commandResult is one batch command's output and exit status.
+type commandResult struct {
+	output []byte
+	status int
+}
+
+// commandRunner types a batch of commands into the remote shell one at a time. After each command
+// it has the shell print a sentinel with the exit status, which is how it tells the command has
+// finished. The sentinels carry a random token and are printed from pieces, so neither a command's
+// own output nor the shell echoing what was typed can match them.
+type commandRunner struct {
+	commands []string
+	token    string
+	done     *regexp.Regexp
+
+	mutex   sync.Mutex
+	ready   bool
+	output  bytes.Buffer
+	started chan struct{}
+	results chan commandResult
+}
+
+func newCommandRunner(commands []string) (*commandRunner, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(random)
+	return &commandRunner{
+		commands: commands,
+		token:    token,
+		done:     regexp.MustCompile(`SMPDONE_` + token + ` (\d+)\r?\n`),
+		started:  make(chan struct{}),
+		results:  make(chan commandResult, len(commands)),
+	}, nil
+}
+
+// setupLine turns off echo and prompts, so captured output is the commands' own, then prints the
+// ready sentinel. The leading space keeps it out of history in shells that ignore such lines.
+func (r *commandRunner) setupLine() []byte {
+	return []byte(fmt.Sprintf(" stty -echo; PS1=; PS2=; printf '%%s_%%s\\n' SMPREADY %s\n", r.token))
+}
+
+// commandLine is command followed by the line printing its completion sentinel.
+func (r *commandRunner) commandLine(command string) []byte {
+	return []byte(fmt.Sprintf("%s\n printf '%%s_%%s %%d\\n' SMPDONE %s \"$?\"\n", command, r.token))
+}
+
+// receive takes shell output, dropping everything up to the ready sentinel and then collecting
+// output until each completion sentinel.
+func (r *commandRunner) receive(payload []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.output.Write(payload)
+	if !r.ready {
+		marker := []byte("SMPREADY_" + r.token)
+		index := bytes.Index(r.output.Bytes(), marker)
+		end := bytes.IndexByte(r.output.Bytes()[max(index, 0):], '\n')
+		if index < 0 || end < 0 {
+			return
+		}
+		r.output.Next(index + end + 1)
+		r.ready = true
+		close(r.started)
+	}
+	for {
+		match := r.done.FindSubmatchIndex(r.output.Bytes())
+		if match == nil {
+			return
+		}
+		held := r.output.Bytes()
+		status, _ := strconv.Atoi(string(held[match[2]:match[3]]))
+		r.results <- commandResult{output: bytes.Clone(held[:match[0]]), status: status}
+		r.output.Next(match[1])
+	}
+}
+
+// runCommands runs the session's command file in place of keyboard input, showing each command's
+// output between markers, then exits the shell.
+func (s *ShellSession) runCommands(log log.T) error {
+	r := s.commands
+	ended := s.sessionEnded()
+	if err := s.DataChannel.SendInputDataMessage(log, message.Output, r.setupLine()); err != nil {
+		return err
+	}
+	select {
+	case <-r.started:
+	case <-ended:
+		return nil
+	}
+
+	failed := 0
+	total := len(r.commands)
+	for i, command := range r.commands {
+		title, _, multiline := strings.Cut(command, "\n")
+		if multiline {
+			title += " ..."
+		}
+		s.display(log, message.ClientMessage{Payload: []byte(fmt.Sprintf("==> [%d/%d] %s\r\n", i+1, total, title))})
+		if err := s.DataChannel.SendInputDataMessage(log, message.Output, r.commandLine(command)); err != nil {
+			return err
+		}
+
+		var result commandResult
+		select {
+		case result = <-r.results:
+		case <-ended:
+			return nil
+		}
+		if len(result.output) > 0 {
+			s.display(log, message.ClientMessage{Payload: result.output})
+		}
+		if result.status != 0 {
+			failed++
+		}
+		s.display(log, message.ClientMessage{Payload: []byte(fmt.Sprintf("<== [%d/%d] exit status %d\r\n", i+1, total, result.status))})
+	}
+	s.display(log, message.ClientMessage{Payload: []byte(fmt.Sprintf("Ran %d commands: %d succeeded, %d failed\r\n", total, total-failed, failed))})
+
+	if err := s.DataChannel.SendInputDataMessage(log, message.Output, []byte(" exit\n")); err != nil {
+		return err
+	}
+	<-ended
+	return nil
+}
+
+// sessionEnded returns a channel closed once the data channel reports the session has ended.
+func (s *ShellSession) sessionEnded() <-chan struct{} {
+	ended := make(chan struct{})
+	go func() {
+		for !s.DataChannel.IsSessionEnded() {
+			time.Sleep(time.Second)
+		}
+		close(ended)
+	}()
+	return ended
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shellsession starts shell session.
+package shellsession
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCommandFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "commands.sh")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+// WHEN a command file has comments, blank lines, a here-string and here-documents, THEN
+// ReadCommandFile SHALL skip the comments and group each here-document with its input.
+func TestReadCommandFile(t *testing.T) {
+	path := writeCommandFile(t, "# setup\nuptime\n\n  # indented comment\ncat <<<hello\n"+
+		"cat > /tmp/x <<'EOF'\nline one\nEOF\nsh <<-END\n\techo hi\n\tEND\r\nwhoami\n")
+	commands, err := ReadCommandFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"uptime",
+		"cat <<<hello",
+		"cat > /tmp/x <<'EOF'\nline one\nEOF",
+		"sh <<-END\n\techo hi\n\tEND",
+		"whoami",
+	}, commands)
+}
+
+// WHEN a command file is missing, empty or has an unclosed here-document, THEN ReadCommandFile
+// SHALL fail.
+func TestReadCommandFileErrors(t *testing.T) {
+	_, err := ReadCommandFile(filepath.Join(t.TempDir(), "missing.sh"))
+	assert.Error(t, err)
+
+	_, err = ReadCommandFile(writeCommandFile(t, "# nothing\n\n"))
+	assert.ErrorContains(t, err, "no commands")
+
+	_, err = ReadCommandFile(writeCommandFile(t, "cat <<EOF\nnever closed\n"))
+	assert.ErrorContains(t, err, "not closed by a EOF line")
+}
+
+// WHEN shell output arrives in pieces, THEN the runner SHALL drop everything before the ready
+// sentinel and split the rest into each command's output and exit status.
+func TestCommandRunnerReceive(t *testing.T) {
+	r, err := newCommandRunner([]string{"true", "false"})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(r.setupLine()), "SMPREADY_"+r.token)
+	assert.NotContains(t, string(r.commandLine("true")), "SMPDONE_"+r.token)
+
+	r.receive([]byte("Last login: today\r\n$ SMPREADY_" + r.token))
+	select {
+	case <-r.started:
+		t.Fatal("Expected the runner to wait for the end of the ready line")
+	default:
+	}
+	r.receive([]byte("\r\nhello\r\nSMPDONE_" + r.token + " 0\r\nSMPDONE_"))
+	<-r.started
+	assert.Equal(t, commandResult{output: []byte("hello\r\n"), status: 0}, <-r.results)
+
+	r.receive([]byte(r.token + " 1\r\n"))
+	assert.Equal(t, commandResult{output: []byte{}, status: 1}, <-r.results)
+}
@@ -0,0 +1,57 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shellsession starts shell session.
+package shellsession
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/log"
+	"github.com/zph/session-manager-plugin/src/message"
+)
+
+// warnOnDuration shows a reminder banner each time another ShellDurationWarning passes while the
+// session is open. It only warns; the session keeps running.
+func (s *ShellSession) warnOnDuration(log log.T) {
+	if s.ShellDurationWarning <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(s.ShellDurationWarning)
+		defer ticker.Stop()
+		var elapsed time.Duration
+		for range ticker.C {
+			if s.DataChannel.IsSessionEnded() {
+				return
+			}
+			elapsed += s.ShellDurationWarning
+			displayMessageCall(&s.DisplayMode, log, message.ClientMessage{Payload: durationWarning(elapsed)})
+		}
+	}()
+}
+
+// durationWarning is the banner shown once the session has been open for elapsed. It starts and
+// ends with a line break so it stands apart from output the shell is in the middle of writing.
+func durationWarning(elapsed time.Duration) []byte {
+	age := elapsed.Round(time.Second).String()
+	if strings.HasSuffix(age, "m0s") {
+		age = strings.TrimSuffix(age, "0s")
+	}
+	if strings.HasSuffix(age, "h0m") {
+		age = strings.TrimSuffix(age, "0m")
+	}
+	return []byte(fmt.Sprintf("\r\n*** This session has been open for %s. Consider ending it when you are done. ***\r\n", age))
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shellsession starts shell session.
+package shellsession
+
+import (
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	dataChannelMock "github.com/zph/session-manager-plugin/src/datachannel/mocks"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+// WHEN ShellDurationWarning is set, THEN a reminder SHALL be displayed each time that much more time
+// passes, and none SHALL be displayed once the session has ended.
+func TestWarnOnDuration(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		displayed := captureDisplay(t)
+		var ended atomic.Bool
+		dataChannel := &dataChannelMock.IDataChannel{}
+		dataChannel.On("IsSessionEnded").Return(func() bool { return ended.Load() })
+
+		shellSession := &ShellSession{Session: session.Session{DataChannel: dataChannel, ShellDurationWarning: time.Hour}}
+		shellSession.warnOnDuration(logger)
+
+		time.Sleep(time.Hour - time.Second)
+		synctest.Wait()
+		assert.Empty(t, displayed.lines())
+
+		time.Sleep(time.Hour + time.Second)
+		synctest.Wait()
+		assert.Equal(t, []string{string(durationWarning(time.Hour)), string(durationWarning(2 * time.Hour))}, displayed.lines())
+
+		ended.Store(true)
+		time.Sleep(time.Hour)
+		synctest.Wait()
+		assert.Len(t, displayed.lines(), 2)
+	})
+}
+
+// WHEN the warning is built, THEN the session age SHALL be shown without zero units.
+func TestDurationWarningFormatsAge(t *testing.T) {
+	for elapsed, age := range map[time.Duration]string{
+		time.Hour:        "1h.",
+		90 * time.Minute: "1h30m.",
+		2 * time.Minute:  "2m.",
+		45 * time.Second: "45s.",
+	} {
+		assert.Contains(t, string(durationWarning(elapsed)), "open for "+age)
+	}
+}
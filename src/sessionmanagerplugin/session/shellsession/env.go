@@ -0,0 +1,70 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shellsession starts shell session.
+package shellsession
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/zph/session-manager-plugin/src/log"
+	"github.com/zph/session-manager-plugin/src/message"
+)
+
+// envName matches a POSIX shell variable name.
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ParseEnv checks that every entry is KEY=VALUE with a shell variable name as KEY and a value
+// that fits on one command line.
+func ParseEnv(entries []string) error {
+	for _, entry := range entries {
+		name, value, found := strings.Cut(entry, "=")
+		if !found {
+			return fmt.Errorf("invalid env entry %q: expected KEY=VALUE", entry)
+		}
+		if !envName.MatchString(name) {
+			return fmt.Errorf("invalid env entry %q: %q is not a valid variable name", entry, name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("invalid env entry %q: value must not contain a line break", entry)
+		}
+	}
+	return nil
+}
+
+// exportCommand returns the shell line that exports entries, which ParseEnv has accepted. Values
+// are single-quoted so the shell expands nothing, and the leading space keeps the line out of
+// history in shells that ignore space-prefixed commands.
+func exportCommand(entries []string) []byte {
+	var line bytes.Buffer
+	line.WriteString(" export")
+	for _, entry := range entries {
+		name, value, _ := strings.Cut(entry, "=")
+		fmt.Fprintf(&line, " %s='%s'", name, strings.ReplaceAll(value, "'", `'\''`))
+	}
+	line.WriteString("\n")
+	return line.Bytes()
+}
+
+// sendEnv types the staged export command into the remote shell, before any keyboard input.
+func (s *ShellSession) sendEnv(log log.T) {
+	if len(s.envCommand) == 0 {
+		return
+	}
+	if err := s.DataChannel.SendInputDataMessage(log, message.Output, s.envCommand); err != nil {
+		log.Errorf("Failed to send environment variables: %v", err)
+	}
+}
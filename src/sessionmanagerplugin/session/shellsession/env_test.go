@@ -0,0 +1,66 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shellsession starts shell session.
+package shellsession
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zph/session-manager-plugin/src/communicator/mocks"
+	dataChannelMock "github.com/zph/session-manager-plugin/src/datachannel/mocks"
+	"github.com/zph/session-manager-plugin/src/message"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+func TestParseEnv(t *testing.T) {
+	assert.NoError(t, ParseEnv([]string{"TERM=xterm-256color", "LANG=C.UTF-8", "_EMPTY=", "GREETING=a=b c"}))
+
+	for _, entry := range []string{"TERM", "=value", "1ST=x", "APP-NAME=x", "MULTI=a\nb"} {
+		assert.Error(t, ParseEnv([]string{entry}), entry)
+	}
+}
+
+// WHEN values hold shell syntax, THEN exportCommand SHALL single-quote them so nothing is expanded.
+func TestExportCommand(t *testing.T) {
+	command := exportCommand([]string{"TERM=xterm-256color", "GREETING=it's $HOME"})
+	assert.Equal(t, ` export TERM='xterm-256color' GREETING='it'\''s $HOME'`+"\n", string(command))
+}
+
+// WHEN ShellEnv is set, THEN Initialize SHALL stage the export command and sendEnv SHALL send it
+// as shell input; WHEN it is invalid, THEN nothing SHALL be sent.
+func TestShellEnvSentAtStart(t *testing.T) {
+	newEnvSession := func(env []string) (*ShellSession, *dataChannelMock.IDataChannel) {
+		dataChannel := &dataChannelMock.IDataChannel{}
+		wsChannel := &mocks.IWebSocketChannel{}
+		dataChannel.On("RegisterOutputStreamHandler", mock.Anything, true)
+		dataChannel.On("GetWsChannel").Return(wsChannel)
+		wsChannel.On("SetOnMessage", mock.Anything)
+
+		shellSession := &ShellSession{}
+		shellSession.Initialize(logger, &session.Session{DataChannel: dataChannel, ShellEnv: env})
+		return shellSession, dataChannel
+	}
+
+	shellSession, dataChannel := newEnvSession([]string{"LANG=C.UTF-8"})
+	dataChannel.On("SendInputDataMessage", mock.Anything, message.Output, mock.Anything).Return(nil)
+	shellSession.sendEnv(logger)
+	dataChannel.AssertCalled(t, "SendInputDataMessage", mock.Anything, message.Output, []byte(" export LANG='C.UTF-8'\n"))
+
+	shellSession, dataChannel = newEnvSession([]string{"LANG"})
+	assert.Empty(t, shellSession.envCommand)
+	shellSession.sendEnv(logger)
+	dataChannel.AssertNotCalled(t, "SendInputDataMessage", mock.Anything, mock.Anything, mock.Anything)
+}
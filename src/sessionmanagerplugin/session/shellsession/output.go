@@ -0,0 +1,81 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shellsession starts shell session.
+package shellsession
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+// OutputMode selects when shell output is handed to the terminal.
+type OutputMode string
+
+const (
+	// OutputUnbuffered displays output as soon as it arrives, so prompts and progress bars that
+	// don't end in a newline show up immediately. It is the default.
+	OutputUnbuffered OutputMode = "unbuffered"
+	// OutputLineBuffered holds a partial line until its newline arrives.
+	OutputLineBuffered OutputMode = "line"
+)
+
+// maxPendingLine caps how much of an unterminated line is held before it is displayed anyway.
+const maxPendingLine = 4096
+
+// ParseOutputMode validates mode; an empty mode means OutputUnbuffered.
+func ParseOutputMode(mode string) (OutputMode, error) {
+	switch OutputMode(mode) {
+	case "", OutputUnbuffered:
+		return OutputUnbuffered, nil
+	case OutputLineBuffered:
+		return OutputLineBuffered, nil
+	default:
+		return "", fmt.Errorf("invalid output mode: %s (expected %s or %s)", mode, OutputUnbuffered, OutputLineBuffered)
+	}
+}
+
+// lineBuffer holds the unterminated tail of line-buffered output.
+type lineBuffer struct {
+	mu      sync.Mutex
+	pending []byte
+}
+
+// complete adds p to the buffer and returns the output that is ready to display: everything
+// up to the last newline, or all of it once the partial line grows past maxPendingLine.
+func (b *lineBuffer) complete(p []byte) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = append(b.pending, p...)
+
+	n := bytes.LastIndexByte(b.pending, '\n') + 1
+	if len(b.pending)-n > maxPendingLine {
+		n = len(b.pending)
+	}
+	if n == 0 {
+		return nil
+	}
+	ready := b.pending[:n:n]
+	b.pending = append([]byte(nil), b.pending[n:]...)
+	return ready
+}
+
+// flush returns and clears any held partial line.
+func (b *lineBuffer) flush() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	pending := b.pending
+	b.pending = nil
+	return pending
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shellsession starts shell session.
+package shellsession
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zph/session-manager-plugin/src/communicator/mocks"
+	dataChannelMock "github.com/zph/session-manager-plugin/src/datachannel/mocks"
+	"github.com/zph/session-manager-plugin/src/log"
+	"github.com/zph/session-manager-plugin/src/message"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session/sessionutil"
+)
+
+// displayLog holds what was handed to the terminal. Output may be displayed from a session
+// goroutine while the test reads it, so access is guarded.
+type displayLog struct {
+	mutex     sync.Mutex
+	displayed []string
+}
+
+// lines returns a copy of the output displayed so far.
+func (d *displayLog) lines() []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]string(nil), d.displayed...)
+}
+
+// captureDisplay records what ProcessStreamMessagePayload hands to the terminal.
+func captureDisplay(t *testing.T) *displayLog {
+	displayed := &displayLog{}
+	original := displayMessageCall
+	displayMessageCall = func(d *sessionutil.DisplayMode, log log.T, outputMessage message.ClientMessage) {
+		displayed.mutex.Lock()
+		defer displayed.mutex.Unlock()
+		displayed.displayed = append(displayed.displayed, string(outputMessage.Payload))
+	}
+	t.Cleanup(func() { displayMessageCall = original })
+	return displayed
+}
+
+// newOutputSession initializes a shell session with the given output mode.
+func newOutputSession(mode string) *ShellSession {
+	dataChannel := &dataChannelMock.IDataChannel{}
+	wsChannel := &mocks.IWebSocketChannel{}
+	dataChannel.On("RegisterOutputStreamHandler", mock.Anything, true)
+	dataChannel.On("GetWsChannel").Return(wsChannel)
+	wsChannel.On("SetOnMessage", mock.Anything)
+
+	shellSession := &ShellSession{}
+	shellSession.Initialize(logger, &session.Session{DataChannel: dataChannel, ShellOutputMode: mode})
+	return shellSession
+}
+
+// WHEN output arrives without a trailing newline in the default mode, THEN it SHALL be
+// displayed immediately, so prompts and progress bars don't appear to stall.
+func TestProcessStreamMessagePayloadShowsPartialLineImmediately(t *testing.T) {
+	displayed := captureDisplay(t)
+	shellSession := newOutputSession("")
+
+	for _, chunk := range []string{"Downloading... 45%", "\rDownloading... 90%", "\nPassword: "} {
+		shellSession.ProcessStreamMessagePayload(logger, message.ClientMessage{Payload: []byte(chunk)})
+	}
+
+	assert.Equal(t, OutputUnbuffered, shellSession.OutputMode)
+	assert.Equal(t, []string{"Downloading... 45%", "\rDownloading... 90%", "\nPassword: "}, displayed.lines())
+}
+
+// WHEN the output mode is line, THEN a partial line SHALL be held until its newline arrives,
+// unless it grows past maxPendingLine.
+func TestProcessStreamMessagePayloadLineBuffered(t *testing.T) {
+	displayed := captureDisplay(t)
+	shellSession := newOutputSession(string(OutputLineBuffered))
+
+	shellSession.ProcessStreamMessagePayload(logger, message.ClientMessage{Payload: []byte("total 8\ndrwx")})
+	shellSession.ProcessStreamMessagePayload(logger, message.ClientMessage{Payload: []byte("r-xr-x 2 root")})
+	assert.Equal(t, []string{"total 8\n"}, displayed.lines())
+
+	shellSession.ProcessStreamMessagePayload(logger, message.ClientMessage{Payload: []byte(" root 4096 .\n$ ")})
+	assert.Equal(t, []string{"total 8\n", "drwxr-xr-x 2 root root 4096 .\n"}, displayed.lines())
+
+	long := strings.Repeat("x", maxPendingLine)
+	shellSession.ProcessStreamMessagePayload(logger, message.ClientMessage{Payload: []byte(long)})
+	assert.Equal(t, "$ "+long, displayed.lines()[2])
+	assert.Empty(t, shellSession.lines.flush())
+}
+
+// WHEN an OutputFilter is set, THEN displayed output SHALL be what it returns, complete lines
+// SHALL reach it in line mode, and output it drops entirely SHALL not be displayed.
+func TestProcessStreamMessagePayloadOutputFilter(t *testing.T) {
+	displayed := captureDisplay(t)
+	shellSession := newOutputSession(string(OutputLineBuffered))
+	secret := regexp.MustCompile(`AKIA[0-9A-Z]{16}`)
+	shellSession.OutputFilter = func(p []byte) []byte {
+		if bytes.HasPrefix(p, []byte("#debug")) {
+			return nil
+		}
+		return secret.ReplaceAll(p, []byte("****"))
+	}
+
+	shellSession.ProcessStreamMessagePayload(logger, message.ClientMessage{Payload: []byte("key=AKIAIOSFOD")})
+	shellSession.ProcessStreamMessagePayload(logger, message.ClientMessage{Payload: []byte("NN7EXAMPLE\n#debu")})
+	shellSession.ProcessStreamMessagePayload(logger, message.ClientMessage{Payload: []byte("g on\n")})
+
+	assert.Equal(t, []string{"key=****\n"}, displayed.lines())
+}
+
+func TestParseOutputMode(t *testing.T) {
+	mode, err := ParseOutputMode("")
+	assert.Nil(t, err)
+	assert.Equal(t, OutputUnbuffered, mode)
+
+	mode, err = ParseOutputMode("line")
+	assert.Nil(t, err)
+	assert.Equal(t, OutputLineBuffered, mode)
+
+	_, err = ParseOutputMode("block")
+	assert.NotNil(t, err)
+}
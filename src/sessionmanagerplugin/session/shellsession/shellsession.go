@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"os"
 	"os/signal"
+	"slices"
 	"time"
 
 	"github.com/zph/session-manager-plugin/src/config"
@@ -30,8 +31,12 @@ import (
 )
 
 const (
-	ResizeSleepInterval = time.Millisecond * 500
-	StdinBufferLimit    = 1024
+	// ResizeSleepInterval is how often the terminal size is checked
+	ResizeSleepInterval = time.Millisecond * 100
+	// ResizeDebounceInterval is how long a new size must hold before it is sent, so a burst of
+	// resizes (dragging a window) sends only the final size
+	ResizeDebounceInterval = time.Millisecond * 250
+	StdinBufferLimit       = 1024
 )
 
 type ShellSession struct {
@@ -40,12 +45,33 @@ type ShellSession struct {
 	// SizeData is used to store size data at session level to compare with new size.
 	SizeData          message.SizeData
 	originalSttyState bytes.Buffer
+	// transcript copies output to the session's Transcript writer, when one is set
+	transcript *transcript
+	// OutputMode selects whether output is displayed as it arrives or a line at a time
+	OutputMode OutputMode
+	// lines holds partial lines when OutputMode is OutputLineBuffered
+	lines *lineBuffer
+	// banner drops output before the first prompt when ShellStripBanner is set
+	banner *bannerFilter
+	// envCommand exports ShellEnv in the remote shell once the session handlers start
+	envCommand []byte
+	// commands runs ShellCommandFile in place of keyboard input, when one is set
+	commands *commandRunner
+	// OutputFilter, if set, transforms output just before it is displayed, e.g. to mask secrets.
+	// It sees complete lines in OutputLineBuffered mode and arbitrary chunks otherwise; the
+	// transcript records output unfiltered.
+	OutputFilter func([]byte) []byte
 }
 
 var GetTerminalSizeCall = func(fd int) (width int, height int, err error) {
 	return terminal.GetSize(fd)
 }
 
+// displayMessageCall hands output to the terminal; tests replace it to observe what is displayed.
+var displayMessageCall = func(d *sessionutil.DisplayMode, log log.T, outputMessage message.ClientMessage) {
+	d.DisplayMessage(log, outputMessage)
+}
+
 func init() {
 	session.Register(&ShellSession{}, func() session.ISessionPlugin { return &ShellSession{} })
 }
@@ -57,6 +83,43 @@ func (ShellSession) Name() string {
 
 func (s *ShellSession) Initialize(log log.T, sessionVar *session.Session) {
 	s.Session = *sessionVar
+	if s.Transcript != nil {
+		s.transcript = newTranscript(log, s.Transcript)
+	}
+	// The mode was validated by the caller; anything unrecognised displays output as it arrives
+	s.OutputMode, _ = ParseOutputMode(s.ShellOutputMode)
+	if s.OutputMode == OutputLineBuffered {
+		s.lines = &lineBuffer{}
+	}
+	if s.ShellStripBanner {
+		// The pattern was validated by the caller; an invalid one leaves the banner in place
+		if prompt, err := ParsePromptPattern(s.ShellPromptPattern); err == nil {
+			s.banner = &bannerFilter{prompt: prompt}
+		} else {
+			log.Warnf("Not stripping banner: %v", err)
+		}
+	}
+	env := s.ShellEnv
+	if s.ShellTerm != "" {
+		// Exported last, so it wins over a TERM entry in ShellEnv
+		env = append(slices.Clone(env), "TERM="+s.ShellTerm)
+	}
+	if len(env) > 0 {
+		// The entries were validated by the caller; invalid ones leave the environment as is
+		if err := ParseEnv(env); err == nil {
+			s.envCommand = exportCommand(env)
+		} else {
+			log.Warnf("Not setting environment variables: %v", err)
+		}
+	}
+	if s.ShellCommandFile != "" {
+		// The file was validated by the caller; if it can no longer be read the session is interactive
+		if commands, err := ReadCommandFile(s.ShellCommandFile); err != nil {
+			log.Warnf("Not running command file: %v", err)
+		} else if s.commands, err = newCommandRunner(commands); err != nil {
+			log.Warnf("Not running command file: %v", err)
+		}
+	}
 	s.DataChannel.RegisterOutputStreamHandler(s.ProcessStreamMessagePayload, true)
 	s.DataChannel.GetWsChannel().SetOnMessage(
 		func(input []byte) {
@@ -67,18 +130,45 @@ func (s *ShellSession) Initialize(log log.T, sessionVar *session.Session) {
 // StartSession takes input and write it to data channel
 func (s *ShellSession) SetSessionHandlers(log log.T) (err error) {
 
+	// set the requested environment before the user types anything
+	s.sendEnv(log)
+
+	// remind the user if the session stays open a long time
+	s.warnOnDuration(log)
+
 	// handle re-size
 	s.handleTerminalResize(log)
 
 	// handle control signals
 	s.handleControlSignals(log)
 
-	//handles keyboard input
-	err = s.handleKeyboardInput(log)
+	if s.commands != nil {
+		// the command file stands in for the keyboard
+		err = s.runCommands(log)
+	} else {
+		//handles keyboard input
+		err = s.handleKeyboardInput(log)
+	}
 
+	// show whatever was still held when the session ended
+	if pending := s.heldOutput(); len(pending) > 0 {
+		s.display(log, message.ClientMessage{Payload: pending})
+	}
 	return
 }
 
+// heldOutput returns and clears output not yet displayed: output that never reached a prompt,
+// then any partial line.
+func (s *ShellSession) heldOutput() (pending []byte) {
+	if s.banner != nil {
+		pending = s.banner.flush()
+	}
+	if s.lines != nil {
+		pending = append(s.lines.complete(pending), s.lines.flush()...)
+	}
+	return pending
+}
+
 // handleControlSignals handles control signals when given by user
 func (s *ShellSession) handleControlSignals(log log.T) {
 	go func() {
@@ -95,13 +185,18 @@ func (s *ShellSession) handleControlSignals(log log.T) {
 	}()
 }
 
-// handleTerminalResize checks size of terminal every 500ms and sends size data.
+// handleTerminalResize checks size of terminal every ResizeSleepInterval and sends size data
+// once a changed size has held for ResizeDebounceInterval.
 func (s *ShellSession) handleTerminalResize(log log.T) {
 	var (
 		width         int
 		height        int
 		inputSizeData []byte
 		err           error
+		pending       message.SizeData
+		changedAt     time.Time
+		sizeErrLogged bool
+		initial       = initialSize{size: s.ShellInitialSize}
 	)
 	go func() {
 		for {
@@ -109,17 +204,28 @@ func (s *ShellSession) handleTerminalResize(log log.T) {
 			if width, height, err = GetTerminalSizeCall(int(os.Stdout.Fd())); err != nil {
 				width = 300
 				height = 100
-				log.Errorf("Could not get size of the terminal: %s, using width %d height %d", err, width, height)
+				if initial.size != (message.SizeData{}) {
+					// Expected when running headless, which is what the initial size is for
+					width, height = int(initial.size.Cols), int(initial.size.Rows)
+				} else if !sizeErrLogged {
+					log.Errorf("Could not get size of the terminal: %s, using width %d height %d", err, width, height)
+					sizeErrLogged = true
+				}
 			}
 
-			if s.SizeData.Rows != uint32(height) || s.SizeData.Cols != uint32(width) {
-				sizeData := message.SizeData{
-					Cols: uint32(width),
-					Rows: uint32(height),
-				}
-				s.SizeData = sizeData
+			current := initial.apply(message.SizeData{
+				Cols: uint32(width),
+				Rows: uint32(height),
+			})
+			if current != pending {
+				pending = current
+				changedAt = time.Now()
+			}
 
-				if inputSizeData, err = json.Marshal(sizeData); err != nil {
+			if pending != s.SizeData && time.Since(changedAt) >= ResizeDebounceInterval {
+				s.SizeData = pending
+
+				if inputSizeData, err = json.Marshal(pending); err != nil {
 					log.Errorf("Cannot marshall size data: %v", err)
 				}
 				log.Debugf("Sending input size data: %s", inputSizeData)
@@ -127,7 +233,6 @@ func (s *ShellSession) handleTerminalResize(log log.T) {
 					log.Errorf("Failed to Send size data: %v", err)
 				}
 			}
-			// repeating this loop for every 500ms
 			time.Sleep(ResizeSleepInterval)
 		}
 	}()
@@ -135,6 +240,32 @@ func (s *ShellSession) handleTerminalResize(log log.T) {
 
 // ProcessStreamMessagePayload prints payload received on datachannel to console
 func (s ShellSession) ProcessStreamMessagePayload(log log.T, outputMessage message.ClientMessage) (isHandlerReady bool, err error) {
-	s.DisplayMode.DisplayMessage(log, outputMessage)
+	s.transcript.write(outputMessage.Payload)
+	if s.commands != nil {
+		// Shown a command at a time by runCommands
+		s.commands.receive(outputMessage.Payload)
+		return true, nil
+	}
+	if s.banner != nil {
+		if outputMessage.Payload = s.banner.filter(outputMessage.Payload); len(outputMessage.Payload) == 0 {
+			return true, nil
+		}
+	}
+	if s.lines != nil {
+		if outputMessage.Payload = s.lines.complete(outputMessage.Payload); len(outputMessage.Payload) == 0 {
+			return true, nil
+		}
+	}
+	s.display(log, outputMessage)
 	return true, nil
 }
+
+// display shows outputMessage on the terminal after applying OutputFilter.
+func (s *ShellSession) display(log log.T, outputMessage message.ClientMessage) {
+	if s.OutputFilter != nil {
+		if outputMessage.Payload = s.OutputFilter(outputMessage.Payload); len(outputMessage.Payload) == 0 {
+			return
+		}
+	}
+	displayMessageCall(&s.DisplayMode, log, outputMessage)
+}
@@ -19,8 +19,13 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
+	"testing/synctest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -129,3 +134,66 @@ func getDataChannel() *datachannel.DataChannel {
 	dataChannel.SetWsChannel(mockWsChannel)
 	return dataChannel
 }
+
+// WHEN the terminal is resized ten times in quick succession, THEN handleTerminalResize SHALL
+// send a single Size message with the final size once the debounce interval has passed.
+func TestTerminalResizeDebouncesResizeStorm(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		origGetTerminalSize := GetTerminalSizeCall
+		defer func() { GetTerminalSizeCall = origGetTerminalSize }()
+
+		var (
+			calls  atomic.Bool
+			stop   atomic.Bool
+			exited = make(chan struct{})
+		)
+		width := 80
+		GetTerminalSizeCall = func(fd int) (int, int, error) {
+			if stop.Load() {
+				// end the resize loop so the bubble can finish
+				close(exited)
+				runtime.Goexit()
+			}
+			calls.Store(true)
+			if width < 90 {
+				width++ // one resize per poll, as while dragging a window
+			}
+			return width, 24, nil
+		}
+
+		var (
+			mutex sync.Mutex
+			sent  []message.SizeData
+		)
+		sentSizes := func() []message.SizeData {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return append([]message.SizeData(nil), sent...)
+		}
+		dataChannel := &dataChannelMock.IDataChannel{}
+		dataChannel.On("SendInputDataMessage", mock.Anything, message.Size, mock.Anything).
+			Run(func(args mock.Arguments) {
+				var size message.SizeData
+				json.Unmarshal(args.Get(2).([]byte), &size)
+				mutex.Lock()
+				sent = append(sent, size)
+				mutex.Unlock()
+			}).Return(nil)
+
+		shellSession := ShellSession{Session: session.Session{DataChannel: dataChannel}}
+		shellSession.handleTerminalResize(logger)
+
+		// ten polls of changing size, then a quiet period just short of the debounce interval
+		time.Sleep(9*ResizeSleepInterval + ResizeDebounceInterval - ResizeSleepInterval/2)
+		synctest.Wait()
+		assert.True(t, calls.Load())
+		assert.Empty(t, sentSizes(), "no size should be sent while resizing")
+
+		time.Sleep(ResizeDebounceInterval)
+		synctest.Wait()
+		assert.Equal(t, []message.SizeData{{Cols: 90, Rows: 24}}, sentSizes())
+
+		stop.Store(true)
+		<-exited
+	})
+}
@@ -55,6 +55,7 @@ func setState(state *bytes.Buffer) error {
 func (s *ShellSession) Stop() {
 	setState(&s.originalSttyState)
 	setState(bytes.NewBufferString("echo")) // for linux and ubuntu
+	s.transcript.close()
 }
 
 // handleKeyboardInput handles input entered by customer on terminal
@@ -55,6 +55,7 @@ var specialKeysInputMap = map[keyboard.Key][]byte{
 // stop restores the terminal settings and exits
 func (s *ShellSession) Stop() {
 	keyboard.Close()
+	s.transcript.close()
 }
 
 // handleKeyboardInput handles input entered by customer on terminal
@@ -0,0 +1,76 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shellsession starts shell session.
+package shellsession
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/zph/session-manager-plugin/src/message"
+)
+
+// termName matches a terminfo name such as xterm-256color or screen.xterm-256color.
+var termName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]*$`)
+
+// maxTerminalDimension is the largest width or height a terminal's window size can hold.
+const maxTerminalDimension = 65535
+
+// ParseTerm checks that term is a terminfo name that can be exported as TERM.
+func ParseTerm(term string) error {
+	if !termName.MatchString(term) {
+		return fmt.Errorf("invalid terminal type %q: expected a name such as xterm-256color", term)
+	}
+	return nil
+}
+
+// ParseSize parses a terminal size given as COLSxROWS, e.g. 200x50.
+func ParseSize(size string) (message.SizeData, error) {
+	cols, rows, found := strings.Cut(strings.ToLower(size), "x")
+	if found {
+		width, colsErr := strconv.ParseUint(cols, 10, 32)
+		height, rowsErr := strconv.ParseUint(rows, 10, 32)
+		if colsErr == nil && rowsErr == nil && width > 0 && height > 0 && width <= maxTerminalDimension && height <= maxTerminalDimension {
+			return message.SizeData{Cols: uint32(width), Rows: uint32(height)}, nil
+		}
+	}
+	return message.SizeData{}, fmt.Errorf("invalid terminal size %q: expected COLSxROWS such as 200x50, each between 1 and %d",
+		size, maxTerminalDimension)
+}
+
+// initialSize stands in for the local terminal's size with the session's ShellInitialSize until
+// the local terminal is resized, so a headless or mismatched terminal starts at a known geometry.
+type initialSize struct {
+	size    message.SizeData
+	local   message.SizeData
+	seen    bool
+	resized bool
+}
+
+// apply returns the size to send for the local terminal's current size.
+func (i *initialSize) apply(current message.SizeData) message.SizeData {
+	if i.size == (message.SizeData{}) || i.resized {
+		return current
+	}
+	if !i.seen {
+		i.local, i.seen = current, true
+	}
+	if current != i.local {
+		i.resized = true
+		return current
+	}
+	return i.size
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shellsession starts shell session.
+package shellsession
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zph/session-manager-plugin/src/communicator/mocks"
+	dataChannelMock "github.com/zph/session-manager-plugin/src/datachannel/mocks"
+	"github.com/zph/session-manager-plugin/src/message"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+func TestParseTerm(t *testing.T) {
+	for _, term := range []string{"xterm-256color", "screen.xterm-256color", "vt100", "rxvt-unicode+x"} {
+		assert.NoError(t, ParseTerm(term), term)
+	}
+	for _, term := range []string{"", "-xterm", "xterm 256", "xterm'", "xterm\n"} {
+		assert.Error(t, ParseTerm(term), term)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	size, err := ParseSize("200x50")
+	assert.NoError(t, err)
+	assert.Equal(t, message.SizeData{Cols: 200, Rows: 50}, size)
+	size, err = ParseSize("80X24")
+	assert.NoError(t, err)
+	assert.Equal(t, message.SizeData{Cols: 80, Rows: 24}, size)
+
+	for _, value := range []string{"", "200", "200x", "x50", "0x50", "200x0", "-1x50", "200x65536", "wide x tall"} {
+		_, err := ParseSize(value)
+		assert.Error(t, err, value)
+	}
+}
+
+// WHEN an initial size is set, THEN it SHALL be sent instead of the local terminal's size until the
+// local terminal is resized, after which the local size SHALL be followed.
+func TestInitialSizeUntilResized(t *testing.T) {
+	local := message.SizeData{Cols: 80, Rows: 24}
+	resized := message.SizeData{Cols: 120, Rows: 40}
+
+	initial := initialSize{size: message.SizeData{Cols: 200, Rows: 50}}
+	assert.Equal(t, initial.size, initial.apply(local))
+	assert.Equal(t, initial.size, initial.apply(local))
+	assert.Equal(t, resized, initial.apply(resized))
+	assert.Equal(t, local, initial.apply(local))
+
+	unset := initialSize{}
+	assert.Equal(t, local, unset.apply(local))
+}
+
+// WHEN ShellTerm is set, THEN Initialize SHALL export it as TERM after ShellEnv, so it wins.
+func TestShellTermExported(t *testing.T) {
+	dataChannel := &dataChannelMock.IDataChannel{}
+	wsChannel := &mocks.IWebSocketChannel{}
+	dataChannel.On("RegisterOutputStreamHandler", mock.Anything, true)
+	dataChannel.On("GetWsChannel").Return(wsChannel)
+	wsChannel.On("SetOnMessage", mock.Anything)
+
+	env := []string{"TERM=vt100", "LANG=C.UTF-8"}
+	shellSession := &ShellSession{}
+	shellSession.Initialize(logger, &session.Session{DataChannel: dataChannel, ShellEnv: env, ShellTerm: "xterm-256color"})
+	assert.Equal(t, " export TERM='vt100' LANG='C.UTF-8' TERM='xterm-256color'\n", string(shellSession.envCommand))
+	assert.Equal(t, []string{"TERM=vt100", "LANG=C.UTF-8"}, env)
+}
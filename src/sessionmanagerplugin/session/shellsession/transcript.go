@@ -0,0 +1,104 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shellsession starts shell session.
+package shellsession
+
+import (
+	"io"
+	"sync"
+
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// transcriptBacklog is how many output chunks may queue for the transcript before new ones are dropped.
+const transcriptBacklog = 1024
+
+// transcript copies session output to a writer from its own goroutine, so a stalled file write
+// never holds up rendering to the terminal. Output arriving while the backlog is full is dropped.
+type transcript struct {
+	log    log.T
+	w      io.WriteCloser
+	chunks chan []byte
+	done   chan struct{}
+
+	mu      sync.Mutex
+	closed  bool
+	dropped int
+}
+
+// newTranscript starts copying output written to the transcript into w.
+func newTranscript(log log.T, w io.WriteCloser) *transcript {
+	t := &transcript{
+		log:    log,
+		w:      w,
+		chunks: make(chan []byte, transcriptBacklog),
+		done:   make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+func (t *transcript) run() {
+	defer close(t.done)
+	failed := false
+	for chunk := range t.chunks {
+		if _, err := t.w.Write(chunk); err != nil && !failed {
+			t.log.Warnf("Failed to write session transcript: %v", err)
+			failed = true
+		}
+	}
+}
+
+// write queues a copy of p without blocking. A nil transcript discards it.
+func (t *transcript) write(p []byte) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+	select {
+	case t.chunks <- append([]byte(nil), p...):
+	default:
+		if t.dropped == 0 {
+			t.log.Warnf("Session transcript is falling behind, dropping output")
+		}
+		t.dropped += len(p)
+	}
+}
+
+// close flushes queued output and closes the underlying writer. It is safe to call more than once.
+func (t *transcript) close() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.closed = true
+	close(t.chunks)
+	t.mu.Unlock()
+
+	<-t.done
+	if t.dropped > 0 {
+		t.log.Warnf("Session transcript dropped %d bytes of output", t.dropped)
+	}
+	if err := t.w.Close(); err != nil {
+		t.log.Warnf("Failed to close session transcript: %v", err)
+	}
+}
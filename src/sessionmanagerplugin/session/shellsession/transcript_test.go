@@ -0,0 +1,102 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shellsession starts shell session.
+package shellsession
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zph/session-manager-plugin/src/message"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session/sessionutil"
+)
+
+// bufferCloser records writes and whether it was closed.
+type bufferCloser struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (b *bufferCloser) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *bufferCloser) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+// blockingWriter stalls every write until release is closed.
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (b *blockingWriter) Write(p []byte) (int, error) {
+	<-b.release
+	return len(p), nil
+}
+
+func (b *blockingWriter) Close() error { return nil }
+
+// WHEN a transcript is set, THEN all displayed output SHALL be written to it in order and
+// flushed when the session stops.
+func TestProcessStreamMessagePayloadWritesTranscript(t *testing.T) {
+	out := &bufferCloser{}
+	shellSession := ShellSession{}
+	shellSession.DisplayMode = sessionutil.NewDisplayMode(logger)
+	shellSession.transcript = newTranscript(logger, out)
+
+	for _, line := range []string{"$ uptime\n", " 10:00 up 3 days\n"} {
+		shellSession.ProcessStreamMessagePayload(logger, message.ClientMessage{Payload: []byte(line)})
+	}
+	shellSession.transcript.close()
+	shellSession.transcript.close()
+
+	assert.Equal(t, "$ uptime\n 10:00 up 3 days\n", out.buf.String())
+	assert.True(t, out.closed)
+}
+
+// WHEN the transcript file stalls, THEN writing output SHALL NOT block, and output beyond the
+// backlog SHALL be dropped.
+func TestTranscriptDoesNotBlockOnStalledWriter(t *testing.T) {
+	w := &blockingWriter{release: make(chan struct{})}
+	tr := newTranscript(logger, w)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < transcriptBacklog+10; i++ {
+			tr.write([]byte("x"))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("write blocked on a stalled transcript")
+	}
+
+	close(w.release)
+	tr.close()
+	assert.Greater(t, tr.dropped, 0)
+	// Writes after close are ignored rather than panicking
+	tr.write([]byte("late"))
+}
@@ -0,0 +1,29 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package session starts the session.
+package session
+
+// StreamInterceptor observes or transforms the bytes a port session exchanges with the agent, for
+// traffic shaping, logging, or fault injection in tests, without touching the forwarding itself.
+// For multiplexed sessions the bytes are smux frames carrying all local connections, so an
+// interceptor there should observe, delay or fail rather than rewrite them.
+//
+// Each method returns the bytes to pass on, which may be the input, a replacement, or empty to
+// drop them, or an error that fails the transfer as a data channel error would.
+type StreamInterceptor interface {
+	// Outbound is called with bytes read from the local side before they are sent to the agent.
+	Outbound(data []byte) ([]byte, error)
+	// Inbound is called with bytes from the agent before they are written to the local side.
+	Inbound(data []byte) ([]byte, error)
+}
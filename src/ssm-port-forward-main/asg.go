@@ -0,0 +1,79 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+var errNoHealthyInstance = errors.New("no healthy running instance")
+
+// pickInstance chooses among candidate instance IDs. Replaced in tests for determinism.
+var pickInstance = func(candidates []string) string {
+	return candidates[rand.Intn(len(candidates))]
+}
+
+// resolveASGInstance picks a healthy, running instance from the named Auto Scaling group.
+// It is called at StartSession time so rotated instances are picked up on every run,
+// and is safe to call again to re-select a target after scale-in.
+func resolveASGInstance(asgClient autoscalingiface.AutoScalingAPI, ec2Client ec2iface.EC2API, asgName string) (string, error) {
+	groups, err := asgClient.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(asgName)},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe auto scaling group %s: %w", asgName, err)
+	}
+	if len(groups.AutoScalingGroups) == 0 {
+		return "", fmt.Errorf("auto scaling group %s not found", asgName)
+	}
+
+	var inService []*string
+	for _, instance := range groups.AutoScalingGroups[0].Instances {
+		if aws.StringValue(instance.LifecycleState) == autoscaling.LifecycleStateInService &&
+			aws.StringValue(instance.HealthStatus) == "Healthy" {
+			inService = append(inService, instance.InstanceId)
+		}
+	}
+	if len(inService) == 0 {
+		return "", fmt.Errorf("%w in auto scaling group %s: no InService instances", errNoHealthyInstance, asgName)
+	}
+
+	statuses, err := ec2Client.DescribeInstanceStatus(&ec2.DescribeInstanceStatusInput{
+		InstanceIds: inService,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe instance status for auto scaling group %s: %w", asgName, err)
+	}
+
+	var running []string
+	for _, status := range statuses.InstanceStatuses {
+		if status.InstanceState != nil && aws.StringValue(status.InstanceState.Name) == ec2.InstanceStateNameRunning {
+			running = append(running, aws.StringValue(status.InstanceId))
+		}
+	}
+	if len(running) == 0 {
+		return "", fmt.Errorf("%w in auto scaling group %s: %d InService instances, none running",
+			errNoHealthyInstance, asgName, len(inService))
+	}
+
+	return pickInstance(running), nil
+}
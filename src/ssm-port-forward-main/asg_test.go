@@ -0,0 +1,121 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+type fakeASG struct {
+	autoscalingiface.AutoScalingAPI
+	instances []*autoscaling.Instance
+}
+
+func (f *fakeASG) DescribeAutoScalingGroups(*autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	if f.instances == nil {
+		return &autoscaling.DescribeAutoScalingGroupsOutput{}, nil
+	}
+	return &autoscaling.DescribeAutoScalingGroupsOutput{
+		AutoScalingGroups: []*autoscaling.Group{{Instances: f.instances}},
+	}, nil
+}
+
+type fakeEC2 struct {
+	ec2iface.EC2API
+	states    map[string]string
+	requested []string
+}
+
+func (f *fakeEC2) DescribeInstanceStatus(input *ec2.DescribeInstanceStatusInput) (*ec2.DescribeInstanceStatusOutput, error) {
+	out := &ec2.DescribeInstanceStatusOutput{}
+	for _, id := range input.InstanceIds {
+		f.requested = append(f.requested, *id)
+		if state, ok := f.states[*id]; ok {
+			out.InstanceStatuses = append(out.InstanceStatuses, &ec2.InstanceStatus{
+				InstanceId:    id,
+				InstanceState: &ec2.InstanceState{Name: aws.String(state)},
+			})
+		}
+	}
+	return out, nil
+}
+
+func asgInstance(id, lifecycle, health string) *autoscaling.Instance {
+	return &autoscaling.Instance{
+		InstanceId:     aws.String(id),
+		LifecycleState: aws.String(lifecycle),
+		HealthStatus:   aws.String(health),
+	}
+}
+
+func TestResolveASGInstancePicksHealthyRunning(t *testing.T) {
+	original := pickInstance
+	defer func() { pickInstance = original }()
+	var candidates []string
+	pickInstance = func(c []string) string {
+		candidates = c
+		return c[0]
+	}
+
+	asgClient := &fakeASG{instances: []*autoscaling.Instance{
+		asgInstance("i-healthy", "InService", "Healthy"),
+		asgInstance("i-unhealthy", "InService", "Unhealthy"),
+		asgInstance("i-pending", "Pending", "Healthy"),
+		asgInstance("i-stopping", "InService", "Healthy"),
+	}}
+	ec2Client := &fakeEC2{states: map[string]string{
+		"i-healthy":  "running",
+		"i-stopping": "stopping",
+	}}
+
+	id, err := resolveASGInstance(asgClient, ec2Client, "bastions")
+	if err != nil {
+		t.Fatalf("Expected success, got: %v", err)
+	}
+	if id != "i-healthy" {
+		t.Errorf("Expected i-healthy, got %s", id)
+	}
+	if len(candidates) != 1 {
+		t.Errorf("Expected a single running candidate, got %v", candidates)
+	}
+	if len(ec2Client.requested) != 2 {
+		t.Errorf("Expected only InService healthy instances to be described, got %v", ec2Client.requested)
+	}
+}
+
+func TestResolveASGInstanceNoneAvailable(t *testing.T) {
+	asgClient := &fakeASG{instances: []*autoscaling.Instance{
+		asgInstance("i-a", "InService", "Healthy"),
+	}}
+	ec2Client := &fakeEC2{states: map[string]string{"i-a": "stopped"}}
+
+	_, err := resolveASGInstance(asgClient, ec2Client, "bastions")
+	if !errors.Is(err, errNoHealthyInstance) {
+		t.Fatalf("Expected errNoHealthyInstance, got: %v", err)
+	}
+}
+
+func TestResolveASGInstanceGroupNotFound(t *testing.T) {
+	_, err := resolveASGInstance(&fakeASG{}, &fakeEC2{}, "missing")
+	if err == nil {
+		t.Fatal("Expected error for missing group")
+	}
+}
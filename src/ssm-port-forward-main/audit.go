@@ -0,0 +1,152 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// Audit record events written to --audit-log-group.
+const (
+	AuditSessionStart = "session_start"
+	AuditSessionStop  = "session_stop"
+)
+
+// auditRecord is one JSON log event in the audit log group.
+type auditRecord struct {
+	Event      string `json:"event"`
+	CallerARN  string `json:"caller_arn"`
+	Account    string `json:"account"`
+	Target     string `json:"target"`
+	Region     string `json:"region"`
+	Document   string `json:"document"`
+	Forwarding string `json:"forwarding"`
+	SessionID  string `json:"session_id,omitempty"`
+	ClientID   string `json:"client_id,omitempty"`
+	StartTime  string `json:"start_time,omitempty"`
+	StopTime   string `json:"stop_time,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// auditLog writes a forward's start and stop records to a log stream of its own in a CloudWatch
+// Logs group, so there is a record of who forwarded what that the local machine can't alter.
+// A nil *auditLog writes nothing.
+type auditLog struct {
+	client cloudwatchlogsiface.CloudWatchLogsAPI
+	group  string
+	stream string
+	record auditRecord
+	stop   sync.Once
+}
+
+// startAudit looks up the caller's identity and creates the forward's log stream in group.
+// record carries the target, region, document and forwarding spec.
+func startAudit(stsClient stsiface.STSAPI, logsClient cloudwatchlogsiface.CloudWatchLogsAPI, group string, record auditRecord) (*auditLog, error) {
+	identity, err := stsClient.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller identity: %w", err)
+	}
+	record.CallerARN = aws.StringValue(identity.Arn)
+	record.Account = aws.StringValue(identity.Account)
+
+	// Stream names may not contain ':' or '*'
+	stream := fmt.Sprintf("ssm-port-forward/%s/%s", record.Target, time.Now().UTC().Format("20060102T150405.000000000Z"))
+	_, err = logsClient.CreateLogStream(&cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(group),
+		LogStreamName: aws.String(stream),
+	})
+	var awsErr awserr.Error
+	if err != nil && !(errors.As(err, &awsErr) && awsErr.Code() == cloudwatchlogs.ErrCodeResourceAlreadyExistsException) {
+		return nil, fmt.Errorf("failed to create log stream in %s: %w", group, err)
+	}
+	return &auditLog{client: logsClient, group: group, stream: stream, record: record}, nil
+}
+
+// sessionStarted writes the start record for sessionID.
+func (a *auditLog) sessionStarted(sessionID, clientID string) error {
+	if a == nil {
+		return nil
+	}
+	a.record.SessionID = sessionID
+	a.record.ClientID = clientID
+	a.record.StartTime = time.Now().Format(time.RFC3339)
+	return a.put(AuditSessionStart)
+}
+
+// recordSessionStart writes the start record for sessionID. When required, a session whose start
+// can't be recorded is terminated and an error returned; otherwise the failure is only logged.
+func recordSessionStart(logger log.T, audit *auditLog, client ssmiface.SSMAPI, required bool, sessionID, clientID string) error {
+	err := audit.sessionStarted(sessionID, clientID)
+	if err == nil {
+		return nil
+	}
+	if !required {
+		logger.Warnf("Failed to write audit start record: %v", err)
+		return nil
+	}
+	if _, termErr := client.TerminateSession(&ssm.TerminateSessionInput{SessionId: aws.String(sessionID)}); termErr != nil {
+		logger.Warnf("Failed to terminate unaudited session %s: %v", sessionID, termErr)
+	}
+	return fmt.Errorf("failed to write audit start record: %w", err)
+}
+
+// sessionStopped writes the stop record with why the forward ended. Only the first call writes;
+// failures are logged, as the forward is ending anyway.
+func (a *auditLog) sessionStopped(logger log.T, reason string) {
+	if a == nil {
+		return
+	}
+	a.stop.Do(func() {
+		a.record.StopTime = time.Now().Format(time.RFC3339)
+		a.record.Reason = reason
+		if err := a.put(AuditSessionStop); err != nil {
+			logger.Warnf("Failed to write audit stop record: %v", err)
+		}
+	})
+}
+
+// put writes the record as event.
+func (a *auditLog) put(event string) error {
+	a.record.Event = event
+	data, err := json.Marshal(a.record)
+	if err != nil {
+		return err
+	}
+	_, err = a.client.PutLogEvents(&cloudwatchlogs.PutLogEventsInput{
+		LogGroupName:  aws.String(a.group),
+		LogStreamName: aws.String(a.stream),
+		LogEvents: []*cloudwatchlogs.InputLogEvent{{
+			Message:   aws.String(string(data)),
+			Timestamp: aws.Int64(time.Now().UnixMilli()),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write to %s: %w", a.group, err)
+	}
+	return nil
+}
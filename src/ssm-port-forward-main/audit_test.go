@@ -0,0 +1,124 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+type fakeSTS struct {
+	stsiface.STSAPI
+	err error
+}
+
+func (f *fakeSTS) GetCallerIdentity(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &sts.GetCallerIdentityOutput{
+		Arn:     aws.String("arn:aws:sts::123456789012:assumed-role/ops/alice"),
+		Account: aws.String("123456789012"),
+	}, nil
+}
+
+type fakeLogs struct {
+	cloudwatchlogsiface.CloudWatchLogsAPI
+	streams   []string
+	events    []auditRecord
+	createErr error
+	putErr    error
+}
+
+func (f *fakeLogs) CreateLogStream(input *cloudwatchlogs.CreateLogStreamInput) (*cloudwatchlogs.CreateLogStreamOutput, error) {
+	f.streams = append(f.streams, aws.StringValue(input.LogStreamName))
+	return &cloudwatchlogs.CreateLogStreamOutput{}, f.createErr
+}
+
+func (f *fakeLogs) PutLogEvents(input *cloudwatchlogs.PutLogEventsInput) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	if f.putErr != nil {
+		return nil, f.putErr
+	}
+	for _, event := range input.LogEvents {
+		var record auditRecord
+		if err := json.Unmarshal([]byte(aws.StringValue(event.Message)), &record); err != nil {
+			return nil, err
+		}
+		f.events = append(f.events, record)
+	}
+	return &cloudwatchlogs.PutLogEventsOutput{}, nil
+}
+
+// WHEN --audit-log-group is set, THEN a start and a single stop record SHALL be written to a new
+// stream with the caller's identity, target and session.
+func TestAuditLog(t *testing.T) {
+	logs := &fakeLogs{}
+	audit, err := startAudit(&fakeSTS{}, logs, "audit", auditRecord{Target: "i-abc", Region: "us-east-1", Document: "AWS-StartPortForwardingSession"})
+	if err != nil {
+		t.Fatalf("Failed to start audit: %v", err)
+	}
+	if len(logs.streams) != 1 || !strings.HasPrefix(logs.streams[0], "ssm-port-forward/i-abc/") || strings.ContainsAny(logs.streams[0], ":*") {
+		t.Errorf("Unexpected log streams: %v", logs.streams)
+	}
+
+	if err := audit.sessionStarted("sess-1", "client-1"); err != nil {
+		t.Fatalf("Failed to write start record: %v", err)
+	}
+	audit.sessionStopped(log.NewMockLog(), "signal: interrupt")
+	audit.sessionStopped(log.NewMockLog(), "session ended")
+
+	if len(logs.events) != 2 {
+		t.Fatalf("Expected a start and a stop record, got %+v", logs.events)
+	}
+	start, stop := logs.events[0], logs.events[1]
+	if start.Event != AuditSessionStart || start.CallerARN != "arn:aws:sts::123456789012:assumed-role/ops/alice" ||
+		start.Account != "123456789012" || start.SessionID != "sess-1" || start.Target != "i-abc" || start.StartTime == "" {
+		t.Errorf("Unexpected start record: %+v", start)
+	}
+	if stop.Event != AuditSessionStop || stop.Reason != "signal: interrupt" || stop.StopTime == "" || stop.StartTime != start.StartTime {
+		t.Errorf("Unexpected stop record: %+v", stop)
+	}
+}
+
+// WHEN the caller identity or log stream can't be set up, THEN startAudit SHALL fail; an
+// existing stream SHALL be reused.
+func TestStartAuditFailures(t *testing.T) {
+	denied := awserr.New("AccessDeniedException", "not authorized", nil)
+	if _, err := startAudit(&fakeSTS{err: denied}, &fakeLogs{}, "audit", auditRecord{}); !errors.Is(err, denied) {
+		t.Errorf("Expected the identity error, got: %v", err)
+	}
+	if _, err := startAudit(&fakeSTS{}, &fakeLogs{createErr: denied}, "audit", auditRecord{}); !errors.Is(err, denied) {
+		t.Errorf("Expected the log stream error, got: %v", err)
+	}
+	exists := awserr.New(cloudwatchlogs.ErrCodeResourceAlreadyExistsException, "exists", nil)
+	if _, err := startAudit(&fakeSTS{}, &fakeLogs{createErr: exists}, "audit", auditRecord{}); err != nil {
+		t.Errorf("Expected an existing stream to be reused, got: %v", err)
+	}
+
+	var audit *auditLog
+	if err := audit.sessionStarted("sess-1", "client-1"); err != nil {
+		t.Errorf("Expected a nil audit log to write nothing, got: %v", err)
+	}
+	audit.sessionStopped(log.NewMockLog(), "session ended")
+}
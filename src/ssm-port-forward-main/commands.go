@@ -0,0 +1,95 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/zph/session-manager-plugin/src/sdkutil"
+)
+
+// Subcommands; bare flags run CommandForward, as before subcommands existed.
+const (
+	CommandForward       = "forward"
+	CommandListSessions  = "list-sessions"
+	CommandListDocuments = "list-documents"
+	CommandTerminate     = "terminate"
+)
+
+// awsOptions are the credential options shared by the session management subcommands.
+type awsOptions struct {
+	Region        string
+	Profile       string
+	SSOLogin      bool
+	SigningRegion string
+	SigningName   string
+}
+
+func (o *awsOptions) register(flags *flag.FlagSet) {
+	flags.StringVar(&o.Region, "region", "", "AWS region")
+	flags.StringVar(&o.Region, "r", "", "AWS region (short form)")
+	flags.StringVar(&o.Profile, "profile", "", "AWS profile")
+	flags.StringVar(&o.Profile, "p", "", "AWS profile (short form)")
+	flags.BoolVar(&o.SSOLogin, "sso-login", false, "Run aws sso login if the profile's SSO session has expired")
+	flags.StringVar(&o.SigningRegion, "signing-region", "", "Region to sign SSM API requests for")
+	flags.StringVar(&o.SigningName, "signing-name", "", "Service name to sign SSM API requests for")
+}
+
+// ssmClient resolves credentials the same way as the forward command and returns an SSM client.
+func (o *awsOptions) ssmClient() (*ssm.SSM, error) {
+	region, profile := sdkutil.ResolveRegion(o.Region), sdkutil.ResolveProfile(o.Profile)
+	sdkutil.SetRegionAndProfile(region.Value, profile.Value)
+	sdkutil.SetSigningOverrides(o.SigningRegion, o.SigningName)
+	newSession := func() (*awssession.Session, error) { return sdkutil.GetNewSessionWithEndpoint("") }
+	sess, err := resolveCredentials(newSession, profile.Value, o.SSOLogin)
+	if err != nil {
+		return nil, stageError(StageAWSSession, CodeAuthFailed, fmt.Errorf("failed to create AWS session: %w", err))
+	}
+	return ssm.New(sess), nil
+}
+
+// newCommandFlags returns a flag set for a subcommand. Its errors and usage are reported by the
+// caller, honouring --output-format.
+func newCommandFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.Usage = func() {}
+	return flags
+}
+
+// parseCommandFlags parses a subcommand's args, printing usage and exiting on -h. On error the
+// output format is reset to text unless it was already parsed as a valid format.
+func parseCommandFlags(flags *flag.FlagSet, args []string, outputFormat *string, usage func()) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			usage()
+			os.Exit(0)
+		}
+		if validateOutputFormat(*outputFormat) != nil {
+			*outputFormat = OutputFormatText
+		}
+		return err
+	}
+	if err := validateOutputFormat(*outputFormat); err != nil {
+		*outputFormat = OutputFormatText
+		return err
+	}
+	return nil
+}
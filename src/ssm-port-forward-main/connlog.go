@@ -0,0 +1,117 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+// connLogBacklog is how many closed-connection records may queue before record blocks.
+const connLogBacklog = 256
+
+// connLog appends one JSON line per closed connection to a file. Records arrive from many
+// connection goroutines, so they are funnelled to a single writer goroutine and never interleave.
+type connLog struct {
+	w       io.WriteCloser
+	records chan session.ConnRecord
+	stop    chan struct{}
+	done    chan struct{}
+	// sample keeps one in every sample records, starting with the first, when above 1
+	sample int
+	count  atomic.Uint64
+}
+
+// openConnLog opens path for appending, creating it if needed, and starts the writer goroutine.
+// With sample above 1 only one in every sample records is written.
+func openConnLog(path string, sample int) (*connLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	l := newConnLog(f)
+	l.sample = sample
+	return l, nil
+}
+
+// parseSampleRate parses a --conn-log-sample rate of the form 1/N into N; empty means 1, every
+// connection.
+func parseSampleRate(rate string) (int, error) {
+	if rate == "" {
+		return 1, nil
+	}
+	one, every, found := strings.Cut(rate, "/")
+	n, err := strconv.Atoi(every)
+	if !found || one != "1" || err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid conn-log-sample %q: expected 1/N such as 1/100", rate)
+	}
+	return n, nil
+}
+
+func newConnLog(w io.WriteCloser) *connLog {
+	l := &connLog{
+		w:       w,
+		records: make(chan session.ConnRecord, connLogBacklog),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go l.writeLoop()
+	return l
+}
+
+// record queues r for writing unless sampling skips it. Records arriving after Close are dropped.
+func (l *connLog) record(r session.ConnRecord) {
+	if l.sample > 1 && l.count.Add(1)%uint64(l.sample) != 1 {
+		return
+	}
+	select {
+	case l.records <- r:
+	case <-l.stop:
+	}
+}
+
+func (l *connLog) writeLoop() {
+	defer close(l.done)
+	encoder := json.NewEncoder(l.w)
+	for {
+		select {
+		case r := <-l.records:
+			encoder.Encode(r)
+		case <-l.stop:
+			// flush what was queued before Close
+			for {
+				select {
+				case r := <-l.records:
+					encoder.Encode(r)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// Close flushes queued records and closes the file.
+func (l *connLog) Close() error {
+	close(l.stop)
+	<-l.done
+	return l.w.Close()
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+type nopWriteCloser struct{ *bytes.Buffer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// WHEN many connections close concurrently, THEN the connection log SHALL hold one
+// complete JSON line per connection after Close.
+func TestConnLogSerializesRecords(t *testing.T) {
+	var buf bytes.Buffer
+	l := newConnLog(nopWriteCloser{&buf})
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l.record(session.ConnRecord{
+				Source:      fmt.Sprintf("127.0.0.1:%d", 40000+i),
+				Opened:      time.Now(),
+				BytesIn:     int64(i),
+				CloseReason: "client_closed",
+			})
+		}(i)
+	}
+	wg.Wait()
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	lines := 0
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var r session.ConnRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("Line %d is not a complete record: %v", lines, err)
+		}
+		lines++
+	}
+	if lines != n {
+		t.Errorf("Expected %d records, got %d", n, lines)
+	}
+
+	// Records after Close are dropped rather than blocking
+	l.record(session.ConnRecord{})
+}
+
+// WHEN the connection log samples 1/N, THEN only the first of every N records SHALL be written.
+func TestConnLogSamplesRecords(t *testing.T) {
+	var buf bytes.Buffer
+	l := newConnLog(nopWriteCloser{&buf})
+	l.sample = 4
+
+	for i := 0; i < 9; i++ {
+		l.record(session.ConnRecord{BytesIn: int64(i)})
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	var got []int64
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var r session.ConnRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("Line is not a complete record: %v", err)
+		}
+		got = append(got, r.BytesIn)
+	}
+	if fmt.Sprint(got) != "[0 4 8]" {
+		t.Errorf("Expected records [0 4 8], got %v", got)
+	}
+}
+
+// WHEN --conn-log-sample is given, THEN 1/N SHALL parse to N and other forms SHALL be rejected.
+func TestParseSampleRate(t *testing.T) {
+	for rate, want := range map[string]int{"": 1, "1/1": 1, "1/100": 100} {
+		if got, err := parseSampleRate(rate); err != nil || got != want {
+			t.Errorf("parseSampleRate(%q) = %d, %v; want %d", rate, got, err, want)
+		}
+	}
+	for _, rate := range []string{"100", "2/100", "1/0", "1/-5", "1/x", "0.01"} {
+		if _, err := parseSampleRate(rate); err == nil {
+			t.Errorf("parseSampleRate(%q) succeeded, want an error", rate)
+		}
+	}
+}
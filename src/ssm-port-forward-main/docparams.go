@@ -0,0 +1,71 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// reservedDocumentParameters are set from the forward specification only, so that the compiled-in
+// policy and --policy, which check the specification, also cover where the session connects.
+var reservedDocumentParameters = []string{"host", "portNumber", "localPortNumber"}
+
+// parseDocumentParameters parses --parameters, a JSON object of document parameter names to
+// lists of strings in the shape StartSession takes, e.g. {"auditTag":["ops"]}.
+func parseDocumentParameters(raw string) (map[string][]*string, error) {
+	var params map[string][]*string
+	if err := json.Unmarshal([]byte(raw), &params); err != nil {
+		return nil, fmt.Errorf("invalid parameters JSON (expected an object of string lists): %w", err)
+	}
+	if params == nil {
+		return nil, errors.New("invalid parameters JSON: expected an object of string lists")
+	}
+	for name, values := range params {
+		if name == "" {
+			return nil, errors.New("invalid parameters JSON: empty parameter name")
+		}
+		if slices.Contains(reservedDocumentParameters, name) {
+			return nil, fmt.Errorf("invalid parameters: %s is set from the forward specification", name)
+		}
+		for _, value := range values {
+			if value == nil {
+				return nil, fmt.Errorf("invalid parameters JSON: null value for %s", name)
+			}
+		}
+	}
+	return params, nil
+}
+
+// mergeDocumentParameters adds extra to the parameters derived from the forward spec. Derived
+// parameters take precedence; an extra one that would replace them is logged and ignored.
+func mergeDocumentParameters(logger log.T, params map[string][]*string, extra map[string][]*string) {
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, derived := params[name]; derived {
+			logger.Warnf("Ignoring %s from --parameters: it is set from the forward specification", name)
+			continue
+		}
+		params[name] = extra[name]
+	}
+}
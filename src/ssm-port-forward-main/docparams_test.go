@@ -0,0 +1,59 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// WHEN --parameters is given, THEN parseDocumentParameters SHALL accept an object of string
+// lists and reject anything else, including the parameters set from the forward specification.
+func TestParseDocumentParameters(t *testing.T) {
+	params, err := parseDocumentParameters(`{"auditTag":["ops"],"ports":["80","443"]}`)
+	if err != nil {
+		t.Fatalf("parseDocumentParameters failed: %v", err)
+	}
+	if got := aws.StringValueSlice(params["ports"]); len(got) != 2 || got[0] != "80" || got[1] != "443" {
+		t.Errorf("Unexpected ports: %v", got)
+	}
+
+	for _, raw := range []string{``, `null`, `[]`, `{"a":"b"}`, `{"a":[1]}`, `{"a":[null]}`, `{"":["x"]}`, `{"a":["b"]`,
+		`{"host":["169.254.169.254"]}`, `{"portNumber":["22"]}`, `{"localPortNumber":["2222"]}`} {
+		if _, err := parseDocumentParameters(raw); err == nil {
+			t.Errorf("Expected %q to be rejected", raw)
+		}
+	}
+}
+
+// WHEN extra parameters are merged, THEN they SHALL be added, except those set from the
+// forward specification, which SHALL keep their derived values.
+func TestMergeDocumentParameters(t *testing.T) {
+	params := map[string][]*string{"portNumber": aws.StringSlice([]string{"5432"})}
+	extra := map[string][]*string{
+		"portNumber": aws.StringSlice([]string{"22"}),
+		"auditTag":   aws.StringSlice([]string{"ops"}),
+	}
+
+	mergeDocumentParameters(log.NewMockLog(), params, extra)
+
+	if got := aws.StringValueSlice(params["portNumber"]); len(got) != 1 || got[0] != "5432" {
+		t.Errorf("Expected the derived portNumber to be kept, got %v", got)
+	}
+	if got := aws.StringValueSlice(params["auditTag"]); len(got) != 1 || got[0] != "ops" {
+		t.Errorf("Expected auditTag to be added, got %v", got)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// pingStatusOnline is the DescribeInstanceInformation ping status of a target that can take sessions.
+const pingStatusOnline = "Online"
+
+// dryRunInfo is what --dry-run reports a forward would do.
+type dryRunInfo struct {
+	Type       string               `json:"type"`
+	Target     string               `json:"target"`
+	Region     string               `json:"region"`
+	Document   string               `json:"document"`
+	Forwarding string               `json:"forwarding"`
+	Parameters map[string][]*string `json:"parameters"`
+	PingStatus string               `json:"ping_status"`
+}
+
+// dryRun checks that the target is a connected managed instance and that the document exists,
+// then writes what StartSession would be called with to w, as a JSON line in json format.
+func dryRun(w io.Writer, client ssmiface.SSMAPI, format string, info dryRunInfo) error {
+	status, err := checkTargetOnline(client, info.Target)
+	if err != nil {
+		return err
+	}
+	info.PingStatus = status
+	if err := checkSessionDocument(client, info.Document); err != nil {
+		return err
+	}
+
+	info.Type = "dry_run"
+	if err := printDryRun(w, format, info); err != nil {
+		return stageError(StageWriteOutput, CodeInternal, fmt.Errorf("failed to write dry run: %w", err))
+	}
+	return nil
+}
+
+// checkTargetOnline returns the ping status of instanceID, failing unless it is a managed
+// instance that is Online.
+func checkTargetOnline(client ssmiface.SSMAPI, instanceID string) (string, error) {
+	out, err := client.DescribeInstanceInformation(&ssm.DescribeInstanceInformationInput{
+		Filters: []*ssm.InstanceInformationStringFilter{{
+			Key:    aws.String("InstanceIds"),
+			Values: []*string{aws.String(instanceID)},
+		}},
+	})
+	if err != nil {
+		return "", stageError(StageCheckTarget, CodeSessionError, fmt.Errorf("failed to describe instance %s: %w", instanceID, err))
+	}
+	if len(out.InstanceInformationList) == 0 {
+		return "", stageError(StageCheckTarget, CodeNoTarget, fmt.Errorf("instance %s is not registered as a managed instance", instanceID))
+	}
+	status := aws.StringValue(out.InstanceInformationList[0].PingStatus)
+	if status != pingStatusOnline {
+		return "", stageError(StageCheckTarget, CodeTargetNotConnected,
+			fmt.Errorf("instance %s is not connected to Systems Manager (ping status: %s)", instanceID, status))
+	}
+	return status, nil
+}
+
+// checkSessionDocument fails unless name is an active Session document the caller can read.
+func checkSessionDocument(client ssmiface.SSMAPI, name string) error {
+	out, err := client.DescribeDocument(&ssm.DescribeDocumentInput{Name: aws.String(name)})
+	if err != nil {
+		return stageError(StageCheckDocument, CodeInvalidArgs, fmt.Errorf("failed to describe document %s: %w", name, err))
+	}
+	doc := out.Document
+	if docType := aws.StringValue(doc.DocumentType); docType != ssm.DocumentTypeSession {
+		return stageError(StageCheckDocument, CodeInvalidArgs, fmt.Errorf("document %s is a %s document, not a Session document", name, docType))
+	}
+	if docStatus := aws.StringValue(doc.Status); docStatus != ssm.DocumentStatusActive {
+		return stageError(StageCheckDocument, CodeInvalidArgs, fmt.Errorf("document %s is %s, not Active", name, docStatus))
+	}
+	return nil
+}
+
+// printDryRun writes info as a JSON line in json format, otherwise as one line per field.
+func printDryRun(w io.Writer, format string, info dryRunInfo) error {
+	if format == OutputFormatJSON {
+		data, err := json.Marshal(info)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	names := make([]string, 0, len(info.Parameters))
+	for name := range info.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var params []string
+	for _, name := range names {
+		params = append(params, name+"="+strings.Join(aws.StringValueSlice(info.Parameters[name]), ","))
+	}
+	_, err := fmt.Fprintf(w, `Dry run: all checks passed, no session started
+Target:     %s (ping status: %s)
+Region:     %s
+Document:   %s
+Forwarding: %s
+Parameters: %s
+`, info.Target, info.PingStatus, info.Region, info.Document, info.Forwarding, strings.Join(params, " "))
+	return err
+}
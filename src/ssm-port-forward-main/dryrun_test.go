@@ -0,0 +1,120 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+type fakeDryRunSSM struct {
+	fakeSSM
+	doc    *ssm.DocumentDescription
+	docErr error
+}
+
+func (f *fakeDryRunSSM) DescribeDocument(*ssm.DescribeDocumentInput) (*ssm.DescribeDocumentOutput, error) {
+	if f.docErr != nil {
+		return nil, f.docErr
+	}
+	return &ssm.DescribeDocumentOutput{Document: f.doc}, nil
+}
+
+func newFakeDryRunSSM(pingStatus string) *fakeDryRunSSM {
+	return &fakeDryRunSSM{
+		fakeSSM: fakeSSM{info: []*ssm.InstanceInformation{{PingStatus: aws.String(pingStatus)}}},
+		doc: &ssm.DocumentDescription{
+			DocumentType: aws.String(ssm.DocumentTypeSession),
+			Status:       aws.String(ssm.DocumentStatusActive),
+		},
+	}
+}
+
+func testDryRunInfo() dryRunInfo {
+	return dryRunInfo{
+		Target:     "i-abc",
+		Region:     "us-east-1",
+		Document:   "AWS-StartPortForwardingSession",
+		Forwarding: "local 8080 -> bastion 80",
+		Parameters: map[string][]*string{"portNumber": {aws.String("80")}, "localPortNumber": {aws.String("8080")}},
+	}
+}
+
+// WHEN the target is Online and the document is an active Session document, THEN dryRun SHALL
+// succeed and print the target, document and parameters.
+func TestDryRun(t *testing.T) {
+	var out bytes.Buffer
+	if err := dryRun(&out, newFakeDryRunSSM("Online"), OutputFormatText, testDryRunInfo()); err != nil {
+		t.Fatalf("Expected dry run to pass, got: %v", err)
+	}
+	for _, want := range []string{"no session started", "i-abc (ping status: Online)", "AWS-StartPortForwardingSession", "localPortNumber=8080 portNumber=80"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := dryRun(&out, newFakeDryRunSSM("Online"), OutputFormatJSON, testDryRunInfo()); err != nil {
+		t.Fatalf("Expected dry run to pass, got: %v", err)
+	}
+	var info dryRunInfo
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("Expected a JSON line, got %q: %v", out.String(), err)
+	}
+	if info.Type != "dry_run" || info.PingStatus != "Online" || info.Target != "i-abc" {
+		t.Errorf("Unexpected dry run output: %+v", info)
+	}
+}
+
+// WHEN a check fails, THEN dryRun SHALL print nothing and fail with the check's stage and code.
+func TestDryRunFailures(t *testing.T) {
+	unregistered := newFakeDryRunSSM("Online")
+	unregistered.info = nil
+	denied := newFakeDryRunSSM("Online")
+	denied.err = awserr.New("AccessDeniedException", "not authorized", nil)
+	missingDoc := newFakeDryRunSSM("Online")
+	missingDoc.docErr = awserr.New("InvalidDocument", "document does not exist", nil)
+	commandDoc := newFakeDryRunSSM("Online")
+	commandDoc.doc.DocumentType = aws.String(ssm.DocumentTypeCommand)
+
+	cases := map[string]struct {
+		client *fakeDryRunSSM
+		stage  Stage
+		code   ErrorCode
+	}{
+		"offline":      {newFakeDryRunSSM("ConnectionLost"), StageCheckTarget, CodeTargetNotConnected},
+		"unregistered": {unregistered, StageCheckTarget, CodeNoTarget},
+		"denied":       {denied, StageCheckTarget, CodeAuthFailed},
+		"missing doc":  {missingDoc, StageCheckDocument, CodeInvalidArgs},
+		"command doc":  {commandDoc, StageCheckDocument, CodeInvalidArgs},
+	}
+	for name, c := range cases {
+		var out bytes.Buffer
+		err := dryRun(&out, c.client, OutputFormatText, testDryRunInfo())
+		var cliErr *cliError
+		if !errors.As(err, &cliErr) || cliErr.Stage != c.stage || cliErr.Code != c.code {
+			t.Errorf("%s: expected %s/%s, got: %v", name, c.stage, c.code, err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("%s: expected no output, got %q", name, out.String())
+		}
+	}
+}
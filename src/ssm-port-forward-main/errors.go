@@ -0,0 +1,209 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+const (
+	OutputFormatText  = "text"
+	OutputFormatJSON  = "json"
+	OutputFormatTable = "table" // forward only: an aligned summary instead of the JSON line
+)
+
+// ErrorCode is a stable, machine-readable failure category. Values must not change between releases.
+type ErrorCode string
+
+const (
+	CodeInvalidArgs        ErrorCode = "invalid_args"
+	CodePolicyDenied       ErrorCode = "policy_denied"
+	CodeAuthFailed         ErrorCode = "auth_failed"
+	CodePortConflict       ErrorCode = "port_conflict"
+	CodeSessionTimeout     ErrorCode = "session_timeout"
+	CodeRemoteUnreachable  ErrorCode = "remote_unreachable"
+	CodeProbeFailed        ErrorCode = "probe_failed"
+	CodeNoTarget           ErrorCode = "no_target"
+	CodeTargetNotConnected ErrorCode = "target_not_connected"
+	CodeStartSessionFailed ErrorCode = "start_session_failed"
+	CodeSessionError       ErrorCode = "session_error"
+	CodeInternal           ErrorCode = "internal"
+)
+
+// Exit statuses, so scripts can tell bad arguments from rejected credentials from a tunnel
+// that never came up. Like ErrorCode values, these must not change between releases.
+const (
+	ExitFailure      = 1
+	ExitInvalidArgs  = 2
+	ExitAuthFailed   = 3
+	ExitStartSession = 4
+	ExitNotReady     = 5
+)
+
+// exitCodes maps error codes to exit statuses; codes not listed exit with ExitFailure.
+var exitCodes = map[ErrorCode]int{
+	CodeInvalidArgs:        ExitInvalidArgs,
+	CodePolicyDenied:       ExitInvalidArgs,
+	CodeAuthFailed:         ExitAuthFailed,
+	CodeNoTarget:           ExitStartSession,
+	CodeTargetNotConnected: ExitStartSession,
+	CodeStartSessionFailed: ExitStartSession,
+	CodeSessionTimeout:     ExitNotReady,
+	CodeRemoteUnreachable:  ExitNotReady,
+	CodeProbeFailed:        ExitNotReady,
+}
+
+// Stage names the step of run that failed.
+type Stage string
+
+const (
+	StageParseArgs     Stage = "parse_args"
+	StageAWSSession    Stage = "aws_session"
+	StageResolveTarget Stage = "resolve_target"
+	StageResolveHost   Stage = "resolve_host"
+	StageLogFile       Stage = "log_file"
+	StageHealthServer  Stage = "health_server"
+	StageConnLog       Stage = "conn_log"
+	StageEventSocket   Stage = "event_socket"
+	StageTracing       Stage = "tracing"
+	StageStatsd        Stage = "statsd"
+	StageLocalTLS      Stage = "local_tls"
+	StageRemoteTLS     Stage = "remote_tls"
+	StageLocalPort     Stage = "local_port"
+	StageAllocatePort  Stage = "allocate_port"
+	StageCheckTarget   Stage = "check_target"
+	StageCheckDocument Stage = "check_document"
+	StageAudit         Stage = "audit"
+	StageStartSession  Stage = "start_session"
+	StageListSessions  Stage = "list_sessions"
+	StageListDocuments Stage = "list_documents"
+	StageTerminate     Stage = "terminate"
+	StageWaitReady     Stage = "wait_ready"
+	StageProbe         Stage = "probe"
+	StageSelfTest      Stage = "selftest"
+	StageWriteOutput   Stage = "write_output"
+	StageSession       Stage = "session"
+	StageCleanup       Stage = "cleanup"
+)
+
+// authErrorCodes are AWS error codes that mean the caller's credentials were rejected or missing.
+var authErrorCodes = map[string]bool{
+	"AccessDeniedException":       true,
+	"ExpiredToken":                true,
+	"ExpiredTokenException":       true,
+	"InvalidClientTokenId":        true,
+	"NoCredentialProviders":       true,
+	"SignatureDoesNotMatch":       true,
+	"SSOProviderInvalidToken":     true,
+	"UnrecognizedClientException": true,
+}
+
+// cliError carries the stage and code of a failure alongside the underlying error.
+type cliError struct {
+	Code  ErrorCode
+	Stage Stage
+	Err   error
+}
+
+func (e *cliError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *cliError) Unwrap() error {
+	return e.Err
+}
+
+// errorOutput is the JSON object written to stderr in json output format.
+type errorOutput struct {
+	Error string    `json:"error"`
+	Code  ErrorCode `json:"code"`
+	Stage Stage     `json:"stage"`
+}
+
+// stageError tags err with the stage it occurred in. The code is derived from the error
+// chain when recognizable, otherwise fallback is used.
+func stageError(stage Stage, fallback ErrorCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &cliError{Code: classifyError(err, fallback), Stage: stage, Err: err}
+}
+
+// classifyError maps well-known errors to a stable code.
+func classifyError(err error, fallback ErrorCode) ErrorCode {
+	var awsErr awserr.Error
+	switch {
+	case errors.As(err, &awsErr) && authErrorCodes[awsErr.Code()]:
+		return CodeAuthFailed
+	case isTargetNotConnected(err):
+		return CodeTargetNotConnected
+	case errors.Is(err, syscall.EADDRINUSE):
+		return CodePortConflict
+	case errors.Is(err, errWaitTimeout):
+		return CodeSessionTimeout
+	case errors.Is(err, errRemotePortFailed):
+		return CodeRemoteUnreachable
+	case errors.Is(err, errProbeFailed):
+		return CodeProbeFailed
+	case errors.Is(err, errPolicyDenied):
+		return CodePolicyDenied
+	}
+	return fallback
+}
+
+// exitCode returns the process exit status for err.
+func exitCode(err error) int {
+	var cliErr *cliError
+	if errors.As(err, &cliErr) {
+		if code, ok := exitCodes[cliErr.Code]; ok {
+			return code
+		}
+	}
+	return ExitFailure
+}
+
+// validateOutputFormat accepts text, json and any extra formats the command supports.
+func validateOutputFormat(format string, extra ...string) error {
+	formats := append([]string{OutputFormatText, OutputFormatJSON}, extra...)
+	if slices.Contains(formats, format) {
+		return nil
+	}
+	last := len(formats) - 1
+	return fmt.Errorf("invalid output format: %s (expected %s or %s)", format, strings.Join(formats[:last], ", "), formats[last])
+}
+
+// writeError reports err on w, as a JSON object in json format or a human string otherwise.
+func writeError(w io.Writer, format string, err error) {
+	if format != OutputFormatJSON {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return
+	}
+
+	out := errorOutput{Error: err.Error(), Code: CodeInternal}
+	var cliErr *cliError
+	if errors.As(err, &cliErr) {
+		out.Code = cliErr.Code
+		out.Stage = cliErr.Stage
+	}
+	data, _ := json.Marshal(out)
+	fmt.Fprintln(w, string(data))
+}
@@ -0,0 +1,146 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCode
+	}{
+		{"aws auth", awserr.New("ExpiredTokenException", "token expired", nil), CodeAuthFailed},
+		{"wrapped aws auth", fmt.Errorf("failed: %w", awserr.New("AccessDeniedException", "denied", nil)), CodeAuthFailed},
+		{"other aws", awserr.New("InvalidDocument", "no such document", nil), CodeStartSessionFailed},
+		{"target not connected", awserr.New("TargetNotConnected", "offline", nil), CodeTargetNotConnected},
+		{"address in use", &os.SyscallError{Syscall: "bind", Err: syscall.EADDRINUSE}, CodePortConflict},
+		{"wait timeout", fmt.Errorf("%w: local port 1 not ready", errWaitTimeout), CodeSessionTimeout},
+		{"remote port", fmt.Errorf("%w: boom", errRemotePortFailed), CodeRemoteUnreachable},
+		{"probe", fmt.Errorf("%w (tcp): closed", errProbeFailed), CodeProbeFailed},
+		{"unknown", errors.New("something else"), CodeStartSessionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err, CodeStartSessionFailed); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStageErrorNil(t *testing.T) {
+	if err := stageError(StageCleanup, CodeSessionError, nil); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+}
+
+func TestWriteErrorJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := stageError(StageStartSession, CodeStartSessionFailed, fmt.Errorf("failed to start SSM session: %w", errors.New("boom")))
+	writeError(&buf, OutputFormatJSON, err)
+
+	var out errorOutput
+	if jsonErr := json.Unmarshal(buf.Bytes(), &out); jsonErr != nil {
+		t.Fatalf("Expected valid JSON, got %q: %v", buf.String(), jsonErr)
+	}
+	if out.Code != CodeStartSessionFailed || out.Stage != StageStartSession {
+		t.Errorf("Unexpected code/stage: %+v", out)
+	}
+	if out.Error != "failed to start SSM session: boom" {
+		t.Errorf("Unexpected error message: %q", out.Error)
+	}
+}
+
+// WHEN an untagged error is reported in json format, THEN the code SHALL be internal.
+func TestWriteErrorJSONUntagged(t *testing.T) {
+	var buf bytes.Buffer
+	writeError(&buf, OutputFormatJSON, errors.New("boom"))
+
+	var out errorOutput
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("Expected valid JSON: %v", err)
+	}
+	if out.Code != CodeInternal {
+		t.Errorf("Expected internal code, got %s", out.Code)
+	}
+}
+
+func TestWriteErrorText(t *testing.T) {
+	var buf bytes.Buffer
+	writeError(&buf, OutputFormatText, stageError(StageParseArgs, CodeInvalidArgs, errors.New("instance-id is required")))
+
+	if got := strings.TrimSpace(buf.String()); got != "Error: instance-id is required" {
+		t.Errorf("Unexpected text output: %q", got)
+	}
+}
+
+func TestValidateOutputFormat(t *testing.T) {
+	if err := validateOutputFormat(OutputFormatJSON); err != nil {
+		t.Errorf("Expected json to be valid: %v", err)
+	}
+	if err := validateOutputFormat("yaml"); err == nil {
+		t.Error("Expected error for yaml")
+	}
+	if err := validateOutputFormat(OutputFormatTable); err == nil {
+		t.Error("Expected table to be rejected unless the command supports it")
+	}
+	if err := validateOutputFormat(OutputFormatTable, OutputFormatTable); err != nil {
+		t.Errorf("Expected table to be valid when supported: %v", err)
+	}
+	err := validateOutputFormat("yaml", OutputFormatTable)
+	if err == nil || !strings.Contains(err.Error(), "expected text, json or table") {
+		t.Errorf("Expected the supported formats to be listed, got %v", err)
+	}
+}
+
+// WHEN run fails, THEN the exit status SHALL follow the documented table, with unlisted codes
+// and untagged errors exiting 1.
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"invalid args", stageError(StageParseArgs, CodeInvalidArgs, errors.New("bad -L")), ExitInvalidArgs},
+		{"auth", stageError(StageAWSSession, CodeStartSessionFailed, awserr.New("ExpiredToken", "expired", nil)), ExitAuthFailed},
+		{"start session", stageError(StageStartSession, CodeStartSessionFailed, errors.New("offline")), ExitStartSession},
+		{"no target", stageError(StageResolveTarget, CodeNoTarget, errors.New("empty asg")), ExitStartSession},
+		{"wait timeout", stageError(StageWaitReady, CodeSessionError, errWaitTimeout), ExitNotReady},
+		{"probe", stageError(StageProbe, CodeProbeFailed, errProbeFailed), ExitNotReady},
+		{"port conflict", stageError(StageAllocatePort, CodePortConflict, syscall.EADDRINUSE), ExitFailure},
+		{"session error", stageError(StageSession, CodeSessionError, errors.New("dropped")), ExitFailure},
+		{"untagged", errors.New("boom"), ExitFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("Expected exit %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+// Lifecycle event types written to the event socket.
+const (
+	EventEstablished      = "established"
+	EventConnectionOpened = "connection_opened"
+	EventConnectionClosed = "connection_closed"
+	EventReconnecting     = "reconnecting"
+	EventReconnected      = "reconnected"
+	EventPaused           = "paused"
+	EventResumed          = "resumed"
+	EventTerminated       = "terminated"
+)
+
+// eventBacklog is how many events may queue for one reader before it is disconnected as too slow.
+const eventBacklog = 256
+
+// eventWriteTimeout bounds flushing queued events to a reader at shutdown.
+var eventWriteTimeout = time.Second
+
+// lifecycleEvent is one NDJSON line on the event socket.
+type lifecycleEvent struct {
+	Type              string `json:"type"`
+	Timestamp         string `json:"timestamp"`
+	ActiveConnections int64  `json:"active_connections"`
+	Port              int    `json:"port,omitempty"`
+	Forwarding        string `json:"forwarding,omitempty"`
+	Source            string `json:"source,omitempty"`
+	Reason            string `json:"reason,omitempty"`
+}
+
+// eventServer streams lifecycle events as NDJSON to every reader connected to a Unix socket.
+// Readers may attach and detach at any time; a reader attaching after the forward is up is
+// first sent the established event. A nil *eventServer discards events.
+type eventServer struct {
+	listener net.Listener
+	active   atomic.Int64
+
+	mutex       sync.Mutex
+	readers     map[*eventReader]struct{}
+	established *lifecycleEvent
+	closed      bool
+	wait        sync.WaitGroup
+}
+
+// eventReader is one connected reader and its queue of encoded events.
+type eventReader struct {
+	conn   net.Conn
+	events chan []byte
+}
+
+// listenEvents creates the event socket at path, readable by the current user only.
+func listenEvents(path string) (*eventServer, error) {
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	s := &eventServer{listener: listener, readers: map[*eventReader]struct{}{}}
+	go s.acceptLoop()
+	return s, nil
+}
+
+func (s *eventServer) acceptLoop() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		r := &eventReader{conn: conn, events: make(chan []byte, eventBacklog)}
+
+		s.mutex.Lock()
+		if s.closed {
+			s.mutex.Unlock()
+			conn.Close()
+			return
+		}
+		if s.established != nil {
+			replay := *s.established
+			replay.ActiveConnections = s.active.Load()
+			r.events <- encodeEvent(replay)
+		}
+		s.readers[r] = struct{}{}
+		s.wait.Add(1)
+		s.mutex.Unlock()
+
+		go s.writeLoop(r)
+	}
+}
+
+// writeLoop sends queued events to r until its queue is closed or the reader goes away.
+func (s *eventServer) writeLoop(r *eventReader) {
+	defer s.wait.Done()
+	defer r.conn.Close()
+	for line := range r.events {
+		if _, err := r.conn.Write(line); err != nil {
+			s.drop(r)
+			// keep draining so emit never blocks on this reader
+			for range r.events {
+			}
+			return
+		}
+	}
+}
+
+// drop disconnects r; its queue is closed so writeLoop exits.
+func (s *eventServer) drop(r *eventReader) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.readers[r]; ok {
+		delete(s.readers, r)
+		close(r.events)
+	}
+}
+
+func encodeEvent(e lifecycleEvent) []byte {
+	line, _ := json.Marshal(e)
+	return append(line, '\n')
+}
+
+// emit timestamps e and queues it for every reader. Readers too slow to keep up are disconnected
+// rather than holding up the forward.
+func (s *eventServer) emit(e lifecycleEvent) {
+	if s == nil {
+		return
+	}
+	e.Timestamp = time.Now().Format(time.RFC3339)
+	e.ActiveConnections = s.active.Load()
+	line := encodeEvent(e)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return
+	}
+	if e.Type == EventEstablished {
+		s.established = &e
+	}
+	for r := range s.readers {
+		select {
+		case r.events <- line:
+		default:
+			delete(s.readers, r)
+			close(r.events)
+		}
+	}
+}
+
+// establishedOn reports that the forward is listening on port.
+func (s *eventServer) establishedOn(port int, forwarding string) {
+	s.emit(lifecycleEvent{Type: EventEstablished, Port: port, Forwarding: forwarding})
+}
+
+// connOpened is the session's OnConnOpened hook.
+func (s *eventServer) connOpened(source string) {
+	if s == nil {
+		return
+	}
+	s.active.Add(1)
+	s.emit(lifecycleEvent{Type: EventConnectionOpened, Source: source})
+}
+
+// connClosed is the session's OnConnClosed hook.
+func (s *eventServer) connClosed(record session.ConnRecord) {
+	if s == nil {
+		return
+	}
+	s.active.Add(-1)
+	s.emit(lifecycleEvent{Type: EventConnectionClosed, Source: record.Source, Reason: record.CloseReason})
+}
+
+// reconnect is the session's OnReconnect hook.
+func (s *eventServer) reconnect(reconnecting bool) {
+	if reconnecting {
+		s.emit(lifecycleEvent{Type: EventReconnecting})
+	} else {
+		s.emit(lifecycleEvent{Type: EventReconnected})
+	}
+}
+
+// paused reports that the forward stopped or resumed accepting connections.
+func (s *eventServer) paused(paused bool) {
+	if paused {
+		s.emit(lifecycleEvent{Type: EventPaused})
+	} else {
+		s.emit(lifecycleEvent{Type: EventResumed})
+	}
+}
+
+// terminated reports that the forward is shutting down and why.
+func (s *eventServer) terminated(reason string) {
+	s.emit(lifecycleEvent{Type: EventTerminated, Reason: reason})
+}
+
+// Close stops accepting readers, flushes queued events to connected ones and removes the socket.
+func (s *eventServer) Close() error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.closed = true
+	err := s.listener.Close()
+	for r := range s.readers {
+		r.conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		delete(s.readers, r)
+		close(r.events)
+	}
+	s.mutex.Unlock()
+
+	s.wait.Wait()
+	return err
+}
@@ -0,0 +1,152 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+// dialEvents connects a reader to the event socket and returns a function reading its next event.
+func dialEvents(t *testing.T, path string) (net.Conn, func() lifecycleEvent) {
+	t.Helper()
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to connect to event socket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	scanner := bufio.NewScanner(conn)
+	return conn, func() lifecycleEvent {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if !scanner.Scan() {
+			t.Fatalf("Expected an event, got: %v", scanner.Err())
+		}
+		var e lifecycleEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Invalid event %q: %v", scanner.Text(), err)
+		}
+		return e
+	}
+}
+
+// waitForReaders waits until n readers are attached, since Accept runs asynchronously.
+func waitForReaders(t *testing.T, s *eventServer, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mutex.Lock()
+		attached := len(s.readers)
+		s.mutex.Unlock()
+		if attached == n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Expected %d readers", n)
+}
+
+// WHEN readers are attached to --event-socket, THEN they SHALL receive lifecycle events as NDJSON
+// with the active connection count, a late reader SHALL first get the established event, and the
+// socket SHALL be removed on Close after terminated is delivered.
+func TestEventSocketStreamsLifecycle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.sock")
+	events, err := listenEvents(path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	_, next := dialEvents(t, path)
+	waitForReaders(t, events, 1)
+
+	events.establishedOn(8080, "8080:db:5432")
+	events.connOpened("127.0.0.1:50000")
+	if e := next(); e.Type != EventEstablished || e.Port != 8080 || e.Forwarding != "8080:db:5432" {
+		t.Errorf("Unexpected established event: %+v", e)
+	}
+	if e := next(); e.Type != EventConnectionOpened || e.ActiveConnections != 1 || e.Source != "127.0.0.1:50000" {
+		t.Errorf("Unexpected connection_opened event: %+v", e)
+	}
+
+	_, lateNext := dialEvents(t, path)
+	waitForReaders(t, events, 2)
+	if e := lateNext(); e.Type != EventEstablished || e.ActiveConnections != 1 {
+		t.Errorf("Expected late reader to get established with 1 connection, got: %+v", e)
+	}
+
+	events.reconnect(true)
+	events.connClosed(session.ConnRecord{Source: "127.0.0.1:50000", CloseReason: "client_closed"})
+	events.terminated("signal: interrupt")
+	for _, read := range []func() lifecycleEvent{next, lateNext} {
+		if e := read(); e.Type != EventReconnecting {
+			t.Errorf("Expected reconnecting, got: %+v", e)
+		}
+		if e := read(); e.Type != EventConnectionClosed || e.ActiveConnections != 0 || e.Reason != "client_closed" {
+			t.Errorf("Unexpected connection_closed event: %+v", e)
+		}
+		if e := read(); e.Type != EventTerminated || e.Reason != "signal: interrupt" {
+			t.Errorf("Unexpected terminated event: %+v", e)
+		}
+	}
+
+	if err := events.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected socket to be removed, got: %v", err)
+	}
+}
+
+// WHEN a reader stops reading, THEN emitting SHALL NOT block and the reader SHALL be disconnected.
+func TestEventSocketDropsSlowReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.sock")
+	events, err := listenEvents(path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer events.Close()
+
+	dialEvents(t, path)
+	waitForReaders(t, events, 1)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < eventBacklog*100; i++ {
+			events.connOpened("127.0.0.1:1")
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("emit blocked on a reader that is not reading")
+	}
+	waitForReaders(t, events, 0)
+}
+
+func TestNilEventServerIgnoresEvents(t *testing.T) {
+	var events *eventServer
+	events.establishedOn(1, "1:2")
+	events.connOpened("a")
+	events.connClosed(session.ConnRecord{})
+	events.reconnect(false)
+	events.terminated("done")
+}
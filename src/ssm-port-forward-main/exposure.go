@@ -0,0 +1,62 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// sensitivePorts are remote services often run without authentication, or whose exposure is a
+// common cause of breaches, named in the --allow-public warning.
+var sensitivePorts = map[int]string{
+	22:    "SSH",
+	1433:  "SQL Server",
+	1521:  "Oracle",
+	2375:  "Docker API",
+	3306:  "MySQL",
+	3389:  "RDP",
+	5432:  "PostgreSQL",
+	5984:  "CouchDB",
+	6379:  "Redis",
+	9200:  "Elasticsearch",
+	11211: "Memcached",
+	27017: "MongoDB",
+}
+
+// isLoopbackHost reports whether host only accepts connections from this machine.
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// publicExposure describes what a forward bound to a non-loopback address exposes to the network,
+// or returns "" when it listens on loopback only.
+func publicExposure(config *PortForwardConfig) string {
+	if config.Stdio != "" || isLoopbackHost(config.BindHost) {
+		return ""
+	}
+	exposure := fmt.Sprintf("listening on %s exposes %s:%s to anyone who can reach this host",
+		net.JoinHostPort(config.BindHost, config.LocalPort), config.RemoteHost, config.RemotePort)
+	if port, err := strconv.Atoi(config.RemotePort); err == nil {
+		if service, ok := sensitivePorts[port]; ok {
+			exposure += fmt.Sprintf(" (%s, which may accept connections without authentication)", service)
+		}
+	}
+	return exposure
+}
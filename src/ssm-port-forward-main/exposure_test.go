@@ -0,0 +1,51 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// WHEN the bind host is loopback, THEN publicExposure SHALL report nothing; otherwise it SHALL
+// name the listener, the destination and any well-known sensitive service.
+func TestPublicExposure(t *testing.T) {
+	for _, host := range []string{"localhost", "127.0.0.1", "127.0.0.2", "::1"} {
+		config := &PortForwardConfig{BindHost: host, LocalPort: "5432", RemoteHost: "db", RemotePort: "5432"}
+		if exposure := publicExposure(config); exposure != "" {
+			t.Errorf("Expected no exposure for %s, got %q", host, exposure)
+		}
+	}
+
+	config := &PortForwardConfig{BindHost: "0.0.0.0", LocalPort: "5432", RemoteHost: "db", RemotePort: "5432"}
+	exposure := publicExposure(config)
+	for _, want := range []string{"0.0.0.0:5432", "db:5432", "PostgreSQL"} {
+		if !strings.Contains(exposure, want) {
+			t.Errorf("Expected exposure to mention %q, got %q", want, exposure)
+		}
+	}
+
+	config = &PortForwardConfig{BindHost: "::", LocalPort: "8080", RemoteHost: "web", RemotePort: "80"}
+	if exposure := publicExposure(config); !strings.Contains(exposure, "[::]:8080") || strings.Contains(exposure, "authentication") {
+		t.Errorf("Unexpected exposure for a web port: %q", exposure)
+	}
+}
+
+// WHEN the forward is over stdio, THEN there is no listener and nothing SHALL be exposed.
+func TestPublicExposureStdio(t *testing.T) {
+	config := &PortForwardConfig{Stdio: "db:5432", BindHost: "0.0.0.0", RemoteHost: "db", RemotePort: "5432"}
+	if exposure := publicExposure(config); exposure != "" {
+		t.Errorf("Expected no exposure for stdio, got %q", exposure)
+	}
+}
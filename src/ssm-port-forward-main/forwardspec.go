@@ -0,0 +1,29 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import "github.com/zph/session-manager-plugin/src/forwardspec"
+
+// specDocumentName returns the SSM document that serves spec. A document other than
+// DefaultDocumentName was chosen explicitly and is kept; otherwise, unless auto is false, a
+// remote host needs RemoteHostDocumentName.
+func specDocumentName(spec forwardspec.Spec, requested string, auto bool) string {
+	if requested != DefaultDocumentName || !auto {
+		return requested
+	}
+	if spec.RemoteHost != "localhost" && spec.RemoteHost != "127.0.0.1" {
+		return RemoteHostDocumentName
+	}
+	return DefaultDocumentName
+}
@@ -0,0 +1,54 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/zph/session-manager-plugin/src/forwardspec"
+)
+
+// WHEN the listener is bound to a wildcard address, THEN dialHost SHALL connect through localhost.
+func TestDialHost(t *testing.T) {
+	cases := map[string]string{"": "localhost", "0.0.0.0": "localhost", "::": "localhost", "::1": "::1", "10.0.0.5": "10.0.0.5", "localhost": "localhost"}
+	for bindHost, want := range cases {
+		if got := dialHost(bindHost); got != want {
+			t.Errorf("dialHost(%q) = %q, want %q", bindHost, got, want)
+		}
+	}
+}
+
+// WHEN no document is chosen, THEN each spec SHALL get the document its destination needs, so a
+// localhost spec and a remote host spec resolve independently; an explicit document SHALL be kept.
+func TestForwardSpecDocumentName(t *testing.T) {
+	local, _ := forwardspec.ParseSpec("8080:80")
+	remote, _ := forwardspec.ParseSpec("5432:db.internal:5432")
+	loopback, _ := forwardspec.ParseSpec("9090:127.0.0.1:9090")
+
+	if got := specDocumentName(local, DefaultDocumentName, true); got != DefaultDocumentName {
+		t.Errorf("localhost spec: got %s, want %s", got, DefaultDocumentName)
+	}
+	if got := specDocumentName(loopback, DefaultDocumentName, true); got != DefaultDocumentName {
+		t.Errorf("127.0.0.1 spec: got %s, want %s", got, DefaultDocumentName)
+	}
+	if got := specDocumentName(remote, DefaultDocumentName, true); got != RemoteHostDocumentName {
+		t.Errorf("remote host spec: got %s, want %s", got, RemoteHostDocumentName)
+	}
+	if got := specDocumentName(remote, "Alice-ForwardToRDS", true); got != "Alice-ForwardToRDS" {
+		t.Errorf("explicit document: got %s, want Alice-ForwardToRDS", got)
+	}
+	if got := specDocumentName(remote, DefaultDocumentName, false); got != DefaultDocumentName {
+		t.Errorf("remote host spec without auto-selection: got %s, want %s", got, DefaultDocumentName)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+)
+
+// healthState tracks forward liveness for the /healthz endpoint.
+// It is updated by run and by the session's reconnect hook, and read by the HTTP handler.
+type healthState struct {
+	ready        atomic.Bool // local listener up and session established
+	reconnecting atomic.Bool // data channel is being resumed
+	stopped      atomic.Bool // session torn down
+}
+
+// status returns the current liveness and a short description.
+func (h *healthState) status() (bool, string) {
+	switch {
+	case h.stopped.Load():
+		return false, "stopped"
+	case h.reconnecting.Load():
+		return false, "reconnecting"
+	case !h.ready.Load():
+		return false, "starting"
+	default:
+		return true, "ok"
+	}
+}
+
+// ServeHTTP answers 200 while the forward is up and 503 otherwise.
+func (h *healthState) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ok, status := h.status()
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	fmt.Fprintln(w, status)
+}
+
+// startHealthServer serves /healthz for state on addr until the returned server is closed.
+// The server's Addr holds the bound address, which differs from addr when its port is 0.
+func startHealthServer(addr string, state *healthState) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", state)
+	server := &http.Server{Addr: listener.Addr().String(), Handler: mux}
+	go server.Serve(listener)
+	return server, nil
+}
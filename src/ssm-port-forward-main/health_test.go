@@ -0,0 +1,80 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func healthCode(state *healthState) int {
+	rec := httptest.NewRecorder()
+	state.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	return rec.Code
+}
+
+// WHEN the forward is starting, up, reconnecting or stopped,
+// THEN /healthz SHALL answer 503, 200, 503 and 503 respectively.
+func TestHealthStateTransitions(t *testing.T) {
+	state := &healthState{}
+	if code := healthCode(state); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 while starting, got %d", code)
+	}
+
+	state.ready.Store(true)
+	if code := healthCode(state); code != http.StatusOK {
+		t.Errorf("Expected 200 when ready, got %d", code)
+	}
+
+	state.reconnecting.Store(true)
+	if code := healthCode(state); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 while reconnecting, got %d", code)
+	}
+
+	state.reconnecting.Store(false)
+	if code := healthCode(state); code != http.StatusOK {
+		t.Errorf("Expected 200 after reconnect, got %d", code)
+	}
+
+	state.stopped.Store(true)
+	if code := healthCode(state); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 after teardown, got %d", code)
+	}
+}
+
+func TestStartHealthServer(t *testing.T) {
+	state := &healthState{}
+	state.ready.Store(true)
+
+	server, err := startHealthServer("localhost:0", state)
+	if err != nil {
+		t.Fatalf("Failed to start health server: %v", err)
+	}
+	defer server.Close()
+
+	resp, err := http.Get("http://" + server.Addr + "/healthz")
+	if err != nil {
+		t.Fatalf("Failed to query health endpoint: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	// An address already in use SHALL be reported rather than ignored
+	if _, err := startHealthServer(server.Addr, state); err == nil {
+		t.Fatal("Expected error when the health address is in use")
+	}
+}
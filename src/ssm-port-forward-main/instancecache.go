@@ -0,0 +1,117 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// defaultResolverCacheTTL is how long an ip: or dns: target's resolved instance ID is reused.
+const defaultResolverCacheTTL = 5 * time.Minute
+
+// resolverCacheFile is the cache's name under the user's cache directory.
+const resolverCacheFile = "ssm-port-forward/instances.json"
+
+// cachedInstance is a resolved instance ID and when it was looked up.
+type cachedInstance struct {
+	InstanceID string    `json:"instance_id"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+// instanceCache keeps the instance IDs ip: and dns: targets resolved to on disk, so launching
+// forwards to the same target again skips DescribeInstances until the entry is ttl old. A missing
+// or unreadable cache file counts as empty; the cache only ever saves lookups.
+type instanceCache struct {
+	path string
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// newInstanceCache returns the cache in the user's cache directory, or nil when there is none.
+func newInstanceCache(ttl time.Duration) *instanceCache {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil
+	}
+	return &instanceCache{path: filepath.Join(dir, resolverCacheFile), ttl: ttl, now: time.Now}
+}
+
+// resolverCacheKey identifies a lookup: the same target can be a different instance in another
+// region or account.
+func resolverCacheKey(target, region, profile string) string {
+	return region + "|" + profile + "|" + target
+}
+
+// lookup returns the instance ID cached for key, if it is younger than the TTL. Nil-safe.
+func (c *instanceCache) lookup(key string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	entry, ok := c.read()[key]
+	if !ok || c.now().Sub(entry.ResolvedAt) >= c.ttl {
+		return "", false
+	}
+	return entry.InstanceID, true
+}
+
+// store records instanceID for key, dropping expired entries. Nil-safe.
+func (c *instanceCache) store(key, instanceID string) error {
+	if c == nil {
+		return nil
+	}
+	entries := c.read()
+	entries[key] = cachedInstance{InstanceID: instanceID, ResolvedAt: c.now()}
+	return c.write(entries)
+}
+
+// forget drops key, e.g. once its cached instance turns out to be gone. Nil-safe.
+func (c *instanceCache) forget(key string) error {
+	if c == nil {
+		return nil
+	}
+	entries := c.read()
+	if _, ok := entries[key]; !ok {
+		return nil
+	}
+	delete(entries, key)
+	return c.write(entries)
+}
+
+func (c *instanceCache) read() map[string]cachedInstance {
+	entries := map[string]cachedInstance{}
+	data, err := os.ReadFile(c.path)
+	if err != nil || json.Unmarshal(data, &entries) != nil {
+		return map[string]cachedInstance{}
+	}
+	return entries
+}
+
+func (c *instanceCache) write(entries map[string]cachedInstance) error {
+	for key, entry := range entries {
+		if c.now().Sub(entry.ResolvedAt) >= c.ttl {
+			delete(entries, key)
+		}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path, data, 0600)
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestInstanceCache(t *testing.T, ttl time.Duration) (*instanceCache, *time.Time) {
+	now := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	cache := &instanceCache{
+		path: filepath.Join(t.TempDir(), resolverCacheFile),
+		ttl:  ttl,
+		now:  func() time.Time { return now },
+	}
+	return cache, &now
+}
+
+// WHEN a target was resolved within the TTL, THEN lookup SHALL return its instance; WHEN the TTL
+// has passed, THEN it SHALL miss so the target is looked up again.
+func TestInstanceCacheTTL(t *testing.T) {
+	cache, now := newTestInstanceCache(t, 5*time.Minute)
+	key := resolverCacheKey("ip:10.0.1.23", "us-east-1", "dev")
+
+	if _, ok := cache.lookup(key); ok {
+		t.Fatal("Expected an empty cache to miss")
+	}
+	if err := cache.store(key, "i-abc"); err != nil {
+		t.Fatalf("Failed to store: %v", err)
+	}
+	*now = now.Add(4 * time.Minute)
+	if id, ok := cache.lookup(key); !ok || id != "i-abc" {
+		t.Errorf("Expected a hit for i-abc, got %q, %v", id, ok)
+	}
+	*now = now.Add(time.Minute)
+	if id, ok := cache.lookup(key); ok {
+		t.Errorf("Expected an expired entry to miss, got %q", id)
+	}
+}
+
+// WHEN the same target is looked up in another region or profile, THEN it SHALL NOT share the entry.
+func TestInstanceCacheKey(t *testing.T) {
+	cache, _ := newTestInstanceCache(t, time.Minute)
+	if err := cache.store(resolverCacheKey("dns:bastion.internal", "us-east-1", "dev"), "i-abc"); err != nil {
+		t.Fatalf("Failed to store: %v", err)
+	}
+	for _, key := range []string{
+		resolverCacheKey("dns:bastion.internal", "us-west-2", "dev"),
+		resolverCacheKey("dns:bastion.internal", "us-east-1", "prod"),
+		resolverCacheKey("ip:10.0.1.23", "us-east-1", "dev"),
+	} {
+		if id, ok := cache.lookup(key); ok {
+			t.Errorf("Expected %s to miss, got %q", key, id)
+		}
+	}
+}
+
+// WHEN an entry is forgotten, THEN lookup SHALL miss while other entries are kept, and expired
+// entries SHALL be dropped from the file on the next write.
+func TestInstanceCacheForget(t *testing.T) {
+	cache, now := newTestInstanceCache(t, time.Minute)
+	cache.store("stale", "i-old")
+	*now = now.Add(2 * time.Minute)
+	cache.store("a", "i-a")
+	cache.store("b", "i-b")
+
+	if err := cache.forget("a"); err != nil {
+		t.Fatalf("Failed to forget: %v", err)
+	}
+	if _, ok := cache.lookup("a"); ok {
+		t.Error("Expected a forgotten entry to miss")
+	}
+	if id, ok := cache.lookup("b"); !ok || id != "i-b" {
+		t.Errorf("Expected b to be kept, got %q, %v", id, ok)
+	}
+	if entries := cache.read(); len(entries) != 1 {
+		t.Errorf("Expected only b on disk, got %v", entries)
+	}
+}
+
+// WHEN the cache file is corrupt or the cache is disabled, THEN lookups SHALL miss and stores
+// SHALL not fail the forward.
+func TestInstanceCacheUnusable(t *testing.T) {
+	cache, _ := newTestInstanceCache(t, time.Minute)
+	os.MkdirAll(filepath.Dir(cache.path), 0700)
+	if err := os.WriteFile(cache.path, []byte("{not json"), 0600); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if _, ok := cache.lookup("a"); ok {
+		t.Error("Expected a corrupt cache to miss")
+	}
+	if err := cache.store("a", "i-a"); err != nil {
+		t.Errorf("Expected a corrupt cache to be replaced, got: %v", err)
+	}
+	if id, ok := cache.lookup("a"); !ok || id != "i-a" {
+		t.Errorf("Expected a hit after replacing the cache, got %q, %v", id, ok)
+	}
+
+	var disabled *instanceCache
+	if _, ok := disabled.lookup("a"); ok {
+		t.Error("Expected a disabled cache to miss")
+	}
+	if err := disabled.store("a", "i-a"); err != nil {
+		t.Errorf("Expected a disabled cache to ignore stores, got: %v", err)
+	}
+}
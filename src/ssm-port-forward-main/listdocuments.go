@@ -0,0 +1,167 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// ListDocumentsConfig holds the list-documents options.
+type ListDocumentsConfig struct {
+	awsOptions
+	OutputFormat string // names one per line or json lines, also for errors
+}
+
+// documentInfo is one document in list-documents json output.
+type documentInfo struct {
+	Name    string `json:"name"`
+	Owner   string `json:"owner"`
+	Version string `json:"document_version"`
+}
+
+// listDocumentsFlags are accepted by forward in place of the list-documents subcommand.
+var listDocumentsFlags = []string{"--list-documents", "-list-documents"}
+
+// stripListDocumentsFlag reports whether args contain --list-documents and returns them without it.
+func stripListDocumentsFlag(args []string) ([]string, bool) {
+	rest := slices.DeleteFunc(slices.Clone(args), func(arg string) bool {
+		return slices.Contains(listDocumentsFlags, arg)
+	})
+	return rest, len(rest) != len(args)
+}
+
+// runListDocuments runs the list-documents command and returns the process exit status.
+func runListDocuments(args []string) int {
+	config, err := parseListDocumentsArgs(args)
+	if err != nil {
+		writeError(os.Stderr, config.OutputFormat, stageError(StageParseArgs, CodeInvalidArgs, err))
+		if config.OutputFormat != OutputFormatJSON {
+			printListDocumentsUsage()
+		}
+		return ExitInvalidArgs
+	}
+
+	client, err := config.ssmClient()
+	if err != nil {
+		writeError(os.Stderr, config.OutputFormat, err)
+		return exitCode(err)
+	}
+
+	documents, err := listSessionDocuments(client)
+	if err != nil {
+		err = stageError(StageListDocuments, CodeSessionError, fmt.Errorf("failed to list documents: %w", err))
+		writeError(os.Stderr, config.OutputFormat, err)
+		return exitCode(err)
+	}
+	if err := printDocuments(os.Stdout, config.OutputFormat, documents); err != nil {
+		err = stageError(StageWriteOutput, CodeInternal, fmt.Errorf("failed to write documents: %w", err))
+		writeError(os.Stderr, config.OutputFormat, err)
+		return exitCode(err)
+	}
+	return 0
+}
+
+func parseListDocumentsArgs(args []string) (*ListDocumentsConfig, error) {
+	config := &ListDocumentsConfig{}
+
+	flags := newCommandFlags(CommandListDocuments)
+	config.awsOptions.register(flags)
+	flags.StringVar(&config.OutputFormat, "output-format", OutputFormatText, "Output format: text or json")
+
+	if err := parseCommandFlags(flags, args, &config.OutputFormat, printListDocumentsUsage); err != nil {
+		return config, err
+	}
+	if flags.NArg() > 0 {
+		return config, fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+	return config, nil
+}
+
+// listSessionDocuments returns every Session Manager document visible to the caller, including
+// AWS-owned ones such as AWS-StartPortForwardingSessionToRemoteHost.
+func listSessionDocuments(client ssmiface.SSMAPI) ([]*ssm.DocumentIdentifier, error) {
+	input := &ssm.ListDocumentsInput{
+		Filters: []*ssm.DocumentKeyValuesFilter{{
+			Key:    aws.String("DocumentType"),
+			Values: []*string{aws.String(ssm.DocumentTypeSession)},
+		}},
+	}
+
+	var documents []*ssm.DocumentIdentifier
+	err := client.ListDocumentsPages(input, func(page *ssm.ListDocumentsOutput, lastPage bool) bool {
+		documents = append(documents, page.DocumentIdentifiers...)
+		return true
+	})
+	return documents, err
+}
+
+// printDocuments writes one document name per line, so the output can feed shell completion, or
+// one JSON object per line for json.
+func printDocuments(w io.Writer, format string, documents []*ssm.DocumentIdentifier) error {
+	if format == OutputFormatJSON {
+		encoder := json.NewEncoder(w)
+		for _, d := range documents {
+			info := documentInfo{
+				Name:    aws.StringValue(d.Name),
+				Owner:   aws.StringValue(d.Owner),
+				Version: aws.StringValue(d.DocumentVersion),
+			}
+			if err := encoder.Encode(info); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, d := range documents {
+		if _, err := fmt.Fprintln(w, aws.StringValue(d.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printListDocumentsUsage() {
+	fmt.Fprintf(os.Stderr, `Usage: ssm-port-forward list-documents [OPTIONS]
+       ssm-port-forward --list-documents [OPTIONS]
+
+List the Session Manager documents in the account, one name per line, to find
+custom forwarding documents for --document-name.
+
+Options:
+  -r, --region           AWS region
+  -p, --profile          AWS profile
+      --sso-login        Run "aws sso login" when the SSO token is missing or expired
+      --signing-region   Region to sign SSM API requests for
+      --signing-name     Service name to sign SSM API requests for (default: ssm)
+      --output-format    text (names only, default) or json (one object per line
+                         with name, owner and document_version); also applies to
+                         errors on stderr
+
+Examples:
+  # Session documents in a region
+  ssm-port-forward list-documents -r us-east-1
+
+  # Only documents owned by this account
+  ssm-port-forward list-documents -r us-east-1 --output-format json | jq -r 'select(.owner != "Amazon") | .name'
+`)
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+type fakeDocumentLister struct {
+	ssmiface.SSMAPI
+	pages [][]*ssm.DocumentIdentifier
+	input *ssm.ListDocumentsInput
+}
+
+func (f *fakeDocumentLister) ListDocumentsPages(input *ssm.ListDocumentsInput, fn func(*ssm.ListDocumentsOutput, bool) bool) error {
+	f.input = input
+	for i, page := range f.pages {
+		if !fn(&ssm.ListDocumentsOutput{DocumentIdentifiers: page}, i == len(f.pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// WHEN documents span several pages, THEN listSessionDocuments SHALL return all of them and only
+// ask for Session documents.
+func TestListSessionDocuments(t *testing.T) {
+	client := &fakeDocumentLister{pages: [][]*ssm.DocumentIdentifier{
+		{{Name: aws.String("AWS-StartPortForwardingSession")}},
+		{{Name: aws.String("Alice-ForwardToRDS")}},
+	}}
+
+	documents, err := listSessionDocuments(client)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(documents) != 2 {
+		t.Errorf("Expected 2 documents, got %d", len(documents))
+	}
+	filters := client.input.Filters
+	if len(filters) != 1 || aws.StringValue(filters[0].Key) != "DocumentType" ||
+		len(filters[0].Values) != 1 || aws.StringValue(filters[0].Values[0]) != ssm.DocumentTypeSession {
+		t.Errorf("Expected a DocumentType=Session filter, got %v", filters)
+	}
+}
+
+// WHEN documents are printed, THEN text SHALL be bare names one per line and json one object per line.
+func TestPrintDocuments(t *testing.T) {
+	documents := []*ssm.DocumentIdentifier{
+		{Name: aws.String("AWS-StartPortForwardingSession"), Owner: aws.String("Amazon"), DocumentVersion: aws.String("1")},
+		{Name: aws.String("Alice-ForwardToRDS"), Owner: aws.String("123456789012"), DocumentVersion: aws.String("3")},
+	}
+
+	var text bytes.Buffer
+	if err := printDocuments(&text, OutputFormatText, documents); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := text.String(); got != "AWS-StartPortForwardingSession\nAlice-ForwardToRDS\n" {
+		t.Errorf("Unexpected text output: %q", got)
+	}
+
+	var out bytes.Buffer
+	if err := printDocuments(&out, OutputFormatJSON, documents[1:]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var info documentInfo
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", out.String(), err)
+	}
+	if info != (documentInfo{Name: "Alice-ForwardToRDS", Owner: "123456789012", Version: "3"}) {
+		t.Errorf("Unexpected document info: %+v", info)
+	}
+}
+
+// WHEN --list-documents appears among forward flags, THEN it SHALL be removed and reported so
+// the remaining flags go to list-documents.
+func TestStripListDocumentsFlag(t *testing.T) {
+	args := []string{"-r", "us-east-1", "--list-documents", "--output-format", "json"}
+	rest, ok := stripListDocumentsFlag(args)
+	if !ok {
+		t.Fatal("Expected --list-documents to be found")
+	}
+	if !slices.Equal(rest, []string{"-r", "us-east-1", "--output-format", "json"}) {
+		t.Errorf("Unexpected remaining args: %v", rest)
+	}
+	if len(args) != 5 {
+		t.Errorf("Expected the input args to be left alone, got %v", args)
+	}
+
+	if _, ok := stripListDocumentsFlag([]string{"-L", "8080:80", "-i", "i-bastion"}); ok {
+		t.Error("Expected no --list-documents in forward args")
+	}
+}
+
+// WHEN list-documents is given positional arguments, THEN parsing SHALL fail.
+func TestParseListDocumentsArgs(t *testing.T) {
+	config, err := parseListDocumentsArgs([]string{"-r", "us-east-1", "--output-format", "json"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Region != "us-east-1" || config.OutputFormat != OutputFormatJSON {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+	if _, err := parseListDocumentsArgs([]string{"extra"}); err == nil {
+		t.Error("Expected an error for positional arguments")
+	}
+}
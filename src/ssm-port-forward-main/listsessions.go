@@ -0,0 +1,173 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// ListSessionsConfig holds the list-sessions options.
+type ListSessionsConfig struct {
+	awsOptions
+	State        string // Active or History
+	Target       string // only sessions to this target when set
+	OutputFormat string // text table or json lines, also for errors
+}
+
+// sessionInfo is one session in list-sessions json output.
+type sessionInfo struct {
+	SessionID string `json:"session_id"`
+	Target    string `json:"target"`
+	Status    string `json:"status"`
+	StartDate string `json:"start_date"`
+	Owner     string `json:"owner"`
+	Document  string `json:"document"`
+}
+
+// runListSessions runs the list-sessions command and returns the process exit status.
+func runListSessions(args []string) int {
+	config, err := parseListSessionsArgs(args)
+	if err != nil {
+		writeError(os.Stderr, config.OutputFormat, stageError(StageParseArgs, CodeInvalidArgs, err))
+		if config.OutputFormat != OutputFormatJSON {
+			printListSessionsUsage()
+		}
+		return ExitInvalidArgs
+	}
+
+	client, err := config.ssmClient()
+	if err != nil {
+		writeError(os.Stderr, config.OutputFormat, err)
+		return exitCode(err)
+	}
+
+	sessions, err := listSessions(client, config.State, config.Target)
+	if err != nil {
+		err = stageError(StageListSessions, CodeSessionError, fmt.Errorf("failed to describe sessions: %w", err))
+		writeError(os.Stderr, config.OutputFormat, err)
+		return exitCode(err)
+	}
+	if err := printSessions(os.Stdout, config.OutputFormat, sessions); err != nil {
+		err = stageError(StageWriteOutput, CodeInternal, fmt.Errorf("failed to write sessions: %w", err))
+		writeError(os.Stderr, config.OutputFormat, err)
+		return exitCode(err)
+	}
+	return 0
+}
+
+func parseListSessionsArgs(args []string) (*ListSessionsConfig, error) {
+	config := &ListSessionsConfig{}
+
+	flags := newCommandFlags(CommandListSessions)
+	config.awsOptions.register(flags)
+	flags.StringVar(&config.State, "state", ssm.SessionStateActive, "Sessions to list: Active or History")
+	flags.StringVar(&config.Target, "target", "", "Only list sessions to this instance ID")
+	flags.StringVar(&config.OutputFormat, "output-format", OutputFormatText, "Output format: text or json")
+
+	if err := parseCommandFlags(flags, args, &config.OutputFormat, printListSessionsUsage); err != nil {
+		return config, err
+	}
+	if flags.NArg() > 0 {
+		return config, fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+	if config.State != ssm.SessionStateActive && config.State != ssm.SessionStateHistory {
+		return config, fmt.Errorf("invalid state: %s (expected %s or %s)", config.State, ssm.SessionStateActive, ssm.SessionStateHistory)
+	}
+	return config, nil
+}
+
+// listSessions returns every session in state, optionally only those to target.
+func listSessions(client ssmiface.SSMAPI, state string, target string) ([]*ssm.Session, error) {
+	input := &ssm.DescribeSessionsInput{State: aws.String(state)}
+	if target != "" {
+		input.Filters = []*ssm.SessionFilter{{Key: aws.String(ssm.SessionFilterKeyTarget), Value: aws.String(target)}}
+	}
+
+	var sessions []*ssm.Session
+	err := client.DescribeSessionsPages(input, func(page *ssm.DescribeSessionsOutput, lastPage bool) bool {
+		sessions = append(sessions, page.Sessions...)
+		return true
+	})
+	return sessions, err
+}
+
+// printSessions writes sessions as an aligned table, or one JSON object per line for json.
+func printSessions(w io.Writer, format string, sessions []*ssm.Session) error {
+	if format == OutputFormatJSON {
+		encoder := json.NewEncoder(w)
+		for _, s := range sessions {
+			if err := encoder.Encode(newSessionInfo(s)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "SESSION ID\tTARGET\tSTATUS\tSTART TIME")
+	for _, s := range sessions {
+		info := newSessionInfo(s)
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", info.SessionID, info.Target, info.Status, info.StartDate)
+	}
+	return table.Flush()
+}
+
+func newSessionInfo(s *ssm.Session) sessionInfo {
+	info := sessionInfo{
+		SessionID: aws.StringValue(s.SessionId),
+		Target:    aws.StringValue(s.Target),
+		Status:    aws.StringValue(s.Status),
+		Owner:     aws.StringValue(s.Owner),
+		Document:  aws.StringValue(s.DocumentName),
+	}
+	if s.StartDate != nil {
+		info.StartDate = s.StartDate.UTC().Format(time.RFC3339)
+	}
+	return info
+}
+
+func printListSessionsUsage() {
+	fmt.Fprintf(os.Stderr, `Usage: ssm-port-forward list-sessions [OPTIONS]
+
+List SSM sessions with their ID, target, status and start time.
+
+Options:
+  -r, --region           AWS region
+  -p, --profile          AWS profile
+      --sso-login        Run "aws sso login" when the SSO token is missing or expired
+      --signing-region   Region to sign SSM API requests for
+      --signing-name     Service name to sign SSM API requests for (default: ssm)
+      --state            Active (default) or History
+      --target           Only list sessions to this instance ID
+      --output-format    text (an aligned table, default) or json (one object per
+                         line with session_id, target, status, start_date, owner
+                         and document); also applies to errors on stderr
+
+Examples:
+  # Active sessions in a region
+  ssm-port-forward list-sessions -r us-east-1
+
+  # Sessions to one bastion as JSON
+  ssm-port-forward list-sessions -r us-east-1 --target i-bastion123 --output-format json
+`)
+}
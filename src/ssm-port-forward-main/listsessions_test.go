@@ -0,0 +1,124 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+type fakeSessionLister struct {
+	ssmiface.SSMAPI
+	pages [][]*ssm.Session
+	input *ssm.DescribeSessionsInput
+}
+
+func (f *fakeSessionLister) DescribeSessionsPages(input *ssm.DescribeSessionsInput, fn func(*ssm.DescribeSessionsOutput, bool) bool) error {
+	f.input = input
+	for i, page := range f.pages {
+		if !fn(&ssm.DescribeSessionsOutput{Sessions: page}, i == len(f.pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// WHEN sessions span several pages, THEN listSessions SHALL return all of them and filter by
+// target when one is given.
+func TestListSessions(t *testing.T) {
+	client := &fakeSessionLister{pages: [][]*ssm.Session{
+		{{SessionId: aws.String("alice-1")}},
+		{{SessionId: aws.String("alice-2")}},
+	}}
+
+	sessions, err := listSessions(client, ssm.SessionStateActive, "i-bastion")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Errorf("Expected 2 sessions, got %d", len(sessions))
+	}
+	if aws.StringValue(client.input.State) != ssm.SessionStateActive {
+		t.Errorf("Expected state Active, got %v", client.input.State)
+	}
+	if len(client.input.Filters) != 1 || aws.StringValue(client.input.Filters[0].Value) != "i-bastion" {
+		t.Errorf("Expected a target filter, got %v", client.input.Filters)
+	}
+
+	listSessions(client, ssm.SessionStateHistory, "")
+	if len(client.input.Filters) != 0 {
+		t.Errorf("Expected no filters without a target, got %v", client.input.Filters)
+	}
+}
+
+// WHEN sessions are printed, THEN text SHALL be a table with a header and json one object per line.
+func TestPrintSessions(t *testing.T) {
+	start := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	sessions := []*ssm.Session{{
+		SessionId: aws.String("alice-0123"),
+		Target:    aws.String("i-bastion"),
+		Status:    aws.String(ssm.SessionStatusConnected),
+		StartDate: &start,
+	}}
+
+	var text bytes.Buffer
+	if err := printSessions(&text, OutputFormatText, sessions); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(text.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "SESSION ID") {
+		t.Fatalf("Expected a header and one row, got:\n%s", text.String())
+	}
+	for _, want := range []string{"alice-0123", "i-bastion", "Connected", "2025-03-04T05:06:07Z"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("Expected %q in row %q", want, lines[1])
+		}
+	}
+
+	var out bytes.Buffer
+	if err := printSessions(&out, OutputFormatJSON, sessions); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var info sessionInfo
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", out.String(), err)
+	}
+	if info.SessionID != "alice-0123" || info.Status != "Connected" || info.StartDate != "2025-03-04T05:06:07Z" {
+		t.Errorf("Unexpected session info: %+v", info)
+	}
+}
+
+// WHEN list-sessions options are parsed, THEN an unknown state or extra arguments SHALL be rejected.
+func TestParseListSessionsArgs(t *testing.T) {
+	config, err := parseListSessionsArgs([]string{"-r", "us-east-1", "--target", "i-bastion"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Region != "us-east-1" || config.Target != "i-bastion" || config.State != ssm.SessionStateActive {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+
+	for _, args := range [][]string{{"--state", "Pending"}, {"extra"}, {"--output-format", "yaml"}, {"--unknown"}} {
+		if _, err := parseListSessionsArgs(args); err == nil {
+			t.Errorf("Expected %v to be rejected", args)
+		}
+	}
+}
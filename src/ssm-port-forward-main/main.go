@@ -16,30 +16,58 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/google/uuid"
+	"github.com/zph/session-manager-plugin/src/communicator"
+	smconfig "github.com/zph/session-manager-plugin/src/config"
 	"github.com/zph/session-manager-plugin/src/datachannel"
+	"github.com/zph/session-manager-plugin/src/forwardspec"
 	"github.com/zph/session-manager-plugin/src/log"
 	"github.com/zph/session-manager-plugin/src/profile"
 	"github.com/zph/session-manager-plugin/src/sdkutil"
 	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
-	_ "github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session/portsession"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session/portsession"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session/sessionutil"
 )
 
 const (
-	DefaultDocumentName = "AWS-StartPortForwardingSession"
+	DefaultDocumentName    = "AWS-StartPortForwardingSession"
+	RemoteHostDocumentName = "AWS-StartPortForwardingSessionToRemoteHost"
+)
+
+// --on-interrupt modes: terminate tears the forward down on SIGINT, detach ignores SIGINT so
+// only SIGTERM or SIGHUP stop it.
+const (
+	InterruptTerminate = "terminate"
+	InterruptDetach    = "detach"
 )
 
 var (
@@ -52,16 +80,164 @@ var (
 )
 
 type PortForwardConfig struct {
+	Protocol     string // Local listener protocol: tcp or udp
+	BindHost     string // Local listener address (default: localhost)
+	AllowPublic  bool   // Permit a non-loopback BindHost
+	Network      string // Listener address family: tcp4, tcp6 or tcp (default: tcp4 for localhost)
 	LocalPort    string
 	RemoteHost   string // Target host from bastion (default: localhost)
 	RemotePort   string
 	InstanceID   string
+	ASG          string // Auto Scaling group to pick InstanceID from at start
 	Region       string
 	Profile      string
 	DocumentName string
 	OutputFile   string
 	Wait         bool
-	Timeout      time.Duration
+	// NoAutoDocument keeps DocumentName for remote hosts instead of switching to RemoteHostDocumentName
+	NoAutoDocument bool
+	// ResolverCacheTTL is how long an ip: or dns: InstanceID's resolved instance is reused across runs
+	ResolverCacheTTL time.Duration
+	// NoCache looks up ip: and dns: targets without the on-disk cache
+	NoCache bool
+	// PortIncrement tries up to this many ports after a busy LocalPort and uses the first free one
+	PortIncrement int
+	// Parameters is a JSON object of extra document parameters for StartSession
+	Parameters string
+	// DocumentParameters holds Parameters once parsed
+	DocumentParameters map[string][]*string
+	// WaitForRemote retries the probe through the tunnel until the remote accepts connections
+	WaitForRemote bool
+	Timeout       time.Duration
+	// WaitPollInterval is the first delay between --wait checks of the local port, which back off
+	// from there to maxPollInterval
+	WaitPollInterval time.Duration
+	// MaxConnections caps concurrently accepted local connections (0 = unlimited)
+	MaxConnections int
+	// AcceptConcurrency caps local connections setting up their tunnel stream at once (0 = unlimited)
+	AcceptConcurrency int
+	// RateLimit caps forwarded bytes per second in each direction (0 = unlimited)
+	RateLimit int64
+	// ListenBacklog sets the local listener's accept backlog (0 = OS maximum)
+	ListenBacklog int
+	// ReuseAddr and ReusePort set SO_REUSEADDR and SO_REUSEPORT on the local socket
+	ReuseAddr bool
+	ReusePort bool
+	// BufferSize sizes the local connection copy buffers in bytes (0 = default)
+	BufferSize int
+	// BufferHighWater warns when more data channel messages than this await acknowledgement (0 = never)
+	BufferHighWater int
+	// Probe is the optional end-to-end check run after the local port is up
+	Probe ProbeConfig
+	// SelfTest, when set, measures throughput and latency against the remote's echo or discard
+	// service once the forward is up, reports them and exits
+	SelfTest string
+	// SelfTestBytes is how much data the self-test sends through the tunnel
+	SelfTestBytes int64
+	// OutputFormat selects text or json error reporting on stderr
+	OutputFormat string
+	// ConnectTimeout bounds opening the mux stream for each accepted connection (0 = no limit)
+	ConnectTimeout time.Duration
+	// DialTimeout bounds connecting to the session's stream URL (0 = the websocket default of 45s)
+	DialTimeout time.Duration
+	// KeepaliveInterval sends application-level traffic over the data channel this often (0 = off)
+	KeepaliveInterval time.Duration
+	// TransferLogInterval logs each connection's bytes sent and received at debug level this often (0 = off)
+	TransferLogInterval time.Duration
+	// MuxIdleTimeout closes multiplexed connections that move no data for this long (0 = never)
+	MuxIdleTimeout time.Duration
+	// IOTimeout closes a multiplexed connection once a write to either side stalls this long (0 = never)
+	IOTimeout time.Duration
+	// DrainTimeout lets open connections finish after SIGINT (0 = cut immediately)
+	DrainTimeout time.Duration
+	// ReconnectOn lists the error classes the data channel is resumed after (session.ReconnectClasses)
+	ReconnectOn []string
+	// OnInterrupt is InterruptTerminate or InterruptDetach
+	OnInterrupt string
+	// OnRemoteClose is RemoteCloseExit or RemoteCloseKeep
+	OnRemoteClose string
+	// Label tags every log line of this forward (default derived from the spec)
+	Label string
+	// PortFD, when positive, receives just the local port number once the forward is up
+	PortFD int
+	// ReadyMarker is printed with the local port on its own stdout line once --wait succeeds
+	ReadyMarker string
+	// SessionJSON reads a pre-started session response from this path ("-" = stdin)
+	// instead of calling StartSession
+	SessionJSON string
+	// DryRun runs every check up to StartSession, reports what would be started and exits
+	DryRun bool
+	// PrintCommand prints the equivalent "aws ssm start-session" command to stderr before starting
+	PrintCommand bool
+	// HealthAddr serves /healthz reporting forward liveness when set
+	HealthAddr string
+	// SSOLogin runs "aws sso login" when the profile's SSO token is missing or expired
+	SSOLogin bool
+	// OTelEndpoint exports session lifecycle spans over OTLP/HTTP when set
+	OTelEndpoint string
+	// StatsdAddr sends connection, byte and reconnect metrics to this statsd endpoint when set
+	StatsdAddr string
+	// StatsdTags are dogstatsd key:value tags, comma-separated, added to every statsd metric
+	StatsdTags string
+	// SigningRegion and SigningName override how SSM API requests are signed (empty = resolved)
+	SigningRegion string
+	SigningName   string
+	// ClientID identifies this client to the data channel (default: random UUID)
+	ClientID string
+	// Quiet suppresses all logging below error level
+	Quiet bool
+	// TraceWS logs each step of starting the session and opening its websocket at trace level
+	TraceWS bool
+	// LogJSON logs the forward label as a JSON "context" field instead of a message prefix
+	LogJSON bool
+	// JSONLogsTo appends a JSON copy of every log entry to this file, logging human-readable lines to stderr
+	JSONLogsTo string
+	// ConnLog appends a JSON record per closed local connection to this file when set
+	ConnLog string
+	// ConnLogSample logs and records only one in every ConnLogSample connections (1 = all)
+	ConnLogSample int
+	// LocalTLSCert and LocalTLSKey terminate TLS on the local listener when both are set
+	LocalTLSCert string
+	LocalTLSKey  string
+	// RemoteTLS originates TLS to the remote through the tunnel, presenting plaintext locally
+	RemoteTLS bool
+	// RemoteTLSServerName overrides the SNI and verified name (default: the remote host)
+	RemoteTLSServerName string
+	// RemoteTLSCA is a PEM CA bundle to verify the remote against instead of the system roots
+	RemoteTLSCA string
+	// RemoteTLSInsecure skips verification of the remote's certificate
+	RemoteTLSInsecure bool
+	// ProxyProtocol sends a PROXY protocol v1 or v2 header with the client's address on each connection
+	ProxyProtocol string
+	// ReadOnly discards bytes from local clients so only the remote's data flows
+	ReadOnly bool
+	// ResetOnRemoteFailure resets local connections the remote closes without data instead of closing them
+	ResetOnRemoteFailure bool
+	// StartRetries is how many times a throttled or transiently failing StartSession is retried
+	StartRetries int
+	// StartRetryMaxDelay caps the backoff between StartSession retries
+	StartRetryMaxDelay time.Duration
+	// Policy is a JSON file restricting which remote hosts and ports may be forwarded to
+	Policy string
+	// EventSocket is a Unix socket path streaming NDJSON lifecycle events to connected readers
+	EventSocket string
+	// AuditLogGroup is a CloudWatch Logs group that gets a record of who started the forward, to
+	// what, and when it stopped
+	AuditLogGroup string
+	// AuditRequired fails the forward when the audit log can't be set up or the start record
+	// can't be written, instead of only logging a warning
+	AuditRequired bool
+	// ResolveLocally resolves RemoteHost on the client and sends the agent the address instead
+	ResolveLocally bool
+	// Resolver is the DNS server (ip[:port]) used by ResolveLocally (default: the system resolver)
+	Resolver string
+	// Stdio forwards stdin and stdout to this host:port instead of listening locally,
+	// e.g. as an SSH ProxyCommand
+	Stdio string
+	// Summary writes a final line with session duration and bytes transferred on shutdown
+	Summary bool
+	// serviceStop is closed when the Windows service is asked to stop or the system shuts down
+	serviceStop <-chan struct{}
 }
 
 type OutputInfo struct {
@@ -72,117 +248,888 @@ type OutputInfo struct {
 	Timestamp  string `json:"timestamp"`
 	Forwarding string `json:"forwarding"`
 	Bastion    string `json:"bastion"`
+	Format     string `json:"format"`
+	ClientID   string `json:"client_id"`
+	// EstablishMs is how long the forward took to become ready, when waited for
+	EstablishMs int64 `json:"establish_ms,omitempty"`
+	// LocalAddress is the listener's host:port, e.g. 127.0.0.1:12345 or [::1]:12345
+	LocalAddress string `json:"local_address,omitempty"`
+	// LocalDialAddress is the loopback host:port to connect to when LocalAddress is a wildcard bind
+	LocalDialAddress string `json:"local_dial_address,omitempty"`
+	// ForwardingType is the implementation picked for the agent: mux, udp, basic (one
+	// connection at a time) or standard-stream. Empty when output is written before it is known
+	ForwardingType string `json:"forwarding_type,omitempty"`
+}
+
+// SummaryInfo is written on shutdown when --summary is set.
+type SummaryInfo struct {
+	Type             string  `json:"type"`
+	ClientID         string  `json:"client_id"`
+	DurationSeconds  float64 `json:"duration_seconds"`
+	BytesSent        int64   `json:"bytes_sent"`
+	BytesReceived    int64   `json:"bytes_received"`
+	BytesTransferred int64   `json:"bytes_transferred"`
 }
 
 func main() {
-	config, err := parseArgs()
+	// A leading subcommand selects the command; bare flags run forward, as before subcommands
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case CommandForward:
+			args = args[1:]
+		case CommandListSessions:
+			os.Exit(runListSessions(args[1:]))
+		case CommandListDocuments:
+			os.Exit(runListDocuments(args[1:]))
+		case CommandTerminate:
+			os.Exit(runTerminate(args[1:]))
+		}
+	}
+
+	if rest, ok := stripListDocumentsFlag(args); ok {
+		os.Exit(runListDocuments(rest))
+	}
+
+	config, err := parseArgs(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		printUsage()
-		os.Exit(1)
+		// parseArgs returns the partially parsed config so errors honour --output-format
+		writeError(os.Stderr, config.OutputFormat, stageError(StageParseArgs, CodeInvalidArgs, err))
+		if config.OutputFormat != OutputFormatJSON {
+			printUsage()
+		}
+		os.Exit(ExitInvalidArgs)
 	}
 
+	// Under the Windows service control manager the forward runs until the service is stopped
+	run := run
+	if runningAsService() {
+		run = runAsService
+	}
 	if err := run(config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		writeError(os.Stderr, config.OutputFormat, err)
+		os.Exit(exitCode(err))
 	}
 }
 
-func parseArgs() (*PortForwardConfig, error) {
+func parseArgs(args []string) (*PortForwardConfig, error) {
 	config := &PortForwardConfig{}
 
-	var localForward string
-	flag.StringVar(&localForward, "L", "", "Local port forward specification (localPort:[remoteHost:]remotePort)")
-	flag.StringVar(&config.InstanceID, "instance-id", "", "EC2 instance ID (bastion host)")
+	var specs forwardSpecs
+	var reconnectOn string
+	var connLogSample string
+	flag.Var(&specs, "L", "Local port forward specification (localPort:[remoteHost:]remotePort)")
+	flag.StringVar(&config.Protocol, "protocol", "tcp", "Local listener protocol: tcp or udp")
+	flag.StringVar(&config.Network, "network", "", "Local listener address family: tcp4, tcp6 or tcp for both (default: tcp4 for localhost, tcp otherwise)")
+	flag.StringVar(&config.InstanceID, "instance-id", "", "EC2 instance ID (bastion host), or ip:ADDRESS or dns:NAME")
 	flag.StringVar(&config.InstanceID, "i", "", "EC2 instance ID (short form)")
+	flag.StringVar(&config.ASG, "asg", "", "Auto Scaling group to pick a healthy bastion from")
+	flag.DurationVar(&config.ResolverCacheTTL, "instance-resolver-cache", defaultResolverCacheTTL, "How long to reuse the instance an ip: or dns: target resolved to (0 disables the cache)")
+	flag.BoolVar(&config.NoCache, "no-cache", false, "Look up ip: and dns: targets without the on-disk cache")
 	flag.StringVar(&config.Region, "region", "", "AWS region")
 	flag.StringVar(&config.Region, "r", "", "AWS region (short form)")
 	flag.StringVar(&config.Profile, "profile", "", "AWS profile")
 	flag.StringVar(&config.Profile, "p", "", "AWS profile (short form)")
 	flag.StringVar(&config.DocumentName, "document-name", DefaultDocumentName, "SSM document name")
 	flag.StringVar(&config.DocumentName, "d", DefaultDocumentName, "SSM document name (short form)")
+	flag.BoolVar(&config.NoAutoDocument, "no-auto-document", false, "Always use --document-name, even for a remote host")
+	flag.StringVar(&config.Parameters, "parameters", "", "Extra document parameters as JSON, e.g. '{\"auditTag\":[\"ops\"]}'")
 	flag.StringVar(&config.OutputFile, "output", "", "Output file for port/PID info (default: stdout)")
 	flag.StringVar(&config.OutputFile, "o", "", "Output file for port/PID info (short form)")
+	flag.BoolVar(&config.SSOLogin, "sso-login", false, "Run aws sso login if the profile's SSO session has expired")
+	flag.StringVar(&config.OTelEndpoint, "otel-endpoint", "", "Export session lifecycle spans to this OTLP/HTTP endpoint")
+	flag.StringVar(&config.StatsdAddr, "statsd-addr", "", "Send connection metrics to this statsd endpoint (host:port)")
+	flag.StringVar(&config.StatsdTags, "statsd-tags", "", "Comma-separated dogstatsd key:value tags for statsd metrics")
+	flag.StringVar(&config.SigningRegion, "signing-region", "", "Region to sign SSM API requests for")
+	flag.StringVar(&config.SigningName, "signing-name", "", "Service name to sign SSM API requests for")
+	flag.BoolVar(&config.AllowPublic, "allow-public", false, "Allow listening on a non-loopback bind address")
 	flag.BoolVar(&config.Wait, "wait", false, "Wait for port forward to be established before exiting")
 	flag.BoolVar(&config.Wait, "w", false, "Wait for port forward to be established (short form)")
+	flag.StringVar(&config.ClientID, "client-id", "", "Client ID for the session, for correlation with CloudTrail (default: random UUID)")
+	flag.BoolVar(&config.Quiet, "quiet", false, "Suppress all logging except errors")
+	flag.BoolVar(&config.Quiet, "q", false, "Suppress all logging except errors (short form)")
+	flag.BoolVar(&config.TraceWS, "trace-ws", false, "Log each step of the websocket handshake at trace level")
+	flag.BoolVar(&config.LogJSON, "log-json", false, "Log context as a JSON field instead of a message prefix")
+	flag.StringVar(&config.JSONLogsTo, "json-logs-to", "", "Append JSON logs to this file while stderr gets human-readable logs")
+	flag.BoolVar(&config.WaitForRemote, "wait-for-remote", false, "Retry a probe through the tunnel until the remote accepts connections (implies --wait)")
 	flag.DurationVar(&config.Timeout, "timeout", 30*time.Second, "Timeout for port forward validation")
+	flag.DurationVar(&config.WaitPollInterval, "wait-poll-interval", defaultPollInterval, "First delay between --wait checks of the local port; later checks back off")
+	flag.IntVar(&config.MaxConnections, "max-connections", 0, "Maximum concurrent local connections (0 = unlimited)")
+	flag.IntVar(&config.AcceptConcurrency, "accept-concurrency", 0, "Maximum local connections setting up their tunnel stream at once (0 = unlimited)")
+	flag.IntVar(&config.BufferSize, "buffer-size", 0, fmt.Sprintf("Copy buffer size in bytes (%d-%d, 0 = default)",
+		smconfig.MinCopyBufferSize, smconfig.MaxCopyBufferSize))
+	flag.IntVar(&config.BufferHighWater, "buffer-high-water", smconfig.OutgoingMessageBufferCapacity/2,
+		"Warn when more than this many sent messages await acknowledgement (0 = never)")
+	flag.IntVar(&config.ListenBacklog, "listen-backlog", 0, "Accept backlog for the local listener (0 = OS maximum)")
+	flag.IntVar(&config.PortIncrement, "port-increment", 0, "Try up to this many following local ports when the local port is in use")
+	flag.BoolVar(&config.ReuseAddr, "reuse-addr", false, "Set SO_REUSEADDR on the local socket")
+	flag.BoolVar(&config.ReusePort, "reuse-port", false, "Set SO_REUSEPORT on the local socket so a restart can bind a port still in use")
+	flag.Int64Var(&config.RateLimit, "rate-limit", 0, "Maximum bytes per second in each direction (0 = unlimited)")
+	flag.StringVar(&config.Probe.Mode, "probe", ProbeNone, "End-to-end readiness probe: tcp, http or tls (implies --wait)")
+	flag.StringVar(&config.Probe.HTTPPath, "probe-path", "/", "Request path for --probe http")
+	flag.IntVar(&config.Probe.HTTPStatus, "probe-status", 200, "Expected status code for --probe http")
+	flag.StringVar(&config.SelfTest, "selftest", SelfTestNone, "Measure the tunnel against the remote's echo or discard service, report and exit")
+	flag.Int64Var(&config.SelfTestBytes, "selftest-bytes", 16<<20, "Bytes sent through the tunnel by --selftest")
+	flag.DurationVar(&config.ConnectTimeout, "connect-timeout", 0, "Close an accepted connection whose mux stream cannot be opened within this duration")
+	flag.DurationVar(&config.DialTimeout, "dial-timeout", 0, "Fail a connection attempt to the session's stream URL after this duration")
+	flag.DurationVar(&config.KeepaliveInterval, "keepalive-interval", 0, "Send application-level keepalive traffic over the data channel about this often")
+	flag.DurationVar(&config.TransferLogInterval, "transfer-log-interval", 0, "Log each connection's bytes sent and received at debug level this often")
+	flag.DurationVar(&config.MuxIdleTimeout, "mux-idle-timeout", 0, "Close a multiplexed connection that moves no data for this duration")
+	flag.DurationVar(&config.IOTimeout, "io-timeout", 0, "Close a multiplexed connection when a write to either side stalls for this duration")
+	flag.DurationVar(&config.DrainTimeout, "drain-timeout", 0, "On SIGINT, stop accepting and wait this long for open connections to finish")
+	flag.StringVar(&reconnectOn, "reconnect-on", strings.Join(session.ReconnectClasses, ","), "Error classes to resume the data channel after: net, throttle and/or server, comma-separated")
+	flag.StringVar(&config.OnInterrupt, "on-interrupt", InterruptTerminate, "On SIGINT: terminate the forward, or detach and keep it running")
+	flag.StringVar(&config.OnRemoteClose, "on-remote-close", RemoteCloseExit, "When the agent ends the session: exit, or keep listening and start a new session")
+	flag.StringVar(&config.Label, "label", "", "Label prefixed to this forward's log lines (default: [localPort->remoteHost:remotePort])")
+	flag.StringVar(&config.SessionJSON, "session-json", "", "Read a StartSession response from this file (- for stdin) instead of starting a session")
+	flag.BoolVar(&config.DryRun, "dry-run", false, "Check credentials, target and document, print what would be started and exit")
+	flag.BoolVar(&config.PrintCommand, "print-command", false, "Print the equivalent aws ssm start-session command to stderr (exits without a session with --dry-run)")
+	flag.StringVar(&config.HealthAddr, "health-addr", "", "Serve /healthz on this address (implies --wait)")
+	flag.StringVar(&config.ConnLog, "conn-log", "", "Append a JSON record per closed connection to this file")
+	flag.StringVar(&connLogSample, "conn-log-sample", "", "Log and record only one in every N connections, given as 1/N")
+	flag.StringVar(&config.LocalTLSCert, "local-tls-cert", "", "PEM certificate for terminating TLS on the local listener")
+	flag.StringVar(&config.LocalTLSKey, "local-tls-key", "", "PEM private key for --local-tls-cert")
+	flag.BoolVar(&config.RemoteTLS, "remote-tls", false, "Originate TLS to the remote through the tunnel")
+	flag.StringVar(&config.RemoteTLSServerName, "remote-tls-server-name", "", "Server name to send and verify for --remote-tls (default: remote host)")
+	flag.StringVar(&config.RemoteTLSCA, "remote-tls-ca", "", "PEM CA bundle to verify the remote against for --remote-tls")
+	flag.BoolVar(&config.RemoteTLSInsecure, "remote-tls-insecure", false, "Skip certificate verification for --remote-tls")
+	flag.StringVar(&config.ProxyProtocol, "proxy-protocol", "", "Send a PROXY protocol header (v1 or v2) with the client's address on each connection")
+	flag.BoolVar(&config.ResetOnRemoteFailure, "reset-on-remote-failure", false, "Close a local connection with a TCP reset when the remote closes it without sending data")
+	flag.BoolVar(&config.ReadOnly, "read-only", false, "Discard bytes from local clients, forwarding only the remote's data to them")
+	flag.IntVar(&config.StartRetries, "start-retries", 3, "Retries for throttled or transiently failing StartSession calls")
+	flag.DurationVar(&config.StartRetryMaxDelay, "start-retry-max-delay", 10*time.Second, "Maximum backoff between StartSession retries")
+	flag.StringVar(&config.Policy, "policy", "", "JSON policy file restricting allowed remote hosts and ports")
+	flag.StringVar(&config.EventSocket, "event-socket", "", "Stream NDJSON lifecycle events to readers of this Unix socket")
+	flag.StringVar(&config.AuditLogGroup, "audit-log-group", "", "CloudWatch Logs group to write session start and stop audit records to")
+	flag.BoolVar(&config.AuditRequired, "audit-required", false, "Fail the forward if its audit start record can't be written")
+	flag.BoolVar(&config.ResolveLocally, "resolve-locally", false, "Resolve the remote host on this machine and forward to the resulting IP")
+	flag.StringVar(&config.Resolver, "resolver", "", "DNS server (ip[:port]) for --resolve-locally (default: system resolver)")
+	flag.StringVar(&config.Stdio, "stdio", "", "Forward stdin/stdout to this host:port instead of a local port (e.g. for ssh ProxyCommand)")
+	flag.BoolVar(&config.Summary, "summary", false, "Write session duration and bytes transferred on shutdown")
+	flag.IntVar(&config.PortFD, "port-fd", 0, "File descriptor to write the local port number to")
+	flag.StringVar(&config.ReadyMarker, "ready-marker", "READY", "Token printed as '<token> port=N' on stdout once --wait succeeds")
+	flag.StringVar(&config.OutputFormat, "output-format", OutputFormatText, "Output format: text, json or table")
 
 	flag.Usage = printUsage
-	flag.Parse()
+	flag.CommandLine.Parse(args)
 
 	// Check for positional argument (non-flag) for -L style
-	if localForward == "" && flag.NArg() > 0 {
-		localForward = flag.Arg(0)
+	if len(specs) == 0 && flag.NArg() > 0 {
+		specs = flag.Args()
 	}
 
-	if localForward == "" {
-		return nil, errors.New("port forward specification required (use -L localPort:[remoteHost:]remotePort)")
+	if err := validateOutputFormat(config.OutputFormat, OutputFormatTable); err != nil {
+		config.OutputFormat = OutputFormatText
+		return config, err
 	}
 
-	if config.InstanceID == "" {
-		return nil, errors.New("instance-id is required")
+	if len(specs) == 0 && config.Stdio == "" {
+		return config, errors.New("port forward specification required (use -L localPort:[remoteHost:]remotePort)")
+	}
+	if len(specs) > 0 && config.Stdio != "" {
+		return config, errors.New("-L and --stdio are mutually exclusive")
+	}
+	// The agent's port forwarding documents take a single host and port per session and its mux
+	// streams carry no destination, so several mappings cannot share one session.
+	if len(specs) > 1 {
+		return config, fmt.Errorf("only one port forward specification per session is supported, got %d (%s); "+
+			"run one ssm-port-forward per mapping", len(specs), strings.Join(specs, ", "))
+	}
+	if config.InstanceID == "" && config.ASG == "" && config.SessionJSON == "" {
+		return config, errors.New("instance-id or asg is required")
+	}
+	if config.SessionJSON != "" && config.ASG != "" {
+		return config, errors.New("session-json and asg are mutually exclusive")
+	}
+	if config.InstanceID != "" && config.ASG != "" {
+		return config, errors.New("instance-id and asg are mutually exclusive")
+	}
+	if err := validateTarget(config.InstanceID); err != nil {
+		return config, err
+	}
+	if config.AuditRequired && config.AuditLogGroup == "" {
+		return config, errors.New("--audit-required needs --audit-log-group")
+	}
+	if config.DryRun && config.SessionJSON != "" {
+		return config, errors.New("dry-run has nothing to check with session-json, which starts no session")
+	}
+	if config.PrintCommand && config.SessionJSON != "" {
+		return config, errors.New("print-command has no command to print with session-json, which starts no session")
+	}
+	if config.Parameters != "" {
+		if config.SessionJSON != "" {
+			return config, errors.New("parameters has no effect with session-json, which starts no session")
+		}
+		params, err := parseDocumentParameters(config.Parameters)
+		if err != nil {
+			return config, err
+		}
+		config.DocumentParameters = params
 	}
 
-	// Parse local forward specification
-	// Supports two formats:
-	//   localPort:remotePort (forwards to localhost:remotePort on bastion)
-	//   localPort:remoteHost:remotePort (forwards to remoteHost:remotePort from bastion)
-	parts := strings.Split(localForward, ":")
-	if len(parts) < 2 || len(parts) > 3 {
-		return nil, fmt.Errorf("invalid port forward specification: %s (expected localPort:[remoteHost:]remotePort)", localForward)
+	if config.ClientID != "" {
+		if err := validateClientID(config.ClientID); err != nil {
+			return config, err
+		}
 	}
 
-	config.LocalPort = parts[0]
-	if len(parts) == 2 {
-		// Format: localPort:remotePort (localhost implied)
-		config.RemoteHost = "localhost"
-		config.RemotePort = parts[1]
-	} else {
-		// Format: localPort:remoteHost:remotePort
-		config.RemoteHost = parts[1]
-		config.RemotePort = parts[2]
+	if config.MaxConnections < 0 {
+		return config, fmt.Errorf("max-connections must not be negative: %d", config.MaxConnections)
+	}
+	if config.AcceptConcurrency < 0 {
+		return config, fmt.Errorf("accept-concurrency must not be negative: %d", config.AcceptConcurrency)
+	}
+
+	if config.BufferSize != 0 && (config.BufferSize < smconfig.MinCopyBufferSize || config.BufferSize > smconfig.MaxCopyBufferSize) {
+		return config, fmt.Errorf("buffer-size out of range (%d-%d): %d",
+			smconfig.MinCopyBufferSize, smconfig.MaxCopyBufferSize, config.BufferSize)
+	}
+
+	if config.BufferHighWater < 0 || config.BufferHighWater > smconfig.OutgoingMessageBufferCapacity {
+		return config, fmt.Errorf("buffer-high-water out of range (0-%d): %d",
+			smconfig.OutgoingMessageBufferCapacity, config.BufferHighWater)
+	}
+
+	if config.ListenBacklog < 0 {
+		return config, fmt.Errorf("listen-backlog must not be negative: %d", config.ListenBacklog)
+	}
+
+	if config.RateLimit < 0 {
+		return config, fmt.Errorf("rate-limit must not be negative: %d", config.RateLimit)
+	}
+
+	if config.StartRetries < 0 {
+		return config, fmt.Errorf("start-retries must not be negative: %d", config.StartRetries)
+	}
+	if config.StartRetryMaxDelay < 0 {
+		return config, fmt.Errorf("start-retry-max-delay must not be negative: %v", config.StartRetryMaxDelay)
+	}
+
+	if config.ResolverCacheTTL < 0 {
+		return config, fmt.Errorf("instance-resolver-cache must not be negative: %v", config.ResolverCacheTTL)
+	}
+	if config.WaitPollInterval <= 0 {
+		return config, fmt.Errorf("wait-poll-interval must be positive: %v", config.WaitPollInterval)
+	}
+	if config.ConnectTimeout < 0 {
+		return config, fmt.Errorf("connect-timeout must not be negative: %v", config.ConnectTimeout)
+	}
+	if config.DialTimeout < 0 {
+		return config, fmt.Errorf("dial-timeout must not be negative: %v", config.DialTimeout)
+	}
+	if config.KeepaliveInterval < 0 || (config.KeepaliveInterval > 0 && config.KeepaliveInterval < time.Second) {
+		return config, fmt.Errorf("keepalive-interval must be 0 or at least 1s: %v", config.KeepaliveInterval)
+	}
+
+	if config.Resolver != "" {
+		if !config.ResolveLocally {
+			return config, errors.New("resolver requires --resolve-locally")
+		}
+		resolver, err := resolverAddress(config.Resolver)
+		if err != nil {
+			return config, err
+		}
+		config.Resolver = resolver
+	}
+
+	if config.TransferLogInterval < 0 {
+		return config, fmt.Errorf("transfer-log-interval must not be negative: %v", config.TransferLogInterval)
 	}
 
-	// Validate local port is a number (0 means OS will choose)
-	if localPortNum, err := strconv.Atoi(config.LocalPort); err != nil {
-		return nil, fmt.Errorf("invalid local port: %s", config.LocalPort)
-	} else if localPortNum < 0 || localPortNum > 65535 {
-		return nil, fmt.Errorf("local port out of range (0-65535): %s", config.LocalPort)
+	if config.MuxIdleTimeout < 0 {
+		return config, fmt.Errorf("mux-idle-timeout must not be negative: %v", config.MuxIdleTimeout)
 	}
-	// Validate remote port is a number
-	if remotePortNum, err := strconv.Atoi(config.RemotePort); err != nil {
-		return nil, fmt.Errorf("invalid remote port: %s", config.RemotePort)
-	} else if remotePortNum <= 0 || remotePortNum > 65535 {
-		return nil, fmt.Errorf("remote port out of range (1-65535): %s", config.RemotePort)
+
+	if config.IOTimeout < 0 {
+		return config, fmt.Errorf("io-timeout must not be negative: %v", config.IOTimeout)
+	}
+
+	if config.TraceWS && config.Quiet {
+		return config, errors.New("--trace-ws logs at trace level, which --quiet suppresses")
+	}
+
+	if config.OTelEndpoint != "" && !otlpBuiltIn {
+		return config, errors.New("--otel-endpoint needs a build with -tags otlp (make build-local TAGS=otlp)")
+	}
+
+	if config.OnInterrupt != InterruptTerminate && config.OnInterrupt != InterruptDetach {
+		return config, fmt.Errorf("invalid on-interrupt: %s (expected %s or %s)", config.OnInterrupt, InterruptTerminate, InterruptDetach)
+	}
+	if config.OnInterrupt == InterruptDetach && config.DrainTimeout > 0 {
+		return config, errors.New("--drain-timeout applies to SIGINT, which --on-interrupt detach ignores")
+	}
+	if config.OnRemoteClose != RemoteCloseExit && config.OnRemoteClose != RemoteCloseKeep {
+		return config, fmt.Errorf("invalid on-remote-close: %s (expected %s or %s)", config.OnRemoteClose, RemoteCloseExit, RemoteCloseKeep)
+	}
+	if config.OnRemoteClose == RemoteCloseKeep && config.SessionJSON != "" {
+		return config, errors.New("--on-remote-close keep needs to start new sessions, which session-json can't")
 	}
 
-	// Auto-select document name if not explicitly specified and remote host is provided
-	if config.DocumentName == DefaultDocumentName {
-		if config.RemoteHost != "localhost" && config.RemoteHost != "127.0.0.1" {
-			config.DocumentName = "AWS-StartPortForwardingSessionToRemoteHost"
+	if config.PortFD < 0 {
+		return config, fmt.Errorf("port-fd must not be negative: %d", config.PortFD)
+	}
+	if config.ReadyMarker == "" || strings.ContainsAny(config.ReadyMarker, "\r\n") {
+		return config, fmt.Errorf("ready-marker must be a non-empty single line: %q", config.ReadyMarker)
+	}
+
+	if err := validateProbeMode(config.Probe.Mode); err != nil {
+		return config, err
+	}
+	if err := validateProbePath(config.Probe.HTTPPath); err != nil {
+		return config, err
+	}
+	// Waiting for the remote is a retried probe, TCP unless another mode was chosen
+	if config.WaitForRemote && config.Probe.Mode == ProbeNone {
+		config.Probe.Mode = ProbeTCP
+	}
+	if err := validateSelfTestMode(config.SelfTest); err != nil {
+		return config, err
+	}
+	if config.SelfTest != SelfTestNone && config.SelfTestBytes <= 0 {
+		return config, fmt.Errorf("selftest-bytes must be positive: %d", config.SelfTestBytes)
+	}
+	if config.StatsdTags != "" && config.StatsdAddr == "" {
+		return config, errors.New("--statsd-tags needs --statsd-addr")
+	}
+	if err := parseStatsdTags(config.StatsdTags); err != nil {
+		return config, err
+	}
+	// Probing, health reporting and the self-test all need to know when the forward is up
+	if config.Probe.Mode != ProbeNone || config.HealthAddr != "" || config.SelfTest != SelfTestNone {
+		config.Wait = true
+	}
+
+	// Parse local forward specification: [protocol/][bindHost:]localPort:[remoteHost:]remotePort
+	//   localPort:remotePort (forwards to localhost:remotePort on bastion)
+	//   localPort:remoteHost:remotePort (forwards to remoteHost:remotePort from bastion)
+	//   bindHost:localPort:remoteHost:remotePort (listens on bindHost instead of localhost)
+	var spec forwardspec.Spec
+	var err error
+	if config.Stdio != "" {
+		spec, err = forwardspec.ParseDestination(config.Stdio)
+	} else {
+		spec, err = forwardspec.ParseSpec(specs[0])
+	}
+	if err != nil {
+		return config, err
+	}
+	if config.ReconnectOn, err = parseReconnectOn(reconnectOn); err != nil {
+		return config, err
+	}
+	if config.ConnLogSample, err = parseSampleRate(connLogSample); err != nil {
+		return config, err
+	}
+	if spec.Protocol != "" {
+		config.Protocol = spec.Protocol
+	}
+	if config.Protocol != "tcp" && config.Protocol != "udp" {
+		return config, fmt.Errorf("invalid protocol: %s (expected tcp or udp)", config.Protocol)
+	}
+	if (config.LocalTLSCert == "") != (config.LocalTLSKey == "") {
+		return config, errors.New("local-tls-cert and local-tls-key must be given together")
+	}
+	if config.LocalTLSCert != "" && config.Protocol == "udp" {
+		return config, errors.New("local TLS termination is not supported with udp")
+	}
+	if !config.RemoteTLS && (config.RemoteTLSServerName != "" || config.RemoteTLSCA != "" || config.RemoteTLSInsecure) {
+		return config, errors.New("remote-tls-server-name, remote-tls-ca and remote-tls-insecure require --remote-tls")
+	}
+	if config.RemoteTLS && config.Protocol == "udp" {
+		return config, errors.New("remote TLS is not supported with udp")
+	}
+	if config.RemoteTLS && config.Probe.Mode == ProbeTLS {
+		return config, errors.New("--probe tls cannot be used with --remote-tls, which presents plaintext locally")
+	}
+	if config.ProxyProtocol != "" {
+		if config.ProxyProtocol != portsession.ProxyProtocolV1 && config.ProxyProtocol != portsession.ProxyProtocolV2 {
+			return config, fmt.Errorf("invalid proxy-protocol: %s (expected v1 or v2)", config.ProxyProtocol)
+		}
+		if config.Protocol == "udp" || config.Stdio != "" {
+			return config, errors.New("--proxy-protocol is only supported for tcp listeners")
 		}
 	}
+	if config.ResetOnRemoteFailure && (config.Protocol == "udp" || config.Stdio != "") {
+		return config, errors.New("--reset-on-remote-failure is only supported for tcp listeners")
+	}
+	if config.OnRemoteClose == RemoteCloseKeep && (config.Protocol == "udp" || config.Stdio != "") {
+		return config, errors.New("--on-remote-close keep is only supported for tcp listeners")
+	}
+	if config.SelfTest != SelfTestNone && config.Protocol == "udp" {
+		return config, errors.New("--selftest is only supported for tcp listeners")
+	}
+	if config.ReadOnly {
+		if config.SelfTest == SelfTestEcho {
+			return config, errors.New("--selftest echo cannot be used with --read-only, which discards the data to echo")
+		}
+		if config.Protocol == "udp" || config.Stdio != "" {
+			return config, errors.New("--read-only is only supported for tcp listeners")
+		}
+		// Probe requests would be discarded before reaching the remote
+		if config.Probe.Mode == ProbeHTTP || config.Probe.Mode == ProbeTLS {
+			return config, fmt.Errorf("--probe %s cannot be used with --read-only, which discards the probe's request", config.Probe.Mode)
+		}
+	}
+	config.BindHost = spec.BindHost
+	if config.BindHost == "" {
+		config.BindHost = "localhost"
+	}
+	if err := resolveNetwork(config); err != nil {
+		return config, err
+	}
+	config.LocalPort = spec.LocalPort
+	config.RemoteHost = spec.RemoteHost
+	config.RemotePort = spec.RemotePort
+	config.Probe.ServerName = config.RemoteHost
+
+	// The spec's ports were validated as it was parsed
+	if config.Stdio != "" {
+		if err := validateStdio(config); err != nil {
+			return config, err
+		}
+	}
+	// A pre-started session already fixed its local port, so it cannot be chosen here
+	if config.SessionJSON != "" && config.LocalPort == "0" {
+		return config, errors.New("session-json requires the local port the session was started with")
+	}
+	if config.PortIncrement < 0 {
+		return config, fmt.Errorf("port-increment must not be negative: %d", config.PortIncrement)
+	}
+	if config.PortIncrement > 0 && (config.Stdio != "" || config.LocalPort == "0") {
+		return config, errors.New("port-increment needs a fixed, non-zero local port")
+	}
+	if config.PortIncrement > 0 && config.SessionJSON != "" {
+		return config, errors.New("port-increment has no effect with session-json, whose local port is fixed")
+	}
+	// A non-loopback bind puts the remote service on the network, so it must be asked for
+	if exposure := publicExposure(config); exposure != "" && !config.AllowPublic {
+		return config, fmt.Errorf("%s; pass --allow-public to proceed", exposure)
+	}
+
+	// Reject destinations outside the policy before any AWS call
+	if err := checkPolicies(config.Policy, config.RemoteHost, config.RemotePort); err != nil {
+		return config, err
+	}
+
+	// Auto-select the document for the spec unless one was specified or --no-auto-document is set
+	config.DocumentName = specDocumentName(spec, config.DocumentName, !config.NoAutoDocument)
 
 	return config, nil
 }
 
+// resolveNetwork validates --network against the bind host and fills in its default: tcp4 for
+// localhost, which Go would otherwise resolve to whichever family comes first while clients
+// commonly dial 127.0.0.1, and tcp for any other host.
+func resolveNetwork(config *PortForwardConfig) error {
+	switch config.Network {
+	case "":
+		config.Network = portsession.NetworkTCP
+		if config.BindHost == "localhost" {
+			config.Network = portsession.NetworkTCP4
+		}
+		return nil
+	case portsession.NetworkTCP4, portsession.NetworkTCP6, portsession.NetworkTCP:
+	default:
+		return fmt.Errorf("invalid network: %s (expected tcp4, tcp6 or tcp)", config.Network)
+	}
+	if ip := net.ParseIP(config.BindHost); ip != nil && !ip.IsUnspecified() {
+		if (ip.To4() != nil) != (config.Network == portsession.NetworkTCP4) && config.Network != portsession.NetworkTCP {
+			return fmt.Errorf("--network %s cannot bind %s", config.Network, config.BindHost)
+		}
+	}
+	return nil
+}
+
+// validateStdio rejects options that need a local listener, which --stdio replaces.
+func validateStdio(config *PortForwardConfig) error {
+	switch {
+	case config.Protocol != "tcp":
+		return errors.New("--stdio forwards tcp only")
+	case config.Wait:
+		return errors.New("--stdio has no local port to wait for; --wait, --wait-for-remote, --probe, --health-addr and --selftest do not apply")
+	case config.PortFD != 0:
+		return errors.New("--stdio has no local port to write to --port-fd")
+	case config.LocalTLSCert != "":
+		return errors.New("--stdio has no local listener to terminate TLS on")
+	}
+	return nil
+}
+
 func printUsage() {
-	fmt.Fprintf(os.Stderr, `Usage: ssm-port-forward [OPTIONS] -L localPort:[remoteHost:]remotePort
+	fmt.Fprintf(os.Stderr, `Usage: ssm-port-forward [forward] [OPTIONS] -L [udp/][bindHost:]localPort:[remoteHost:]remotePort
+       ssm-port-forward [forward] [OPTIONS] --stdio remoteHost:remotePort
+       ssm-port-forward list-sessions [OPTIONS]
+       ssm-port-forward list-documents [OPTIONS]
+       ssm-port-forward terminate [OPTIONS] session-id...
 
 SSH-style port forwarding for AWS SSM sessions with multi-hop support.
 
+Commands:
+  forward         Forward a local port through a bastion (default when the first
+                  argument is a flag)
+  list-sessions   List active SSM sessions; see list-sessions --help
+  list-documents  List Session Manager documents (also --list-documents); see
+                  list-documents --help
+  terminate       Terminate sessions by ID, or all to a target; see terminate --help
+
 Options:
   -L, --local-forward    Port forward specification
                          localPort:remotePort          (forward to localhost on bastion)
                          localPort:remoteHost:remotePort  (multi-hop through bastion)
-  -i, --instance-id      EC2 instance ID (bastion host) (required)
-  -r, --region           AWS region
-  -p, --profile          AWS profile
+                         bindHost:localPort:remoteHost:remotePort
+                                                       (listen on bindHost, e.g. 0.0.0.0;
+                                                       requires --allow-public unless loopback)
+                         udp/localPort:...             (UDP, see --protocol)
+                         Bracket IPv6 hosts: [::1]:8080:[fd00::5]:80
+                         Only one mapping per session: the agent binds a single
+                         remote host:port, so run one process per mapping
+      --allow-public     Allow a bindHost other than localhost/127.0.0.1/::1, which
+                         exposes the remote service to anyone who can reach this
+                         host; a warning naming the exposure is still logged
+      --resolve-locally  Resolve the remote host on this machine instead of the
+                         bastion and forward to the resulting IP, for when the
+                         bastion's DNS view differs. IPv4 is preferred when the
+                         name has several addresses; the choice is logged.
+                         --remote-tls and --probe tls still verify the name
+      --resolver         DNS server for --resolve-locally as ip[:port]
+                         (default: the system resolver; port 53)
+      --stdio            Forward stdin and stdout to remoteHost:remotePort through the
+                         bastion instead of listening locally, for use as an SSH
+                         ProxyCommand. Exits when the connection closes. Port info
+                         and --summary are only written with --output, since
+                         stdout carries the connection (tcp only; not with --wait)
+      --protocol         Local listener protocol: tcp or udp (default: tcp).
+                         The agent only forwards TCP, so UDP datagrams reach the
+                         remote as 2-byte length-prefixed frames (DNS over TCP
+                         framing); requires a multiplexing-capable agent
+      --network          Local listener address family: tcp4, tcp6, or tcp for
+                         whichever the bindHost resolves to (default: tcp4 for
+                         localhost, so clients dialing 127.0.0.1 reach it on
+                         dual-stack hosts; tcp otherwise). Applies to udp too
+  -i, --instance-id      EC2 instance ID (bastion host), or ip:ADDRESS or dns:NAME to
+                         look up the running instance with that private IP or
+                         private DNS name; more than one match is an error
+      --instance-resolver-cache
+                         Reuse the instance an ip: or dns: target resolved to for
+                         this long on later runs with the same region and profile,
+                         skipping DescribeInstances (default: 5m; 0 disables).
+                         Kept under the user cache directory; an entry is dropped
+                         when starting a session on its instance fails
+      --no-cache         Look the target up without reading or writing the cache
+      --asg              Auto Scaling group name; a healthy running instance is
+                         picked at start (alternative to --instance-id)
+  -r, --region           AWS region (default: AWS_REGION, then AWS_DEFAULT_REGION,
+                         then the profile's, then the instance's own on EC2)
+  -p, --profile          AWS profile (default: AWS_PROFILE, then AWS_DEFAULT_PROFILE,
+                         then default); IAM Identity Center (SSO) profiles use the
+                         token cache from "aws sso login"
+      --sso-login        Run "aws sso login" (device authorization) when the SSO
+                         token is missing or expired, then continue
+      --signing-region   Region to sign SSM API requests for, when a private
+                         endpoint expects a different one than --region
+      --signing-name     Service name to sign SSM API requests for (default: ssm);
+                         the data channel authenticates with the session token,
+                         so only API calls are affected
   -d, --document-name    SSM document name (default: auto-selected based on remote host)
                          Auto-uses AWS-StartPortForwardingSessionToRemoteHost for remote hosts
-  -o, --output           Output file for port/PID info (default: stdout)
-  -w, --wait             Wait for port forward to be established
-      --timeout          Timeout for port forward validation (default: 30s)
+      --no-auto-document Always use --document-name (or its default) as given, for a
+                         custom document that serves both localhost and remote hosts;
+                         the host parameter is still sent for a remote host
+      --parameters       Extra document parameters as a JSON object of string lists,
+                         e.g. '{"auditTag":["ops"]}', for custom port forwarding
+                         documents. portNumber, localPortNumber and host come from
+                         the forward specification only and are rejected here
+  -o, --output           Output file for port/PID info (default: stdout). It is
+                         replaced atomically, so a watcher never reads a partial record.
+                         local_address is the listener's host:port; for a 0.0.0.0 or
+                         :: bind local_dial_address is the loopback form to connect to.
+                         forwarding_type is mux, udp, basic (agents without
+                         multiplexing; one connection at a time) or standard-stream,
+                         once known (always with --wait).
+                         When the forward exits, on a signal or an error, the record
+                         is rewritten with status "terminated" and the exit time
+                         (appended as a final line instead with --summary)
+  -w, --wait             Wait for port forward to be established, then print a
+                         'READY port=N' line after the output (see --ready-marker)
+      --wait-for-remote  Also retry a connection through the tunnel until the remote
+                         accepts it or --timeout elapses, e.g. while it boots; uses
+                         --probe if set, else tcp (implies --wait). The JSON output
+                         reports establish_ms
+      --timeout          Timeout for port forward validation (default: 30s); only
+                         bounds the wait for the listener, not each connection.
+                         Time spent reconnecting the data channel does not count
+      --wait-poll-interval
+                         First delay between checks of the local port while
+                         waiting (default: 100ms). Later checks back off up to 1s,
+                         or this interval if longer, for slow-starting listeners
+      --connect-timeout  Close an accepted connection, with a logged reason, if its
+                         mux stream cannot be opened (and --remote-tls handshake
+                         completed) within this duration (default: no limit).
+                         Opening the stream does not wait for the remote to reach
+                         the target, so a target that never answers is not caught.
+                         Basic (non-multiplexed) forwarding ignores it
+      --dial-timeout     Give up on each attempt to reach the session's stream URL
+                         (TCP, TLS and websocket handshake) after this duration,
+                         e.g. 5s, so a blocked endpoint fails promptly with
+                         "failed to connect to stream URL within 5s" (default: 45s;
+                         the attempt is retried up to 5 times)
+      --keepalive-interval
+                         Last resort for proxies or firewalls that close idle
+                         connections despite websocket pings: resend the latest
+                         acknowledgement over the data channel about this often,
+                         e.g. 30s, varied by up to a fifth so many clients don't
+                         send in step. Nothing is sent before the first message
+                         from the agent (default: 0, off)
+  -q, --quiet            Suppress all logging except errors. Logs always go to
+                         stderr, so stdout carries only the JSON output (and the
+                         --ready-marker line with --wait)
+      --trace-ws         Log each step of setting up the session with the time since
+                         start: SigV4 signing and response of SSM API calls, DNS,
+                         TCP connect, TLS and upgrade of the websocket, the open
+                         data channel message and the agent's handshake. Turns on
+                         trace level logging (LOG_LEVEL=trace); for a connection
+                         that hangs, the last step logged shows where it stalled
+      --log-json         Log the forward label as a "context" array field instead of
+                         a message prefix, for log aggregation (also LOG_JSON=1)
+      --json-logs-to     Append a JSON copy of every log entry to this file and log
+                         human-readable lines to stderr instead (stderr stays JSON
+                         with --log-json). LOG_LEVEL and --quiet apply to both
+      --max-connections  Maximum concurrent local connections; extra connections
+                         are closed immediately (default: 0, unlimited)
+      --accept-concurrency
+                         Maximum connections opening their tunnel stream (and
+                         --remote-tls handshake) at once; the rest wait their turn,
+                         with --connect-timeout counted from when they start. Each
+                         connection is otherwise set up in parallel, so a burst of
+                         short connections isn't serialized (default: 0, unlimited;
+                         needs an agent with multiplexing)
+      --buffer-size      Copy buffer size in bytes for local connections, 1024 to
+                         4194304; raise it (e.g. 262144) for bulk transfers over
+                         high-latency links (default: 0, io.Copy defaults)
+      --buffer-high-water
+                         Log a warning when more than this many messages sent over
+                         the data channel await acknowledgement, i.e. data is
+                         produced faster than AWS absorbs it. The buffer holds
+                         10000 and drops the oldest beyond that (default: 5000;
+                         0 disables the warning)
+      --listen-backlog   Queue length for connections not yet accepted; raise it if
+                         many simultaneous connects get resets. The OS caps it
+                         (net.core.somaxconn on Linux, kern.ipc.somaxconn on
+                         macOS/BSD, SOMAXCONN on Windows), so raise those too
+                         (default: 0, the OS maximum; tcp only)
+      --reuse-addr       Set SO_REUSEADDR on the local socket, so a restarted
+                         forward can bind a fixed port still in TIME_WAIT. On
+                         Windows it also lets other sockets share the port
+                         (default: false)
+      --reuse-port       Set SO_REUSEPORT on the local socket, so a supervisor
+                         can start the replacement forward before the old one
+                         exits; connections are spread across both meanwhile.
+                         Not supported on Windows (default: false)
+      --port-increment   When the local port is in use, try up to this many ports
+                         after it and listen on the first free one; the JSON
+                         output reports the port used (default: 0, fail instead)
+      --rate-limit       Maximum bytes per second forwarded in each direction
+                         (default: 0, unlimited)
+      --probe            Verify the tunnel end-to-end before reporting ready
+                         (implies --wait):
+                         tcp   connection through the tunnel stays open
+                         http  GET --probe-path returns --probe-status
+                         tls   TLS handshake completes
+      --probe-path       Request path for --probe http, starting with / (default: /)
+      --probe-status     Expected status for --probe http (default: 200)
+      --selftest         Benchmark the path instead of serving it: once the forward
+                         is up, send --selftest-bytes through the tunnel, print
+                         MB/s (and for echo, round-trip latency p50/p90/p99 over
+                         100 pings) as a line or JSON object, and exit. The remote
+                         must be a service of the named kind (implies --wait):
+                         echo     returns what it is sent, e.g. port 7 or
+                                  socat TCP-LISTEN:9000,fork PIPE
+                         discard  reads and drops it; measures upload only
+      --selftest-bytes   Bytes sent by --selftest (default: 16777216)
+      --transfer-log-interval
+                         Log each open connection's sent=X recv=Y byte counts at
+                         debug level (LOG_LEVEL=debug) this often, telling a remote
+                         that stops sending from a client that stops reading
+                         (default: 0, off)
+      --mux-idle-timeout Close a local connection that has moved no data in either
+                         direction for this long, freeing its tunnel stream when a
+                         client vanishes without closing; requires a multiplexing
+                         agent, and long-idle protocols (e.g. database pools)
+                         need keepalives shorter than it (default: 0, never)
+      --io-timeout       Close a local connection when a write to the client or to
+                         the remote makes no progress for this long, as when the
+                         other end stops reading, instead of hanging it forever;
+                         requires a multiplexing agent (default: 0, never)
+      --drain-timeout    On Ctrl-C, stop accepting new connections and let open ones
+                         finish for up to this long; a second Ctrl-C forces exit
+                         (default: 0, close immediately)
+      --reconnect-on     Comma-separated error classes after which a dropped data
+                         channel is resumed: net (websocket or network errors),
+                         throttle (ResumeSession rate limited) and server (AWS 5xx)
+                         (default: net,throttle,server; '' never reconnects). A
+                         session that was terminated or a permanent error such as
+                         AccessDeniedException is never retried
+      --on-interrupt     What Ctrl-C (SIGINT) does: terminate (default) tears the
+                         forward down; detach ignores it so a backgrounded forward
+                         survives Ctrl-C in the launching shell and keeps running
+                         until SIGTERM or SIGHUP
+      --on-remote-close  What happens when the agent ends the session, e.g. because
+                         the remote service restarted: exit (default) ends the
+                         forward; keep holds the local port open and starts a new
+                         session, so clients connecting meanwhile wait rather than
+                         being refused. Unlike --reconnect-on, which resumes a
+                         dropped data channel, this replaces a session that was
+                         ended on purpose (tcp only)
+      --label            Label prefixed to this forward's log lines
+                         (default: [localPort->remoteHost:remotePort])
+      --session-json     Read a StartSession response ({SessionId, StreamUrl,
+                         TokenValue, TargetId}) from this file or - for stdin and
+                         only run the data channel; -L must match the session
+      --dry-run          Run every check up to StartSession without starting a
+                         session: load credentials, confirm the target is an
+                         Online managed instance (DescribeInstanceInformation),
+                         confirm the document is an active Session document
+                         (DescribeDocument), then print the target, region,
+                         document and parameters and exit 0. Failures exit
+                         non-zero with the failing stage
+      --print-command    Print the equivalent "aws ssm start-session" command, with
+                         the resolved target, document and parameters, to stderr
+                         before starting the session, to reproduce it without this
+                         tool. Add --dry-run to print it and exit instead; the AWS
+                         CLI needs the session-manager-plugin to run it
+      --health-addr      Serve /healthz on this address (e.g. :8086), answering 200
+                         while the forward is up and 503 while starting, during a
+                         data channel reconnect, or after teardown (implies --wait)
+      --conn-log         Append one JSON line per closed local connection to this
+                         file: source, opened, duration, bytes_in, bytes_out and
+                         close_reason (client_closed, remote_closed, remote_refused,
+                         remote_unreachable, idle_timeout, session_ended or the
+                         error)
+      --conn-log-sample  On busy forwards, log the opening and progress of only
+                         one in every N local connections and keep only one in N
+                         --conn-log records, given as 1/N (e.g. 1/100). Warnings,
+                         errors, --event-socket, --statsd-addr and --summary
+                         still count every connection
+      --local-tls-cert   PEM certificate to terminate TLS on the local listener;
+                         connections are forwarded as plaintext (tcp only)
+      --local-tls-key    PEM private key for --local-tls-cert
+      --remote-tls       Originate TLS to the remote host through the tunnel and
+                         present plaintext locally, e.g. for RDS with
+                         rds.force_ssl (tcp only; not with --probe tls)
+      --remote-tls-server-name
+                         Server name to send and verify (default: remote host)
+      --remote-tls-ca    PEM CA bundle to verify the remote against instead of
+                         the system roots
+      --remote-tls-insecure
+                         Skip verification of the remote's certificate
+      --proxy-protocol   Send a PROXY protocol header (v1 or v2) carrying the local
+                         client's address at the start of each connection, so
+                         backends that accept it log the real client IP. Must be
+                         enabled on the backend; needs a multiplexing agent
+                         (tcp only)
+      --read-only        One-way forward: bytes from local clients are read and
+                         discarded, so the remote can send to clients but never
+                         receives from them, e.g. for monitoring endpoints. A
+                         --remote-tls handshake still completes; --probe http and
+                         tls do not apply (tcp only)
+      --reset-on-remote-failure
+                         When the remote closes a connection without sending any
+                         data, as the agent does when it cannot reach the remote
+                         host, close the local connection with a TCP reset so the
+                         client reports a failed connect instead of an empty
+                         reply. Such closes are always logged as remote_refused
+                         (within 10s, e.g. service down) or remote_unreachable
+                         (e.g. firewalled); needs a multiplexing agent (tcp only)
+      --start-retries    Retry StartSession this many times on throttling or AWS 5xx
+                         errors, with exponential backoff and jitter (default: 3).
+                         Access and target errors are not retried; 0 makes a single
+                         attempt
+      --start-retry-max-delay
+                         Maximum backoff between StartSession retries (default: 10s)
+      --policy           JSON file restricting which remote destinations may be
+                         forwarded to, checked before StartSession:
+                           {"allow": [{"host": "*.rds.amazonaws.com", "ports": ["5432"]},
+                                      {"host": "10.0.0.0/16", "ports": ["8000-8100"]}],
+                            "deny":  [{"host": "169.254.169.254"}]}
+                         Hosts are names, globs or CIDRs; no ports means any port.
+                         A deny match rejects; if allow rules exist one must match
+      --event-socket     Listen on this Unix socket and stream NDJSON lifecycle
+                         events to every connected reader: established,
+                         connection_opened, connection_closed, reconnecting,
+                         reconnected, paused, resumed and terminated, each with
+                         active_connections. Readers may attach at any time and
+                         first receive the established event. Removed on exit
+      --audit-log-group  Write a JSON audit record to a new log stream in this
+                         CloudWatch Logs group when the session starts and stops:
+                         caller ARN and account (from STS GetCallerIdentity),
+                         target, region, document, forwarding, session ID, start
+                         and stop times and the stop reason. Needs
+                         sts:GetCallerIdentity, logs:CreateLogStream and
+                         logs:PutLogEvents. Write failures are logged as warnings
+      --audit-required   With --audit-log-group, fail instead: the forward exits
+                         before StartSession if the log stream can't be created,
+                         and terminates the session if the start record can't be
+                         written
+      --otel-endpoint    Export OpenTelemetry spans over OTLP/HTTP to this collector,
+                         e.g. http://localhost:4318 (host:port uses HTTPS): a
+                         ssm-port-forward span with StartSession and
+                         data_channel.establish children and reconnecting,
+                         reconnected and terminated events. Attributes are the
+                         instance ID, region, document name and session ID only.
+                         OTEL_EXPORTER_OTLP_HEADERS is honoured for collector auth.
+                         Only in builds with -tags otlp (make build-local TAGS=otlp)
+      --statsd-addr      Send metrics over UDP to this statsd endpoint (host:port):
+                         counters ssm_port_forward.connections.opened,
+                         .connections.closed, .bytes.in, .bytes.out and
+                         .reconnects, and the gauge .connections.active. Bytes
+                         are counted when a connection closes
+      --statsd-tags      Comma-separated key:value tags added to every statsd
+                         metric in the dogstatsd format (e.g. env:prod,team:db)
+      --summary          On shutdown, write a second JSON line to the output with
+                         duration_seconds, bytes_sent, bytes_received and
+                         bytes_transferred
+      --client-id        Client ID sent with the session, for correlating with your
+                         own logs and CloudTrail; up to 64 letters, digits, '.', '_'
+                         or '-' (default: random UUID). Reported in the JSON output
+      --port-fd          Write only the local port number and a newline to this
+                         file descriptor, then close it (e.g. exec 3>port.txt;
+                         ssm-port-forward --port-fd 3 ...)
+      --ready-marker     Once --wait succeeds, print this token and the local port
+                         on their own stdout line after the output, e.g.
+                         'READY port=12345', for wrappers to wait for (default:
+                         READY; not printed with --stdio)
+      --output-format    text (default), json or table; json errors on stderr look
+                         like {"error":"...","code":"...","stage":"..."}, table
+                         prints the forward's status as an aligned table (Local
+                         Port, Destination, Instance, PID, Status) instead of the
+                         JSON line; errors are text for table
+
+Signals:
+  SIGINT, SIGTERM, SIGHUP
+          Stop the forward (see --on-interrupt and --drain-timeout)
+  SIGUSR1 Pause: close new local connections as soon as they are accepted,
+          keeping the session and open connections up, e.g. for a maintenance
+          window; needs a multiplexing agent (not on Windows)
+  SIGUSR2 Resume accepting new connections
+
+  On Windows, Ctrl-C and Ctrl-Break act as SIGINT, and closing the console,
+  logoff and shutdown as SIGTERM. Started as a Windows service (e.g. with
+  sc.exe create NAME binPath= "ssm-port-forward.exe -i ... -L ..."), a service
+  stop or system shutdown stops the forward like SIGTERM, terminating its
+  session. A service has no console, so log with --json-logs-to
+
+Exit status:
+  0  Clean shutdown
+  1  Other failures, e.g. local port in use or the session dropping once up
+  2  Invalid arguments or options, or a destination outside --policy
+     (invalid_args, policy_denied)
+  3  AWS rejected or found no credentials (auth_failed)
+  4  No target could be resolved, its SSM agent is not connected, or
+     StartSession failed (no_target, target_not_connected,
+     start_session_failed)
+  5  The forward did not become ready: wait timeout, remote host not
+     resolvable with --resolve-locally, remote port unreachable or probe
+     failure (session_timeout, remote_unreachable,
+     probe_failed)
 
 Examples:
   # Forward local port 8080 to port 80 on bastion
@@ -199,9 +1146,38 @@ Examples:
   # Let OS choose local port (port 0)
   ssm-port-forward -L 0:80 -i i-bastion -r us-east-1 -w
 
+  # Find the bastion by its private IP
+  ssm-port-forward -L 8080:80 -i ip:10.0.1.23 -r us-east-1 -w
+
+  # Pick a healthy bastion from an Auto Scaling group
+  ssm-port-forward -L 5432:mydb.internal:5432 --asg bastion-asg -r us-east-1 -w
+
+  # Run only the data channel for a session your own tooling started
+  # (with localPortNumber 8080 and portNumber 80)
+  my-start-session | ssm-port-forward -L 8080:80 --session-json - -w
+
+  # Check credentials, target and document without starting a session
+  ssm-port-forward -L 5432:mydb.internal:5432 -i i-bastion -r us-east-1 --dry-run
+
+  # Measure throughput and latency to a bastion in another region through
+  # an echo service listening on its port 9000
+  ssm-port-forward -L 0:9000 -i i-bastion -r eu-west-1 --selftest echo
+
+  # Forward DNS queries to the VPC resolver (resolver must accept DNS over TCP)
+  ssm-port-forward -L udp/5353:10.0.0.2:53 -i i-bastion -r us-east-1 -w
+
+  # SSH to a private host through the bastion (in ~/.ssh/config)
+  #   Host db-host
+  #     HostName db.internal
+  #     ProxyCommand ssm-port-forward --stdio %%h:%%p -i i-bastion -r us-east-1 -q
+  ssh db-host
+
   # Use AWS profile and output to file
   ssm-port-forward -L 3306:mysql-server:3306 -i i-bastion -p prod -o /tmp/db-forward.json
 
+  # Confirm the remote web server answers before reporting ready
+  ssm-port-forward -L 8080:internal-web:80 -i i-bastion -r us-east-1 --probe http
+
   # Simple localhost forward with validation
   ssm-port-forward -L 8080:8080 -i i-webserver -r us-east-1 -w
 
@@ -212,104 +1188,479 @@ Examples:
 }
 
 // SIGNAL-001, SIGNAL-002, SIGNAL-003, SIGNAL-007, SIGNAL-008
-func run(config *PortForwardConfig) error {
-	logger := log.Logger(true, "ssm-port-forward")
+func run(config *PortForwardConfig) (err error) {
+	// Logs go to stderr so stdout carries only the JSON output, e.g. for piping into jq
+	logConfig := log.DefaultLogConfig("ssm-port-forward")
+	logConfig.JSON = logConfig.JSON || config.LogJSON
+	if config.JSONLogsTo != "" {
+		jsonLogs, err := os.OpenFile(config.JSONLogsTo, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return stageError(StageLogFile, CodeInvalidArgs, fmt.Errorf("failed to open JSON log file: %w", err))
+		}
+		defer jsonLogs.Close()
+		logConfig.JSONWriter = jsonLogs
+	}
+	logger := log.LoggerWithConfig(true, logConfig)
+	if config.Quiet {
+		log.Quiet()
+	}
+	// wsTrace stays nil without --trace-ws; its methods then do nothing
+	var wsTrace *communicator.HandshakeTrace
+	if config.TraceWS {
+		log.Verbose()
+		wsTrace = communicator.NewHandshakeTrace(logger)
+	}
+
+	// parseArgs only lets a non-loopback bind through with --allow-public; still say what it exposes
+	if exposure := publicExposure(config); exposure != "" {
+		logger.Warnf("Non-loopback bind address: %s", exposure)
+	}
 
 	// PROFILE-001: opt-in profiling via SSM_PROFILE env var
 	prof := profile.New()
 	defer prof.Emit(os.Stderr)
 
+	health := &healthState{}
+	if config.HealthAddr != "" {
+		server, err := startHealthServer(config.HealthAddr, health)
+		if err != nil {
+			return stageError(StageHealthServer, CodePortConflict, fmt.Errorf("failed to start health server: %w", err))
+		}
+		defer server.Close()
+		logger.Infof("Serving health checks on %s/healthz", config.HealthAddr)
+	}
+
+	var connLog *connLog
+	if config.ConnLog != "" {
+		var err error
+		if connLog, err = openConnLog(config.ConnLog, config.ConnLogSample); err != nil {
+			return stageError(StageConnLog, CodeInvalidArgs, fmt.Errorf("failed to open connection log: %w", err))
+		}
+		defer connLog.Close()
+	}
+
+	// events stays nil without --event-socket; its methods then do nothing
+	var events *eventServer
+	if config.EventSocket != "" {
+		var err error
+		if events, err = listenEvents(config.EventSocket); err != nil {
+			return stageError(StageEventSocket, CodeInvalidArgs, fmt.Errorf("failed to listen on event socket: %w", err))
+		}
+		defer events.Close()
+	}
+	// tracer stays nil without --otel-endpoint; its methods then do nothing
+	var tracer *sessionTracer
+	if config.OTelEndpoint != "" {
+		if tracer, err = startTracing(logger, config.OTelEndpoint); err != nil {
+			return stageError(StageTracing, CodeInvalidArgs, fmt.Errorf("failed to set up tracing: %w", err))
+		}
+	}
+	// Ends the spans with the error run returns
+	defer func() { tracer.Close(err) }()
+	// stats stays nil without --statsd-addr; its methods then do nothing
+	var stats *statsdClient
+	if config.StatsdAddr != "" {
+		if stats, err = dialStatsd(config.StatsdAddr, config.StatsdTags); err != nil {
+			return stageError(StageStatsd, CodeInvalidArgs, fmt.Errorf("failed to set up statsd: %w", err))
+		}
+		defer stats.Close()
+	}
+
+	var onConnOpened func(string)
+	var onConnClosed func(session.ConnRecord)
+	if events != nil || stats != nil || connLog != nil {
+		onConnOpened = func(source string) {
+			events.connOpened(source)
+			stats.connOpened()
+		}
+		onConnClosed = func(record session.ConnRecord) {
+			if connLog != nil {
+				connLog.record(record)
+			}
+			events.connClosed(record)
+			stats.connClosed(record)
+		}
+	}
+	// The listener's actual address, for the output's local_address
+	listening := make(chan net.Addr, 1)
+	// The forwarding implementation picked for the agent, for the output's forwarding_type
+	forwardingTypes := make(chan string, 1)
+	// Closed once the session opens its listener, which is when a pre-bound port is being served
+	served := make(chan struct{})
+	var servedOnce sync.Once
+
+	// Load the certificate now so a bad cert/key fails before a session is started
+	var localTLS *tls.Config
+	if config.LocalTLSCert != "" {
+		var err error
+		if localTLS, err = loadLocalTLS(config.LocalTLSCert, config.LocalTLSKey); err != nil {
+			return stageError(StageLocalTLS, CodeInvalidArgs, err)
+		}
+	}
+
+	var remoteTLS *tls.Config
+	if config.RemoteTLS {
+		serverName := config.RemoteTLSServerName
+		if serverName == "" {
+			serverName = config.RemoteHost
+		}
+		var err error
+		if remoteTLS, err = loadRemoteTLS(serverName, config.RemoteTLSCA, config.RemoteTLSInsecure); err != nil {
+			return stageError(StageRemoteTLS, CodeInvalidArgs, err)
+		}
+	}
+
+	// Local listeners open in the --network address family
+	network := portsession.ListenNetwork(config.Protocol, config.Network)
+
+	// A busy local port would only fail once the session is up, so check it before starting one
+	if config.Stdio == "" && config.LocalPort != "0" {
+		reuse := sessionutil.ReuseControl(config.ReuseAddr, config.ReusePort)
+		port, err := findLocalPort(network, config.BindHost, config.LocalPort, config.PortIncrement, reuse)
+		if err != nil {
+			return stageError(StageLocalPort, CodePortConflict, err)
+		}
+		if port != config.LocalPort {
+			logger.Infof("Local port %s is in use; using local port %s", config.LocalPort, port)
+			config.LocalPort = port
+		}
+	}
+
+	// The agent gets the locally resolved address, while TLS and probes still verify the name
+	hostParam := config.RemoteHost
+	if config.ResolveLocally && config.RemoteHost != "localhost" && config.RemoteHost != "127.0.0.1" {
+		ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+		chosen, all, err := resolveRemoteHost(ctx, newResolver(config.Resolver), config.RemoteHost)
+		cancel()
+		if err != nil {
+			return stageError(StageResolveHost, CodeRemoteUnreachable, err)
+		}
+		logger.Infof("Resolved %s locally to %s (found: %s)", config.RemoteHost, chosen, strings.Join(all, ", "))
+		hostParam = chosen
+	}
+
 	// Set up signal handling - buffered to prevent signal loss
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	if config.OnInterrupt == InterruptDetach {
+		signal.Ignore(syscall.SIGINT)
+		logger.Info("Ignoring interrupts (--on-interrupt detach); stop the forward with SIGTERM")
+	}
+	signal.Notify(sigChan, shutdownSignals(config.OnInterrupt)...)
+	forwardServiceStop(config.serviceStop, sigChan)
+	// Pausing only closes newly accepted connections, so it works before the session is up too
+	paused := new(atomic.Bool)
+	defer watchPauseSignals(logger, paused, events)()
 
 	// Create SSM client — PROFILE-002: aws_session phase
 	span := prof.Begin(profile.PhaseAWSSession)
-	sdkutil.SetRegionAndProfile(config.Region, config.Profile)
-	sess, err := sdkutil.GetNewSessionWithEndpoint("")
+	region, awsProfile := sdkutil.ResolveRegion(config.Region), sdkutil.ResolveProfile(config.Profile)
+	config.Profile = awsProfile.Value
+	sdkutil.SetRegionAndProfile(region.Value, awsProfile.Value)
+	sdkutil.SetSigningOverrides(config.SigningRegion, config.SigningName)
+	newSession := func() (*awssession.Session, error) { return sdkutil.GetNewSessionWithEndpoint("") }
+	var sess *awssession.Session
+	if config.SessionJSON != "" {
+		// A pre-started session needs no credentials, so they are not loaded up front
+		sess, err = newSession()
+	} else {
+		sess, err = resolveCredentials(newSession, config.Profile, config.SSOLogin)
+	}
 	if err != nil {
 		span.EndWithError(err)
-		return fmt.Errorf("failed to create AWS session: %w", err)
+		return stageError(StageAWSSession, CodeAuthFailed, fmt.Errorf("failed to create AWS session: %w", err))
 	}
+	logger.Debugf("Using region %s from %s and profile %s from %s", aws.StringValue(sess.Config.Region),
+		sdkutil.RegionSource(sess, region), cmp.Or(awsProfile.Value, "default"), awsProfile.Source)
 	ssmClient := ssm.New(sess)
+	traceRequests(ssmClient.Client, wsTrace)
 	span.End()
 
+	if config.ASG != "" {
+		instanceID, err := resolveASGInstance(autoscaling.New(sess), ec2.New(sess), config.ASG)
+		if err != nil {
+			return stageError(StageResolveTarget, CodeNoTarget, err)
+		}
+		logger.Infof("Selected instance %s from auto scaling group %s", instanceID, config.ASG)
+		config.InstanceID = instanceID
+	}
+	// A cached instance that fails to start a session is forgotten, in case it was replaced
+	forgetCachedTarget := func() {}
+	if _, _, ok := targetFilter(config.InstanceID); ok {
+		var cache *instanceCache
+		if !config.NoCache && config.ResolverCacheTTL > 0 {
+			cache = newInstanceCache(config.ResolverCacheTTL)
+		}
+		cacheKey := resolverCacheKey(config.InstanceID, aws.StringValue(sess.Config.Region), config.Profile)
+		if instanceID, ok := cache.lookup(cacheKey); ok {
+			logger.Infof("Resolved %s to instance %s (cached)", config.InstanceID, instanceID)
+			forgetCachedTarget = func() {
+				if err := cache.forget(cacheKey); err != nil {
+					logger.Warnf("Failed to update the instance resolver cache: %v", err)
+				}
+			}
+			config.InstanceID = instanceID
+		} else {
+			instanceID, err := resolveTargetInstance(ec2.New(sess), config.InstanceID)
+			if err != nil {
+				return stageError(StageResolveTarget, CodeNoTarget, err)
+			}
+			logger.Infof("Resolved %s to instance %s", config.InstanceID, instanceID)
+			if err := cache.store(cacheKey, instanceID); err != nil {
+				logger.Warnf("Failed to update the instance resolver cache: %v", err)
+			}
+			config.InstanceID = instanceID
+		}
+	}
+	tracer.target(config.InstanceID, aws.StringValue(sess.Config.Region), config.DocumentName)
+
 	// If local port is 0, use OS to allocate an available port
 	actualLocalPort := config.LocalPort
-	if config.LocalPort == "0" {
+	// A TCP port is allocated by binding the session's listener, which is handed over still open
+	var preBound net.Listener
+	if config.Stdio != "" {
+		actualLocalPort = "stdio"
+	} else if config.LocalPort == "0" {
 		logger.Info("Local port 0 specified, allocating available port from OS...")
-		allocatedPort, err := allocatePort()
+		var allocatedPort string
+		var err error
+		if config.Protocol == "udp" {
+			allocatedPort, err = allocatePort(network, config.BindHost)
+		} else {
+			preBound, allocatedPort, err = bindLocalPort(network, config.BindHost, sessionutil.ReuseControl(config.ReuseAddr, config.ReusePort))
+		}
 		if err != nil {
-			return fmt.Errorf("failed to allocate port: %w", err)
+			return stageError(StageAllocatePort, CodePortConflict, fmt.Errorf("failed to allocate port: %w", err))
+		}
+		if preBound != nil {
+			// The session closes it when it stops; this covers failing before the session starts
+			defer preBound.Close()
 		}
 		actualLocalPort = allocatedPort
 		logger.Infof("OS allocated port: %s", actualLocalPort)
 	}
+	// With --on-remote-close keep the port stays bound across sessions, so a fixed one is bound now too
+	var persistent *persistentListener
+	if config.OnRemoteClose == RemoteCloseKeep {
+		if preBound == nil {
+			listenConfig := net.ListenConfig{Control: sessionutil.ReuseControl(config.ReuseAddr, config.ReusePort)}
+			var err error
+			if preBound, err = listenConfig.Listen(context.Background(), network, net.JoinHostPort(config.BindHost, actualLocalPort)); err != nil {
+				return stageError(StageLocalPort, CodePortConflict, fmt.Errorf("failed to listen on local port %s: %w", actualLocalPort, err))
+			}
+			defer preBound.Close()
+		}
+		persistent = newPersistentListener(preBound)
+		preBound = persistent.handoff()
+	}
+
+	// Tag all further logs, including those from the session goroutines, with this forward's label
+	logger = logger.WithContext(forwardLabel(config, actualLocalPort))
 
 	// Prepare port forwarding parameters
 	params := map[string][]*string{
-		"portNumber":      {&config.RemotePort},
-		"localPortNumber": {&actualLocalPort},
+		"portNumber": {&config.RemotePort},
+	}
+	if config.Stdio == "" {
+		params["localPortNumber"] = []*string{&actualLocalPort}
 	}
 
 	// Add host parameter if not localhost (for multi-hop forwarding)
 	if config.RemoteHost != "localhost" && config.RemoteHost != "127.0.0.1" {
-		params["host"] = []*string{&config.RemoteHost}
+		params["host"] = []*string{&hostParam}
 	}
+	mergeDocumentParameters(logger, params, config.DocumentParameters)
 
 	// Start SSM session
+	localDesc := "local " + config.LocalPort
+	if config.Stdio != "" {
+		localDesc = "stdio"
+	}
 	var forwardDesc string
 	if config.RemoteHost == "localhost" || config.RemoteHost == "127.0.0.1" {
-		forwardDesc = fmt.Sprintf("local %s -> bastion %s", config.LocalPort, config.RemotePort)
+		forwardDesc = fmt.Sprintf("%s -> bastion %s", localDesc, config.RemotePort)
 	} else {
-		forwardDesc = fmt.Sprintf("local %s -> bastion -> %s:%s", config.LocalPort, config.RemoteHost, config.RemotePort)
+		forwardDesc = fmt.Sprintf("%s -> bastion -> %s:%s", localDesc, config.RemoteHost, config.RemotePort)
 	}
-	logger.Infof("Starting port forward: %s on instance %s (document: %s)", forwardDesc, config.InstanceID, config.DocumentName)
-
 	startSessionInput := &ssm.StartSessionInput{
 		Target:       &config.InstanceID,
 		DocumentName: &config.DocumentName,
 		Parameters:   params,
 	}
+	if config.PrintCommand {
+		command, err := startSessionCommand(startSessionInput, aws.StringValue(sess.Config.Region), config.Profile)
+		if err != nil {
+			return stageError(StageWriteOutput, CodeInternal, fmt.Errorf("failed to build the aws CLI command: %w", err))
+		}
+		fmt.Fprintln(os.Stderr, command)
+	}
+	if config.DryRun {
+		return dryRun(os.Stdout, ssmClient, config.OutputFormat, dryRunInfo{
+			Target:     config.InstanceID,
+			Region:     aws.StringValue(sess.Config.Region),
+			Document:   config.DocumentName,
+			Forwarding: forwardDesc,
+			Parameters: params,
+		})
+	}
 
-	// PROFILE-002: ssm_start_session phase
-	span = prof.Begin(profile.PhaseSSMStartSession)
-	startSessionOutput, err := ssmClient.StartSession(startSessionInput)
-	if err != nil {
-		span.EndWithError(err)
-		return fmt.Errorf("failed to start SSM session: %w", err)
+	// audit stays nil without --audit-log-group; its methods then do nothing
+	var audit *auditLog
+	if config.AuditLogGroup != "" {
+		var err error
+		audit, err = startAudit(sts.New(sess), cloudwatchlogs.New(sess), config.AuditLogGroup, auditRecord{
+			Target:     config.InstanceID,
+			Region:     aws.StringValue(sess.Config.Region),
+			Document:   config.DocumentName,
+			Forwarding: forwardDesc,
+		})
+		if err != nil {
+			if config.AuditRequired {
+				return stageError(StageAudit, CodeSessionError, fmt.Errorf("failed to set up audit log: %w", err))
+			}
+			logger.Warnf("Not writing audit records: %v", err)
+		}
+	}
+
+	var startSessionOutput *ssm.StartSessionOutput
+	if config.SessionJSON != "" {
+		resp, err := readSessionResponse(config.SessionJSON, os.Stdin)
+		if err != nil {
+			return stageError(StageStartSession, CodeInvalidArgs, err)
+		}
+		if config.InstanceID == "" {
+			config.InstanceID = resp.TargetId
+		}
+		logger.Infof("Using pre-started session for port forward: %s on instance %s", forwardDesc, config.InstanceID)
+		startSessionOutput = resp.toStartSessionOutput()
+	} else {
+		logger.Infof("Starting port forward: %s on instance %s (document: %s)", forwardDesc, config.InstanceID, config.DocumentName)
+
+		// PROFILE-002: ssm_start_session phase
+		span = prof.Begin(profile.PhaseSSMStartSession)
+		endTrace := tracer.startSession(config.InstanceID, aws.StringValue(sess.Config.Region), config.DocumentName)
+		startSessionOutput, err = startSessionWithRetry(logger, func() (*ssm.StartSessionOutput, error) {
+			return ssmClient.StartSessionWithContext(aws.BackgroundContext(), startSessionInput, withoutSDKRetries)
+		}, config.StartRetries, config.StartRetryMaxDelay, sigChan)
+		if isTargetNotConnected(err) {
+			err = explainTargetNotConnected(ssmClient, config.InstanceID, err)
+		}
+		endTrace(err)
+		if err != nil {
+			forgetCachedTarget()
+			span.EndWithError(err)
+			return stageError(StageStartSession, CodeStartSessionFailed, fmt.Errorf("failed to start SSM session: %w", err))
+		}
+		span.End()
 	}
-	span.End()
 
 	if startSessionOutput.SessionId == nil || startSessionOutput.TokenValue == nil || startSessionOutput.StreamUrl == nil {
-		return errors.New("invalid session response: missing required fields")
+		return stageError(StageStartSession, CodeStartSessionFailed, errors.New("invalid session response: missing required fields"))
 	}
 
 	logger.Infof("Session started: %s", *startSessionOutput.SessionId)
+	tracer.sessionStarted(*startSessionOutput.SessionId)
 
 	// Create session
-	clientId := uuid.NewString()
+	clientId := config.ClientID
+	if clientId == "" {
+		clientId = uuid.NewString()
+	}
+	if err := recordSessionStart(logger, audit, ssmClient, config.AuditRequired, *startSessionOutput.SessionId, clientId); err != nil {
+		return stageError(StageAudit, CodeSessionError, err)
+	}
+	// Covers returns before the session is up; the shutdown paths below record their own reason first
+	defer func() {
+		reason := "session ended"
+		if err != nil {
+			reason = fmt.Sprintf("error: %v", err)
+		}
+		audit.sessionStopped(logger, reason)
+	}()
 	sess2 := &session.Session{
 		SessionId:   *startSessionOutput.SessionId,
 		StreamUrl:   *startSessionOutput.StreamUrl,
 		TokenValue:  *startSessionOutput.TokenValue,
 		ClientId:    clientId,
 		TargetId:    config.InstanceID,
-		DataChannel: &datachannel.DataChannel{},
+		DataChannel: &datachannel.DataChannel{OutgoingBufferHighWater: config.BufferHighWater},
 		// READY-007, READY-008: Readiness signaling channels
-		PortReady: make(chan struct{}),
-		PortError: make(chan error, 1),
+		PortReady:      make(chan struct{}),
+		PortError:      make(chan error, 1),
+		MaxConnections: config.MaxConnections,
+		ListenBacklog:  config.ListenBacklog,
+		LocalListener:  preBound,
+		RateLimit:      config.RateLimit,
+		BufferSize:     config.BufferSize,
+		ConnectTimeout: config.ConnectTimeout,
+		DialTimeout:    config.DialTimeout,
+		// Opt-in socket reuse for fast restarts on a fixed port
+		ListenReuseAddr: config.ReuseAddr,
+		ListenReusePort: config.ReusePort,
+		// Application-level traffic for proxies that ignore websocket pings
+		KeepaliveInterval: config.KeepaliveInterval,
+		// Bounds parallel tunnel stream setup for bursts of connections
+		AcceptConcurrency: config.AcceptConcurrency,
+		// Per-connection byte counts for diagnosing one-way stalls
+		TransferLogInterval: config.TransferLogInterval,
+		ConnLogSample:       config.ConnLogSample,
+		MuxIdleTimeout:      config.MuxIdleTimeout,
+		IOTimeout:           config.IOTimeout,
+		DrainTimeout:        config.DrainTimeout,
+		Drained:             make(chan struct{}),
+		Paused:              paused,
+		HandshakeTrace:      wsTrace,
+		// Local listener protocol (tcp or udp)
+		PortForwardingProtocol: config.Protocol,
+		PortForwardingBindHost: config.BindHost,
+		PortForwardingNetwork:  config.Network,
+		// Lets the port session reject agents too old for remote host forwarding up front
+		PortForwardingToRemoteHost: config.RemoteHost != "localhost" && config.RemoteHost != "127.0.0.1",
+		PortForwardingStdio:        config.Stdio != "",
+		ReconnectOn:                config.ReconnectOn,
+		OnReconnect: func(reconnecting bool) {
+			health.reconnecting.Store(reconnecting)
+			events.reconnect(reconnecting)
+			tracer.reconnect(reconnecting)
+			stats.reconnect(reconnecting)
+		},
+		OnListening: func(addr net.Addr) {
+			servedOnce.Do(func() { close(served) })
+			select {
+			case listening <- addr:
+			default:
+			}
+		},
+		OnForwardingType: func(forwardingType string) {
+			select {
+			case forwardingTypes <- forwardingType:
+			default:
+			}
+		},
+		OnConnOpened:    onConnOpened,
+		OnConnClosed:    onConnClosed,
+		LocalTLSConfig:  localTLS,
+		RemoteTLSConfig: remoteTLS,
+		Transfer:        &session.TransferStats{},
+		ProxyProtocol:   config.ProxyProtocol,
+		ReadOnly:        config.ReadOnly,
+		// Lets clients tell a remote that could not be reached from an empty reply
+		ResetOnRemoteFailure: config.ResetOnRemoteFailure,
+		// Keeps the port session from turning SIGINT back on once it is ignored
+		IgnoreInterrupt: config.OnInterrupt == InterruptDetach,
 	}
 
 	// Start session in goroutine — PROFILE-002: websocket_open phase starts here
 	// (covers WebSocket connect, TLS, datachannel open, handshake, session type, port session init)
 	span = prof.Begin(profile.PhaseWebSocketOpen)
-	sessionErr := make(chan error, 1)
-	go func() {
-		if err := sess2.Execute(logger); err != nil {
-			sessionErr <- err
-		}
-	}()
+	tracer.establishing(sess2.PortReady)
+	// Sessions started for --on-remote-close keep copy this one before it runs
+	template := *sess2
+	sessionStart := time.Now()
+	sessionErr, sessionEnded := executeSession(logger, sess2)
+
+	// "verified" when a probe confirmed the remote end is reachable
+	status := "active"
+	var establishTime time.Duration
 
 	// READY-001, READY-002, READY-007, READY-008, SIGNAL-011
 	// Wait for port to be available if requested
@@ -325,13 +1676,43 @@ func run(config *PortForwardConfig) error {
 		}()
 
 		logger.Infof("Waiting for port %s to be ready (timeout: %v)", actualLocalPort, config.Timeout)
-		if err := waitForReady(actualLocalPort, sess2.PortReady, sess2.PortError, config.Timeout, done, prof, span); err != nil {
+		waitStart := time.Now()
+		// A pre-bound listener accepts dials before the session serves it, so they prove nothing
+		var servedSignal <-chan struct{}
+		if preBound != nil {
+			servedSignal = served
+		}
+		poll := readyPoll{interval: config.WaitPollInterval, reconnecting: &health.reconnecting}
+		if err := waitForReady(network, config.BindHost, actualLocalPort, servedSignal, sess2.PortReady, sess2.PortError, config.Timeout, poll, done, prof, span); err != nil {
 			if errors.Is(err, errSignalReceived) {
-				return cleanupSession(logger, sess2)
+				return stageError(StageCleanup, CodeSessionError, cleanupSession(logger, sess2))
 			}
-			return fmt.Errorf("port forward failed to establish: %w", err)
+			return stageError(StageWaitReady, CodeSessionError, fmt.Errorf("port forward failed to establish: %w", err))
 		}
 		logger.Infof("Port forward established on local port %s", actualLocalPort)
+
+		if config.Probe.Mode != ProbeNone {
+			logger.Infof("Probing port forward end-to-end (%s)", config.Probe.Mode)
+			var err error
+			if config.WaitForRemote {
+				err = waitForRemote(dialHost(config.BindHost), actualLocalPort, config.Probe, time.Until(waitStart.Add(config.Timeout)), done)
+			} else {
+				err = runProbe(dialHost(config.BindHost), actualLocalPort, config.Probe, config.Timeout)
+			}
+			if errors.Is(err, errSignalReceived) {
+				return stageError(StageCleanup, CodeSessionError, cleanupSession(logger, sess2))
+			}
+			if err != nil {
+				if cleanupErr := cleanupSession(logger, sess2); cleanupErr != nil {
+					logger.Warnf("Cleanup error during error handling: %v", cleanupErr)
+				}
+				return stageError(StageProbe, CodeProbeFailed, fmt.Errorf("port forward failed to establish: %w", err))
+			}
+			status = "verified"
+			logger.Info("Probe succeeded")
+		}
+		establishTime = time.Since(waitStart)
+		health.ready.Store(true)
 	}
 
 	// Construct forwarding specification with actual port
@@ -342,40 +1723,217 @@ func run(config *PortForwardConfig) error {
 		forwardingSpec = fmt.Sprintf("%s:%s:%s", actualLocalPort, config.RemoteHost, config.RemotePort)
 	}
 
-	// Convert port to integer for output
-	portNum, err := strconv.Atoi(actualLocalPort)
-	if err != nil {
-		return fmt.Errorf("failed to convert port to integer: %w", err)
+	// Convert port to integer for output; a stdio forward has none and reports 0
+	var portNum int
+	if config.Stdio == "" {
+		if portNum, err = strconv.Atoi(actualLocalPort); err != nil {
+			return stageError(StageWriteOutput, CodeInternal, fmt.Errorf("failed to convert port to integer: %w", err))
+		}
+	}
+	// Stdout carries a stdio forward's connection, so output is only written to --output files
+	reportOutput := config.Stdio == "" || config.OutputFile != ""
+	var localAddress, localDialAddress string
+	if config.Stdio == "" {
+		// Without --wait the listener may not be open yet, so fall back to the configured bind host
+		var listenAddr net.Addr
+		select {
+		case listenAddr = <-listening:
+		default:
+		}
+		localAddress, localDialAddress = localAddresses(config.BindHost, actualLocalPort, listenAddr)
+	}
+	// Without --wait the session may not have picked its forwarding yet
+	var forwardingType string
+	select {
+	case forwardingType = <-forwardingTypes:
+	default:
 	}
 
 	// Output port and PID info
 	output := OutputInfo{
-		Type:       "ssm-port-forward",
-		Port:       portNum,
-		PID:        os.Getpid(),
-		Status:     "active",
-		Timestamp:  time.Now().Format(time.RFC3339),
-		Forwarding: forwardingSpec,
-		Bastion:    config.InstanceID,
+		Type:             "ssm-port-forward",
+		Port:             portNum,
+		PID:              os.Getpid(),
+		Status:           status,
+		Timestamp:        time.Now().Format(time.RFC3339),
+		Forwarding:       forwardingSpec,
+		Bastion:          config.InstanceID,
+		Format:           config.OutputFormat,
+		ClientID:         clientId,
+		EstablishMs:      establishTime.Milliseconds(),
+		LocalAddress:     localAddress,
+		LocalDialAddress: localDialAddress,
+		ForwardingType:   forwardingType,
+	}
+
+	if reportOutput {
+		if err := writeOutput(config.OutputFile, output); err != nil {
+			return stageError(StageWriteOutput, CodeInternal, fmt.Errorf("failed to write output: %w", err))
+		}
+		if config.OutputFile != "" {
+			// However the forward exits, leave the record saying it is gone rather than active.
+			// Deferred, so it follows the summary that the exit paths append with --summary.
+			defer func() {
+				if err := writeTerminatedOutput(config.OutputFile, output, config.Summary); err != nil {
+					logger.Warnf("Failed to mark the output terminated: %v", err)
+				}
+			}()
+		}
+	}
+	events.establishedOn(portNum, forwardingSpec)
+
+	if config.Wait && config.Stdio == "" {
+		if err := writeReadyMarker(os.Stdout, config.ReadyMarker, portNum); err != nil {
+			return stageError(StageWriteOutput, CodeInternal, fmt.Errorf("failed to write ready marker: %w", err))
+		}
+	}
+
+	if config.PortFD > 0 {
+		if err := writePortFD(config.PortFD, actualLocalPort); err != nil {
+			return stageError(StageWriteOutput, CodeInternal, fmt.Errorf("failed to write port to fd %d: %w", config.PortFD, err))
+		}
 	}
 
-	if err := writeOutput(config.OutputFile, output); err != nil {
-		return fmt.Errorf("failed to write output: %w", err)
+	if config.SelfTest != SelfTestNone {
+		logger.Infof("Running %s self-test through the tunnel (%d bytes)", config.SelfTest, config.SelfTestBytes)
+		report, err := runSelfTest(net.JoinHostPort(dialHost(config.BindHost), actualLocalPort), config.SelfTest, config.SelfTestBytes)
+		health.stopped.Store(true)
+		events.terminated("self-test finished")
+		tracer.terminated("self-test finished")
+		audit.sessionStopped(logger, "self-test finished")
+		cleanupErr := cleanupSession(logger, sess2)
+		if err != nil {
+			return stageError(StageSelfTest, CodeSessionError, err)
+		}
+		if err := printSelfTest(os.Stdout, config.OutputFormat, report); err != nil {
+			return stageError(StageWriteOutput, CodeInternal, fmt.Errorf("failed to write self-test report: %w", err))
+		}
+		return stageError(StageCleanup, CodeSessionError, cleanupErr)
+	}
+
+	// sessionFailed tears the forward down after the session fails
+	sessionFailed := func(err error) error {
+		logger.Errorf("Session error: %v", err)
+		health.stopped.Store(true)
+		events.terminated(fmt.Sprintf("session error: %v", err))
+		tracer.terminated(fmt.Sprintf("session error: %v", err))
+		audit.sessionStopped(logger, fmt.Sprintf("session error: %v", err))
+		if cleanupErr := cleanupSession(logger, sess2); cleanupErr != nil {
+			logger.Warnf("Cleanup error during error handling: %v", cleanupErr)
+		}
+		if config.Summary && reportOutput {
+			writeSessionSummary(logger, config.OutputFile, clientId, sessionStart, sess2.Transfer)
+		}
+		return stageError(StageSession, CodeSessionError, fmt.Errorf("session error: %w", err))
+	}
+	// replaceSession starts a new session behind the same local listener once the agent has
+	// ended the current one, for --on-remote-close keep
+	replaceSession := func() error {
+		logger.Infof("Session %s was ended by the remote; starting a new session", sess2.SessionId)
+		health.reconnecting.Store(true)
+		events.reconnect(true)
+		tracer.reconnect(true)
+		stats.reconnect(true)
+		defer func() {
+			health.reconnecting.Store(false)
+			events.reconnect(false)
+			tracer.reconnect(false)
+		}()
+		out, err := startSessionWithRetry(logger, func() (*ssm.StartSessionOutput, error) {
+			return ssmClient.StartSessionWithContext(aws.BackgroundContext(), startSessionInput, withoutSDKRetries)
+		}, config.StartRetries, config.StartRetryMaxDelay, sigChan)
+		if err != nil {
+			return fmt.Errorf("failed to replace the session ended by the remote: %w", err)
+		}
+		if out.SessionId == nil || out.TokenValue == nil || out.StreamUrl == nil {
+			return errors.New("failed to replace the session ended by the remote: missing required fields")
+		}
+		logger.Infof("Session started: %s", *out.SessionId)
+		if err := recordSessionStart(logger, audit, ssmClient, config.AuditRequired, *out.SessionId, clientId); err != nil {
+			return err
+		}
+		sess2 = nextSession(template, out, config.BufferHighWater, persistent.handoff())
+		sessionErr, sessionEnded = executeSession(logger, sess2)
+		return nil
 	}
 
 	// SIGNAL-004, SIGNAL-005, SIGNAL-006, SIGNAL-009, SIGNAL-010
 	// Always wait for signal or error with cleanup (SIGNAL-007, SIGNAL-008)
 	// This ensures proper cleanup regardless of --wait flag
+	for {
+		select {
+		case sig := <-sigChan:
+			logger.Infof("Received signal %v, initiating shutdown...", sig)
+			health.stopped.Store(true)
+			events.terminated(fmt.Sprintf("signal: %v", sig))
+			tracer.terminated(fmt.Sprintf("signal: %v", sig))
+			audit.sessionStopped(logger, fmt.Sprintf("signal: %v", sig))
+			if sig == os.Interrupt && config.DrainTimeout > 0 {
+				waitForDrain(logger, sess2.Drained, config.DrainTimeout, sigChan)
+			}
+			cleanupErr := cleanupSession(logger, sess2)
+			if config.Summary && reportOutput {
+				writeSessionSummary(logger, config.OutputFile, clientId, sessionStart, sess2.Transfer)
+			}
+			return stageError(StageCleanup, CodeSessionError, cleanupErr)
+		case err := <-sessionErr:
+			if persistent != nil && sess2.DataChannel.IsSessionEnded() {
+				if err = replaceSession(); err == nil {
+					continue
+				}
+			}
+			return sessionFailed(err)
+		case <-sessionEnded:
+			if persistent != nil && sess2.DataChannel.IsSessionEnded() {
+				if err := replaceSession(); err != nil {
+					return sessionFailed(err)
+				}
+				continue
+			}
+			// A stdio forward ends the session itself once its connection closes
+			logger.Info("Session ended")
+			health.stopped.Store(true)
+			events.terminated("session ended")
+			tracer.terminated("session ended")
+			audit.sessionStopped(logger, "session ended")
+			cleanupErr := cleanupSession(logger, sess2)
+			if config.Summary && reportOutput {
+				writeSessionSummary(logger, config.OutputFile, clientId, sessionStart, sess2.Transfer)
+			}
+			return stageError(StageCleanup, CodeSessionError, cleanupErr)
+		}
+	}
+}
+
+// forwardLabel returns the log label for a forward: the --label override if set,
+// otherwise [localPort->remoteHost:remotePort].
+func forwardLabel(config *PortForwardConfig, localPort string) string {
+	if config.Label != "" {
+		return config.Label
+	}
+	return fmt.Sprintf("[%s->%s:%s]", localPort, config.RemoteHost, config.RemotePort)
+}
+
+// shutdownSignals returns the signals that stop the forward for an --on-interrupt mode.
+func shutdownSignals(onInterrupt string) []os.Signal {
+	if onInterrupt == InterruptDetach {
+		return []os.Signal{syscall.SIGTERM, syscall.SIGHUP}
+	}
+	return []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+}
+
+// waitForDrain blocks until the port session reports that open connections have drained,
+// the drain timeout (plus a grace period for the session's own deadline) expires, or a
+// second signal forces an immediate shutdown.
+func waitForDrain(logger log.T, drained <-chan struct{}, timeout time.Duration, sigChan <-chan os.Signal) {
+	logger.Infof("Draining open connections (timeout: %v)", timeout)
 	select {
+	case <-drained:
+		logger.Info("Connections drained")
+	case <-time.After(timeout + time.Second):
+		logger.Warn("Drain timeout exceeded")
 	case sig := <-sigChan:
-		logger.Infof("Received signal %v, initiating shutdown...", sig)
-		return cleanupSession(logger, sess2)
-	case err := <-sessionErr:
-		logger.Errorf("Session error: %v", err)
-		if cleanupErr := cleanupSession(logger, sess2); cleanupErr != nil {
-			logger.Warnf("Cleanup error during error handling: %v", cleanupErr)
-		}
-		return fmt.Errorf("session error: %w", err)
+		logger.Infof("Received signal %v during drain, forcing shutdown", sig)
 	}
 }
 
@@ -416,46 +1974,88 @@ func cleanupSession(logger log.T, sess *session.Session) error {
 
 // READY-009: removed grace timer — Phase 2 is now a non-blocking check.
 
-// waitForReady waits for the local TCP port and optionally for remote readiness.
+// defaultPollInterval is the first delay between checks of the local port while waiting.
+const defaultPollInterval = 100 * time.Millisecond
+
+// maxPollInterval caps the backoff between checks of the local port, unless the first delay is longer.
+const maxPollInterval = time.Second
+
+// readyPoll paces waitForReady's checks of the local port.
+type readyPoll struct {
+	// interval is the first delay between checks (default: defaultPollInterval); each failed check
+	// doubles it up to maxPollInterval
+	interval time.Duration
+	// reconnecting, when set, reports a data channel reconnect, which does not count toward the timeout
+	reconnecting *atomic.Bool
+}
+
+// delays returns the first delay between checks and the most it backs off to.
+func (p readyPoll) delays() (time.Duration, time.Duration) {
+	interval := p.interval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	return interval, max(interval, maxPollInterval)
+}
+
+// isReconnecting reports whether a reconnect is in progress.
+func (p readyPoll) isReconnecting() bool {
+	return p.reconnecting != nil && p.reconnecting.Load()
+}
+
+// waitForReady waits for the local port and optionally for remote readiness.
 // The done channel allows the caller to cancel the wait (e.g. on signal receipt).
+// When served is set the local port counts as ready once it is closed instead of when it accepts a dial.
+// A refused check is retried with backoff per poll, and time spent reconnecting does not count
+// toward timeout, since the port may briefly go away and come back while the session resumes.
 // The prof parameter records per-phase timing (nil-safe).
 // The sessionSpan is ended when Phase 1 succeeds (local port ready = session setup complete).
-// READY-001, READY-002, READY-003, READY-004, READY-007, READY-008, READY-009, SIGNAL-011, PROFILE-002
-func waitForReady(port string, portReady <-chan struct{}, portError <-chan error, timeout time.Duration, done <-chan struct{}, prof *profile.Profiler, sessionSpan profile.Span) error {
-	deadline := time.After(timeout)
+// READY-001, READY-002, READY-003, READY-004, READY-007, READY-008, READY-009, READY-010, READY-011, SIGNAL-011, PROFILE-002
+func waitForReady(network string, bindHost string, port string, served <-chan struct{}, portReady <-chan struct{}, portError <-chan error, timeout time.Duration, poll readyPoll, done <-chan struct{}, prof *profile.Profiler, sessionSpan profile.Span) error {
+	remaining := timeout
+	last := time.Now()
+	delay, maxDelay := poll.delays()
 
 	// Phase 1: READY-002 — Wait for local TCP listener to accept connections
 	// PROFILE-002: wait_local_port phase
 	p1 := prof.Begin(profile.PhaseWaitLocalPort)
 	for {
-		conn, dialErr := net.DialTimeout("tcp", "localhost:"+port, 100*time.Millisecond)
-		if dialErr == nil {
-			conn.Close()
+		if localServed(network, bindHost, port, served) {
 			p1.End()
 			// PROFILE-002: websocket_open span ends when local port is ready
 			// (session setup = WebSocket + handshake + port session init is complete)
 			sessionSpan.End()
 			break // Local port ready
 		}
+
+		// READY-010: Only time outside a reconnect counts toward the timeout
+		now := time.Now()
+		if !poll.isReconnecting() {
+			remaining -= now.Sub(last)
+		}
+		last = now
+		if remaining <= 0 {
+			p1.EndWithError(errWaitTimeout)
+			sessionSpan.EndWithError(errWaitTimeout)
+			// READY-004: Timeout waiting for local port
+			return fmt.Errorf("%w: local port %s not ready", errWaitTimeout, port)
+		}
+
+		// READY-011: A refused check is not a failure; check again after a backoff delay
 		select {
 		case err := <-portError:
 			p1.EndWithError(err)
 			sessionSpan.EndWithError(err)
 			// READY-003: ConnectToPortError before local port is ready
 			return fmt.Errorf("%w: %v", errRemotePortFailed, err)
-		case <-deadline:
-			p1.EndWithError(errWaitTimeout)
-			sessionSpan.EndWithError(errWaitTimeout)
-			// READY-004: Timeout waiting for local port
-			return fmt.Errorf("%w: local port %s not ready", errWaitTimeout, port)
 		case <-done:
 			p1.EndWithError(errSignalReceived)
 			sessionSpan.EndWithError(errSignalReceived)
 			// SIGNAL-011: Signal received during Phase 1
 			return errSignalReceived
-		default:
-			time.Sleep(100 * time.Millisecond)
+		case <-time.After(min(delay, remaining)):
 		}
+		delay = min(delay*2, maxDelay)
 	}
 
 	// Phase 2: READY-007, READY-009 — Non-blocking check for remote readiness.
@@ -480,22 +2080,90 @@ func waitForReady(port string, portReady <-chan struct{}, portError <-chan error
 	}
 }
 
-// allocatePort uses the OS to allocate an available port.
+// localServed reports whether the session serves the local port: once served is closed when it is
+// set, otherwise once localPortReady says so.
+func localServed(network string, bindHost string, port string, served <-chan struct{}) bool {
+	if served == nil {
+		return localPortReady(network, bindHost, port)
+	}
+	select {
+	case <-served:
+		return true
+	default:
+		return false
+	}
+}
+
+// localPortReady reports whether the forward's local listener is up. TCP listeners are dialed;
+// UDP has no handshake, so a UDP port counts as ready once binding it fails because it is in use.
+func localPortReady(network string, bindHost string, port string) bool {
+	if strings.HasPrefix(network, "udp") {
+		conn, err := net.ListenPacket(network, net.JoinHostPort(bindHost, port))
+		if err != nil {
+			return errors.Is(err, syscall.EADDRINUSE)
+		}
+		conn.Close()
+		return false
+	}
+
+	conn, err := net.DialTimeout(network, net.JoinHostPort(dialHost(bindHost), port), 100*time.Millisecond)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// dialHost is the host to connect to for a listener bound to bindHost. Wildcard binds are
+// reached through localhost.
+func dialHost(bindHost string) string {
+	if ip := net.ParseIP(bindHost); bindHost == "" || ip != nil && ip.IsUnspecified() {
+		return "localhost"
+	}
+	return bindHost
+}
+
+// localAddresses returns the host:port a forward listens on, from listenAddr when the listener is
+// open and bindHost otherwise, and for a wildcard bind also the loopback host:port to connect to.
+func localAddresses(bindHost, port string, listenAddr net.Addr) (local string, dial string) {
+	local = net.JoinHostPort(bindHost, port)
+	if listenAddr != nil {
+		local = listenAddr.String()
+	}
+	host, _, _ := net.SplitHostPort(local)
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		loopback := net.IPv4(127, 0, 0, 1)
+		if ip.To4() == nil {
+			loopback = net.IPv6loopback
+		}
+		dial = net.JoinHostPort(loopback.String(), port)
+	}
+	return local, dial
+}
+
+// allocatePort uses the OS to allocate an available port on network (e.g. tcp4 or udp6).
 //
 // RACE CONDITION WARNING: There is a known race condition between when we close
 // the test listener and when SSM binds to the port. In the brief window between
 // listener.Close() and SSM starting its listener, another process could grab the port.
 //
-// Mitigation approaches considered:
-// - Keep listener open: Not possible - conflicts with SSM trying to bind
-// - Let SSM allocate: Would require port detection (scanning), which adds complexity
-// - Retry logic: Could be added if SSM session start fails due to port conflict
+// TCP forwards avoid it with bindLocalPort, whose listener the session serves directly.
+// UDP still uses this: its socket is opened by the session, which takes no pre-bound one.
 //
 // In practice, the race window is very small (milliseconds) and the ephemeral port
 // range is large (49152-65535), making collisions unlikely in normal operation.
-func allocatePort() (string, error) {
+func allocatePort(network string, bindHost string) (string, error) {
+	if strings.HasPrefix(network, "udp") {
+		conn, err := net.ListenPacket(network, net.JoinHostPort(bindHost, "0"))
+		if err != nil {
+			return "", fmt.Errorf("failed to allocate port: %w", err)
+		}
+		defer conn.Close()
+		return strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port), nil
+	}
+
 	// Listen on port 0 to let OS choose an available port
-	listener, err := net.Listen("tcp", "localhost:0")
+	listener, err := net.Listen(network, net.JoinHostPort(bindHost, "0"))
 	if err != nil {
 		return "", fmt.Errorf("failed to allocate port: %w", err)
 	}
@@ -508,8 +2176,160 @@ func allocatePort() (string, error) {
 	return port, nil
 }
 
+// bindLocalPort binds an OS-allocated TCP port on bindHost in network's family and returns the open
+// listener, for the session to serve via Session.LocalListener, and its port. control sets socket
+// options as for the session's own listeners.
+func bindLocalPort(network string, bindHost string, control func(network, address string, conn syscall.RawConn) error) (net.Listener, string, error) {
+	config := net.ListenConfig{Control: control}
+	listener, err := config.Listen(context.Background(), network, net.JoinHostPort(bindHost, "0"))
+	if err != nil {
+		return nil, "", err
+	}
+	return listener, strconv.Itoa(listener.Addr().(*net.TCPAddr).Port), nil
+}
+
+// checkLocalPort fails fast, as ssh does, when port is already bound on bindHost, so no SSM
+// session is started for a forward whose listener cannot open. The port is released at once.
+// control sets the same socket options as the forward's listener, so --reuse-port passes while
+// the forward being replaced still holds the port.
+func checkLocalPort(network string, bindHost string, port string, control func(network, address string, conn syscall.RawConn) error) error {
+	address := net.JoinHostPort(bindHost, port)
+	config := net.ListenConfig{Control: control}
+	var err error
+	if strings.HasPrefix(network, "udp") {
+		var conn net.PacketConn
+		if conn, err = config.ListenPacket(context.Background(), network, address); err == nil {
+			conn.Close()
+		}
+	} else {
+		var listener net.Listener
+		if listener, err = config.Listen(context.Background(), network, address); err == nil {
+			listener.Close()
+		}
+	}
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return fmt.Errorf("local port %s in use on %s: %w", port, bindHost, syscall.EADDRINUSE)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot listen on local port %s: %w", port, err)
+	}
+	return nil
+}
+
+// findLocalPort checks port as checkLocalPort does and, while it is in use, up to increment
+// ports after it, returning the first free one. Errors other than a busy port end the search.
+func findLocalPort(network string, bindHost string, port string, increment int, control func(network, address string, conn syscall.RawConn) error) (string, error) {
+	first, err := strconv.Atoi(port)
+	if err != nil {
+		return "", fmt.Errorf("invalid local port: %s", port)
+	}
+	last := min(first+increment, 65535)
+	for candidate := first; ; candidate++ {
+		err = checkLocalPort(network, bindHost, strconv.Itoa(candidate), control)
+		if err == nil {
+			return strconv.Itoa(candidate), nil
+		}
+		if !errors.Is(err, syscall.EADDRINUSE) || candidate >= last {
+			break
+		}
+	}
+	if last > first && errors.Is(err, syscall.EADDRINUSE) {
+		return "", fmt.Errorf("local ports %d-%d in use on %s: %w", first, last, bindHost, syscall.EADDRINUSE)
+	}
+	return "", err
+}
+
+// writeOutput writes output as a JSON line, or a table for --output-format table, to filename or
+// stdout when filename is empty.
 func writeOutput(filename string, output OutputInfo) error {
-	data, err := json.Marshal(output)
+	var data []byte
+	var err error
+	if output.Format == OutputFormatTable {
+		data, err = formatOutputTable(output)
+	} else if data, err = json.Marshal(output); err == nil {
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return err
+	}
+
+	if filename == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return writeFileAtomic(filename, data, 0644)
+}
+
+// writeFileAtomic replaces filename with data by writing a temporary file in the same directory
+// and renaming it over filename, so a reader polling the file sees the old or the new contents,
+// never a partial write.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) (err error) {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
+
+// formatOutputTable renders output as an aligned status table for people watching the terminal.
+func formatOutputTable(output OutputInfo) ([]byte, error) {
+	localPort := strconv.Itoa(output.Port)
+	if output.Port == 0 {
+		localPort = "stdio"
+	}
+	// Forwarding is localPort:[remoteHost:]remotePort; the remote host is omitted for localhost
+	_, destination, _ := strings.Cut(output.Forwarding, ":")
+	if !strings.Contains(destination, ":") {
+		destination = "localhost:" + destination
+	}
+
+	var buf bytes.Buffer
+	table := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "LOCAL PORT\tDESTINATION\tINSTANCE\tPID\tSTATUS")
+	fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%s\n", localPort, destination, output.Bastion, output.PID, output.Status)
+	if err := table.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// newSummary builds the shutdown summary for a session that started at start.
+func newSummary(clientID string, start time.Time, transfer *session.TransferStats) SummaryInfo {
+	sent, received := transfer.Sent(), transfer.Received()
+	return SummaryInfo{
+		Type:             "ssm-port-forward-summary",
+		ClientID:         clientID,
+		DurationSeconds:  time.Since(start).Seconds(),
+		BytesSent:        sent,
+		BytesReceived:    received,
+		BytesTransferred: sent + received,
+	}
+}
+
+// writeSummary appends summary as a JSON line to filename, after the line written by
+// writeOutput, or prints it to stdout when filename is empty.
+func writeSummary(filename string, summary SummaryInfo) error {
+	data, err := json.Marshal(summary)
 	if err != nil {
 		return err
 	}
@@ -518,10 +2338,106 @@ func writeOutput(filename string, output OutputInfo) error {
 		fmt.Println(string(data))
 		return nil
 	}
+	return appendLine(filename, data)
+}
+
+// writeTerminatedOutput marks the output record in filename as terminated at the current time, so
+// a watcher knows the tunnel is gone. The record is replaced atomically unless appendRecord is set,
+// for an NDJSON file that --summary appends to, in which case the terminated record is appended.
+func writeTerminatedOutput(filename string, output OutputInfo, appendRecord bool) error {
+	output.Status = "terminated"
+	output.Timestamp = time.Now().Format(time.RFC3339)
+	if !appendRecord {
+		return writeOutput(filename, output)
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		return err
+	}
+	return appendLine(filename, data)
+}
+
+// appendLine appends data and a newline to filename, creating it if needed.
+func appendLine(filename string, data []byte) error {
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// writeSessionSummary writes the shutdown summary; failures are logged rather than
+// returned so they never mask the session's own exit status.
+func writeSessionSummary(logger log.T, filename, clientID string, start time.Time, transfer *session.TransferStats) {
+	if err := writeSummary(filename, newSummary(clientID, start, transfer)); err != nil {
+		logger.Warnf("Failed to write session summary: %v", err)
+	}
+}
+
+// writePortFD writes the port number and a newline to file descriptor fd and closes it,
+// so a wrapper reading the descriptor sees EOF once the port is known.
+func writePortFD(fd int, port string) error {
+	f := os.NewFile(uintptr(fd), "port-fd")
+	if f == nil {
+		return fmt.Errorf("invalid file descriptor %d", fd)
+	}
+	defer f.Close()
+
+	_, err := fmt.Fprintln(f, port)
+	return err
+}
+
+// writeReadyMarker writes "<marker> port=<port>" as a line of its own to w, a stable token for
+// wrappers to wait for instead of parsing logs or the JSON output.
+func writeReadyMarker(w io.Writer, marker string, port int) error {
+	_, err := fmt.Fprintf(w, "%s port=%d\n", marker, port)
+	return err
+}
+
+// maxClientIDLength bounds --client-id; a UUID is 36 characters.
+const maxClientIDLength = 64
+
+// validateClientID accepts IDs of letters, digits, '.', '_' and '-' up to maxClientIDLength long,
+// which covers UUIDs and the usual correlation ID formats.
+func validateClientID(id string) error {
+	if len(id) > maxClientIDLength {
+		return fmt.Errorf("client-id too long: %d characters (max %d)", len(id), maxClientIDLength)
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-') {
+			return fmt.Errorf("invalid client-id %q: only letters, digits, '.', '_' and '-' are allowed", id)
+		}
+	}
+	return nil
+}
+
+// parseReconnectOn parses the comma-separated --reconnect-on classes; an empty value allows none.
+func parseReconnectOn(value string) ([]string, error) {
+	classes := []string{}
+	for _, class := range strings.Split(value, ",") {
+		if class = strings.TrimSpace(class); class == "" {
+			continue
+		}
+		if !slices.Contains(session.ReconnectClasses, class) {
+			return nil, fmt.Errorf("invalid reconnect-on class: %s (expected %s)", class, strings.Join(session.ReconnectClasses, ", "))
+		}
+		classes = append(classes, class)
+	}
+	return classes, nil
+}
+
+// forwardSpecs collects repeated -L flags.
+type forwardSpecs []string
+
+func (f *forwardSpecs) String() string {
+	return strings.Join(*f, ",")
+}
 
-	// Write as single line with newline at end for file
-	data = append(data, '\n')
-	return os.WriteFile(filename, data, 0644)
+func (f *forwardSpecs) Set(value string) error {
+	*f = append(*f, value)
+	return nil
 }
 
 func stringPtr(s string) *string {
@@ -15,17 +15,27 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	"time"
 
+	"github.com/rs/zerolog"
+	"github.com/zph/session-manager-plugin/src/log"
 	"github.com/zph/session-manager-plugin/src/profile"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
 )
 
 // neverDone is a channel that is never closed, for tests that don't need signal cancellation.
@@ -166,12 +176,183 @@ func TestWaitForReadyLocalPortAndRemoteReady(t *testing.T) {
 		close(portReady)
 	}()
 
-	err = waitForReady(port, portReady, portError, 5*time.Second, neverDone, nil, noSpan)
+	err = waitForReady("tcp", "localhost", port, nil, portReady, portError, 5*time.Second, readyPoll{}, neverDone, nil, noSpan)
 	if err != nil {
 		t.Fatalf("Expected success, got error: %v", err)
 	}
 }
 
+// WHEN forwarding UDP, THEN the local port SHALL count as ready once it is bound by the forwarder
+func TestLocalPortReadyUDP(t *testing.T) {
+	port, err := allocatePort("udp", "localhost")
+	if err != nil {
+		t.Fatalf("Failed to allocate port: %v", err)
+	}
+	if localPortReady("udp", "localhost", port) {
+		t.Fatal("Expected unbound UDP port to not be ready")
+	}
+
+	conn, err := net.ListenPacket("udp", "localhost:"+port)
+	if err != nil {
+		t.Fatalf("Failed to bind UDP port: %v", err)
+	}
+	defer conn.Close()
+	if !localPortReady("udp", "localhost", port) {
+		t.Fatal("Expected bound UDP port to be ready")
+	}
+}
+
+// WHEN the local port is already bound, THEN checkLocalPort SHALL fail with a port conflict before
+// any session is started, and SHALL pass once the port is free again.
+func TestCheckLocalPort(t *testing.T) {
+	for _, network := range []string{"tcp", "udp"} {
+		port, err := allocatePort(network, "localhost")
+		if err != nil {
+			t.Fatalf("Failed to allocate port: %v", err)
+		}
+		if err := checkLocalPort(network, "localhost", port, nil); err != nil {
+			t.Errorf("Expected free %s port %s to pass, got: %v", network, port, err)
+		}
+
+		var holder io.Closer
+		if network == "udp" {
+			holder, err = net.ListenPacket("udp", "localhost:"+port)
+		} else {
+			holder, err = net.Listen("tcp", "localhost:"+port)
+		}
+		if err != nil {
+			t.Fatalf("Failed to bind %s port: %v", network, err)
+		}
+		err = checkLocalPort(network, "localhost", port, nil)
+		holder.Close()
+		if err == nil || !strings.Contains(err.Error(), "local port "+port+" in use") {
+			t.Errorf("Expected %s port %s to be reported in use, got: %v", network, port, err)
+		}
+		if code := classifyError(err, CodeInternal); code != CodePortConflict {
+			t.Errorf("Expected %s, got %s", CodePortConflict, code)
+		}
+	}
+}
+
+// WHEN the local port is in use and --port-increment allows it, THEN findLocalPort SHALL use a free
+// port after it; WHEN every allowed port is in use, THEN it SHALL report a port conflict.
+func TestFindLocalPort(t *testing.T) {
+	port, err := allocatePort("tcp", "localhost")
+	if err != nil {
+		t.Fatalf("Failed to allocate port: %v", err)
+	}
+	if got, err := findLocalPort("tcp", "localhost", port, 3, nil); err != nil || got != port {
+		t.Errorf("Expected free port %s to be used, got %s, %v", port, got, err)
+	}
+
+	holder, err := net.Listen("tcp", "localhost:"+port)
+	if err != nil {
+		t.Fatalf("Failed to bind port: %v", err)
+	}
+	defer holder.Close()
+	_, err = findLocalPort("tcp", "localhost", port, 0, nil)
+	if err == nil || !strings.Contains(err.Error(), "local port "+port+" in use") {
+		t.Errorf("Expected port %s to be reported in use, got: %v", port, err)
+	}
+
+	first, _ := strconv.Atoi(port)
+	got, err := findLocalPort("tcp", "localhost", port, 3, nil)
+	if n, _ := strconv.Atoi(got); err != nil || n <= first || n > first+3 {
+		t.Errorf("Expected a free port in %d-%d, got %s, %v", first+1, first+3, got, err)
+	}
+
+	next := strconv.Itoa(first + 1)
+	if nextHolder, err := net.Listen("tcp", "localhost:"+next); err == nil {
+		defer nextHolder.Close()
+		_, err = findLocalPort("tcp", "localhost", port, 1, nil)
+		if err == nil || !strings.Contains(err.Error(), "local ports "+port+"-"+next+" in use") {
+			t.Errorf("Expected ports %s-%s to be reported in use, got: %v", port, next, err)
+		}
+		if code := classifyError(err, CodeInternal); code != CodePortConflict {
+			t.Errorf("Expected %s, got %s", CodePortConflict, code)
+		}
+	}
+}
+
+// WHEN a listener family is selected, THEN allocatePort and bindLocalPort SHALL return a port that
+// is bound, and so valid, in that family.
+func TestAllocatePortNetwork(t *testing.T) {
+	for _, tc := range []struct {
+		network string
+		host    string
+	}{
+		{"tcp4", "localhost"},
+		{"udp4", "localhost"},
+		{"tcp6", "::1"},
+		{"udp6", "::1"},
+		{"tcp", "localhost"},
+	} {
+		port, err := allocatePort(tc.network, tc.host)
+		if err != nil && strings.HasSuffix(tc.network, "6") {
+			t.Logf("Skipping %s: IPv6 is not available: %v", tc.network, err)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Failed to allocate %s port: %v", tc.network, err)
+		}
+		if err := checkLocalPort(tc.network, tc.host, port, nil); err != nil {
+			t.Errorf("Expected %s port %s to be free on %s, got: %v", tc.network, port, tc.host, err)
+		}
+		if strings.HasPrefix(tc.network, "udp") {
+			continue
+		}
+
+		listener, port, err := bindLocalPort(tc.network, tc.host, nil)
+		if err != nil {
+			t.Fatalf("Failed to bind %s port: %v", tc.network, err)
+		}
+		ip := listener.Addr().(*net.TCPAddr).IP
+		if tc.network == "tcp4" && ip.To4() == nil || tc.network == "tcp6" && ip.To4() != nil {
+			t.Errorf("Expected a %s listener, got %s", tc.network, listener.Addr())
+		}
+		if !localPortReady(tc.network, tc.host, port) {
+			t.Errorf("Expected %s port %s to be ready", tc.network, port)
+		}
+		listener.Close()
+	}
+}
+
+// WHEN --network is unset, THEN it SHALL default to tcp4 for localhost and tcp for other hosts;
+// WHEN it names a family the bind address is not in, THEN it SHALL be rejected.
+func TestResolveNetwork(t *testing.T) {
+	for _, tc := range []struct {
+		network  string
+		bindHost string
+		want     string
+		wantErr  bool
+	}{
+		{"", "localhost", "tcp4", false},
+		{"", "0.0.0.0", "tcp", false},
+		{"", "::1", "tcp", false},
+		{"tcp6", "localhost", "tcp6", false},
+		{"tcp", "localhost", "tcp", false},
+		{"tcp4", "0.0.0.0", "tcp4", false},
+		{"tcp6", "::", "tcp6", false},
+		{"tcp4", "::1", "", true},
+		{"tcp6", "127.0.0.1", "", true},
+		{"udp", "localhost", "", true},
+	} {
+		config := PortForwardConfig{Network: tc.network, BindHost: tc.bindHost}
+		err := resolveNetwork(&config)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("Expected --network %q to be rejected for %s", tc.network, tc.bindHost)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for --network %q on %s: %v", tc.network, tc.bindHost, err)
+		} else if config.Network != tc.want {
+			t.Errorf("Expected network %s for %q on %s, got %s", tc.want, tc.network, tc.bindHost, config.Network)
+		}
+	}
+}
+
 // READY-003: ConnectToPortError already in channel when Phase 2 runs SHALL report failure
 func TestWaitForReadyConnectToPortError(t *testing.T) {
 	// Start a local TCP listener
@@ -189,7 +370,7 @@ func TestWaitForReadyConnectToPortError(t *testing.T) {
 	// simulates agent reporting ConnectToPortError during Phase 1 polling
 	portError <- errors.New("ConnectToPortError: agent failed to connect to remote port")
 
-	err = waitForReady(port, portReady, portError, 5*time.Second, neverDone, nil, noSpan)
+	err = waitForReady("tcp", "localhost", port, nil, portReady, portError, 5*time.Second, readyPoll{}, neverDone, nil, noSpan)
 	if err == nil {
 		t.Fatal("Expected error, got nil")
 	}
@@ -198,13 +379,39 @@ func TestWaitForReadyConnectToPortError(t *testing.T) {
 	}
 }
 
+// WHEN the session serves a pre-bound listener, THEN waitForReady SHALL wait for it to be served,
+// not for the port to accept a dial, which it does from the moment it is bound.
+func TestWaitForReadyPreBound(t *testing.T) {
+	listener, port, err := bindLocalPort("tcp", "localhost", nil)
+	if err != nil {
+		t.Fatalf("Failed to bind port: %v", err)
+	}
+	defer listener.Close()
+	if got := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port); got != port {
+		t.Fatalf("Expected port %s, got %s", got, port)
+	}
+
+	served := make(chan struct{})
+	portReady := make(chan struct{})
+	portError := make(chan error, 1)
+	err = waitForReady("tcp", "localhost", port, served, portReady, portError, 300*time.Millisecond, readyPoll{}, neverDone, nil, noSpan)
+	if !errors.Is(err, errWaitTimeout) {
+		t.Fatalf("Expected timeout before the listener is served, got: %v", err)
+	}
+
+	close(served)
+	if err := waitForReady("tcp", "localhost", port, served, portReady, portError, 5*time.Second, readyPoll{}, neverDone, nil, noSpan); err != nil {
+		t.Fatalf("Expected success once served, got: %v", err)
+	}
+}
+
 // READY-004: Timeout before readiness SHALL report failure
 func TestWaitForReadyTimeout(t *testing.T) {
 	// Use a port that doesn't have a listener
 	portReady := make(chan struct{})
 	portError := make(chan error, 1)
 
-	err := waitForReady("0", portReady, portError, 200*time.Millisecond, neverDone, nil, noSpan)
+	err := waitForReady("tcp", "localhost", "0", nil, portReady, portError, 200*time.Millisecond, readyPoll{}, neverDone, nil, noSpan)
 	if err == nil {
 		t.Fatal("Expected timeout error, got nil")
 	}
@@ -213,6 +420,78 @@ func TestWaitForReadyTimeout(t *testing.T) {
 	}
 }
 
+// READY-010: WHEN a reconnect is in progress, THEN the time it takes SHALL NOT count toward the
+// timeout, so a port that comes back after the reconnect is still reported ready.
+func TestWaitForReadyHoldsTimeoutWhileReconnecting(t *testing.T) {
+	port, err := allocatePort("tcp", "localhost")
+	if err != nil {
+		t.Fatalf("Failed to allocate port: %v", err)
+	}
+	var reconnecting atomic.Bool
+	reconnecting.Store(true)
+	listening := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(400 * time.Millisecond)
+		listener, err := net.Listen("tcp", "localhost:"+port)
+		if err != nil {
+			t.Errorf("Failed to listen: %v", err)
+		}
+		listening <- listener
+		reconnecting.Store(false)
+	}()
+
+	poll := readyPoll{interval: 20 * time.Millisecond, reconnecting: &reconnecting}
+	err = waitForReady("tcp", "localhost", port, nil, make(chan struct{}), make(chan error, 1), 200*time.Millisecond, poll, neverDone, nil, noSpan)
+	if listener := <-listening; listener != nil {
+		listener.Close()
+	}
+	if err != nil {
+		t.Fatalf("Expected the port to be ready after the reconnect, got: %v", err)
+	}
+}
+
+// READY-011: WHEN the local port refuses connections for a while, THEN waitForReady SHALL keep
+// checking with backoff and succeed once it accepts them within the timeout.
+func TestWaitForReadyRetriesRefusedPort(t *testing.T) {
+	port, err := allocatePort("tcp", "localhost")
+	if err != nil {
+		t.Fatalf("Failed to allocate port: %v", err)
+	}
+	listening := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(250 * time.Millisecond)
+		listener, _ := net.Listen("tcp", "localhost:"+port)
+		listening <- listener
+	}()
+
+	err = waitForReady("tcp", "localhost", port, nil, make(chan struct{}), make(chan error, 1), 5*time.Second, readyPoll{}, neverDone, nil, noSpan)
+	if listener := <-listening; listener != nil {
+		listener.Close()
+	}
+	if err != nil {
+		t.Fatalf("Expected the port to be ready, got: %v", err)
+	}
+}
+
+// WHEN no poll interval is set, THEN checks SHALL start at the default and back off to one second;
+// WHEN a longer one is set, THEN it SHALL also be the cap.
+func TestReadyPollDelays(t *testing.T) {
+	for _, tc := range []struct {
+		interval  time.Duration
+		wantFirst time.Duration
+		wantMax   time.Duration
+	}{
+		{0, defaultPollInterval, maxPollInterval},
+		{50 * time.Millisecond, 50 * time.Millisecond, maxPollInterval},
+		{2 * time.Second, 2 * time.Second, 2 * time.Second},
+	} {
+		first, most := readyPoll{interval: tc.interval}.delays()
+		if first != tc.wantFirst || most != tc.wantMax {
+			t.Errorf("Expected delays %v..%v for %v, got %v..%v", tc.wantFirst, tc.wantMax, tc.interval, first, most)
+		}
+	}
+}
+
 // READY-009: WHEN the agent does not send StartPublicationMessage,
 // THEN waitForReady SHALL succeed after local port is confirmed ready
 // (graceful fallback matching pre-Phase-2 behavior).
@@ -229,7 +508,7 @@ func TestWaitForReadySucceedsWithoutStartPublication(t *testing.T) {
 	portReady := make(chan struct{})
 	portError := make(chan error, 1)
 
-	err = waitForReady(port, portReady, portError, 5*time.Second, neverDone, nil, noSpan)
+	err = waitForReady("tcp", "localhost", port, nil, portReady, portError, 5*time.Second, readyPoll{}, neverDone, nil, noSpan)
 	if err != nil {
 		t.Fatalf("Expected success (graceful fallback), got error: %v", err)
 	}
@@ -250,7 +529,7 @@ func TestWaitForReadySignalDuringPhase1(t *testing.T) {
 	}()
 
 	start := time.Now()
-	err := waitForReady("0", portReady, portError, 30*time.Second, done, nil, noSpan)
+	err := waitForReady("tcp", "localhost", "0", nil, portReady, portError, 30*time.Second, readyPoll{}, done, nil, noSpan)
 	elapsed := time.Since(start)
 
 	if err == nil {
@@ -264,8 +543,6 @@ func TestWaitForReadySignalDuringPhase1(t *testing.T) {
 	}
 }
 
-
-
 // READY-003: Error before local port is ready SHALL report failure
 func TestWaitForReadyErrorBeforeLocalPort(t *testing.T) {
 	// Don't start a listener - port won't be available
@@ -275,7 +552,7 @@ func TestWaitForReadyErrorBeforeLocalPort(t *testing.T) {
 	// Send error immediately
 	portError <- errors.New("ConnectToPortError: agent failed to connect")
 
-	err := waitForReady("0", portReady, portError, 5*time.Second, neverDone, nil, noSpan)
+	err := waitForReady("tcp", "localhost", "0", nil, portReady, portError, 5*time.Second, readyPoll{}, neverDone, nil, noSpan)
 	if err == nil {
 		t.Fatal("Expected error, got nil")
 	}
@@ -283,3 +560,324 @@ func TestWaitForReadyErrorBeforeLocalPort(t *testing.T) {
 		t.Fatalf("Expected errRemotePortFailed, got: %v", err)
 	}
 }
+
+// WHEN the port session reports drained, THEN waitForDrain SHALL return promptly.
+func TestWaitForDrainReturnsWhenDrained(t *testing.T) {
+	drained := make(chan struct{})
+	sigChan := make(chan os.Signal, 1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(drained)
+	}()
+
+	start := time.Now()
+	waitForDrain(log.NewMockLog(), drained, 10*time.Second, sigChan)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("waitForDrain did not return after drain: took %v", elapsed)
+	}
+}
+
+// WHEN a second signal arrives during drain, THEN waitForDrain SHALL return immediately.
+func TestWaitForDrainSecondSignalForcesExit(t *testing.T) {
+	sigChan := make(chan os.Signal, 1)
+	sigChan <- syscall.SIGINT
+
+	start := time.Now()
+	waitForDrain(log.NewMockLog(), make(chan struct{}), 10*time.Second, sigChan)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("second signal did not force exit: took %v", elapsed)
+	}
+}
+
+func TestForwardLabel(t *testing.T) {
+	config := &PortForwardConfig{RemoteHost: "db", RemotePort: "5432"}
+	if got := forwardLabel(config, "8080"); got != "[8080->db:5432]" {
+		t.Errorf("Unexpected default label: %q", got)
+	}
+
+	config.Label = "primary-db"
+	if got := forwardLabel(config, "8080"); got != "primary-db" {
+		t.Errorf("Expected --label override, got %q", got)
+	}
+}
+
+// WHEN --wait succeeds, THEN the ready marker SHALL be a line of its own naming the local port,
+// using the --ready-marker token when one is given.
+func TestWriteReadyMarker(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeReadyMarker(&buf, "READY", 12345); err != nil {
+		t.Fatalf("Expected success, got: %v", err)
+	}
+	if err := writeReadyMarker(&buf, "tunnel-up", 8080); err != nil {
+		t.Fatalf("Expected success, got: %v", err)
+	}
+	if got, want := buf.String(), "READY port=12345\ntunnel-up port=8080\n"; got != want {
+		t.Errorf("Unexpected ready markers: %q, want %q", got, want)
+	}
+}
+
+// WHEN --quiet is set and --output is empty, THEN the JSON output SHALL be the only thing on stdout
+// and informational logging SHALL be suppressed.
+func TestQuietKeepsStdoutForJSON(t *testing.T) {
+	// Quiet must win over a verbose LOG_LEVEL
+	t.Setenv("LOG_LEVEL", "info")
+	stdout, stderr := os.Stdout, os.Stderr
+	outRead, outWrite, _ := os.Pipe()
+	errRead, errWrite, _ := os.Pipe()
+	os.Stdout, os.Stderr = outWrite, errWrite
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	// Quiet lowers zerolog's global level, which later tests must not inherit
+	level := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(level) })
+
+	logger := log.Logger(true, "ssm-port-forward")
+	log.Quiet()
+	logger.Infof("Port forward established")
+	logger.Warnf("Retrying")
+	err := writeOutput("", OutputInfo{Type: "port_forward", Port: 8080, Status: "active"})
+	outWrite.Close()
+	errWrite.Close()
+	if err != nil {
+		t.Fatalf("writeOutput failed: %v", err)
+	}
+
+	out, _ := io.ReadAll(outRead)
+	var info OutputInfo
+	if err := json.Unmarshal(out, &info); err != nil {
+		t.Fatalf("Expected only JSON on stdout, got %q: %v", out, err)
+	}
+	if info.Port != 8080 {
+		t.Errorf("Expected port 8080, got %d", info.Port)
+	}
+	if logs, _ := io.ReadAll(errRead); len(logs) != 0 {
+		t.Errorf("Expected no logs in quiet mode, got %q", logs)
+	}
+}
+
+// WHEN --client-id is given, THEN validateClientID SHALL accept UUID-like IDs and reject
+// other characters and overlong values.
+func TestValidateClientID(t *testing.T) {
+	valid := []string{"3f2b8c1e-9d4a-4c7e-8f00-1a2b3c4d5e6f", "ci-run_42.deploy", strings.Repeat("a", maxClientIDLength)}
+	for _, id := range valid {
+		if err := validateClientID(id); err != nil {
+			t.Errorf("Expected %q to be valid, got: %v", id, err)
+		}
+	}
+
+	invalid := []string{"has space", "semi;colon", "ünïcode", strings.Repeat("a", maxClientIDLength+1)}
+	for _, id := range invalid {
+		if err := validateClientID(id); err == nil {
+			t.Errorf("Expected %q to be rejected", id)
+		}
+	}
+}
+
+// WHEN the output is written, THEN local_address SHALL be the listener's address when known, and a
+// wildcard bind SHALL also report the loopback address to connect to.
+func TestLocalAddresses(t *testing.T) {
+	cases := []struct {
+		bindHost, port string
+		listenAddr     net.Addr
+		local, dial    string
+	}{
+		{"localhost", "12345", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 12345}, "127.0.0.1:12345", ""},
+		{"::1", "12345", &net.TCPAddr{IP: net.IPv6loopback, Port: 12345}, "[::1]:12345", ""},
+		{"0.0.0.0", "8080", &net.TCPAddr{IP: net.IPv4zero, Port: 8080}, "0.0.0.0:8080", "127.0.0.1:8080"},
+		{"::", "8080", &net.UDPAddr{IP: net.IPv6unspecified, Port: 8080}, "[::]:8080", "[::1]:8080"},
+		{"localhost", "8080", nil, "localhost:8080", ""},
+		{"0.0.0.0", "8080", nil, "0.0.0.0:8080", "127.0.0.1:8080"},
+	}
+	for _, c := range cases {
+		local, dial := localAddresses(c.bindHost, c.port, c.listenAddr)
+		if local != c.local || dial != c.dial {
+			t.Errorf("localAddresses(%q, %q, %v) = %q, %q; want %q, %q", c.bindHost, c.port, c.listenAddr, local, dial, c.local, c.dial)
+		}
+	}
+}
+
+// WHEN --reconnect-on is given, THEN parseReconnectOn SHALL accept known classes, treat an empty
+// value as none, and reject unknown classes.
+func TestParseReconnectOn(t *testing.T) {
+	classes, err := parseReconnectOn("net, throttle")
+	if err != nil || !slices.Equal(classes, []string{session.ReconnectNet, session.ReconnectThrottle}) {
+		t.Errorf("Expected net and throttle, got %v (err %v)", classes, err)
+	}
+	if classes, err := parseReconnectOn(""); err != nil || classes == nil || len(classes) != 0 {
+		t.Errorf("Expected an empty, non-nil list, got %#v (err %v)", classes, err)
+	}
+	if _, err := parseReconnectOn("net,auth"); err == nil {
+		t.Error("Expected an unknown class to be rejected")
+	}
+}
+
+// WHEN --stdio is combined with options that need a local listener, THEN validateStdio SHALL reject them.
+func TestValidateStdio(t *testing.T) {
+	if err := validateStdio(&PortForwardConfig{Protocol: "tcp", MaxConnections: 1}); err != nil {
+		t.Errorf("Expected plain tcp stdio to be valid, got: %v", err)
+	}
+
+	invalid := map[string]*PortForwardConfig{
+		"udp":       {Protocol: "udp"},
+		"wait":      {Protocol: "tcp", Wait: true},
+		"port-fd":   {Protocol: "tcp", PortFD: 3},
+		"local-tls": {Protocol: "tcp", LocalTLSCert: "cert.pem", LocalTLSKey: "key.pem"},
+	}
+	for name, config := range invalid {
+		if err := validateStdio(config); err == nil {
+			t.Errorf("Expected %s to be rejected with --stdio", name)
+		}
+	}
+}
+
+// WHEN --summary is set with --output, THEN the summary SHALL be appended after the output line
+// and report the bytes counted in each direction and their total.
+func TestWriteSummaryAppendsToOutput(t *testing.T) {
+	path := t.TempDir() + "/out.json"
+	if err := writeOutput(path, OutputInfo{Type: "ssm-port-forward", Port: 8080}); err != nil {
+		t.Fatalf("writeOutput failed: %v", err)
+	}
+
+	transfer := &session.TransferStats{}
+	transfer.AddSent(100)
+	transfer.AddReceived(2048)
+	summary := newSummary("client", time.Now().Add(-2*time.Second), transfer)
+	if err := writeSummary(path, summary); err != nil {
+		t.Fatalf("writeSummary failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected output and summary lines, got %q", data)
+	}
+	var got SummaryInfo
+	if err := json.Unmarshal([]byte(lines[1]), &got); err != nil {
+		t.Fatalf("Failed to parse summary: %v", err)
+	}
+	if got.BytesSent != 100 || got.BytesReceived != 2048 || got.BytesTransferred != 2148 {
+		t.Errorf("Unexpected byte counts: %+v", got)
+	}
+	if got.DurationSeconds < 2 {
+		t.Errorf("Expected duration of at least 2s, got %v", got.DurationSeconds)
+	}
+}
+
+// WHEN the forward exits, THEN the output record SHALL be rewritten as terminated; WHEN the file
+// is NDJSON because --summary appends to it, THEN a terminated record SHALL be appended instead.
+func TestWriteTerminatedOutput(t *testing.T) {
+	output := OutputInfo{Type: "ssm-port-forward", Port: 8080, Status: "active", Timestamp: "2025-01-15T10:30:45Z"}
+	for _, appendRecord := range []bool{false, true} {
+		path := t.TempDir() + "/out.json"
+		if err := writeOutput(path, output); err != nil {
+			t.Fatalf("writeOutput failed: %v", err)
+		}
+		if appendRecord {
+			if err := writeSummary(path, newSummary("client", time.Now(), &session.TransferStats{})); err != nil {
+				t.Fatalf("writeSummary failed: %v", err)
+			}
+		}
+		if err := writeTerminatedOutput(path, output, appendRecord); err != nil {
+			t.Fatalf("writeTerminatedOutput failed: %v", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read output: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		wantLines := 1
+		if appendRecord {
+			wantLines = 3
+		}
+		if len(lines) != wantLines {
+			t.Fatalf("Expected %d lines, got %q", wantLines, data)
+		}
+		var got OutputInfo
+		if err := json.Unmarshal([]byte(lines[len(lines)-1]), &got); err != nil {
+			t.Fatalf("Failed to parse output: %v", err)
+		}
+		if got.Status != "terminated" || got.Port != 8080 || got.Timestamp == output.Timestamp {
+			t.Errorf("Expected a terminated record with a new timestamp, got %+v", got)
+		}
+	}
+}
+
+// WHEN --output-format table is set, THEN writeOutput SHALL write an aligned table with the
+// local port, destination, instance, PID and status instead of JSON.
+func TestWriteOutputTable(t *testing.T) {
+	path := t.TempDir() + "/out.txt"
+	output := OutputInfo{Port: 8080, PID: 42, Status: "verified", Forwarding: "8080:db.internal:5432", Bastion: "i-123", Format: OutputFormatTable}
+	if err := writeOutput(path, output); err != nil {
+		t.Fatalf("writeOutput failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	want := "LOCAL PORT  DESTINATION       INSTANCE  PID  STATUS\n" +
+		"8080        db.internal:5432  i-123     42   verified\n"
+	if string(data) != want {
+		t.Errorf("Unexpected table:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+// WHEN the remote host is omitted from the forwarding spec, THEN the table SHALL show localhost.
+func TestFormatOutputTableLocalhost(t *testing.T) {
+	data, err := formatOutputTable(OutputInfo{Port: 8080, Forwarding: "8080:80"})
+	if err != nil {
+		t.Fatalf("formatOutputTable failed: %v", err)
+	}
+	if !strings.Contains(string(data), "localhost:80") {
+		t.Errorf("Expected localhost destination, got %q", data)
+	}
+}
+
+// WHEN --on-interrupt is detach, THEN SIGINT SHALL not be a shutdown signal, while SIGTERM and
+// SIGHUP still are; WHEN it is terminate, THEN SIGINT SHALL also stop the forward.
+func TestShutdownSignals(t *testing.T) {
+	terminate := shutdownSignals(InterruptTerminate)
+	for _, sig := range []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP} {
+		if !slices.Contains(terminate, sig) {
+			t.Errorf("Expected %v to stop the forward with terminate", sig)
+		}
+	}
+
+	detach := shutdownSignals(InterruptDetach)
+	if slices.Contains(detach, os.Signal(syscall.SIGINT)) {
+		t.Error("Expected SIGINT to be ignored with detach")
+	}
+	if !slices.Contains(detach, os.Signal(syscall.SIGTERM)) || !slices.Contains(detach, os.Signal(syscall.SIGHUP)) {
+		t.Errorf("Expected SIGTERM and SIGHUP to stop the forward with detach, got %v", detach)
+	}
+}
+
+// WHEN the output file is rewritten, THEN writeFileAtomic SHALL replace it whole and leave no
+// temporary files behind; WHEN the directory is missing, THEN it SHALL fail without leftovers.
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.json")
+	if err := os.WriteFile(path, []byte(`{"port":1111,"status":"starting"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomic(path, []byte(`{"port":8080}`+"\n"), 0644); err != nil {
+		t.Fatalf("writeFileAtomic failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != `{"port":8080}`+"\n" {
+		t.Errorf("Unexpected contents: %q", data)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the output file, got %v", entries)
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "out.json"), []byte("{}"), 0644); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+	entries, _ = os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected no leftovers after a failed write, got %v", entries)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package main
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestWritePortFD(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer r.Close()
+
+	// Hand writePortFD its own descriptor, as a shell redirection would
+	fd, err := syscall.Dup(int(w.Fd()))
+	if err != nil {
+		t.Fatalf("Failed to dup pipe: %v", err)
+	}
+	w.Close()
+
+	if err := writePortFD(fd, "54321"); err != nil {
+		t.Fatalf("Expected success, got: %v", err)
+	}
+
+	// writePortFD closes the descriptor, so the read side sees EOF after the port
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to read pipe: %v", err)
+	}
+	if string(data) != "54321\n" {
+		t.Errorf("Unexpected port-fd contents: %q", data)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync/atomic"
+
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// setPaused pauses or resumes accepting connections on the forward, logging and emitting an
+// event when that changes. Open connections are left to finish either way.
+func setPaused(logger log.T, paused *atomic.Bool, events *eventServer, pause bool) {
+	if paused.Swap(pause) == pause {
+		return
+	}
+	if pause {
+		logger.Info("Forward paused: new connections are closed until resumed; open connections continue")
+	} else {
+		logger.Info("Forward resumed: accepting new connections")
+	}
+	events.paused(pause)
+}
+
+// watchPauseSignals pauses the forward on pauseSignal and resumes it on resumeSignal until the
+// returned function is called. It does nothing on platforms without those signals.
+func watchPauseSignals(logger log.T, paused *atomic.Bool, events *eventServer) (stop func()) {
+	if pauseSignal == nil {
+		return func() {}
+	}
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, pauseSignal, resumeSignal)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-sigChan:
+				setPaused(logger, paused, events, sig == pauseSignal)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigChan)
+		close(done)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// WHEN the forward is paused and resumed, THEN the flag SHALL follow and the event socket SHALL
+// get one paused and one resumed event, ignoring repeats.
+func TestSetPaused(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.sock")
+	events, err := listenEvents(path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer events.Close()
+	_, next := dialEvents(t, path)
+	waitForReaders(t, events, 1)
+
+	var paused atomic.Bool
+	setPaused(log.NewMockLog(), &paused, events, true)
+	setPaused(log.NewMockLog(), &paused, events, true)
+	if !paused.Load() {
+		t.Error("Expected the forward to be paused")
+	}
+	setPaused(log.NewMockLog(), &paused, events, false)
+	if paused.Load() {
+		t.Error("Expected the forward to be resumed")
+	}
+
+	for _, want := range []string{EventPaused, EventResumed} {
+		if e := next(); e.Type != want {
+			t.Errorf("Expected %s event, got %+v", want, e)
+		}
+	}
+}
+
+// WHEN the process receives the pause and resume signals, THEN the forward SHALL pause and resume.
+func TestWatchPauseSignals(t *testing.T) {
+	if pauseSignal == nil {
+		t.Skip("no pause signals on this platform")
+	}
+	var paused atomic.Bool
+	stop := watchPauseSignals(log.NewMockLog(), &paused, nil)
+	defer stop()
+
+	self, _ := os.FindProcess(os.Getpid())
+	for _, c := range []struct {
+		sig  os.Signal
+		want bool
+	}{{pauseSignal, true}, {resumeSignal, false}} {
+		if err := self.Signal(c.sig); err != nil {
+			t.Fatalf("Failed to signal: %v", err)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for paused.Load() != c.want && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if paused.Load() != c.want {
+			t.Errorf("After %v expected paused=%v", c.sig, c.want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// builtinPolicy is a destination policy compiled into guardrailed builds, as JSON in the same
+// format as --policy files, e.g. -ldflags "-X 'main.builtinPolicy={\"allow\":[...]}'". It applies
+// in addition to any --policy file and cannot be turned off at run time.
+var builtinPolicy string
+
+var errPolicyDenied = errors.New("destination not allowed by policy")
+
+// destinationPolicy constrains which remote host and port a forward may reach. A destination
+// matching any deny rule is rejected; otherwise, when allow rules exist, it must match one.
+type destinationPolicy struct {
+	Allow []policyRule `json:"allow"`
+	Deny  []policyRule `json:"deny"`
+}
+
+// policyRule matches destinations by host and port.
+type policyRule struct {
+	// Host is an exact name, a glob such as *.rds.amazonaws.com, or a CIDR matching IP addresses
+	Host string `json:"host"`
+	// Ports lists ports ("5432") and ranges ("8000-8100"); empty matches every port
+	Ports []string `json:"ports"`
+}
+
+// parsePolicy decodes and validates a policy document.
+func parsePolicy(data []byte) (*destinationPolicy, error) {
+	var policy destinationPolicy
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+	for _, rule := range append(policy.Allow, policy.Deny...) {
+		if rule.Host == "" {
+			return nil, errors.New("invalid policy: every rule needs a host")
+		}
+		if _, err := path.Match(strings.ToLower(rule.Host), ""); err != nil {
+			return nil, fmt.Errorf("invalid policy host %q: %w", rule.Host, err)
+		}
+		for _, ports := range rule.Ports {
+			if _, _, err := parsePortRange(ports); err != nil {
+				return nil, fmt.Errorf("invalid policy: %w", err)
+			}
+		}
+	}
+	return &policy, nil
+}
+
+// loadPolicy reads a policy file.
+func loadPolicy(file string) (*destinationPolicy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	return parsePolicy(data)
+}
+
+// check returns an error wrapping errPolicyDenied when host:port is not allowed.
+func (p *destinationPolicy) check(host, port string) error {
+	for _, rule := range p.Deny {
+		if rule.matches(host, port) {
+			return fmt.Errorf("%w: %s is denied (rule host %q)", errPolicyDenied, net.JoinHostPort(host, port), rule.Host)
+		}
+	}
+	if len(p.Allow) == 0 {
+		return nil
+	}
+	for _, rule := range p.Allow {
+		if rule.matches(host, port) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not in the allowlist", errPolicyDenied, net.JoinHostPort(host, port))
+}
+
+func (r policyRule) matches(host, port string) bool {
+	return r.matchesHost(host) && r.matchesPort(port)
+}
+
+func (r policyRule) matchesHost(host string) bool {
+	if _, network, err := net.ParseCIDR(r.Host); err == nil {
+		ip := net.ParseIP(host)
+		return ip != nil && network.Contains(ip)
+	}
+	matched, _ := path.Match(strings.ToLower(r.Host), strings.ToLower(host))
+	return matched
+}
+
+func (r policyRule) matchesPort(port string) bool {
+	if len(r.Ports) == 0 {
+		return true
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return false
+	}
+	for _, ports := range r.Ports {
+		if low, high, _ := parsePortRange(ports); n >= low && n <= high {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePortRange parses "N" or "N-M" into an inclusive range.
+func parsePortRange(s string) (low, high int, err error) {
+	lowStr, highStr, isRange := strings.Cut(s, "-")
+	if !isRange {
+		highStr = lowStr
+	}
+	if low, err = strconv.Atoi(lowStr); err == nil {
+		high, err = strconv.Atoi(highStr)
+	}
+	if err != nil || low < 1 || high > 65535 || low > high {
+		return 0, 0, fmt.Errorf("invalid port range %q", s)
+	}
+	return low, high, nil
+}
+
+// checkPolicies applies the compiled-in policy and then the --policy file, if any.
+func checkPolicies(policyFile, host, port string) error {
+	var policies []*destinationPolicy
+	if builtinPolicy != "" {
+		policy, err := parsePolicy([]byte(builtinPolicy))
+		if err != nil {
+			return fmt.Errorf("built-in %w", err)
+		}
+		policies = append(policies, policy)
+	}
+	if policyFile != "" {
+		policy, err := loadPolicy(policyFile)
+		if err != nil {
+			return err
+		}
+		policies = append(policies, policy)
+	}
+	for _, policy := range policies {
+		if err := policy.check(host, port); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testPolicy = `{
+	"allow": [
+		{"host": "*.rds.amazonaws.com", "ports": ["5432", "3306"]},
+		{"host": "10.0.0.0/16", "ports": ["8000-8100"]},
+		{"host": "localhost"}
+	],
+	"deny": [{"host": "10.0.9.0/24"}]
+}`
+
+// WHEN a policy is set, THEN only destinations matching an allow rule and no deny rule SHALL pass.
+func TestDestinationPolicyCheck(t *testing.T) {
+	policy, err := parsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Failed to parse policy: %v", err)
+	}
+
+	tests := []struct {
+		host, port string
+		allowed    bool
+	}{
+		{"mydb.abc.us-east-1.RDS.amazonaws.com", "5432", true},
+		{"mydb.abc.us-east-1.rds.amazonaws.com", "22", false},
+		{"10.0.1.5", "8080", true},
+		{"10.0.1.5", "8101", false},
+		{"10.0.9.5", "8080", false},
+		{"localhost", "22", true},
+		{"internal-web", "80", false},
+	}
+	for _, tt := range tests {
+		err := policy.check(tt.host, tt.port)
+		if tt.allowed && err != nil {
+			t.Errorf("Expected %s:%s to be allowed, got: %v", tt.host, tt.port, err)
+		}
+		if !tt.allowed && !errors.Is(err, errPolicyDenied) {
+			t.Errorf("Expected %s:%s to be denied, got: %v", tt.host, tt.port, err)
+		}
+	}
+}
+
+func TestParsePolicyRejectsInvalidRules(t *testing.T) {
+	invalid := []string{
+		`{"allow": [{"ports": ["22"]}]}`,
+		`{"allow": [{"host": "db", "ports": ["0"]}]}`,
+		`{"allow": [{"host": "db", "ports": ["90-80"]}]}`,
+		`{"allow": [{"host": "[db"}]}`,
+		`{"allowed": []}`,
+	}
+	for _, doc := range invalid {
+		if _, err := parsePolicy([]byte(doc)); err == nil {
+			t.Errorf("Expected %s to be rejected", doc)
+		}
+	}
+}
+
+// WHEN a policy is compiled in, THEN it SHALL apply even without --policy, and a --policy file
+// SHALL only narrow it further.
+func TestCheckPolicies(t *testing.T) {
+	original := builtinPolicy
+	t.Cleanup(func() { builtinPolicy = original })
+	builtinPolicy = `{"deny": [{"host": "169.254.169.254"}]}`
+
+	if err := checkPolicies("", "169.254.169.254", "80"); !errors.Is(err, errPolicyDenied) {
+		t.Errorf("Expected built-in deny, got: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(file, []byte(testPolicy), 0600); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+	if err := checkPolicies(file, "10.0.1.5", "8080"); err != nil {
+		t.Errorf("Expected allowed destination, got: %v", err)
+	}
+	if err := checkPolicies(file, "internal-web", "80"); !errors.Is(err, errPolicyDenied) {
+		t.Errorf("Expected destination outside the allowlist to be denied, got: %v", err)
+	}
+	if err := checkPolicies(filepath.Join(t.TempDir(), "missing.json"), "10.0.1.5", "8080"); err == nil {
+		t.Error("Expected a missing policy file to fail")
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// startSessionCommand returns the aws CLI command that starts the same session as input in
+// region, with profile when one is set, for --print-command.
+func startSessionCommand(input *ssm.StartSessionInput, region, profile string) (string, error) {
+	args := []string{"aws", "ssm", "start-session", "--target", aws.StringValue(input.Target)}
+	if name := aws.StringValue(input.DocumentName); name != "" {
+		args = append(args, "--document-name", name)
+	}
+	if len(input.Parameters) > 0 {
+		// Map keys are marshalled in sorted order, so the command is stable
+		params, err := json.Marshal(input.Parameters)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, "--parameters", string(params))
+	}
+	if region != "" {
+		args = append(args, "--region", region)
+	}
+	if profile != "" {
+		args = append(args, "--profile", profile)
+	}
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " "), nil
+}
+
+// shellQuote quotes s for a POSIX shell, leaving words that need no quoting as they are.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@,+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// WHEN --print-command is set, THEN the printed command SHALL carry the resolved target, document,
+// parameters as sorted JSON, region and profile, quoted for the shell.
+func TestStartSessionCommand(t *testing.T) {
+	input := &ssm.StartSessionInput{
+		Target:       aws.String("i-abc"),
+		DocumentName: aws.String("AWS-StartPortForwardingSessionToRemoteHost"),
+		Parameters: map[string][]*string{
+			"portNumber":      {aws.String("5432")},
+			"localPortNumber": {aws.String("15432")},
+			"host":            {aws.String("db.internal")},
+		},
+	}
+	got, err := startSessionCommand(input, "us-east-1", "dev")
+	if err != nil {
+		t.Fatalf("startSessionCommand failed: %v", err)
+	}
+	want := `aws ssm start-session --target i-abc --document-name AWS-StartPortForwardingSessionToRemoteHost ` +
+		`--parameters '{"host":["db.internal"],"localPortNumber":["15432"],"portNumber":["5432"]}' --region us-east-1 --profile dev`
+	if got != want {
+		t.Errorf("Unexpected command:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// WHEN no profile is set, THEN the command SHALL leave --profile out so the CLI uses its default.
+func TestStartSessionCommandNoProfile(t *testing.T) {
+	input := &ssm.StartSessionInput{Target: aws.String("i-abc"), DocumentName: aws.String("AWS-StartPortForwardingSession")}
+	got, err := startSessionCommand(input, "eu-west-1", "")
+	if err != nil {
+		t.Fatalf("startSessionCommand failed: %v", err)
+	}
+	if want := "aws ssm start-session --target i-abc --document-name AWS-StartPortForwardingSession --region eu-west-1"; got != want {
+		t.Errorf("Unexpected command:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// WHEN a value holds shell metacharacters or quotes, THEN it SHALL be single-quoted safely.
+func TestShellQuote(t *testing.T) {
+	for in, want := range map[string]string{
+		"i-abc":     "i-abc",
+		"":          "''",
+		"a b":       "'a b'",
+		"it's":      `'it'\''s'`,
+		"$(reboot)": "'$(reboot)'",
+	} {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ProbeNone = ""
+	ProbeTCP  = "tcp"
+	ProbeHTTP = "http"
+	ProbeTLS  = "tls"
+)
+
+// tcpProbeHold is how long a TCP probe keeps its connection open. The agent only dials the
+// remote once a client connects, so a tunnel to an unreachable remote closes within this window.
+var tcpProbeHold = 500 * time.Millisecond
+
+var errProbeFailed = errors.New("probe failed")
+
+// ProbeConfig describes the end-to-end check performed through the tunnel once the local port is up.
+type ProbeConfig struct {
+	Mode       string
+	HTTPPath   string
+	HTTPStatus int
+	ServerName string // TLS SNI, usually the remote host
+}
+
+func validateProbeMode(mode string) error {
+	switch mode {
+	case ProbeNone, ProbeTCP, ProbeHTTP, ProbeTLS:
+		return nil
+	default:
+		return fmt.Errorf("invalid probe mode: %s (expected tcp, http or tls)", mode)
+	}
+}
+
+// validateProbePath checks that --probe-path is an absolute request path (empty means /), which
+// the probe URL is built by appending to the host and port.
+func validateProbePath(path string) error {
+	if path != "" && !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("invalid probe path: %s (must start with /)", path)
+	}
+	return nil
+}
+
+// runProbe connects to the forwarded local port and verifies the remote side responds.
+func runProbe(host string, port string, probe ProbeConfig, timeout time.Duration) error {
+	addr := net.JoinHostPort(host, port)
+
+	var err error
+	switch probe.Mode {
+	case ProbeTCP:
+		err = probeTCP(addr, timeout)
+	case ProbeHTTP:
+		err = probeHTTP(addr, probe.HTTPPath, probe.HTTPStatus, timeout)
+	case ProbeTLS:
+		err = probeTLS(addr, probe.ServerName, timeout)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w (%s): %v", errProbeFailed, probe.Mode, err)
+	}
+	return nil
+}
+
+// remoteRetryInterval is the pause between probe attempts while waiting for the remote.
+var remoteRetryInterval = 500 * time.Millisecond
+
+// waitForRemote repeats the probe until it succeeds, timeout elapses or done is closed.
+// The timeout covers all attempts; the last attempt's error is reported.
+func waitForRemote(host string, port string, probe ProbeConfig, timeout time.Duration, done <-chan struct{}) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%w (%s): timed out waiting for remote", errProbeFailed, probe.Mode)
+		}
+		err := runProbe(host, port, probe, remaining)
+		if err == nil {
+			return nil
+		}
+		if time.Until(deadline) <= remoteRetryInterval {
+			return err
+		}
+
+		select {
+		case <-done:
+			return errSignalReceived
+		case <-time.After(remoteRetryInterval):
+		}
+	}
+}
+
+// probeTCP succeeds when the tunnelled connection stays open or yields data.
+func probeTCP(addr string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(tcpProbeHold))
+	buf := make([]byte, 1)
+	if _, err = conn.Read(buf); err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil
+		}
+		if err == io.EOF {
+			return errors.New("connection closed by tunnel")
+		}
+		return err
+	}
+	return nil
+}
+
+// probeHTTP issues a GET through the tunnel and compares the response status.
+func probeHTTP(addr string, path string, wantStatus int, timeout time.Duration) error {
+	if path == "" {
+		path = "/"
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get("http://" + addr + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		return fmt.Errorf("unexpected HTTP status %d (want %d)", resp.StatusCode, wantStatus)
+	}
+	return nil
+}
+
+// probeTLS completes a TLS handshake through the tunnel. Certificates are not verified:
+// the probe checks reachability, and the certificate never matches localhost anyway.
+func probeTLS(addr string, serverName string, timeout time.Duration) error {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
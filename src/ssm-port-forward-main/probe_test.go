@@ -0,0 +1,203 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// listenerPort returns the port of a test listener as a string.
+func listenerPort(listener net.Listener) string {
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func TestValidateProbeMode(t *testing.T) {
+	for _, mode := range []string{ProbeNone, ProbeTCP, ProbeHTTP, ProbeTLS} {
+		if err := validateProbeMode(mode); err != nil {
+			t.Errorf("Expected %q to be valid, got: %v", mode, err)
+		}
+	}
+	if err := validateProbeMode("udp"); err == nil {
+		t.Error("Expected error for unknown probe mode")
+	}
+}
+
+// WHEN --probe-path is given, THEN paths starting with / SHALL be accepted and others rejected.
+func TestValidateProbePath(t *testing.T) {
+	for _, path := range []string{"", "/", "/healthz", "/status?verbose=1"} {
+		if err := validateProbePath(path); err != nil {
+			t.Errorf("Expected %q to be valid, got: %v", path, err)
+		}
+	}
+	for _, path := range []string{"healthz", "status/ok", "?verbose=1"} {
+		if err := validateProbePath(path); err == nil {
+			t.Errorf("Expected %q to be rejected", path)
+		}
+	}
+}
+
+// WHEN the tunnel keeps the connection open, THEN the TCP probe SHALL succeed.
+func TestProbeTCPConnectionHeldOpen(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to start listener: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	port := listenerPort(listener)
+	if err := runProbe("localhost", port, ProbeConfig{Mode: ProbeTCP}, time.Second); err != nil {
+		t.Fatalf("Expected success, got: %v", err)
+	}
+}
+
+// WHEN the tunnel closes the connection immediately (remote unreachable),
+// THEN the TCP probe SHALL fail.
+func TestProbeTCPConnectionClosed(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to start listener: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+
+	port := listenerPort(listener)
+	err = runProbe("localhost", port, ProbeConfig{Mode: ProbeTCP}, time.Second)
+	if !errors.Is(err, errProbeFailed) {
+		t.Fatalf("Expected errProbeFailed, got: %v", err)
+	}
+}
+
+func TestProbeHTTPStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	port := listenerPort(server.Listener)
+
+	if err := runProbe("localhost", port, ProbeConfig{Mode: ProbeHTTP, HTTPPath: "/healthz", HTTPStatus: 204}, time.Second); err != nil {
+		t.Fatalf("Expected success, got: %v", err)
+	}
+
+	err := runProbe("localhost", port, ProbeConfig{Mode: ProbeHTTP, HTTPPath: "/", HTTPStatus: 200}, time.Second)
+	if !errors.Is(err, errProbeFailed) {
+		t.Fatalf("Expected errProbeFailed, got: %v", err)
+	}
+}
+
+func TestProbeTLSHandshake(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	if err := runProbe("localhost", listenerPort(server.Listener), ProbeConfig{Mode: ProbeTLS, ServerName: "example.internal"}, time.Second); err != nil {
+		t.Fatalf("Expected success, got: %v", err)
+	}
+
+	// A plain HTTP server cannot complete a TLS handshake
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plain.Close()
+
+	err := runProbe("localhost", listenerPort(plain.Listener), ProbeConfig{Mode: ProbeTLS}, time.Second)
+	if !errors.Is(err, errProbeFailed) {
+		t.Fatalf("Expected errProbeFailed, got: %v", err)
+	}
+}
+
+// WHEN the remote is still booting, THEN waitForRemote SHALL retry until the tunnelled
+// connection stays open.
+func TestWaitForRemoteRetriesUntilRemoteUp(t *testing.T) {
+	origInterval, origHold := remoteRetryInterval, tcpProbeHold
+	remoteRetryInterval, tcpProbeHold = 20*time.Millisecond, 50*time.Millisecond
+	defer func() { remoteRetryInterval, tcpProbeHold = origInterval, origHold }()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to start listener: %v", err)
+	}
+	defer listener.Close()
+
+	// The tunnel closes the first connections, as it does while the remote refuses them
+	attempts := 0
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			attempts++
+			if attempts < 3 {
+				conn.Close()
+				continue
+			}
+			go func() {
+				defer conn.Close()
+				time.Sleep(time.Second)
+			}()
+		}
+	}()
+
+	if err := waitForRemote("localhost", listenerPort(listener), ProbeConfig{Mode: ProbeTCP}, 5*time.Second, neverDone); err != nil {
+		t.Fatalf("Expected success once the remote is up, got: %v", err)
+	}
+}
+
+// WHEN the remote never comes up, THEN waitForRemote SHALL fail with errProbeFailed at the timeout,
+// and a signal SHALL end the wait early.
+func TestWaitForRemoteTimeoutAndSignal(t *testing.T) {
+	origInterval := remoteRetryInterval
+	remoteRetryInterval = 20 * time.Millisecond
+	defer func() { remoteRetryInterval = origInterval }()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to start listener: %v", err)
+	}
+	port := listenerPort(listener)
+	listener.Close()
+
+	start := time.Now()
+	err = waitForRemote("localhost", port, ProbeConfig{Mode: ProbeTCP}, 200*time.Millisecond, neverDone)
+	if !errors.Is(err, errProbeFailed) {
+		t.Fatalf("Expected errProbeFailed, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected to give up near the timeout, took %v", elapsed)
+	}
+
+	done := make(chan struct{})
+	close(done)
+	if err := waitForRemote("localhost", port, ProbeConfig{Mode: ProbeTCP}, 5*time.Second, done); !errors.Is(err, errSignalReceived) {
+		t.Errorf("Expected errSignalReceived, got: %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"net"
+	"sync"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/zph/session-manager-plugin/src/datachannel"
+	"github.com/zph/session-manager-plugin/src/log"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+// --on-remote-close modes: exit ends the forward when the agent ends the session, keep starts a
+// new session behind the same local listener.
+const (
+	RemoteCloseExit = "exit"
+	RemoteCloseKeep = "keep"
+)
+
+// persistentListener keeps the local port bound across the sessions of a --on-remote-close keep
+// forward. Each session is handed its own view, whose Close ends that session's accepts without
+// unbinding the port, so clients connecting while a new session starts wait in the accept backlog.
+type persistentListener struct {
+	net.Listener
+	conns  chan net.Conn
+	failed chan struct{} // closed with err once the underlying listener stops accepting
+	err    error
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newPersistentListener(listener net.Listener) *persistentListener {
+	p := &persistentListener{
+		Listener: listener,
+		conns:    make(chan net.Conn),
+		failed:   make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+	go p.acceptLoop()
+	return p
+}
+
+// acceptLoop hands accepted connections to whichever session's view is accepting.
+func (p *persistentListener) acceptLoop() {
+	for {
+		conn, err := p.Listener.Accept()
+		if err != nil {
+			p.err = err
+			close(p.failed)
+			return
+		}
+		select {
+		case p.conns <- conn:
+		case <-p.closed:
+			conn.Close()
+			return
+		}
+	}
+}
+
+// Close unbinds the port, ending every view's accepts.
+func (p *persistentListener) Close() error {
+	p.once.Do(func() { close(p.closed) })
+	return p.Listener.Close()
+}
+
+// handoff returns a view of the listener for the next session.
+func (p *persistentListener) handoff() net.Listener {
+	return &listenerView{parent: p, closed: make(chan struct{})}
+}
+
+// listenerView is one session's share of a persistentListener.
+type listenerView struct {
+	parent *persistentListener
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (v *listenerView) Accept() (net.Conn, error) {
+	// Checked first so a closed view never takes a connection meant for the next session
+	select {
+	case <-v.closed:
+		return nil, net.ErrClosed
+	default:
+	}
+	select {
+	case conn := <-v.parent.conns:
+		return conn, nil
+	case <-v.closed:
+		return nil, net.ErrClosed
+	case <-v.parent.failed:
+		return nil, v.parent.err
+	}
+}
+
+// Close ends this view's accepts, leaving the port bound for the next session.
+func (v *listenerView) Close() error {
+	v.once.Do(func() { close(v.closed) })
+	return nil
+}
+
+func (v *listenerView) Addr() net.Addr {
+	return v.parent.Addr()
+}
+
+// SyscallConn exposes the underlying socket, so the session can still apply its listen backlog.
+func (v *listenerView) SyscallConn() (syscall.RawConn, error) {
+	if raw, ok := v.parent.Listener.(syscall.Conn); ok {
+		return raw.SyscallConn()
+	}
+	return nil, syscall.EINVAL
+}
+
+// nextSession returns a session for out that shares template's options, hooks and transfer
+// counters but has its own data channel, readiness channels and listener view.
+func nextSession(template session.Session, out *ssm.StartSessionOutput, bufferHighWater int, listener net.Listener) *session.Session {
+	next := template
+	next.SessionId = *out.SessionId
+	next.StreamUrl = *out.StreamUrl
+	next.TokenValue = *out.TokenValue
+	next.DataChannel = &datachannel.DataChannel{OutgoingBufferHighWater: bufferHighWater}
+	next.PortReady = make(chan struct{})
+	next.PortError = make(chan error, 1)
+	next.Drained = make(chan struct{})
+	next.LocalListener = listener
+	return &next
+}
+
+// executeSession runs sess in the background. The first channel receives the error it fails
+// with; the second is closed if it ends without one.
+func executeSession(logger log.T, sess *session.Session) (<-chan error, <-chan struct{}) {
+	sessionErr := make(chan error, 1)
+	sessionEnded := make(chan struct{})
+	go func() {
+		if err := sess.Execute(logger); err != nil {
+			sessionErr <- err
+			return
+		}
+		close(sessionEnded)
+	}()
+	return sessionErr, sessionEnded
+}
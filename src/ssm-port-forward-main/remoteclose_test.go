@@ -0,0 +1,125 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+// acceptAsync returns a channel receiving the result of one Accept on listener.
+func acceptAsync(listener net.Listener) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if conn != nil {
+			conn.Close()
+		}
+		result <- err
+	}()
+	return result
+}
+
+func awaitAccept(t *testing.T, result <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(2 * time.Second):
+		t.Fatal("Accept did not return")
+		return nil
+	}
+}
+
+// WHEN a session's view of the persistent listener is closed, THEN its accepts SHALL end while
+// the port stays bound and the next session's view SHALL accept new clients.
+func TestPersistentListenerHandoff(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	persistent := newPersistentListener(listener)
+	defer persistent.Close()
+
+	first := persistent.handoff()
+	if first.Addr().String() != listener.Addr().String() {
+		t.Errorf("Expected the view to report %v, got %v", listener.Addr(), first.Addr())
+	}
+	accepted := acceptAsync(first)
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	client.Close()
+	if err := awaitAccept(t, accepted); err != nil {
+		t.Errorf("Expected the first view to accept, got: %v", err)
+	}
+
+	accepted = acceptAsync(first)
+	first.Close()
+	if err := awaitAccept(t, accepted); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Expected a closed view to stop accepting, got: %v", err)
+	}
+
+	// A client connecting between sessions waits for the next one
+	client, err = net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Expected the port to stay bound, got: %v", err)
+	}
+	defer client.Close()
+	second := persistent.handoff()
+	if err := awaitAccept(t, acceptAsync(second)); err != nil {
+		t.Errorf("Expected the next view to accept the waiting client, got: %v", err)
+	}
+
+	accepted = acceptAsync(second)
+	persistent.Close()
+	if err := awaitAccept(t, accepted); err == nil {
+		t.Error("Expected closing the listener to end the view's accepts")
+	}
+}
+
+// WHEN a replacement session is made, THEN it SHALL take the new session's credentials with fresh
+// channels and data channel while sharing the template's options and transfer counters.
+func TestNextSession(t *testing.T) {
+	template := session.Session{
+		SessionId:      "sess-1",
+		MaxConnections: 4,
+		PortReady:      make(chan struct{}),
+		Drained:        make(chan struct{}),
+		Transfer:       &session.TransferStats{},
+	}
+	listener := &listenerView{closed: make(chan struct{})}
+	next := nextSession(template, &ssm.StartSessionOutput{
+		SessionId:  aws.String("sess-2"),
+		StreamUrl:  aws.String("wss://example"),
+		TokenValue: aws.String("token"),
+	}, 10, listener)
+
+	if next.SessionId != "sess-2" || next.StreamUrl != "wss://example" || next.TokenValue != "token" {
+		t.Errorf("Unexpected session credentials: %+v", next)
+	}
+	if next.MaxConnections != 4 || next.Transfer != template.Transfer || next.LocalListener != listener {
+		t.Error("Expected options, transfer counters and the new listener view to carry over")
+	}
+	if next.PortReady == template.PortReady || next.Drained == template.Drained || next.DataChannel == nil {
+		t.Error("Expected fresh readiness channels and data channel")
+	}
+}
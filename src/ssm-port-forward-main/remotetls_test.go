@@ -0,0 +1,49 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// WHEN --remote-tls-ca names a PEM bundle, THEN loadRemoteTLS SHALL verify against it; WHEN the
+// file holds no certificates, THEN it SHALL fail before the session starts.
+func TestLoadRemoteTLS(t *testing.T) {
+	cfg, err := loadRemoteTLS("db.internal", "", false)
+	if err != nil {
+		t.Fatalf("Expected config without CA, got: %v", err)
+	}
+	if cfg.ServerName != "db.internal" || cfg.RootCAs != nil || cfg.InsecureSkipVerify {
+		t.Errorf("Unexpected config without CA: %+v", cfg)
+	}
+
+	certFile, _ := writeSelfSignedCert(t, t.TempDir())
+	cfg, err = loadRemoteTLS("db.internal", certFile, false)
+	if err != nil {
+		t.Fatalf("Expected CA bundle to load, got: %v", err)
+	}
+	if cfg.RootCAs == nil {
+		t.Error("Expected RootCAs from the CA bundle")
+	}
+
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0600); err != nil {
+		t.Fatalf("Failed to write CA bundle: %v", err)
+	}
+	if _, err := loadRemoteTLS("db.internal", notPEM, false); err == nil {
+		t.Error("Expected bundle without certificates to be rejected")
+	}
+}
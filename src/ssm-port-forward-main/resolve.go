@@ -0,0 +1,81 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// defaultResolverPort is used when --resolver names no port.
+const defaultResolverPort = "53"
+
+// resolveTimeout bounds the local lookup of the remote host.
+var resolveTimeout = 5 * time.Second
+
+// resolverAddress normalises a --resolver value to host:port, adding port 53 when none is given.
+func resolverAddress(resolver string) (string, error) {
+	if host, _, err := net.SplitHostPort(resolver); err == nil && host != "" {
+		return resolver, nil
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(resolver, "["), "]")
+	if net.ParseIP(host) == nil {
+		return "", fmt.Errorf("invalid resolver %s: expected an IP address with optional port", resolver)
+	}
+	return net.JoinHostPort(host, defaultResolverPort), nil
+}
+
+// newResolver returns the system resolver, or one sending every query to addr when it is set.
+func newResolver(addr string) *net.Resolver {
+	if addr == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// resolveRemoteHost looks host up on the client and returns the address to give the agent along
+// with every address found. IPv4 is preferred, as bastions more often lack IPv6 routes, and within
+// a family the resolver's first answer wins. IP literals are returned unchanged.
+func resolveRemoteHost(ctx context.Context, resolver *net.Resolver, host string) (chosen string, all []string, err error) {
+	if net.ParseIP(host) != nil {
+		return host, []string{host}, nil
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to resolve %s locally: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return "", nil, fmt.Errorf("failed to resolve %s locally: no addresses found", host)
+	}
+	for _, addr := range addrs {
+		all = append(all, addr.IP.String())
+	}
+	chosen = all[0]
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			chosen = addr.IP.String()
+			break
+		}
+	}
+	return chosen, all, nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// WHEN --resolver is given, THEN resolverAddress SHALL default the port to 53 and reject non-IP values.
+func TestResolverAddress(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.2":        "10.0.0.2:53",
+		"10.0.0.2:5353":   "10.0.0.2:5353",
+		"fd00::2":         "[fd00::2]:53",
+		"[fd00::2]":       "[fd00::2]:53",
+		"[fd00::2]:5353":  "[fd00::2]:5353",
+		"dns.example.com": "",
+		":53":             "",
+	}
+	for resolver, want := range tests {
+		got, err := resolverAddress(resolver)
+		if want == "" {
+			if err == nil {
+				t.Errorf("Expected %q to be rejected, got %s", resolver, got)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("resolverAddress(%q) = %q, %v; expected %q", resolver, got, err, want)
+		}
+	}
+}
+
+// WHEN the remote host is an IP literal, THEN resolveRemoteHost SHALL return it without a lookup.
+func TestResolveRemoteHostLiteral(t *testing.T) {
+	chosen, all, err := resolveRemoteHost(context.Background(), newResolver("192.0.2.1:53"), "fd00::5")
+	if err != nil || chosen != "fd00::5" || len(all) != 1 {
+		t.Errorf("Unexpected result %q %v %v", chosen, all, err)
+	}
+}
+
+// WHEN a name resolves to IPv4 and IPv6 addresses, THEN resolveRemoteHost SHALL choose IPv4.
+func TestResolveRemoteHostPrefersIPv4(t *testing.T) {
+	chosen, all, err := resolveRemoteHost(context.Background(), net.DefaultResolver, "localhost")
+	if err != nil {
+		t.Skipf("localhost does not resolve here: %v", err)
+	}
+	if chosen != "127.0.0.1" {
+		t.Errorf("Expected 127.0.0.1 to be chosen from %v, got %s", all, chosen)
+	}
+}
+
+// WHEN a resolver address is set, THEN newResolver SHALL send queries to it.
+func TestNewResolverUsesAddress(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	queried := make(chan struct{})
+	go func() {
+		buf := make([]byte, 512)
+		if _, _, err := server.ReadFrom(buf); err == nil {
+			close(queried)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	// Nothing answers, so the lookup fails; only the query's destination matters
+	resolveRemoteHost(ctx, newResolver(server.LocalAddr().String()), "db.internal.example")
+
+	select {
+	case <-queried:
+	case <-time.After(time.Second):
+		t.Error("Expected a query at the configured resolver")
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sort"
+	"time"
+)
+
+// --selftest modes, naming what the forward's remote endpoint does with the bytes it is sent.
+const (
+	SelfTestNone    = ""
+	SelfTestEcho    = "echo"
+	SelfTestDiscard = "discard"
+)
+
+// Latency is measured with selfTestPings sequential round trips of selfTestPingSize bytes.
+const (
+	selfTestPings    = 100
+	selfTestPingSize = 64
+)
+
+// selfTestTimeout bounds the whole self-test, so a stalled tunnel fails rather than hangs.
+var selfTestTimeout = 2 * time.Minute
+
+var errSelfTestFailed = errors.New("self-test failed")
+
+// selfTestReport is the result of --selftest, written as a JSON line in json output format.
+type selfTestReport struct {
+	Type            string  `json:"type"`
+	Mode            string  `json:"mode"`
+	Bytes           int64   `json:"bytes"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	MBPerSecond     float64 `json:"mb_per_second"`
+	LatencyP50Ms    float64 `json:"latency_p50_ms,omitempty"`
+	LatencyP90Ms    float64 `json:"latency_p90_ms,omitempty"`
+	LatencyP99Ms    float64 `json:"latency_p99_ms,omitempty"`
+}
+
+func validateSelfTestMode(mode string) error {
+	switch mode {
+	case SelfTestNone, SelfTestEcho, SelfTestDiscard:
+		return nil
+	default:
+		return fmt.Errorf("invalid selftest mode: %s (expected echo or discard)", mode)
+	}
+}
+
+// runSelfTest measures the forward on addr by sending size bytes through the tunnel. Against an
+// echo endpoint the bytes are read back, so throughput covers both directions, and round-trip
+// latency is sampled first; against a discard endpoint only the upload rate is measured, up to
+// the point the tunnel has taken the last byte.
+func runSelfTest(addr, mode string, size int64) (selfTestReport, error) {
+	report := selfTestReport{Type: "selftest", Mode: mode, Bytes: size}
+	conn, err := net.DialTimeout("tcp", addr, selfTestTimeout)
+	if err != nil {
+		return report, fmt.Errorf("%w: %v", errSelfTestFailed, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(selfTestTimeout))
+
+	if mode == SelfTestEcho {
+		latencies, err := measureLatency(conn)
+		if err != nil {
+			return report, fmt.Errorf("%w: latency: %v", errSelfTestFailed, err)
+		}
+		report.LatencyP50Ms = percentileMs(latencies, 0.50)
+		report.LatencyP90Ms = percentileMs(latencies, 0.90)
+		report.LatencyP99Ms = percentileMs(latencies, 0.99)
+	}
+
+	start := time.Now()
+	if err := measureThroughput(conn, mode, size); err != nil {
+		return report, fmt.Errorf("%w: throughput: %v", errSelfTestFailed, err)
+	}
+	elapsed := time.Since(start)
+	report.DurationSeconds = elapsed.Seconds()
+	report.MBPerSecond = float64(size) / elapsed.Seconds() / 1e6
+	return report, nil
+}
+
+// measureLatency times sequential echoed pings, checking each comes back unchanged.
+func measureLatency(conn net.Conn) ([]time.Duration, error) {
+	ping := bytes.Repeat([]byte("p"), selfTestPingSize)
+	reply := make([]byte, selfTestPingSize)
+	latencies := make([]time.Duration, 0, selfTestPings)
+	for i := 0; i < selfTestPings; i++ {
+		start := time.Now()
+		if _, err := conn.Write(ping); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return nil, err
+		}
+		latencies = append(latencies, time.Since(start))
+		if !bytes.Equal(ping, reply) {
+			return nil, errors.New("the remote did not echo the data sent; is it an echo service?")
+		}
+	}
+	return latencies, nil
+}
+
+// measureThroughput sends size bytes and, for an echo endpoint, reads them all back meanwhile.
+func measureThroughput(conn net.Conn, mode string, size int64) error {
+	payload := io.LimitReader(patternReader{}, size)
+	if mode != SelfTestEcho {
+		_, err := io.Copy(conn, payload)
+		return err
+	}
+
+	sent := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(conn, payload)
+		sent <- err
+	}()
+	received, err := io.CopyN(io.Discard, conn, size)
+	if err != nil {
+		return fmt.Errorf("received %d of %d bytes: %v", received, size, err)
+	}
+	return <-sent
+}
+
+// patternReader yields an endless non-zero byte pattern, so no layer can shortcut zeros.
+type patternReader struct{}
+
+func (patternReader) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = byte(i%251) + 1
+	}
+	return len(b), nil
+}
+
+// percentileMs returns the q-th percentile of latencies in milliseconds, by the nearest-rank method.
+func percentileMs(latencies []time.Duration, q float64) float64 {
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return float64(sorted[max(rank, 0)].Microseconds()) / 1000
+}
+
+// printSelfTest writes report as a JSON line in json format, otherwise as a summary line.
+func printSelfTest(w io.Writer, format string, report selfTestReport) error {
+	if format == OutputFormatJSON {
+		data, err := json.Marshal(report)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "Self-test (%s): %.1f MB in %.2fs, %.2f MB/s", report.Mode,
+		float64(report.Bytes)/1e6, report.DurationSeconds, report.MBPerSecond)
+	if err == nil && report.Mode == SelfTestEcho {
+		_, err = fmt.Fprintf(w, "; latency p50 %.1fms p90 %.1fms p99 %.1fms",
+			report.LatencyP50Ms, report.LatencyP90Ms, report.LatencyP99Ms)
+	}
+	if err == nil {
+		_, err = fmt.Fprintln(w)
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveTest accepts connections on a loopback port and handles each with handle.
+func serveTest(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// WHEN the remote is an echo service, THEN the self-test SHALL report throughput for every byte
+// read back and latency percentiles; against a discard service only throughput.
+func TestRunSelfTest(t *testing.T) {
+	echo := serveTest(t, func(conn net.Conn) { io.Copy(conn, conn) })
+	report, err := runSelfTest(echo, SelfTestEcho, 1<<20)
+	if err != nil {
+		t.Fatalf("Expected the echo self-test to pass, got: %v", err)
+	}
+	if report.Bytes != 1<<20 || report.MBPerSecond <= 0 || report.LatencyP50Ms <= 0 ||
+		report.LatencyP50Ms > report.LatencyP90Ms || report.LatencyP90Ms > report.LatencyP99Ms {
+		t.Errorf("Unexpected echo report: %+v", report)
+	}
+
+	discard := serveTest(t, func(conn net.Conn) { io.Copy(io.Discard, conn) })
+	report, err = runSelfTest(discard, SelfTestDiscard, 1<<20)
+	if err != nil {
+		t.Fatalf("Expected the discard self-test to pass, got: %v", err)
+	}
+	if report.MBPerSecond <= 0 || report.LatencyP50Ms != 0 {
+		t.Errorf("Unexpected discard report: %+v", report)
+	}
+}
+
+// WHEN the echo self-test runs against a service that doesn't echo, THEN it SHALL fail.
+func TestRunSelfTestNotEcho(t *testing.T) {
+	original := selfTestTimeout
+	defer func() { selfTestTimeout = original }()
+	selfTestTimeout = time.Second
+
+	discard := serveTest(t, func(conn net.Conn) { io.Copy(io.Discard, conn) })
+	if _, err := runSelfTest(discard, SelfTestEcho, 1<<20); !errors.Is(err, errSelfTestFailed) {
+		t.Errorf("Expected the self-test to fail, got: %v", err)
+	}
+	garbled := serveTest(t, func(conn net.Conn) { io.Copy(conn, io.LimitReader(patternReader{}, 1<<20)) })
+	if _, err := runSelfTest(garbled, SelfTestEcho, 1<<20); err == nil || !strings.Contains(err.Error(), "did not echo") {
+		t.Errorf("Expected a mismatch, got: %v", err)
+	}
+}
+
+// WHEN percentiles are taken, THEN they SHALL use the nearest rank.
+func TestPercentileMs(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	for q, want := range map[float64]float64{0.5: 50, 0.9: 90, 0.99: 99, 1: 100} {
+		if got := percentileMs(latencies, q); got != want {
+			t.Errorf("p%v: expected %vms, got %vms", q*100, want, got)
+		}
+	}
+}
+
+// WHEN the report is printed, THEN it SHALL be a JSON line in json format and a summary otherwise.
+func TestPrintSelfTest(t *testing.T) {
+	report := selfTestReport{Type: "selftest", Mode: SelfTestEcho, Bytes: 16e6, DurationSeconds: 2, MBPerSecond: 8,
+		LatencyP50Ms: 12.5, LatencyP90Ms: 15, LatencyP99Ms: 30}
+	var out bytes.Buffer
+	if err := printSelfTest(&out, OutputFormatText, report); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Self-test (echo): 16.0 MB in 2.00s, 8.00 MB/s; latency p50 12.5ms p90 15.0ms p99 30.0ms\n"; out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+
+	out.Reset()
+	if err := printSelfTest(&out, OutputFormatJSON, report); err != nil {
+		t.Fatal(err)
+	}
+	var decoded selfTestReport
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || decoded != report {
+		t.Errorf("Expected the report as JSON, got %q: %v", out.String(), err)
+	}
+}
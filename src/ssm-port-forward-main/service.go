@@ -0,0 +1,34 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// serviceName is the name the forward reports to the Windows service control manager.
+const serviceName = "ssm-port-forward"
+
+// forwardServiceStop delivers SIGTERM on sigChan once stop is closed, so a Windows service stop
+// or system shutdown tears the forward down, terminating its session, like any other SIGTERM.
+func forwardServiceStop(stop <-chan struct{}, sigChan chan<- os.Signal) {
+	if stop == nil {
+		return
+	}
+	go func() {
+		<-stop
+		sigChan <- syscall.SIGTERM
+	}()
+}
@@ -0,0 +1,30 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package main
+
+import "errors"
+
+// runningAsService reports whether the process was started by the Windows service control
+// manager, which is never the case elsewhere.
+func runningAsService() bool {
+	return false
+}
+
+// runAsService is only supported on Windows.
+func runAsService(*PortForwardConfig) error {
+	return errors.New("running as a service is only supported on Windows")
+}
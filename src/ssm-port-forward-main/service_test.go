@@ -0,0 +1,56 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// WHEN the service stop channel is closed, THEN SIGTERM SHALL be delivered so the forward shuts
+// down through the usual signal path.
+func TestForwardServiceStop(t *testing.T) {
+	stop := make(chan struct{})
+	sigChan := make(chan os.Signal, 1)
+	forwardServiceStop(stop, sigChan)
+
+	select {
+	case sig := <-sigChan:
+		t.Fatalf("Unexpected signal %v before the service stopped", sig)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(stop)
+	select {
+	case sig := <-sigChan:
+		if sig != syscall.SIGTERM {
+			t.Errorf("Expected SIGTERM, got %v", sig)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected SIGTERM after the service stopped")
+	}
+}
+
+// WHEN not running as a service, THEN no stop channel SHALL be watched.
+func TestForwardServiceStopNil(t *testing.T) {
+	sigChan := make(chan os.Signal, 1)
+	forwardServiceStop(nil, sigChan)
+	select {
+	case sig := <-sigChan:
+		t.Errorf("Unexpected signal %v", sig)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"sync"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// runningAsService reports whether the process was started by the service control manager.
+func runningAsService() bool {
+	isService, err := svc.IsWindowsService()
+	return err == nil && isService
+}
+
+// runAsService runs the forward as a Windows service until it ends or the service is stopped.
+func runAsService(config *PortForwardConfig) error {
+	service := &forwardService{config: config}
+	if err := svc.Run(serviceName, service); err != nil {
+		return err
+	}
+	return service.err
+}
+
+// forwardService runs the forward for the service control manager, turning stop and shutdown
+// requests into SIGTERM for run.
+type forwardService struct {
+	config *PortForwardConfig
+	err    error
+}
+
+func (s *forwardService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	s.config.serviceStop = stop
+	done := make(chan error, 1)
+	go func() { done <- run(s.config) }()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case err := <-done:
+			s.err = err
+			if err != nil {
+				// Reported as a service-specific exit code, matching the process exit status
+				return true, uint32(exitCode(err))
+			}
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				stopOnce.Do(func() { close(stop) })
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// sessionResponse is a StartSession response obtained by the caller, plus the target it was started on.
+// This mirrors the session response the AWS CLI hands to session-manager-plugin.
+type sessionResponse struct {
+	SessionId  string
+	StreamUrl  string
+	TokenValue string
+	TargetId   string
+}
+
+// readSessionResponse loads a session response from path, or from stdin when path is "-".
+func readSessionResponse(path string, stdin io.Reader) (*sessionResponse, error) {
+	var r io.Reader = stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var resp sessionResponse
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("invalid session JSON: %w", err)
+	}
+	if resp.SessionId == "" || resp.StreamUrl == "" || resp.TokenValue == "" {
+		return nil, errors.New("invalid session JSON: SessionId, StreamUrl and TokenValue are required")
+	}
+	return &resp, nil
+}
+
+// toStartSessionOutput converts the response into the shape returned by the StartSession API.
+func (r *sessionResponse) toStartSessionOutput() *ssm.StartSessionOutput {
+	return &ssm.StartSessionOutput{
+		SessionId:  &r.SessionId,
+		StreamUrl:  &r.StreamUrl,
+		TokenValue: &r.TokenValue,
+	}
+}
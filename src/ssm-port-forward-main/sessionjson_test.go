@@ -0,0 +1,62 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sessionJSON = `{"SessionId":"s-123","StreamUrl":"wss://example","TokenValue":"tok","TargetId":"i-abc"}`
+
+func TestReadSessionResponseFromStdin(t *testing.T) {
+	resp, err := readSessionResponse("-", strings.NewReader(sessionJSON))
+	if err != nil {
+		t.Fatalf("Expected success, got: %v", err)
+	}
+	if resp.SessionId != "s-123" || resp.StreamUrl != "wss://example" || resp.TokenValue != "tok" || resp.TargetId != "i-abc" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+
+	out := resp.toStartSessionOutput()
+	if *out.SessionId != "s-123" || *out.StreamUrl != "wss://example" || *out.TokenValue != "tok" {
+		t.Errorf("Unexpected StartSessionOutput: %v", out)
+	}
+}
+
+func TestReadSessionResponseFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := os.WriteFile(path, []byte(sessionJSON), 0600); err != nil {
+		t.Fatalf("Failed to write session file: %v", err)
+	}
+
+	resp, err := readSessionResponse(path, strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("Expected success, got: %v", err)
+	}
+	if resp.SessionId != "s-123" {
+		t.Errorf("Unexpected SessionId: %s", resp.SessionId)
+	}
+}
+
+func TestReadSessionResponseMissingFields(t *testing.T) {
+	if _, err := readSessionResponse("-", strings.NewReader(`{"SessionId":"s-123"}`)); err == nil {
+		t.Fatal("Expected error for missing StreamUrl/TokenValue")
+	}
+	if _, err := readSessionResponse("-", strings.NewReader(`not json`)); err == nil {
+		t.Fatal("Expected error for invalid JSON")
+	}
+}
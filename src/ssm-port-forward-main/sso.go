@@ -0,0 +1,79 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials/ssocreds"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+)
+
+// ssoLogin runs the AWS CLI device-authorization flow for profile. Its prompts go to stderr so
+// stdout keeps carrying only the JSON output. Replaced in tests.
+var ssoLogin = func(profile string) error {
+	args := []string{"sso", "login"}
+	if profile != "" {
+		args = append(args, "--profile", profile)
+	}
+	cmd := exec.Command("aws", args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// isSSOTokenError reports whether err means the cached IAM Identity Center token is missing,
+// expired or otherwise unusable.
+func isSSOTokenError(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == ssocreds.ErrCodeSSOProviderInvalidToken
+}
+
+// resolveCredentials creates an AWS session and loads its credentials up front, so an expired
+// SSO token is reported clearly instead of surfacing as an opaque error from the first API call.
+// With login set, an SSO token error triggers "aws sso login" and one retry.
+func resolveCredentials(newSession func() (*awssession.Session, error), profile string, login bool) (*awssession.Session, error) {
+	sess, err := newSession()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = sess.Config.Credentials.Get(); err == nil || !isSSOTokenError(err) {
+		// Other credential problems keep surfacing from the API calls that need them
+		return sess, nil
+	}
+
+	if !login {
+		loginCmd := "aws sso login"
+		if profile != "" {
+			loginCmd += " --profile " + profile
+		}
+		return nil, fmt.Errorf("AWS SSO session expired or not started; run %q or pass --sso-login: %w", loginCmd, err)
+	}
+
+	if err = ssoLogin(profile); err != nil {
+		return nil, fmt.Errorf("aws sso login failed: %w", err)
+	}
+	if sess, err = newSession(); err != nil {
+		return nil, err
+	}
+	if _, err = sess.Config.Credentials.Get(); err != nil {
+		return nil, fmt.Errorf("failed to load credentials after aws sso login: %w", err)
+	}
+	return sess, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/ssocreds"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+)
+
+// ssoProvider fails with an SSO token error until loggedIn is set.
+type ssoProvider struct {
+	loggedIn *bool
+}
+
+func (p ssoProvider) Retrieve() (credentials.Value, error) {
+	if !*p.loggedIn {
+		return credentials.Value{}, awserr.New(ssocreds.ErrCodeSSOProviderInvalidToken, "the SSO session has expired or is invalid", nil)
+	}
+	return credentials.Value{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+}
+
+func (p ssoProvider) IsExpired() bool { return !*p.loggedIn }
+
+// stubSSOLogin replaces ssoLogin for the duration of a test.
+func stubSSOLogin(t *testing.T, login func(profile string) error) {
+	orig := ssoLogin
+	ssoLogin = login
+	t.Cleanup(func() { ssoLogin = orig })
+}
+
+func ssoSessionFactory(loggedIn *bool) func() (*awssession.Session, error) {
+	return func() (*awssession.Session, error) {
+		return awssession.NewSession(&aws.Config{
+			Region:      aws.String("us-east-1"),
+			Credentials: credentials.NewCredentials(ssoProvider{loggedIn}),
+		})
+	}
+}
+
+// WHEN the SSO token is expired and --sso-login is not set, THEN resolveCredentials SHALL fail
+// with an auth error that names the aws sso login command.
+func TestResolveCredentialsSSOExpired(t *testing.T) {
+	loggedIn := false
+	stubSSOLogin(t, func(string) error {
+		t.Fatal("ssoLogin should not run without --sso-login")
+		return nil
+	})
+
+	_, err := resolveCredentials(ssoSessionFactory(&loggedIn), "dev", false)
+	if err == nil {
+		t.Fatal("Expected error for expired SSO token")
+	}
+	if !strings.Contains(err.Error(), "aws sso login --profile dev") {
+		t.Errorf("Expected login hint, got: %v", err)
+	}
+	if code := classifyError(err, CodeInternal); code != CodeAuthFailed {
+		t.Errorf("Expected %s, got %s", CodeAuthFailed, code)
+	}
+}
+
+// WHEN the SSO token is expired and --sso-login is set, THEN resolveCredentials SHALL run the
+// login flow for the profile and retry.
+func TestResolveCredentialsSSOLogin(t *testing.T) {
+	loggedIn := false
+	var gotProfile string
+	stubSSOLogin(t, func(profile string) error {
+		gotProfile = profile
+		loggedIn = true
+		return nil
+	})
+
+	sess, err := resolveCredentials(ssoSessionFactory(&loggedIn), "dev", true)
+	if err != nil {
+		t.Fatalf("Expected success after login, got: %v", err)
+	}
+	if sess == nil || gotProfile != "dev" {
+		t.Errorf("Expected login for profile dev, got %q", gotProfile)
+	}
+}
+
+// WHEN the login flow fails, THEN resolveCredentials SHALL report it.
+func TestResolveCredentialsSSOLoginFails(t *testing.T) {
+	loggedIn := false
+	stubSSOLogin(t, func(string) error { return errors.New("exit status 255") })
+
+	if _, err := resolveCredentials(ssoSessionFactory(&loggedIn), "", true); err == nil {
+		t.Fatal("Expected error when aws sso login fails")
+	}
+}
+
+// WHEN credentials fail for a non-SSO reason, THEN resolveCredentials SHALL leave reporting to the API calls.
+func TestResolveCredentialsIgnoresOtherErrors(t *testing.T) {
+	newSession := func() (*awssession.Session, error) {
+		return awssession.NewSession(&aws.Config{
+			Region:      aws.String("us-east-1"),
+			Credentials: credentials.NewCredentials(&credentials.ErrorProvider{Err: errors.New("no credentials")}),
+		})
+	}
+	if _, err := resolveCredentials(newSession, "", false); err != nil {
+		t.Errorf("Expected non-SSO credential errors to be deferred, got: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/zph/session-manager-plugin/src/log"
+	"github.com/zph/session-manager-plugin/src/sdkutil"
+)
+
+// startRetryBaseDelay is the backoff ceiling before the first StartSession retry; it doubles per attempt.
+var startRetryBaseDelay = 500 * time.Millisecond
+
+// errStartInterrupted is returned when a signal arrives while waiting to retry StartSession.
+var errStartInterrupted = errors.New("interrupted while retrying StartSession")
+
+// withoutSDKRetries makes a StartSession request a single attempt. The session's retryer would
+// otherwise retry it as well, multiplying --start-retries, and --start-retries 0 would still retry.
+var withoutSDKRetries request.Option = func(r *request.Request) {
+	r.Retryer = client.NoOpRetryer{}
+}
+
+// isRetryableStartError reports whether a StartSession failure is throttling or a transient
+// service fault. Client errors such as AccessDeniedException or TargetNotConnected are final.
+func isRetryableStartError(err error) bool {
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) && reqErr.StatusCode() >= 500 {
+		return true
+	}
+	return sdkutil.IsThrottling(err)
+}
+
+// startRetryDelay returns a full-jitter backoff for the given retry attempt (0-based): a random
+// duration up to startRetryBaseDelay doubled per attempt, capped at maxDelay.
+func startRetryDelay(attempt int, maxDelay time.Duration) time.Duration {
+	ceiling := maxDelay
+	if attempt < 32 {
+		if d := startRetryBaseDelay << attempt; d > 0 && d < maxDelay {
+			ceiling = d
+		}
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// startSessionWithRetry calls start, retrying throttling and transient errors up to retries more
+// times with exponential backoff and jitter. A signal on sigChan abandons the wait.
+func startSessionWithRetry(logger log.T, start func() (*ssm.StartSessionOutput, error), retries int, maxDelay time.Duration, sigChan <-chan os.Signal) (*ssm.StartSessionOutput, error) {
+	for attempt := 0; ; attempt++ {
+		output, err := start()
+		if err == nil || attempt >= retries || !isRetryableStartError(err) {
+			return output, err
+		}
+
+		delay := startRetryDelay(attempt, maxDelay)
+		logger.Warnf("StartSession failed (attempt %d of %d), retrying in %v: %v", attempt+1, retries+1, delay.Round(time.Millisecond), err)
+		select {
+		case <-time.After(delay):
+		case sig := <-sigChan:
+			return nil, fmt.Errorf("%w: received %v: %w", errStartInterrupted, sig, err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// fastStartRetries shrinks the backoff so retry tests run quickly.
+func fastStartRetries(t *testing.T) {
+	original := startRetryBaseDelay
+	startRetryBaseDelay = time.Millisecond
+	t.Cleanup(func() { startRetryBaseDelay = original })
+}
+
+// failingStart returns a StartSession func that fails with errs in turn, then succeeds.
+func failingStart(calls *int, errs ...error) func() (*ssm.StartSessionOutput, error) {
+	return func() (*ssm.StartSessionOutput, error) {
+		*calls++
+		if *calls <= len(errs) {
+			return nil, errs[*calls-1]
+		}
+		return &ssm.StartSessionOutput{}, nil
+	}
+}
+
+func TestIsRetryableStartError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"throttling", awserr.New("ThrottlingException", "rate exceeded", nil), true},
+		{"server error", awserr.NewRequestFailure(awserr.New("InternalServerError", "oops", nil), 500, "req"), true},
+		{"unavailable", awserr.NewRequestFailure(awserr.New("ServiceUnavailable", "busy", nil), 503, "req"), true},
+		{"access denied", awserr.NewRequestFailure(awserr.New("AccessDeniedException", "denied", nil), 400, "req"), false},
+		{"target not connected", awserr.NewRequestFailure(awserr.New("TargetNotConnected", "offline", nil), 400, "req"), false},
+		{"not aws", errors.New("dial tcp: timeout"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableStartError(tt.err); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// WHEN StartSession is throttled, THEN it SHALL be retried until it succeeds within --start-retries.
+func TestStartSessionWithRetryRecoversFromThrottling(t *testing.T) {
+	fastStartRetries(t)
+	calls := 0
+	throttled := awserr.New("ThrottlingException", "rate exceeded", nil)
+
+	output, err := startSessionWithRetry(log.NewMockLog(), failingStart(&calls, throttled, throttled), 3, time.Second, nil)
+	if err != nil || output == nil {
+		t.Fatalf("Expected success after retries, got: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+}
+
+// WHEN StartSession fails with a non-transient error or retries run out, THEN the last error SHALL
+// be returned without further attempts.
+func TestStartSessionWithRetryStops(t *testing.T) {
+	fastStartRetries(t)
+
+	calls := 0
+	denied := awserr.New("AccessDeniedException", "denied", nil)
+	if _, err := startSessionWithRetry(log.NewMockLog(), failingStart(&calls, denied), 3, time.Second, nil); !errors.Is(err, denied) {
+		t.Errorf("Expected access denied, got: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected no retry for access denied, got %d calls", calls)
+	}
+
+	calls = 0
+	throttled := awserr.New("ThrottlingException", "rate exceeded", nil)
+	if _, err := startSessionWithRetry(log.NewMockLog(), failingStart(&calls, throttled, throttled, throttled), 2, time.Second, nil); !errors.Is(err, throttled) {
+		t.Errorf("Expected throttling error, got: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 1 call plus 2 retries, got %d", calls)
+	}
+}
+
+// WHEN a signal arrives while waiting to retry, THEN startSessionWithRetry SHALL give up at once.
+func TestStartSessionWithRetryInterrupted(t *testing.T) {
+	sigChan := make(chan os.Signal, 1)
+	sigChan <- syscall.SIGINT
+	calls := 0
+	throttled := awserr.New("ThrottlingException", "rate exceeded", nil)
+
+	_, err := startSessionWithRetry(log.NewMockLog(), failingStart(&calls, throttled, throttled), 3, time.Hour, sigChan)
+	if !errors.Is(err, errStartInterrupted) {
+		t.Errorf("Expected interruption, got: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single call, got %d", calls)
+	}
+}
+
+func TestStartRetryDelayIsCapped(t *testing.T) {
+	for attempt := 0; attempt < 70; attempt++ {
+		if d := startRetryDelay(attempt, 2*time.Second); d < 0 || d > 2*time.Second {
+			t.Fatalf("Attempt %d: delay %v outside [0, 2s]", attempt, d)
+		}
+	}
+}
+
+// WHEN a StartSession request is made with withoutSDKRetries, THEN the SDK SHALL not retry it,
+// leaving --start-retries as the only retries.
+func TestWithoutSDKRetries(t *testing.T) {
+	client := ssm.New(awssession.Must(awssession.NewSession(&aws.Config{Region: aws.String("us-east-1"), MaxRetries: aws.Int(3)})))
+	req, _ := client.StartSessionRequest(&ssm.StartSessionInput{})
+	if req.MaxRetries() == 0 {
+		t.Fatalf("Expected the client to retry by default")
+	}
+
+	req.ApplyOptions(withoutSDKRetries)
+
+	if got := req.MaxRetries(); got != 0 {
+		t.Errorf("Expected no SDK retries, got %d", got)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+// statsdPrefix namespaces every metric sent to statsd.
+const statsdPrefix = "ssm_port_forward."
+
+// statsdClient sends connection metrics to a statsd endpoint over UDP, one datagram per metric.
+// Sends are fire-and-forget: a missing or slow collector never holds up a connection. A nil
+// *statsdClient sends nothing.
+type statsdClient struct {
+	conn   net.Conn
+	tags   string
+	active atomic.Int64
+}
+
+// dialStatsd connects to the statsd endpoint at addr. tags, when not empty, are comma-separated
+// key:value pairs added to every metric in the dogstatsd format.
+func dialStatsd(addr, tags string) (*statsdClient, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return newStatsdClient(conn, tags), nil
+}
+
+func newStatsdClient(conn net.Conn, tags string) *statsdClient {
+	s := &statsdClient{conn: conn}
+	if tags != "" {
+		s.tags = "|#" + tags
+	}
+	return s
+}
+
+// parseStatsdTags checks the --statsd-tags list of key:value pairs.
+func parseStatsdTags(tags string) error {
+	if tags == "" {
+		return nil
+	}
+	for _, tag := range strings.Split(tags, ",") {
+		key, _, _ := strings.Cut(tag, ":")
+		if key == "" || strings.ContainsAny(tag, "|#@ \n") {
+			return fmt.Errorf("invalid statsd tag %q: expected key:value", tag)
+		}
+	}
+	return nil
+}
+
+// send writes one metric of the given statsd type ("c" or "g").
+func (s *statsdClient) send(name string, value int64, kind string) {
+	fmt.Fprintf(s.conn, "%s%s:%d|%s%s", statsdPrefix, name, value, kind, s.tags)
+}
+
+// connOpened counts a local client connection and updates the active gauge.
+func (s *statsdClient) connOpened() {
+	if s == nil {
+		return
+	}
+	s.send("connections.opened", 1, "c")
+	s.send("connections.active", s.active.Add(1), "g")
+}
+
+// connClosed counts a finished local client connection and the bytes it carried.
+func (s *statsdClient) connClosed(record session.ConnRecord) {
+	if s == nil {
+		return
+	}
+	s.send("connections.closed", 1, "c")
+	s.send("connections.active", s.active.Add(-1), "g")
+	s.send("bytes.in", record.BytesIn, "c")
+	s.send("bytes.out", record.BytesOut, "c")
+}
+
+// reconnect counts the start of a data channel reconnect or session replacement.
+func (s *statsdClient) reconnect(reconnecting bool) {
+	if s == nil || !reconnecting {
+		return
+	}
+	s.send("reconnects", 1, "c")
+}
+
+// Close closes the UDP socket.
+func (s *statsdClient) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
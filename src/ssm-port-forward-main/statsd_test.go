@@ -0,0 +1,100 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
+)
+
+// readDatagrams reads n datagrams from conn.
+func readDatagrams(t *testing.T, conn net.PacketConn, n int) []string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var got []string
+	buf := make([]byte, 512)
+	for i := 0; i < n; i++ {
+		size, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Failed to read datagram %d: %v", i, err)
+		}
+		got = append(got, string(buf[:size]))
+	}
+	return got
+}
+
+// WHEN connections open and close and the data channel reconnects, THEN statsd SHALL receive
+// counters for each, the active gauge and the closed connection's bytes, with the tags.
+func TestStatsdClientMetrics(t *testing.T) {
+	collector, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer collector.Close()
+
+	stats, err := dialStatsd(collector.LocalAddr().String(), "env:test")
+	if err != nil {
+		t.Fatalf("Failed to dial statsd: %v", err)
+	}
+	defer stats.Close()
+
+	stats.connOpened()
+	stats.connClosed(session.ConnRecord{BytesIn: 10, BytesOut: 20})
+	stats.reconnect(true)
+	stats.reconnect(false)
+
+	want := []string{
+		"ssm_port_forward.connections.opened:1|c|#env:test",
+		"ssm_port_forward.connections.active:1|g|#env:test",
+		"ssm_port_forward.connections.closed:1|c|#env:test",
+		"ssm_port_forward.connections.active:0|g|#env:test",
+		"ssm_port_forward.bytes.in:10|c|#env:test",
+		"ssm_port_forward.bytes.out:20|c|#env:test",
+		"ssm_port_forward.reconnects:1|c|#env:test",
+	}
+	got := readDatagrams(t, collector, len(want))
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Datagram %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// WHEN statsd is not configured, THEN the nil client SHALL send nothing and not panic.
+func TestStatsdClientNil(t *testing.T) {
+	var stats *statsdClient
+	stats.connOpened()
+	stats.connClosed(session.ConnRecord{})
+	stats.reconnect(true)
+	if err := stats.Close(); err != nil {
+		t.Errorf("Close() = %v, want nil", err)
+	}
+}
+
+// WHEN statsd tags are given, THEN key:value pairs SHALL be accepted and malformed ones rejected.
+func TestParseStatsdTags(t *testing.T) {
+	for _, tags := range []string{"", "env:prod", "env:prod,team:db", "canary"} {
+		if err := parseStatsdTags(tags); err != nil {
+			t.Errorf("parseStatsdTags(%q) = %v, want nil", tags, err)
+		}
+	}
+	for _, tags := range []string{",", ":prod", "env:prod|x", "env:a b"} {
+		if err := parseStatsdTags(tags); err == nil {
+			t.Errorf("parseStatsdTags(%q) = nil, want an error", tags)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+// Prefixes of an --instance-id looked up by private address rather than given as an instance ID.
+const (
+	targetPrefixIP  = "ip:"
+	targetPrefixDNS = "dns:"
+)
+
+var errAmbiguousTarget = errors.New("matches more than one running instance")
+
+// targetFilter returns the DescribeInstances filter an ip: or dns: target is looked up by.
+// ok is false for anything else, such as a plain instance ID, which is used as given.
+func targetFilter(target string) (filter, value string, ok bool) {
+	if value, found := strings.CutPrefix(target, targetPrefixIP); found {
+		return "private-ip-address", value, true
+	}
+	if value, found := strings.CutPrefix(target, targetPrefixDNS); found {
+		return "private-dns-name", value, true
+	}
+	return "", "", false
+}
+
+// validateTarget checks the address of an ip: or dns: target.
+func validateTarget(target string) error {
+	filter, value, ok := targetFilter(target)
+	switch {
+	case !ok:
+		return nil
+	case value == "":
+		return fmt.Errorf("instance-id %q is missing the address to look up", target)
+	case filter == "private-ip-address" && net.ParseIP(value) == nil:
+		return fmt.Errorf("instance-id %q: %q is not an IP address", target, value)
+	}
+	return nil
+}
+
+// resolveTargetInstance returns the ID of the one running instance with the private IP or DNS
+// name of an ip: or dns: target. It fails rather than guess when several match, as the same
+// private address can be in use in more than one VPC.
+func resolveTargetInstance(ec2Client ec2iface.EC2API, target string) (string, error) {
+	filter, value, _ := targetFilter(target)
+	var running []string
+	err := ec2Client.DescribeInstancesPages(&ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String(filter), Values: []*string{aws.String(value)}},
+			{Name: aws.String("instance-state-name"), Values: []*string{aws.String(ec2.InstanceStateNameRunning)}},
+		},
+	}, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				running = append(running, aws.StringValue(instance.InstanceId))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe instances with %s %s: %w", filter, value, err)
+	}
+	switch len(running) {
+	case 0:
+		return "", fmt.Errorf("%w with %s %s", errNoHealthyInstance, filter, value)
+	case 1:
+		return running[0], nil
+	}
+	sort.Strings(running)
+	return "", fmt.Errorf("%s %s %w (%s); pass an instance ID instead", filter, value, errAmbiguousTarget, strings.Join(running, ", "))
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+type fakeEC2Instances struct {
+	ec2iface.EC2API
+	ids     []string
+	filters map[string]string
+}
+
+func (f *fakeEC2Instances) DescribeInstancesPages(input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+	f.filters = map[string]string{}
+	for _, filter := range input.Filters {
+		f.filters[aws.StringValue(filter.Name)] = aws.StringValue(filter.Values[0])
+	}
+	// One instance per page, to cover collecting across pages
+	for i, id := range f.ids {
+		page := &ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{{InstanceId: aws.String(id)}}}}}
+		if !fn(page, i == len(f.ids)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// WHEN the target is ip: or dns:, THEN it SHALL be looked up by the matching private address
+// filter among running instances.
+func TestResolveTargetInstance(t *testing.T) {
+	for target, filter := range map[string]string{
+		"ip:10.0.1.23":                  "private-ip-address",
+		"dns:ip-10-0-1-23.ec2.internal": "private-dns-name",
+	} {
+		client := &fakeEC2Instances{ids: []string{"i-abc"}}
+		id, err := resolveTargetInstance(client, target)
+		if err != nil || id != "i-abc" {
+			t.Errorf("%s: expected i-abc, got %q, %v", target, id, err)
+		}
+		_, value, _ := strings.Cut(target, ":")
+		if client.filters[filter] != value || client.filters["instance-state-name"] != "running" {
+			t.Errorf("%s: unexpected filters %v", target, client.filters)
+		}
+	}
+}
+
+// WHEN no instance or several match, THEN resolution SHALL fail rather than pick one.
+func TestResolveTargetInstanceNoneOrAmbiguous(t *testing.T) {
+	_, err := resolveTargetInstance(&fakeEC2Instances{}, "ip:10.0.1.23")
+	if !errors.Is(err, errNoHealthyInstance) {
+		t.Errorf("Expected no instance error, got: %v", err)
+	}
+
+	_, err = resolveTargetInstance(&fakeEC2Instances{ids: []string{"i-b", "i-a"}}, "ip:10.0.1.23")
+	if !errors.Is(err, errAmbiguousTarget) || !strings.Contains(err.Error(), "i-a, i-b") {
+		t.Errorf("Expected ambiguity error listing both instances, got: %v", err)
+	}
+}
+
+// WHEN the target is a plain instance ID, THEN it SHALL bypass lookup; WHEN an ip: or dns: target
+// has no valid address, THEN it SHALL be rejected.
+func TestValidateTarget(t *testing.T) {
+	if _, _, ok := targetFilter("i-0123456789abcdef0"); ok {
+		t.Error("Expected an instance ID not to be looked up")
+	}
+	for target, valid := range map[string]bool{
+		"i-0123456789abcdef0": true,
+		"ip:10.0.1.23":        true,
+		"ip:fd00::1":          true,
+		"dns:ip-10-0-1-23":    true,
+		"ip:":                 false,
+		"dns:":                false,
+		"ip:db.internal":      false,
+	} {
+		if err := validateTarget(target); (err == nil) != valid {
+			t.Errorf("%s: expected valid=%v, got: %v", target, valid, err)
+		}
+	}
+}
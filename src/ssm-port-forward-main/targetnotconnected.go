@@ -0,0 +1,74 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// targetNotConnectedCode is the StartSession error code for a target whose SSM agent is not online.
+const targetNotConnectedCode = "TargetNotConnected"
+
+// isTargetNotConnected reports whether err is StartSession's TargetNotConnected error.
+func isTargetNotConnected(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == targetNotConnectedCode
+}
+
+// describePingStatus describes the instance's Systems Manager registration, e.g. "ping status:
+// ConnectionLost, last ping 2025-01-02T15:04:05Z". It returns "" when the lookup itself fails,
+// for instance because the caller may not call DescribeInstanceInformation.
+func describePingStatus(client ssmiface.SSMAPI, instanceID string) string {
+	out, err := client.DescribeInstanceInformation(&ssm.DescribeInstanceInformationInput{
+		Filters: []*ssm.InstanceInformationStringFilter{{
+			Key:    aws.String("InstanceIds"),
+			Values: []*string{aws.String(instanceID)},
+		}},
+	})
+	if err != nil {
+		return ""
+	}
+	if len(out.InstanceInformationList) == 0 {
+		return "not registered as a managed instance"
+	}
+	info := out.InstanceInformationList[0]
+	status := "ping status: " + aws.StringValue(info.PingStatus)
+	if info.LastPingDateTime != nil {
+		status += ", last ping " + info.LastPingDateTime.UTC().Format(time.RFC3339)
+	}
+	return status
+}
+
+// explainTargetNotConnected wraps a TargetNotConnected error with the instance's ping status, when
+// it can be looked up, and the usual fixes, so the failure can be resolved without a support trip.
+func explainTargetNotConnected(client ssmiface.SSMAPI, instanceID string, err error) error {
+	status := ""
+	if s := describePingStatus(client, instanceID); s != "" {
+		status = " (" + s + ")"
+	}
+	return fmt.Errorf(`instance %s is not connected to Systems Manager%s: %w
+To fix:
+  - check the SSM agent is installed and running on the instance (systemctl status amazon-ssm-agent)
+  - check the instance profile allows Systems Manager, e.g. AmazonSSMManagedInstanceCore
+  - check the instance can reach the ssm, ssmmessages and ec2messages endpoints
+  - confirm it is Online: aws ssm describe-instance-information --filters Key=InstanceIds,Values=%s`,
+		instanceID, status, err, instanceID)
+}
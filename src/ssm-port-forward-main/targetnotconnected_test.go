@@ -0,0 +1,75 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+type fakeSSM struct {
+	ssmiface.SSMAPI
+	info []*ssm.InstanceInformation
+	err  error
+}
+
+func (f *fakeSSM) DescribeInstanceInformation(*ssm.DescribeInstanceInformationInput) (*ssm.DescribeInstanceInformationOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ssm.DescribeInstanceInformationOutput{InstanceInformationList: f.info}, nil
+}
+
+// WHEN StartSession returns TargetNotConnected, THEN the error SHALL keep the AWS error, include
+// the instance's ping status and list the fixes.
+func TestExplainTargetNotConnected(t *testing.T) {
+	notConnected := awserr.New(targetNotConnectedCode, "i-abc is not connected.", nil)
+	lastPing := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	client := &fakeSSM{info: []*ssm.InstanceInformation{{
+		PingStatus:       aws.String("ConnectionLost"),
+		LastPingDateTime: &lastPing,
+	}}}
+
+	err := explainTargetNotConnected(client, "i-abc", fmt.Errorf("start: %w", notConnected))
+	if !isTargetNotConnected(err) {
+		t.Errorf("Expected the AWS error to stay in the chain, got: %v", err)
+	}
+	for _, want := range []string{"ping status: ConnectionLost", "2025-01-02T15:04:05Z", "amazon-ssm-agent", "Values=i-abc"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in:\n%v", want, err)
+		}
+	}
+	if got := classifyError(err, CodeStartSessionFailed); got != CodeTargetNotConnected {
+		t.Errorf("Expected %s, got %s", CodeTargetNotConnected, got)
+	}
+}
+
+// WHEN the instance is unknown to Systems Manager or the lookup fails, THEN the guidance SHALL say
+// so or leave the status out.
+func TestDescribePingStatus(t *testing.T) {
+	if got := describePingStatus(&fakeSSM{}, "i-abc"); got != "not registered as a managed instance" {
+		t.Errorf("Unexpected status for unregistered instance: %q", got)
+	}
+	if got := describePingStatus(&fakeSSM{err: errors.New("AccessDenied")}, "i-abc"); got != "" {
+		t.Errorf("Expected no status when the lookup fails, got %q", got)
+	}
+}
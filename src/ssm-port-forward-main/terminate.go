@@ -0,0 +1,164 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// TerminateConfig holds the terminate options.
+type TerminateConfig struct {
+	awsOptions
+	SessionIDs   []string
+	All          bool   // terminate every active session to Target
+	Target       string // instance ID for All
+	OutputFormat string // text lines or json lines, also for errors
+}
+
+// terminatedSession is one line of terminate json output.
+type terminatedSession struct {
+	SessionID string `json:"session_id"`
+	Status    string `json:"status"`
+}
+
+// runTerminate runs the terminate command and returns the process exit status.
+func runTerminate(args []string) int {
+	config, err := parseTerminateArgs(args)
+	if err != nil {
+		writeError(os.Stderr, config.OutputFormat, stageError(StageParseArgs, CodeInvalidArgs, err))
+		if config.OutputFormat != OutputFormatJSON {
+			printTerminateUsage()
+		}
+		return ExitInvalidArgs
+	}
+
+	client, err := config.ssmClient()
+	if err != nil {
+		writeError(os.Stderr, config.OutputFormat, err)
+		return exitCode(err)
+	}
+
+	ids := config.SessionIDs
+	if config.All {
+		sessions, err := listSessions(client, ssm.SessionStateActive, config.Target)
+		if err != nil {
+			err = stageError(StageListSessions, CodeSessionError, fmt.Errorf("failed to describe sessions: %w", err))
+			writeError(os.Stderr, config.OutputFormat, err)
+			return exitCode(err)
+		}
+		for _, s := range sessions {
+			ids = append(ids, aws.StringValue(s.SessionId))
+		}
+		if len(ids) == 0 && config.OutputFormat != OutputFormatJSON {
+			fmt.Fprintf(os.Stderr, "No active sessions to %s\n", config.Target)
+		}
+	}
+
+	// Every session is attempted; those that were terminated are reported even if others fail
+	err = terminateSessions(client, ids, func(id string) {
+		printTerminated(os.Stdout, config.OutputFormat, id)
+	})
+	if err != nil {
+		err = stageError(StageTerminate, CodeSessionError, err)
+		writeError(os.Stderr, config.OutputFormat, err)
+		return exitCode(err)
+	}
+	return 0
+}
+
+func parseTerminateArgs(args []string) (*TerminateConfig, error) {
+	config := &TerminateConfig{}
+
+	flags := newCommandFlags(CommandTerminate)
+	config.awsOptions.register(flags)
+	flags.BoolVar(&config.All, "all", false, "Terminate every active session to --target")
+	flags.StringVar(&config.Target, "target", "", "Instance ID whose sessions --all terminates")
+	flags.StringVar(&config.OutputFormat, "output-format", OutputFormatText, "Output format: text or json")
+
+	if err := parseCommandFlags(flags, args, &config.OutputFormat, printTerminateUsage); err != nil {
+		return config, err
+	}
+	config.SessionIDs = flags.Args()
+
+	switch {
+	case config.All && config.Target == "":
+		return config, errors.New("--all requires --target")
+	case config.All && len(config.SessionIDs) > 0:
+		return config, errors.New("session IDs and --all are mutually exclusive")
+	case !config.All && config.Target != "":
+		return config, errors.New("--target requires --all")
+	case !config.All && len(config.SessionIDs) == 0:
+		return config, errors.New("session ID required (or --all --target instance-id)")
+	}
+	return config, nil
+}
+
+// terminateSessions terminates each session, calling terminated for those that succeed. It
+// carries on past failures and returns them joined.
+func terminateSessions(client ssmiface.SSMAPI, ids []string, terminated func(id string)) error {
+	var errs []error
+	for _, id := range ids {
+		if _, err := client.TerminateSession(&ssm.TerminateSessionInput{SessionId: aws.String(id)}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to terminate session %s: %w", id, err))
+			continue
+		}
+		terminated(id)
+	}
+	return errors.Join(errs...)
+}
+
+// printTerminated reports a terminated session as a text line or a json object.
+func printTerminated(w io.Writer, format string, id string) {
+	if format == OutputFormatJSON {
+		data, _ := json.Marshal(terminatedSession{SessionID: id, Status: "terminated"})
+		fmt.Fprintln(w, string(data))
+		return
+	}
+	fmt.Fprintf(w, "Terminated %s\n", id)
+}
+
+func printTerminateUsage() {
+	fmt.Fprintf(os.Stderr, `Usage: ssm-port-forward terminate [OPTIONS] session-id...
+       ssm-port-forward terminate [OPTIONS] --all --target instance-id
+
+Terminate SSM sessions, e.g. ones left behind by a forward that exited without
+cleaning up. Each terminated session is printed.
+
+Options:
+  -r, --region           AWS region
+  -p, --profile          AWS profile
+      --sso-login        Run "aws sso login" when the SSO token is missing or expired
+      --signing-region   Region to sign SSM API requests for
+      --signing-name     Service name to sign SSM API requests for (default: ssm)
+      --all              Terminate every active session to --target
+      --target           Instance ID whose sessions --all terminates
+      --output-format    text (default) or json (one {"session_id","status"}
+                         object per line); also applies to errors on stderr
+
+Examples:
+  # Terminate one session
+  ssm-port-forward terminate -r us-east-1 alice-0123456789abcdef0
+
+  # Clean up every session to a bastion
+  ssm-port-forward terminate -r us-east-1 --all --target i-bastion123
+`)
+}
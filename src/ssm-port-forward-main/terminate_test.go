@@ -0,0 +1,107 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+type fakeTerminator struct {
+	ssmiface.SSMAPI
+	failing    map[string]bool
+	terminated []string
+}
+
+func (f *fakeTerminator) TerminateSession(input *ssm.TerminateSessionInput) (*ssm.TerminateSessionOutput, error) {
+	id := aws.StringValue(input.SessionId)
+	if f.failing[id] {
+		return nil, awserr.New("DoesNotExistException", id+" does not exist", nil)
+	}
+	f.terminated = append(f.terminated, id)
+	return &ssm.TerminateSessionOutput{SessionId: input.SessionId}, nil
+}
+
+// WHEN one of several sessions fails to terminate, THEN terminateSessions SHALL still terminate
+// and report the others and return the failure.
+func TestTerminateSessionsContinuesPastFailures(t *testing.T) {
+	client := &fakeTerminator{failing: map[string]bool{"alice-2": true}}
+	var reported []string
+
+	err := terminateSessions(client, []string{"alice-1", "alice-2", "alice-3"}, func(id string) {
+		reported = append(reported, id)
+	})
+	if err == nil || !strings.Contains(err.Error(), "alice-2") {
+		t.Errorf("Expected an error naming alice-2, got: %v", err)
+	}
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		t.Errorf("Expected the AWS error to stay in the chain, got: %v", err)
+	}
+	want := []string{"alice-1", "alice-3"}
+	if !reflect.DeepEqual(client.terminated, want) || !reflect.DeepEqual(reported, want) {
+		t.Errorf("Expected %v terminated and reported, got %v and %v", want, client.terminated, reported)
+	}
+}
+
+// WHEN a session is terminated, THEN printTerminated SHALL report it as text or a json object.
+func TestPrintTerminated(t *testing.T) {
+	var text, out bytes.Buffer
+	printTerminated(&text, OutputFormatText, "alice-1")
+	printTerminated(&out, OutputFormatJSON, "alice-1")
+
+	if text.String() != "Terminated alice-1\n" {
+		t.Errorf("Unexpected text output %q", text.String())
+	}
+	if out.String() != `{"session_id":"alice-1","status":"terminated"}`+"\n" {
+		t.Errorf("Unexpected json output %q", out.String())
+	}
+}
+
+// WHEN terminate options are parsed, THEN session IDs or --all with --target SHALL be required,
+// but not both.
+func TestParseTerminateArgs(t *testing.T) {
+	config, err := parseTerminateArgs([]string{"-r", "us-east-1", "alice-1", "alice-2"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(config.SessionIDs, []string{"alice-1", "alice-2"}) {
+		t.Errorf("Unexpected session IDs: %v", config.SessionIDs)
+	}
+
+	config, err = parseTerminateArgs([]string{"--all", "--target", "i-bastion"})
+	if err != nil || !config.All || config.Target != "i-bastion" {
+		t.Errorf("Unexpected result %+v, %v", config, err)
+	}
+
+	invalid := [][]string{
+		{},
+		{"--all"},
+		{"--target", "i-bastion"},
+		{"--all", "--target", "i-bastion", "alice-1"},
+	}
+	for _, args := range invalid {
+		if _, err := parseTerminateArgs(args); err == nil {
+			t.Errorf("Expected %v to be rejected", args)
+		}
+	}
+}
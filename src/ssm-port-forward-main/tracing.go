@@ -0,0 +1,187 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span and attribute names reported with --otel-endpoint.
+const (
+	tracerName = "github.com/zph/session-manager-plugin/src/ssm-port-forward-main"
+
+	SpanSession          = "ssm-port-forward"
+	SpanStartSession     = "StartSession"
+	SpanDataChannelSetup = "data_channel.establish"
+
+	AttrInstanceID   = "aws.ssm.instance_id"
+	AttrRegion       = "aws.region"
+	AttrDocumentName = "aws.ssm.document_name"
+	AttrSessionID    = "aws.ssm.session_id"
+	AttrReason       = "reason"
+)
+
+// tracingShutdownTimeout bounds flushing spans to the collector when the forward exits.
+var tracingShutdownTimeout = 5 * time.Second
+
+// sessionTracer records the forward's lifecycle as OpenTelemetry spans: a root span for the
+// whole forward, children for StartSession and data channel setup, and events for reconnects
+// and termination. Only IDs, region and document name are recorded, never tokens or stream
+// URLs. A nil *sessionTracer records nothing, so tracing costs nothing when not configured.
+type sessionTracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+	ctx      context.Context
+	root     trace.Span
+
+	mutex sync.Mutex
+	setup trace.Span
+}
+
+// startTracing exports spans over OTLP/HTTP to endpoint, either a URL such as
+// http://localhost:4318 or a host:port reached over HTTPS. Export failures are logged as warnings.
+// The exporter is only built in with -tags otlp; see newOTLPExporter.
+func startTracing(logger log.T, endpoint string) (*sessionTracer, error) {
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warnf("Tracing: %v", err)
+	}))
+	exporter, err := newOTLPExporter(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", "ssm-port-forward")))
+	if err != nil {
+		return nil, err
+	}
+	return newSessionTracer(sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))), nil
+}
+
+// newSessionTracer starts the root span on provider.
+func newSessionTracer(provider *sdktrace.TracerProvider) *sessionTracer {
+	t := &sessionTracer{provider: provider, tracer: provider.Tracer(tracerName)}
+	t.ctx, t.root = t.tracer.Start(context.Background(), SpanSession)
+	return t
+}
+
+// target records the target of the forward on the root span, once it is resolved.
+func (t *sessionTracer) target(instanceID string, region string, documentName string) {
+	if t == nil {
+		return
+	}
+	t.root.SetAttributes(targetAttributes(instanceID, region, documentName)...)
+}
+
+func targetAttributes(instanceID string, region string, documentName string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String(AttrInstanceID, instanceID),
+		attribute.String(AttrRegion, region),
+		attribute.String(AttrDocumentName, documentName),
+	}
+}
+
+// startSession opens the StartSession span; the returned function ends it with the call's error.
+func (t *sessionTracer) startSession(instanceID string, region string, documentName string) func(error) {
+	if t == nil {
+		return func(error) {}
+	}
+	_, span := t.tracer.Start(t.ctx, SpanStartSession, trace.WithAttributes(targetAttributes(instanceID, region, documentName)...))
+	return func(err error) { endSpan(span, err) }
+}
+
+// sessionStarted records the session ID returned by StartSession.
+func (t *sessionTracer) sessionStarted(sessionID string) {
+	if t == nil {
+		return
+	}
+	t.root.SetAttributes(attribute.String(AttrSessionID, sessionID))
+}
+
+// establishing opens the data channel setup span, ended once ready is closed, or with an error
+// if the forward ends first.
+func (t *sessionTracer) establishing(ready <-chan struct{}) {
+	if t == nil {
+		return
+	}
+	_, span := t.tracer.Start(t.ctx, SpanDataChannelSetup)
+	t.mutex.Lock()
+	t.setup = span
+	t.mutex.Unlock()
+	go func() {
+		<-ready
+		t.endSetup(nil)
+	}()
+}
+
+// endSetup ends the data channel setup span, if it is still open.
+func (t *sessionTracer) endSetup(err error) {
+	t.mutex.Lock()
+	span := t.setup
+	t.setup = nil
+	t.mutex.Unlock()
+	if span != nil {
+		endSpan(span, err)
+	}
+}
+
+// reconnect records the data channel dropping and coming back as events on the root span.
+func (t *sessionTracer) reconnect(reconnecting bool) {
+	if t == nil {
+		return
+	}
+	if reconnecting {
+		t.root.AddEvent(EventReconnecting)
+	} else {
+		t.root.AddEvent(EventReconnected)
+	}
+}
+
+// terminated records why the forward is shutting down.
+func (t *sessionTracer) terminated(reason string) {
+	if t == nil {
+		return
+	}
+	t.root.AddEvent(EventTerminated, trace.WithAttributes(attribute.String(AttrReason, reason)))
+}
+
+// Close ends any open spans, marking them failed with err, and flushes them to the collector.
+func (t *sessionTracer) Close(err error) {
+	if t == nil {
+		return
+	}
+	t.endSetup(errors.New("forward ended before the data channel was established"))
+	endSpan(t.root, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+	t.provider.Shutdown(ctx)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
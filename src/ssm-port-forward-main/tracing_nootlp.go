@@ -0,0 +1,34 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build !otlp
+
+package main
+
+import (
+	"errors"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// otlpBuiltIn reports whether --otel-endpoint can be used in this build. The OTLP exporter
+// depends on gRPC and protobuf, which more than double the binary, so it is opt-in.
+const otlpBuiltIn = false
+
+// errOTLPNotBuiltIn is returned for --otel-endpoint by a build without -tags otlp.
+var errOTLPNotBuiltIn = errors.New("this build has no OTLP exporter; rebuild with -tags otlp")
+
+// newOTLPExporter fails: the OTLP exporter is not built in.
+func newOTLPExporter(endpoint string) (sdktrace.SpanExporter, error) {
+	return nil, errOTLPNotBuiltIn
+}
@@ -0,0 +1,31 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build !otlp
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// WHEN the OTLP exporter is not built in, THEN startTracing SHALL fail rather than silently
+// drop spans.
+func TestStartTracingWithoutOTLP(t *testing.T) {
+	if _, err := startTracing(log.NewMockLog(), "http://127.0.0.1:1"); !errors.Is(err, errOTLPNotBuiltIn) {
+		t.Errorf("Expected errOTLPNotBuiltIn, got %v", err)
+	}
+}
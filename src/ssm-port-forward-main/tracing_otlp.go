@@ -0,0 +1,36 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build otlp
+
+package main
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// otlpBuiltIn reports whether --otel-endpoint can be used in this build.
+const otlpBuiltIn = true
+
+// newOTLPExporter returns an OTLP/HTTP span exporter for endpoint.
+func newOTLPExporter(endpoint string) (sdktrace.SpanExporter, error) {
+	option := otlptracehttp.WithEndpoint(endpoint)
+	if strings.Contains(endpoint, "://") {
+		option = otlptracehttp.WithEndpointURL(endpoint)
+	}
+	return otlptracehttp.New(context.Background(), option)
+}
@@ -0,0 +1,43 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build otlp
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// WHEN the collector is unreachable, THEN startTracing SHALL still succeed and Close SHALL give
+// up on flushing after tracingShutdownTimeout rather than hang the exit.
+func TestStartTracingUnreachableCollector(t *testing.T) {
+	original := tracingShutdownTimeout
+	tracingShutdownTimeout = 100 * time.Millisecond
+	defer func() { tracingShutdownTimeout = original }()
+
+	for _, endpoint := range []string{"http://127.0.0.1:1", "127.0.0.1:1"} {
+		tracer, err := startTracing(log.NewMockLog(), endpoint)
+		if err != nil {
+			t.Fatalf("startTracing(%q) failed: %v", endpoint, err)
+		}
+		start := time.Now()
+		tracer.Close(nil)
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("Close took %v with an unreachable collector", elapsed)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newRecordedTracer returns a sessionTracer whose spans are kept in memory.
+func newRecordedTracer() (*sessionTracer, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	return newSessionTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))), recorder
+}
+
+// spansByName indexes ended spans by name.
+func spansByName(recorder *tracetest.SpanRecorder) map[string]sdktrace.ReadOnlySpan {
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	return spans
+}
+
+// WHEN a forward is traced through to termination, THEN StartSession and data channel setup
+// SHALL be children of the root span, with the target attributes and lifecycle events.
+func TestSessionTracerLifecycle(t *testing.T) {
+	tracer, recorder := newRecordedTracer()
+
+	tracer.target("i-123", "us-east-1", "AWS-StartPortForwardingSession")
+	tracer.startSession("i-123", "us-east-1", "AWS-StartPortForwardingSession")(nil)
+	tracer.sessionStarted("sess-1")
+	ready := make(chan struct{})
+	tracer.establishing(ready)
+	close(ready)
+	deadline := time.Now().Add(time.Second)
+	for len(recorder.Ended()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	tracer.reconnect(true)
+	tracer.reconnect(false)
+	tracer.terminated("signal: interrupt")
+	tracer.Close(nil)
+
+	spans := spansByName(recorder)
+	root, ok := spans[SpanSession]
+	if !ok {
+		t.Fatalf("Expected a %s span, got %v", SpanSession, spans)
+	}
+	for _, name := range []string{SpanStartSession, SpanDataChannelSetup} {
+		child, ok := spans[name]
+		if !ok {
+			t.Fatalf("Expected a %s span", name)
+		}
+		if child.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("Expected %s to be a child of the root span", name)
+		}
+		if child.Status().Code == codes.Error {
+			t.Errorf("Expected %s to succeed, got %v", name, child.Status())
+		}
+	}
+
+	attrs := map[string]string{}
+	for _, attr := range root.Attributes() {
+		attrs[string(attr.Key)] = attr.Value.AsString()
+	}
+	want := map[string]string{AttrInstanceID: "i-123", AttrRegion: "us-east-1", AttrDocumentName: "AWS-StartPortForwardingSession", AttrSessionID: "sess-1"}
+	for key, value := range want {
+		if attrs[key] != value {
+			t.Errorf("Expected root attribute %s=%q, got %q", key, value, attrs[key])
+		}
+	}
+
+	var events []string
+	for _, event := range root.Events() {
+		events = append(events, event.Name)
+	}
+	if len(events) != 3 || events[0] != EventReconnecting || events[1] != EventReconnected || events[2] != EventTerminated {
+		t.Errorf("Unexpected root events: %v", events)
+	}
+}
+
+// WHEN the forward fails before the data channel is up, THEN the setup and root spans SHALL be
+// marked failed.
+func TestSessionTracerFailure(t *testing.T) {
+	tracer, recorder := newRecordedTracer()
+	tracer.establishing(make(chan struct{}))
+	tracer.Close(errors.New("port forward failed to establish"))
+
+	spans := spansByName(recorder)
+	for _, name := range []string{SpanSession, SpanDataChannelSetup} {
+		if span, ok := spans[name]; !ok || span.Status().Code != codes.Error {
+			t.Errorf("Expected %s to be ended with an error status", name)
+		}
+	}
+}
+
+// WHEN StartSession fails, THEN its span SHALL record the error.
+func TestSessionTracerStartSessionError(t *testing.T) {
+	tracer, recorder := newRecordedTracer()
+	tracer.startSession("i-123", "us-east-1", "doc")(errors.New("TargetNotConnected"))
+	tracer.Close(nil)
+
+	if span := spansByName(recorder)[SpanStartSession]; span == nil || span.Status().Code != codes.Error {
+		t.Error("Expected the StartSession span to be marked failed")
+	}
+}
+
+// WHEN tracing is not configured, THEN a nil sessionTracer SHALL accept every call.
+func TestNilSessionTracer(t *testing.T) {
+	var tracer *sessionTracer
+	tracer.target("i-123", "us-east-1", "doc")
+	tracer.startSession("i-123", "us-east-1", "doc")(nil)
+	tracer.sessionStarted("sess-1")
+	tracer.establishing(make(chan struct{}))
+	tracer.reconnect(true)
+	tracer.terminated("done")
+	tracer.Close(nil)
+}
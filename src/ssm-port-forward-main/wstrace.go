@@ -0,0 +1,43 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/zph/session-manager-plugin/src/communicator"
+)
+
+// traceRequests adds the SigV4 signing and response of each API call made through c to trace,
+// so --trace-ws shows StartSession as the first steps of the handshake.
+func traceRequests(c *client.Client, trace *communicator.HandshakeTrace) {
+	if trace == nil {
+		return
+	}
+	c.Handlers.Sign.PushBack(func(r *request.Request) {
+		if r.Error != nil {
+			trace.Step("signing %s request failed: %v", r.Operation.Name, r.Error)
+			return
+		}
+		trace.Step("signed %s request with SigV4 for %s", r.Operation.Name, aws.StringValue(r.Config.Region))
+	})
+	c.Handlers.Complete.PushBack(func(r *request.Request) {
+		if r.Error != nil {
+			trace.Step("%s failed: %v", r.Operation.Name, r.Error)
+			return
+		}
+		trace.Step("%s returned HTTP %d", r.Operation.Name, r.HTTPResponse.StatusCode)
+	})
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 Zander Hill. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/zph/session-manager-plugin/src/communicator"
+	"github.com/zph/session-manager-plugin/src/log"
+)
+
+// WHEN --trace-ws is set, THEN each SSM API call SHALL trace its signing and its response.
+func TestTraceRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			t.Errorf("Expected a SigV4 signed request, got %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+	sess := awssession.Must(awssession.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(server.URL),
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+	}))
+	client := ssm.New(sess)
+	traceLog := log.NewMockLog()
+	traceRequests(client.Client, communicator.NewHandshakeTrace(traceLog))
+
+	if _, err := client.DescribeInstanceInformation(&ssm.DescribeInstanceInformationInput{}); err != nil {
+		t.Fatalf("Expected the call to succeed, got: %v", err)
+	}
+	var steps []string
+	for _, call := range traceLog.Calls {
+		if call.Method == "Tracef" {
+			steps = append(steps, call.Arguments.Get(1).([]interface{})[1].(string))
+		}
+	}
+	want := []string{
+		"signed DescribeInstanceInformation request with SigV4 for us-east-1",
+		"DescribeInstanceInformation returned HTTP 200",
+	}
+	if strings.Join(steps, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected steps %q, got %q", want, steps)
+	}
+}
+
+// WHEN --trace-ws is not set, THEN no handlers SHALL be added.
+func TestTraceRequestsDisabled(t *testing.T) {
+	client := ssm.New(awssession.Must(awssession.NewSession(&aws.Config{Region: aws.String("us-east-1")})))
+	signers := client.Handlers.Sign.Len()
+	traceRequests(client.Client, nil)
+	if client.Handlers.Sign.Len() != signers {
+		t.Errorf("Expected no sign handlers added")
+	}
+}
@@ -19,7 +19,10 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
+	"os"
 	"strings"
+	"time"
 
 	sdkSession "github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ssm"
@@ -27,24 +30,34 @@ import (
 	"github.com/zph/session-manager-plugin/src/datachannel"
 	"github.com/zph/session-manager-plugin/src/jsonutil"
 	"github.com/zph/session-manager-plugin/src/log"
+	"github.com/zph/session-manager-plugin/src/message"
 	"github.com/zph/session-manager-plugin/src/sdkutil"
 	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
 	_ "github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session/portsession"
-	_ "github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session/shellsession"
+	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session/shellsession"
 	"github.com/zph/session-manager-plugin/src/ssmclicommands/utils"
 )
 
 const (
-	START_SESSION = "start-session"
-	INSTANCE_ID   = "instance-id"
-	REGION        = "region"
-	PROFILE       = "profile"
-	ENDPOINT      = "endpoint"
-	DOCUMENT_NAME = "document-name"
-	PARAMETERS    = "parameters"
+	START_SESSION      = "start-session"
+	INSTANCE_ID        = "instance-id"
+	REGION             = "region"
+	PROFILE            = "profile"
+	ENDPOINT           = "endpoint"
+	DOCUMENT_NAME      = "document-name"
+	PARAMETERS         = "parameters"
+	TEE                = "tee"
+	OUTPUT_MODE        = "output-mode"
+	STRIP_BANNER       = "strip-banner"
+	PROMPT_PATTERN     = "prompt-pattern"
+	ENV                = "env"
+	DURATION_WARNING   = "duration-warning"
+	TERM               = "term"
+	INITIAL_SIZE       = "initial-size"
+	STDIN_COMMAND_FILE = "stdin-command-file"
 )
 
-var ParameterKeys = []string{INSTANCE_ID, REGION, PROFILE, ENDPOINT, DOCUMENT_NAME, PARAMETERS}
+var ParameterKeys = []string{INSTANCE_ID, REGION, PROFILE, ENDPOINT, DOCUMENT_NAME, PARAMETERS, TEE, OUTPUT_MODE, STRIP_BANNER, PROMPT_PATTERN, ENV, DURATION_WARNING, TERM, INITIAL_SIZE, STDIN_COMMAND_FILE}
 
 const START_SESSION_HELP = `NAME : {{.StartSessionName}}
 
@@ -61,6 +74,43 @@ PARAMETERS:
 	{{.Region}} (string) Region
 	Region is required if not configured in aws config file (https://docs.aws.amazon.com/credref/latest/refdocs/creds-config-files.html)
 
+	{{.Tee}} (string) File
+	Shell sessions also write all output to this file as a plain-text transcript
+
+	{{.OutputMode}} (string) unbuffered | line
+	How shell output is displayed: unbuffered (default) shows it as it arrives, line holds
+	partial lines until their newline
+
+	{{.StripBanner}}
+	Drop shell output before the first prompt, such as the login banner or MOTD, so captured
+	output starts at the prompt. If no prompt is recognized, all output is shown
+
+	{{.PromptPattern}} (string) Regex
+	Regular expression that recognizes the first prompt for {{.StripBanner}}
+	(default: a line ending in $, #, % or >)
+
+	{{.Env}} (list) KEY=VALUE ...
+	Environment variables exported in the remote shell when the session starts, e.g. TERM or
+	LANG. The target's shell must understand POSIX export
+
+	{{.DurationWarning}} (string) Duration
+	Show a reminder in the terminal each time a shell session has been open this much longer,
+	e.g. 1h. The session is not ended
+
+	{{.Term}} (string) Terminal type
+	Exported as TERM in the remote shell when the session starts, e.g. xterm-256color.
+	Overrides a TERM given with {{.Env}}
+
+	{{.InitialSize}} (string) COLSxROWS
+	Terminal size sent when a shell session starts, e.g. 200x50, instead of the local
+	terminal's, which is unknown when running without one. A later local resize is still sent
+
+	{{.StdinCommandFile}} (string) File
+	Run the commands in this file one at a time instead of reading the keyboard, showing each
+	command's output and exit status, then exit the shell. One command per line; blank lines and
+	lines starting with # are skipped, and a command with a here-document takes the lines up to
+	its delimiter as its input. The target's shell must be POSIX
+
 Command:
       For any region,
       {{.SsmCliName}} {{.StartSessionName}} --{{.InstanceId}} i-123456 --{{.Region}} us-east-1
@@ -70,6 +120,24 @@ Command:
 
       For any document with parameters,
       {{.SsmCliName}} {{.StartSessionName}} --{{.InstanceId}} i-123456 --{{.DocumentName}} AWS-StartPortForwardingSession --{{.Parameters}}  '{"localPortNumber":["6789"]}'
+
+      For a transcript of a shell session,
+      {{.SsmCliName}} {{.StartSessionName}} --{{.InstanceId}} i-123456 --{{.Tee}} session.log
+
+      For shell output without the login banner,
+      {{.SsmCliName}} {{.StartSessionName}} --{{.InstanceId}} i-123456 --{{.StripBanner}} --{{.PromptPattern}} '(?m)^\[ec2-user@.*\]\$ $'
+
+      For a shell with its own environment,
+      {{.SsmCliName}} {{.StartSessionName}} --{{.InstanceId}} i-123456 --{{.Env}} TERM=xterm-256color LANG=C.UTF-8
+
+      For a reminder every hour a shell stays open,
+      {{.SsmCliName}} {{.StartSessionName}} --{{.InstanceId}} i-123456 --{{.DurationWarning}} 1h
+
+      For a headless shell with a known terminal,
+      {{.SsmCliName}} {{.StartSessionName}} --{{.InstanceId}} i-123456 --{{.Term}} xterm-256color --{{.InitialSize}} 200x50
+
+      For a batch of commands run in a shell,
+      {{.SsmCliName}} {{.StartSessionName}} --{{.InstanceId}} i-123456 --{{.StdinCommandFile}} commands.sh
 `
 
 type StartSessionHelpParams struct {
@@ -81,6 +149,15 @@ type StartSessionHelpParams struct {
 	Endpoint         string
 	DocumentName     string
 	Parameters       string
+	Tee              string
+	OutputMode       string
+	StripBanner      string
+	PromptPattern    string
+	Env              string
+	DurationWarning  string
+	Term             string
+	InitialSize      string
+	StdinCommandFile string
 }
 
 type StartSessionCommand struct {
@@ -133,6 +210,15 @@ func (c *StartSessionCommand) Help() string {
 			ENDPOINT,
 			DOCUMENT_NAME,
 			PARAMETERS,
+			TEE,
+			OUTPUT_MODE,
+			STRIP_BANNER,
+			PROMPT_PATTERN,
+			ENV,
+			DURATION_WARNING,
+			TERM,
+			INITIAL_SIZE,
+			STDIN_COMMAND_FILE,
 		}
 		buf := new(bytes.Buffer)
 		t.Execute(buf, params)
@@ -149,6 +235,12 @@ func (s *StartSessionCommand) Execute(parameters map[string][]string) (error, st
 		profile    string
 		endpoint   string
 		instanceId string
+		outputMode string
+		prompt     string
+		warnAfter  time.Duration
+		term       string
+		size       message.SizeData
+		commands   string
 	)
 	validation := s.validateStartSessionInput(parameters)
 	if len(validation) > 0 {
@@ -170,6 +262,36 @@ func (s *StartSessionCommand) Execute(parameters map[string][]string) (error, st
 	if parameters[INSTANCE_ID] != nil {
 		instanceId = parameters[INSTANCE_ID][0]
 	}
+	if parameters[OUTPUT_MODE] != nil {
+		outputMode = parameters[OUTPUT_MODE][0]
+	}
+	if parameters[PROMPT_PATTERN] != nil {
+		prompt = parameters[PROMPT_PATTERN][0]
+	}
+	if parameters[DURATION_WARNING] != nil {
+		// Validated above
+		warnAfter, _ = time.ParseDuration(parameters[DURATION_WARNING][0])
+	}
+	if parameters[TERM] != nil {
+		term = parameters[TERM][0]
+	}
+	if parameters[INITIAL_SIZE] != nil {
+		// Validated above
+		size, _ = shellsession.ParseSize(parameters[INITIAL_SIZE][0])
+	}
+	if parameters[STDIN_COMMAND_FILE] != nil {
+		commands = parameters[STDIN_COMMAND_FILE][0]
+	}
+	_, stripBanner := parameters[STRIP_BANNER]
+	env := parameters[ENV]
+
+	// Open the transcript up front so a bad path fails before a session is started
+	var transcript io.WriteCloser
+	if parameters[TEE] != nil {
+		if transcript, err = os.Create(parameters[TEE][0]); err != nil {
+			return fmt.Errorf("cannot open %v file: %v", TEE, err), "StartSession failed"
+		}
+	}
 
 	if s.sdk, err = getSSMClient(log, region, profile, endpoint); err != nil {
 		return err, "StartSession failed"
@@ -185,13 +307,22 @@ func (s *StartSessionCommand) Execute(parameters map[string][]string) (error, st
 	clientId := uuid.NewString()
 
 	session := session.Session{
-		SessionId:   sessionId,
-		StreamUrl:   streamUrl,
-		TokenValue:  tokenValue,
-		Endpoint:    endpoint,
-		ClientId:    clientId,
-		TargetId:    instanceId,
-		DataChannel: &datachannel.DataChannel{},
+		SessionId:            sessionId,
+		StreamUrl:            streamUrl,
+		TokenValue:           tokenValue,
+		Endpoint:             endpoint,
+		ClientId:             clientId,
+		TargetId:             instanceId,
+		DataChannel:          &datachannel.DataChannel{},
+		Transcript:           transcript,
+		ShellOutputMode:      outputMode,
+		ShellStripBanner:     stripBanner,
+		ShellPromptPattern:   prompt,
+		ShellEnv:             env,
+		ShellDurationWarning: warnAfter,
+		ShellTerm:            term,
+		ShellInitialSize:     size,
+		ShellCommandFile:     commands,
 	}
 
 	if err = executeSession(log, &session); err != nil {
@@ -214,6 +345,65 @@ func (StartSessionCommand) validateStartSessionInput(parameters map[string][]str
 			utils.FormatFlag(INSTANCE_ID)))
 	}
 
+	if mode := parameters[OUTPUT_MODE]; mode != nil {
+		if _, err := shellsession.ParseOutputMode(mode[0]); err != nil {
+			validation = append(validation, err.Error())
+		}
+	}
+
+	if pattern, ok := parameters[PROMPT_PATTERN]; ok {
+		if _, stripBanner := parameters[STRIP_BANNER]; !stripBanner {
+			validation = append(validation, fmt.Sprintf("%v requires %v",
+				utils.FormatFlag(PROMPT_PATTERN), utils.FormatFlag(STRIP_BANNER)))
+		}
+		if len(pattern) != 1 {
+			validation = append(validation, fmt.Sprintf("%v requires one value", utils.FormatFlag(PROMPT_PATTERN)))
+		} else if _, err := shellsession.ParsePromptPattern(pattern[0]); err != nil {
+			validation = append(validation, err.Error())
+		}
+	}
+
+	if env, ok := parameters[ENV]; ok {
+		if len(env) == 0 {
+			validation = append(validation, fmt.Sprintf("%v requires at least one KEY=VALUE", utils.FormatFlag(ENV)))
+		} else if err := shellsession.ParseEnv(env); err != nil {
+			validation = append(validation, err.Error())
+		}
+	}
+
+	if warnAfter, ok := parameters[DURATION_WARNING]; ok {
+		if len(warnAfter) != 1 {
+			validation = append(validation, fmt.Sprintf("%v requires one value", utils.FormatFlag(DURATION_WARNING)))
+		} else if d, err := time.ParseDuration(warnAfter[0]); err != nil || d <= 0 {
+			validation = append(validation, fmt.Sprintf("%v must be a positive duration such as 1h, got %q",
+				utils.FormatFlag(DURATION_WARNING), warnAfter[0]))
+		}
+	}
+
+	if term, ok := parameters[TERM]; ok {
+		if len(term) != 1 {
+			validation = append(validation, fmt.Sprintf("%v requires one value", utils.FormatFlag(TERM)))
+		} else if err := shellsession.ParseTerm(term[0]); err != nil {
+			validation = append(validation, err.Error())
+		}
+	}
+
+	if size, ok := parameters[INITIAL_SIZE]; ok {
+		if len(size) != 1 {
+			validation = append(validation, fmt.Sprintf("%v requires one value", utils.FormatFlag(INITIAL_SIZE)))
+		} else if _, err := shellsession.ParseSize(size[0]); err != nil {
+			validation = append(validation, err.Error())
+		}
+	}
+
+	if file, ok := parameters[STDIN_COMMAND_FILE]; ok {
+		if len(file) != 1 {
+			validation = append(validation, fmt.Sprintf("%v requires one value", utils.FormatFlag(STDIN_COMMAND_FILE)))
+		} else if _, err := shellsession.ReadCommandFile(file[0]); err != nil {
+			validation = append(validation, err.Error())
+		}
+	}
+
 	for key := range parameters {
 		if !contains(ParameterKeys, key) {
 			validation = append(validation, fmt.Sprintf("%v not a valid command parameter flag", key))
@@ -247,6 +437,15 @@ func (s *StartSessionCommand) getStartSessionParams(log log.T, parameters map[st
 	delete(parameters, INSTANCE_ID)
 	delete(parameters, DOCUMENT_NAME)
 	delete(parameters, REGION)
+	delete(parameters, TEE)
+	delete(parameters, OUTPUT_MODE)
+	delete(parameters, STRIP_BANNER)
+	delete(parameters, PROMPT_PATTERN)
+	delete(parameters, ENV)
+	delete(parameters, DURATION_WARNING)
+	delete(parameters, TERM)
+	delete(parameters, INITIAL_SIZE)
+	delete(parameters, STDIN_COMMAND_FILE)
 
 	if parameters["parameters"] != nil && len(parameters["parameters"]) == 1 {
 
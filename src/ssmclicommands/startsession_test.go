@@ -16,10 +16,14 @@ package ssmclicommands
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/zph/session-manager-plugin/src/log"
+	"github.com/zph/session-manager-plugin/src/message"
 	"github.com/zph/session-manager-plugin/src/sessionmanagerplugin/session"
 
 	"github.com/stretchr/testify/assert"
@@ -121,6 +125,43 @@ func TestStartSessionCommand_ExecuteSessionFailure(t *testing.T) {
 	assert.Equal(t, msg, "StartSession failed")
 }
 
+func TestStartSessionCommand_ExecuteWithTee(t *testing.T) {
+	parameter, _ := getCommandParameter()
+	parameter[TEE] = []string{filepath.Join(t.TempDir(), "session.log")}
+	command := &StartSessionCommand{}
+	getSSMClient = func(log log.T, region string, profile string, endpoint string) (*ssm.SSM, error) {
+		return &ssm.SSM{}, nil
+	}
+
+	executeSession = func(log log.T, session *session.Session) (err error) {
+		assert.NotNil(t, session.Transcript)
+		return session.Transcript.Close()
+	}
+
+	startSession = func(s *StartSessionCommand, input *ssm.StartSessionInput) (*ssm.StartSessionOutput, error) {
+		assert.Nil(t, input.Parameters[TEE])
+		return startSessionOutput, nil
+	}
+
+	err, msg := command.Execute(parameter)
+	assert.Nil(t, err)
+	assert.Equal(t, msg, "StartSession executed successfully")
+}
+
+func TestStartSessionCommand_ExecuteWithUnwritableTee(t *testing.T) {
+	parameter, _ := getCommandParameter()
+	parameter[TEE] = []string{filepath.Join(t.TempDir(), "missing", "session.log")}
+	command := &StartSessionCommand{}
+	getSSMClient = func(log log.T, region string, profile string, endpoint string) (*ssm.SSM, error) {
+		t.Fatal("no session should be started when the transcript cannot be opened")
+		return nil, nil
+	}
+
+	err, msg := command.Execute(parameter)
+	assert.NotNil(t, err)
+	assert.Equal(t, msg, "StartSession failed")
+}
+
 func TestStartSessionCommand_validateStartSessionInput(t *testing.T) {
 	parameter, _ := getCommandParameter()
 	command := &StartSessionCommand{}
@@ -146,6 +187,40 @@ func TestStartSessionCommand_validateStartSessionInputWithInvalidParameters(t *t
 	assert.Equal(t, validation[1], "random-params not a valid command parameter flag")
 }
 
+func TestStartSessionCommand_validateStartSessionInputWithOutputMode(t *testing.T) {
+	parameters, _ := getCommandParameter()
+	command := &StartSessionCommand{}
+
+	parameters[OUTPUT_MODE] = []string{"line"}
+	assert.Empty(t, command.validateStartSessionInput(parameters))
+
+	parameters[OUTPUT_MODE] = []string{"block"}
+	validation := command.validateStartSessionInput(parameters)
+	assert.Equal(t, len(validation), 1)
+	assert.Contains(t, validation[0], "invalid output mode")
+}
+
+func TestStartSessionCommand_ExecuteWithOutputMode(t *testing.T) {
+	parameter, _ := getCommandParameter()
+	parameter[OUTPUT_MODE] = []string{"line"}
+	command := &StartSessionCommand{}
+	getSSMClient = func(log log.T, region string, profile string, endpoint string) (*ssm.SSM, error) {
+		return &ssm.SSM{}, nil
+	}
+
+	executeSession = func(log log.T, session *session.Session) (err error) {
+		assert.Equal(t, "line", session.ShellOutputMode)
+		return nil
+	}
+
+	startSession = func(s *StartSessionCommand, input *ssm.StartSessionInput) (*ssm.StartSessionOutput, error) {
+		return startSessionOutput, nil
+	}
+
+	err, _ := command.Execute(parameter)
+	assert.Nil(t, err)
+}
+
 func TestStartSessionCommand_getStartSessionParams(t *testing.T) {
 	parameters, _ := getCommandParameter()
 	command := &StartSessionCommand{}
@@ -199,3 +274,205 @@ func getCommandParameter() (parameters map[string][]string, err error) {
 	err, _, _, _, parameter := ParseCliCommand(args)
 	return parameter, err
 }
+
+func TestStartSessionCommand_validateStartSessionInputWithPromptPattern(t *testing.T) {
+	parameters, _ := getCommandParameter()
+	command := &StartSessionCommand{}
+
+	parameters[STRIP_BANNER] = []string{}
+	parameters[PROMPT_PATTERN] = []string{`\$ $`}
+	assert.Empty(t, command.validateStartSessionInput(parameters))
+
+	parameters[PROMPT_PATTERN] = []string{"["}
+	validation := command.validateStartSessionInput(parameters)
+	assert.Equal(t, len(validation), 1)
+	assert.Contains(t, validation[0], "invalid prompt pattern")
+
+	delete(parameters, STRIP_BANNER)
+	parameters[PROMPT_PATTERN] = []string{`\$ $`}
+	validation = command.validateStartSessionInput(parameters)
+	assert.Equal(t, len(validation), 1)
+	assert.Equal(t, validation[0], "--prompt-pattern requires --strip-banner")
+}
+
+func TestStartSessionCommand_ExecuteWithStripBanner(t *testing.T) {
+	parameter, _ := getCommandParameter()
+	parameter[STRIP_BANNER] = []string{}
+	parameter[PROMPT_PATTERN] = []string{`\$ $`}
+	command := &StartSessionCommand{}
+	getSSMClient = func(log log.T, region string, profile string, endpoint string) (*ssm.SSM, error) {
+		return &ssm.SSM{}, nil
+	}
+
+	executeSession = func(log log.T, session *session.Session) (err error) {
+		assert.True(t, session.ShellStripBanner)
+		assert.Equal(t, `\$ $`, session.ShellPromptPattern)
+		return nil
+	}
+
+	startSession = func(s *StartSessionCommand, input *ssm.StartSessionInput) (*ssm.StartSessionOutput, error) {
+		assert.Nil(t, input.Parameters[STRIP_BANNER])
+		assert.Nil(t, input.Parameters[PROMPT_PATTERN])
+		return startSessionOutput, nil
+	}
+
+	err, _ := command.Execute(parameter)
+	assert.Nil(t, err)
+}
+
+func TestStartSessionCommand_validateStartSessionInputWithEnv(t *testing.T) {
+	parameters, _ := getCommandParameter()
+	command := &StartSessionCommand{}
+
+	parameters[ENV] = []string{"TERM=xterm-256color", "LANG=C.UTF-8"}
+	assert.Empty(t, command.validateStartSessionInput(parameters))
+
+	parameters[ENV] = []string{"TERM"}
+	validation := command.validateStartSessionInput(parameters)
+	assert.Equal(t, len(validation), 1)
+	assert.Contains(t, validation[0], "expected KEY=VALUE")
+
+	parameters[ENV] = []string{}
+	validation = command.validateStartSessionInput(parameters)
+	assert.Equal(t, len(validation), 1)
+	assert.Equal(t, validation[0], "--env requires at least one KEY=VALUE")
+}
+
+func TestStartSessionCommand_ExecuteWithEnv(t *testing.T) {
+	parameter, _ := getCommandParameter()
+	parameter[ENV] = []string{"TERM=xterm-256color"}
+	command := &StartSessionCommand{}
+	getSSMClient = func(log log.T, region string, profile string, endpoint string) (*ssm.SSM, error) {
+		return &ssm.SSM{}, nil
+	}
+
+	executeSession = func(log log.T, session *session.Session) (err error) {
+		assert.Equal(t, []string{"TERM=xterm-256color"}, session.ShellEnv)
+		return nil
+	}
+
+	startSession = func(s *StartSessionCommand, input *ssm.StartSessionInput) (*ssm.StartSessionOutput, error) {
+		assert.Nil(t, input.Parameters[ENV])
+		return startSessionOutput, nil
+	}
+
+	err, _ := command.Execute(parameter)
+	assert.Nil(t, err)
+}
+
+func TestStartSessionCommand_validateStartSessionInputWithDurationWarning(t *testing.T) {
+	parameters, _ := getCommandParameter()
+	command := &StartSessionCommand{}
+
+	parameters[DURATION_WARNING] = []string{"1h30m"}
+	assert.Empty(t, command.validateStartSessionInput(parameters))
+
+	for _, value := range []string{"soon", "0s", "-1h"} {
+		parameters[DURATION_WARNING] = []string{value}
+		validation := command.validateStartSessionInput(parameters)
+		assert.Equal(t, len(validation), 1)
+		assert.Contains(t, validation[0], "--duration-warning must be a positive duration")
+	}
+}
+
+func TestStartSessionCommand_ExecuteWithDurationWarning(t *testing.T) {
+	parameter, _ := getCommandParameter()
+	parameter[DURATION_WARNING] = []string{"1h"}
+	command := &StartSessionCommand{}
+	getSSMClient = func(log log.T, region string, profile string, endpoint string) (*ssm.SSM, error) {
+		return &ssm.SSM{}, nil
+	}
+
+	executeSession = func(log log.T, session *session.Session) (err error) {
+		assert.Equal(t, time.Hour, session.ShellDurationWarning)
+		return nil
+	}
+
+	startSession = func(s *StartSessionCommand, input *ssm.StartSessionInput) (*ssm.StartSessionOutput, error) {
+		assert.Nil(t, input.Parameters[DURATION_WARNING])
+		return startSessionOutput, nil
+	}
+
+	err, _ := command.Execute(parameter)
+	assert.Nil(t, err)
+}
+
+func TestStartSessionCommand_validateStartSessionInputWithTerminal(t *testing.T) {
+	parameters, _ := getCommandParameter()
+	command := &StartSessionCommand{}
+
+	parameters[TERM] = []string{"xterm-256color"}
+	parameters[INITIAL_SIZE] = []string{"200x50"}
+	assert.Empty(t, command.validateStartSessionInput(parameters))
+
+	parameters[TERM] = []string{"xterm 256"}
+	parameters[INITIAL_SIZE] = []string{"200"}
+	validation := command.validateStartSessionInput(parameters)
+	assert.Equal(t, 2, len(validation))
+	assert.Contains(t, validation[0], "invalid terminal type")
+	assert.Contains(t, validation[1], "invalid terminal size")
+}
+
+func TestStartSessionCommand_ExecuteWithTerminal(t *testing.T) {
+	parameter, _ := getCommandParameter()
+	parameter[TERM] = []string{"xterm-256color"}
+	parameter[INITIAL_SIZE] = []string{"200x50"}
+	command := &StartSessionCommand{}
+	getSSMClient = func(log log.T, region string, profile string, endpoint string) (*ssm.SSM, error) {
+		return &ssm.SSM{}, nil
+	}
+
+	executeSession = func(log log.T, session *session.Session) (err error) {
+		assert.Equal(t, "xterm-256color", session.ShellTerm)
+		assert.Equal(t, message.SizeData{Cols: 200, Rows: 50}, session.ShellInitialSize)
+		return nil
+	}
+
+	startSession = func(s *StartSessionCommand, input *ssm.StartSessionInput) (*ssm.StartSessionOutput, error) {
+		assert.Nil(t, input.Parameters[TERM])
+		assert.Nil(t, input.Parameters[INITIAL_SIZE])
+		return startSessionOutput, nil
+	}
+
+	err, _ := command.Execute(parameter)
+	assert.Nil(t, err)
+}
+
+func TestStartSessionCommand_validateStartSessionInputWithCommandFile(t *testing.T) {
+	parameters, _ := getCommandParameter()
+	command := &StartSessionCommand{}
+
+	path := filepath.Join(t.TempDir(), "commands.sh")
+	assert.Nil(t, os.WriteFile(path, []byte("uptime\n"), 0600))
+	parameters[STDIN_COMMAND_FILE] = []string{path}
+	assert.Empty(t, command.validateStartSessionInput(parameters))
+
+	parameters[STDIN_COMMAND_FILE] = []string{filepath.Join(t.TempDir(), "missing.sh")}
+	validation := command.validateStartSessionInput(parameters)
+	assert.Equal(t, 1, len(validation))
+	assert.Contains(t, validation[0], "missing.sh")
+}
+
+func TestStartSessionCommand_ExecuteWithCommandFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "commands.sh")
+	assert.Nil(t, os.WriteFile(path, []byte("uptime\n"), 0600))
+	parameter, _ := getCommandParameter()
+	parameter[STDIN_COMMAND_FILE] = []string{path}
+	command := &StartSessionCommand{}
+	getSSMClient = func(log log.T, region string, profile string, endpoint string) (*ssm.SSM, error) {
+		return &ssm.SSM{}, nil
+	}
+
+	executeSession = func(log log.T, session *session.Session) (err error) {
+		assert.Equal(t, path, session.ShellCommandFile)
+		return nil
+	}
+
+	startSession = func(s *StartSessionCommand, input *ssm.StartSessionInput) (*ssm.StartSessionOutput, error) {
+		assert.Nil(t, input.Parameters[STDIN_COMMAND_FILE])
+		return startSessionOutput, nil
+	}
+
+	err, _ := command.Execute(parameter)
+	assert.Nil(t, err)
+}
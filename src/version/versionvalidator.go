@@ -29,6 +29,11 @@ func DoesAgentSupportDisableSmuxKeepAlive(log log.T, agentVersion string) (suppo
 	return isAgentVersionGreaterThanSupportedVersion(log, agentVersion, config.TCPMultiplexingWithSmuxKeepAliveDisabledAfterThisAgentVersion)
 }
 
+// DoesAgentSupportRemoteHostPortForwarding returns true if given agentVersion can forward to hosts other than the instance, false otherwise
+func DoesAgentSupportRemoteHostPortForwarding(log log.T, agentVersion string) (supported bool) {
+	return isAgentVersionGreaterThanSupportedVersion(log, agentVersion, config.RemoteHostPortForwardingSupportedAfterThisAgentVersion)
+}
+
 // DoesAgentSupportTerminateSessionFlag returns true if given agentVersion supports TerminateSession flag, false otherwise
 func DoesAgentSupportTerminateSessionFlag(log log.T, agentVersion string) (supported bool) {
 	return isAgentVersionGreaterThanSupportedVersion(log, agentVersion, config.TerminateSessionFlagSupportedAfterThisAgentVersion)
@@ -15,6 +15,7 @@
 package websocketutil
 
 import (
+	"context"
 	"errors"
 
 	"github.com/gorilla/websocket"
@@ -55,10 +56,16 @@ func NewWebsocketUtil(logger log.T, dialerInput *websocket.Dialer) *WebsocketUti
 
 // OpenConnection opens a websocket connection provided an input url.
 func (u *WebsocketUtil) OpenConnection(url string) (*websocket.Conn, error) {
+	return u.OpenConnectionContext(context.Background(), url)
+}
+
+// OpenConnectionContext opens a websocket connection provided an input url, dialing with ctx so
+// an httptrace.ClientTrace on it sees the dial.
+func (u *WebsocketUtil) OpenConnectionContext(ctx context.Context, url string) (*websocket.Conn, error) {
 
 	u.log.Infof("Opening websocket connection to: ", url)
 
-	conn, _, err := u.dialer.Dial(url, nil)
+	conn, _, err := u.dialer.DialContext(ctx, url, nil)
 	if err != nil {
 		u.log.Errorf("Failed to dial websocket: %s", err.Error())
 		return nil, err